@@ -0,0 +1,265 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// This file defines the -diffalgo and -diffrev modes, which compare
+// two call graphs of a program.
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// doDiff reports the differences between two call graphs of the
+// program denoted by args: the old graph, constructed by algo from
+// revision rev of the enclosing git repository (or from the working
+// tree, if rev is empty), and the new graph, constructed by newAlgo
+// (or algo, if newAlgo is empty) from the working tree.
+func doDiff(dir, gopath, algo, newAlgo, rev string, tests bool, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, Usage)
+		return nil
+	}
+	if newAlgo == "" {
+		newAlgo = algo
+	}
+
+	prog, pkgs, err := loadProgram(dir, gopath, tests, args)
+	if err != nil {
+		return err
+	}
+	newSummary, err := summarize(prog, pkgs, newAlgo, false)
+	if err != nil {
+		return err
+	}
+
+	oldLabel, newLabel := algo, newAlgo
+	if rev != "" {
+		worktree, worktreeGopath, cleanup, err := checkout(dir, gopath, rev)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		prog, pkgs, err = loadProgram(worktree, worktreeGopath, tests, args)
+		if err != nil {
+			return fmt.Errorf("at revision %s: %v", rev, err)
+		}
+		oldLabel += "@" + rev
+	}
+	oldSummary, err := summarize(prog, pkgs, algo, true)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n", oldLabel)
+	fmt.Fprintf(&buf, "+++ %s\n", newLabel)
+	diffSets(&buf, oldSummary.funcs, newSummary.funcs)
+	diffSets(&buf, oldSummary.edges, newSummary.edges)
+	_, err = stdout.Write(buf.Bytes())
+	return err
+}
+
+// A summary records the reachable functions and the call edges of a
+// call graph, keyed by function names (see funcKey), which (unlike
+// functions and positions) are comparable across programs.
+type summary struct {
+	funcs map[string]bool // "pkg.f"
+	edges map[string]bool // "pkg.f --> pkg.g"
+}
+
+// summarize constructs the call graph of prog using algo and returns
+// a summary of its functions reachable from the -roots functions, or,
+// if there are none, from the init and main functions of its main
+// packages, if any. Only functions selected by -filter are included.
+// In the old graph, -roots functions that do not exist are ignored,
+// so that they appear as added.
+func summarize(prog *ssa.Program, pkgs []*ssa.Package, algo string, old bool) (*summary, error) {
+	cg, err := callGraph(prog, pkgs, algo)
+	if err != nil {
+		return nil, err
+	}
+	s := &summary{
+		funcs: make(map[string]bool),
+		edges: make(map[string]bool),
+	}
+	roots := *rootsFlag
+	if old && roots != "" {
+		roots = existingRoots(cg, roots)
+		if roots == "" {
+			return s, nil // no roots, so nothing is reachable
+		}
+	}
+	keep, err := selectNodes(cg, *filterFlag, roots)
+	if err != nil {
+		return nil, err
+	}
+	if roots == "" {
+		if mains, err := mainPackages(pkgs); err == nil {
+			var roots []*callgraph.Node
+			for _, main := range mains {
+				for _, fn := range []*ssa.Function{main.Func("init"), main.Func("main")} {
+					if n := cg.Nodes[fn]; fn != nil && n != nil {
+						roots = append(roots, n)
+					}
+				}
+			}
+			reach := reachable(roots)
+			if keep == nil {
+				keep = reach
+			} else {
+				for n := range keep {
+					if !reach[n] {
+						delete(keep, n)
+					}
+				}
+			}
+		}
+	}
+
+	for fn, n := range cg.Nodes {
+		if fn == nil || keep != nil && !keep[n] {
+			continue
+		}
+		caller := funcKey(fn)
+		s.funcs[caller] = true
+		for _, e := range n.Out {
+			if keep != nil && !keep[e.Callee] {
+				continue
+			}
+			callee := funcKey(e.Callee.Func)
+			if callee == caller && e.Callee.Func.Parent() != nil {
+				continue // call of a function literal by its enclosing function
+			}
+			s.edges[caller+" --> "+callee] = true
+		}
+	}
+	return s, nil
+}
+
+// funcKey returns the name by which fn is compared across call graphs.
+// A function literal is identified with the named function that
+// encloses it, since its own name, such as "pkg.f$2", changes
+// whenever a function literal is added before it.
+func funcKey(fn *ssa.Function) string {
+	for fn.Parent() != nil {
+		fn = fn.Parent()
+	}
+	return fn.String()
+}
+
+// existingRoots returns the comma-separated list of the functions in
+// roots that are nodes of cg.
+func existingRoots(cg *callgraph.Graph, roots string) string {
+	names := make(map[string]bool)
+	for fn := range cg.Nodes {
+		if fn != nil {
+			names[fn.String()] = true
+		}
+	}
+	var existing []string
+	for _, name := range strings.Split(roots, ",") {
+		if name = strings.TrimSpace(name); names[name] {
+			existing = append(existing, name)
+		}
+	}
+	return strings.Join(existing, ",")
+}
+
+// diffSets writes to buf a line for each element of old or new that
+// is not in the other set, prefixed by "-" or "+", respectively,
+// in sorted order.
+func diffSets(buf *bytes.Buffer, old, new map[string]bool) {
+	var lines []string
+	for k := range old {
+		if !new[k] {
+			lines = append(lines, "- "+k)
+		}
+	}
+	for k := range new {
+		if !old[k] {
+			lines = append(lines, "+ "+k)
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		// Sort by element, then removals before additions.
+		if x, y := lines[i][2:], lines[j][2:]; x != y {
+			return x < y
+		}
+		return lines[i] < lines[j]
+	})
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
+
+// checkout creates a temporary git worktree containing revision rev
+// of the repository enclosing dir. It returns the directories of the
+// worktree corresponding to dir and (if non-empty) gopath, and a
+// function that removes the worktree.
+func checkout(dir, gopath, rev string) (string, string, func(), error) {
+	git := func(args ...string) (string, error) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	top, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", nil, err
+	}
+	if real, err := filepath.EvalSymlinks(top); err == nil {
+		top = real
+	}
+	tmp, err := os.MkdirTemp("", "callgraph-diff-")
+	if err != nil {
+		return "", "", nil, err
+	}
+	if _, err := git("worktree", "add", "--detach", tmp, rev); err != nil {
+		os.RemoveAll(tmp)
+		return "", "", nil, err
+	}
+	cleanup := func() {
+		git("worktree", "remove", "--force", tmp) // ignore error
+		os.RemoveAll(tmp)
+	}
+
+	// relocate returns the path within the worktree corresponding
+	// to path, or path itself if it is outside the repository.
+	relocate := func(path string) string {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real
+		}
+		rel, err := filepath.Rel(top, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return path
+		}
+		return filepath.Join(tmp, rel)
+	}
+	worktreeGopath := gopath
+	if gopath != "" {
+		worktreeGopath = relocate(gopath)
+	}
+	return relocate(dir), worktreeGopath, cleanup, nil
+}
@@ -9,13 +9,9 @@ package main // import "golang.org/x/tools/cmd/callgraph"
 // TODO(adonovan):
 //
 // Features:
-// - restrict graph to a single package
 // - output
-//   - functions reachable from root (use digraph tool?)
 //   - unreachable functions (use digraph tool?)
 //   - dynamic (runtime) types
-//   - indexed output (numbered nodes)
-//   - JSON output
 //   - additional template fields:
 //     callee file/line/col
 
@@ -31,6 +27,7 @@ import (
 
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/kcfa"
 	"golang.org/x/tools/go/callgraph/rta"
 	"golang.org/x/tools/go/callgraph/static"
 	"golang.org/x/tools/go/callgraph/vta"
@@ -42,7 +39,9 @@ import (
 // flags
 var (
 	algoFlag = flag.String("algo", "rta",
-		`Call graph construction algorithm (static, cha, rta, vta)`)
+		`Call graph construction algorithm (static, cha, rta, vta, kcfa, kobj)`)
+
+	kFlag = flag.Int("k", 1, "Maximum context length for the kcfa and kobj algorithms")
 
 	testFlag = flag.Bool("test", false,
 		"Loads test code (*_test.go) for imported packages")
@@ -51,6 +50,14 @@ var (
 		"{{.Caller}}\t--{{.Dynamic}}-{{.Line}}:{{.Column}}-->\t{{.Callee}}",
 		"A template expression specifying how to format an edge")
 
+	filterFlag = flag.String("filter", "", "Report only functions of packages whose import path matches this regular expression")
+
+	rootsFlag = flag.String("roots", "", "Report only functions reachable from this comma-separated list of functions")
+
+	diffAlgoFlag = flag.String("diffalgo", "", "Report the differences between the call graphs constructed by -algo and this algorithm")
+
+	diffRevFlag = flag.String("diffrev", "", "Report the differences between the call graphs of this git revision and the working tree")
+
 	tagsFlag = flag.String("tags", "", "comma-separated list of extra build tags (see: go help buildconstraint)")
 )
 
@@ -58,7 +65,11 @@ const Usage = `callgraph: display the call graph of a Go program.
 
 Usage:
 
-  callgraph [-algo=static|cha|rta|vta] [-test] [-format=...] package...
+  callgraph [-algo=static|cha|rta|vta|kcfa|kobj] [-k=n] [-test] [-format=...]
+            [-filter=regexp] [-roots=func,...] package...
+
+  callgraph [-algo=...] [-diffalgo=...] [-diffrev=rev] [-test]
+            [-filter=regexp] [-roots=func,...] package...
 
 Flags:
 
@@ -68,11 +79,17 @@ Flags:
             cha         Class Hierarchy Analysis
             rta         Rapid Type Analysis
             vta         Variable Type Analysis
+            kcfa        context-sensitive analysis (call-site contexts)
+            kobj        context-sensitive analysis (object contexts)
 
            The algorithms are ordered by increasing precision in their
            treatment of dynamic calls (and thus also computational cost).
-           RTA requires a whole program (main or test), and
-           include only functions reachable from main.
+           RTA, kcfa and kobj require a whole program (main or test),
+           and include only functions reachable from main.
+
+-k         Specifies the maximum length of the contexts that
+           distinguish the analyses of a function by kcfa and kobj.
+           The default is 1; 0 makes them context-insensitive.
 
 -test      Include the package's tests in the analysis.
 
@@ -81,7 +98,17 @@ Flags:
 
             digraph     output suitable for input to
                         golang.org/x/tools/cmd/digraph.
-            graphviz    output in AT&T GraphViz (.dot) format.
+            graphviz    output in AT&T GraphViz (.dot) format, with the
+                        functions of each package grouped in a cluster
+                        and dynamic calls drawn dashed.
+            json        a JSON object with "nodes" and "edges" arrays;
+                        each edge refers to its caller and callee by
+                        the "id" of their node.
+            graphml     output in GraphML format.
+
+           The graphviz, json and graphml formats include functions that
+           make no calls and are not called, and list nodes and edges
+           in a deterministic order.
 
            All other values are interpreted using text/template syntax.
            The default value is:
@@ -120,6 +147,38 @@ Flags:
            Consult the documentation for go/token, text/template, and
            golang.org/x/tools/go/ssa for more detail.
 
+-filter    Report only the functions of packages whose import path
+           matches the specified regular expression, and the calls
+           among them.
+
+-roots     Report only the functions reachable from the specified
+           comma-separated list of functions, named as they are
+           printed, such as "main.main" or "(*main.T).f".
+           If both -roots and -filter are specified, the functions
+           reachable from the roots are computed first.
+
+-diffalgo  Instead of displaying the call graph, report the differences
+           between the graph constructed by -algo (old) and the graph
+           constructed by the specified algorithm (new).
+
+-diffrev   Instead of displaying the call graph, report the differences
+           between the graph of the program at the specified revision
+           of its git repository (old) and the graph of the program in
+           the working tree (new). The revision is checked out in a
+           temporary git worktree. May be combined with -diffalgo.
+
+           The report lists the reachable functions and the call edges
+           present in only one of the graphs, prefixed by "-" (old only)
+           or "+" (new only). Functions are reachable from the -roots
+           functions, or else from the init and main functions of the
+           main packages, if any. A -roots function absent from the
+           old graph is treated as added. Functions are identified by
+           name, so edges are compared without regard to their call
+           sites; function literals, whose names depend on the number
+           of literals that precede them, are identified with the named
+           function that encloses them. The -format flag may not be
+           used with -diffalgo or -diffrev.
+
 Examples:
 
   Show the call graph of the trivial web server application:
@@ -131,6 +190,12 @@ Examples:
     callgraph -format '{{.Caller.Pkg.Pkg.Path}} -> {{.Callee.Pkg.Pkg.Path}}' \
       $GOROOT/src/net/http/triv.go | sort | uniq
 
+  Show the calls within the net/http package, grouped by package,
+  in a form suitable for rendering with GraphViz:
+
+    callgraph -format=graphviz -filter='^net/http$' $GOROOT/src/net/http/triv.go |
+      dot -Tsvg > triv.svg
+
   Show functions that make dynamic calls into the 'fmt' test package,
   using the Rapid Type Analysis algorithm:
 
@@ -138,6 +203,14 @@ Examples:
       sed -ne 's/-dynamic-/--/p' |
       sed -ne 's/-->.*fmt_test.*$//p' | sort | uniq
 
+  Show the calls that VTA proves impossible, relative to CHA:
+
+    callgraph -algo=cha -diffalgo=vta $GOROOT/src/net/http/triv.go | grep '^- '
+
+  Show the call edges added since the previous commit:
+
+    callgraph -diffrev=HEAD~1 ./cmd/myprogram | grep '^+ .* --> '
+
   Show all functions directly called by the callgraph tool's main function:
 
     callgraph -format=digraph golang.org/x/tools/cmd/callgraph |
@@ -158,6 +231,19 @@ func init() {
 
 func main() {
 	flag.Parse()
+	if *diffAlgoFlag != "" || *diffRevFlag != "" {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "format" {
+				fmt.Fprintln(os.Stderr, "callgraph: -format cannot be used with -diffalgo or -diffrev")
+				os.Exit(2)
+			}
+		})
+		if err := doDiff("", "", *algoFlag, *diffAlgoFlag, *diffRevFlag, *testFlag, flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "callgraph: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := doCallgraph("", "", *algoFlag, *formatFlag, *testFlag, flag.Args()); err != nil {
 		fmt.Fprintf(os.Stderr, "callgraph: %s\n", err)
 		os.Exit(1)
@@ -172,6 +258,78 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 		return nil
 	}
 
+	prog, pkgs, err := loadProgram(dir, gopath, tests, args)
+	if err != nil {
+		return err
+	}
+	cg, err := callGraph(prog, pkgs, algo)
+	if err != nil {
+		return err
+	}
+
+	keep, err := selectNodes(cg, *filterFlag, *rootsFlag)
+	if err != nil {
+		return err
+	}
+
+	// -- output------------------------------------------------------------
+
+	// Pre-canned formats.
+	switch format {
+	case "json":
+		return makeGraph(prog.Fset, cg, keep).writeJSON(stdout)
+
+	case "graphviz":
+		return makeGraph(prog.Fset, cg, keep).writeDOT(stdout)
+
+	case "graphml":
+		return makeGraph(prog.Fset, cg, keep).writeGraphML(stdout)
+
+	case "digraph":
+		format = `{{printf "%q %q" .Caller .Callee}}`
+	}
+
+	funcMap := template.FuncMap{
+		"posn": func(f *ssa.Function) token.Position {
+			return f.Prog.Fset.Position(f.Pos())
+		},
+	}
+	tmpl, err := template.New("-format").Funcs(funcMap).Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid -format template: %v", err)
+	}
+
+	// Allocate these once, outside the traversal.
+	var buf bytes.Buffer
+	data := Edge{fset: prog.Fset}
+
+	if err := callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
+		if keep != nil && !(keep[edge.Caller] && keep[edge.Callee]) {
+			return nil
+		}
+		data.position.Offset = -1
+		data.edge = edge
+		data.Caller = edge.Caller.Func
+		data.Callee = edge.Callee.Func
+
+		buf.Reset()
+		if err := tmpl.Execute(&buf, &data); err != nil {
+			return err
+		}
+		stdout.Write(buf.Bytes())
+		if len := buf.Len(); len == 0 || buf.Bytes()[len-1] != '\n' {
+			fmt.Fprintln(stdout)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// loadProgram loads the packages denoted by args, and their
+// dependencies, and builds the SSA representation of the program.
+func loadProgram(dir, gopath string, tests bool, args []string) (*ssa.Program, []*ssa.Package, error) {
 	cfg := &packages.Config{
 		Mode:       packages.LoadAllSyntax,
 		BuildFlags: []string{"-tags=" + *tagsFlag},
@@ -183,19 +341,22 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 	}
 	initial, err := packages.Load(cfg, args...)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if packages.PrintErrors(initial) > 0 {
-		return fmt.Errorf("packages contain errors")
+		return nil, nil, fmt.Errorf("packages contain errors")
 	}
 
 	// Create and build SSA-form program representation.
 	mode := ssa.InstantiateGenerics // instantiate generics by default for soundness
 	prog, pkgs := ssautil.AllPackages(initial, mode)
 	prog.Build()
+	return prog, pkgs, nil
+}
 
-	// -- call graph construction ------------------------------------------
-
+// callGraph constructs the call graph of prog using the specified
+// algorithm, without synthetic nodes.
+func callGraph(prog *ssa.Program, pkgs []*ssa.Package, algo string) (*callgraph.Graph, error) {
 	var cg *callgraph.Graph
 
 	switch algo {
@@ -206,12 +367,12 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 		cg = cha.CallGraph(prog)
 
 	case "pta":
-		return fmt.Errorf("pointer analysis is no longer supported (see Go issue #59676)")
+		return nil, fmt.Errorf("pointer analysis is no longer supported (see Go issue #59676)")
 
 	case "rta":
 		mains, err := mainPackages(pkgs)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		var roots []*ssa.Function
 		for _, main := range mains {
@@ -225,62 +386,27 @@ func doCallgraph(dir, gopath, algo, format string, tests bool, args []string) er
 	case "vta":
 		cg = vta.CallGraph(ssautil.AllFunctions(prog), nil)
 
+	case "kcfa", "kobj":
+		mains, err := mainPackages(pkgs)
+		if err != nil {
+			return nil, err
+		}
+		var roots []*ssa.Function
+		for _, main := range mains {
+			roots = append(roots, main.Func("init"), main.Func("main"))
+		}
+		config := &kcfa.Config{K: *kFlag, Sensitivity: kcfa.CallSite}
+		if algo == "kobj" {
+			config.Sensitivity = kcfa.Object
+		}
+		cg = kcfa.Analyze(roots, config).CallGraph
+
 	default:
-		return fmt.Errorf("unknown algorithm: %s", algo)
+		return nil, fmt.Errorf("unknown algorithm: %s", algo)
 	}
 
 	cg.DeleteSyntheticNodes()
-
-	// -- output------------------------------------------------------------
-
-	var before, after string
-
-	// Pre-canned formats.
-	switch format {
-	case "digraph":
-		format = `{{printf "%q %q" .Caller .Callee}}`
-
-	case "graphviz":
-		before = "digraph callgraph {\n"
-		after = "}\n"
-		format = `  {{printf "%q" .Caller}} -> {{printf "%q" .Callee}}`
-	}
-
-	funcMap := template.FuncMap{
-		"posn": func(f *ssa.Function) token.Position {
-			return f.Prog.Fset.Position(f.Pos())
-		},
-	}
-	tmpl, err := template.New("-format").Funcs(funcMap).Parse(format)
-	if err != nil {
-		return fmt.Errorf("invalid -format template: %v", err)
-	}
-
-	// Allocate these once, outside the traversal.
-	var buf bytes.Buffer
-	data := Edge{fset: prog.Fset}
-
-	fmt.Fprint(stdout, before)
-	if err := callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
-		data.position.Offset = -1
-		data.edge = edge
-		data.Caller = edge.Caller.Func
-		data.Callee = edge.Callee.Func
-
-		buf.Reset()
-		if err := tmpl.Execute(&buf, &data); err != nil {
-			return err
-		}
-		stdout.Write(buf.Bytes())
-		if len := buf.Len(); len == 0 || buf.Bytes()[len-1] != '\n' {
-			fmt.Fprintln(stdout)
-		}
-		return nil
-	}); err != nil {
-		return err
-	}
-	fmt.Fprint(stdout, after)
-	return nil
+	return cg, nil
 }
 
 // mainPackages returns the main packages to analyze.
@@ -319,11 +445,6 @@ func (e *Edge) Column() int      { return e.pos().Column }
 func (e *Edge) Line() int        { return e.pos().Line }
 func (e *Edge) Offset() int      { return e.pos().Offset }
 
-func (e *Edge) Dynamic() string {
-	if e.edge.Site != nil && e.edge.Site.Common().StaticCallee() == nil {
-		return "dynamic"
-	}
-	return "static"
-}
+func (e *Edge) Dynamic() string { return edgeKind(e.edge) }
 
 func (e *Edge) Description() string { return e.edge.Description() }
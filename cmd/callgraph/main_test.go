@@ -11,10 +11,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 
@@ -60,6 +63,16 @@ func TestCallgraph(t *testing.T) {
 			"pkg.main --> pkg.main2",
 			"pkg.main2 --> (pkg.D).f",
 		}},
+		{"kcfa", false, []string{
+			"pkg.main --> (pkg.C).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.D).f",
+		}},
+		{"kobj", false, []string{
+			"pkg.main --> (pkg.C).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.D).f",
+		}},
 		// tests: both the package's main and the test's main are called.
 		// The callgraph includes all the guts of the "testing" package.
 		{"rta", true, []string{
@@ -99,3 +112,260 @@ func TestCallgraph(t *testing.T) {
 		}
 	}
 }
+
+func TestFormats(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(format, filter, roots string) string {
+		defer func(filter, roots string) {
+			*filterFlag, *rootsFlag = filter, roots
+		}(*filterFlag, *rootsFlag)
+		*filterFlag, *rootsFlag = filter, roots
+
+		var out bytes.Buffer
+		stdout = &out
+		if err := doCallgraph("testdata/src", gopath, "vta", format, false, []string{"pkg"}); err != nil {
+			t.Fatalf("callgraph(-format=%s -filter=%q -roots=%q): %v", format, filter, roots, err)
+		}
+		return out.String()
+	}
+
+	// json
+	var data struct {
+		Nodes []struct {
+			ID   int
+			Func string
+		}
+		Edges []struct {
+			Caller, Callee int
+			Kind           string
+		}
+	}
+	if err := json.Unmarshal([]byte(run("json", "", "")), &data); err != nil {
+		t.Fatal(err)
+	}
+	var edges []string
+	for _, e := range data.Edges {
+		edges = append(edges, fmt.Sprintf("%s -%s-> %s",
+			data.Nodes[e.Caller].Func, e.Kind, data.Nodes[e.Callee].Func))
+	}
+	got := strings.Join(edges, "\n")
+	want := strings.Join([]string{
+		"pkg.main -dynamic-> (pkg.C).f",
+		"pkg.main -static-> pkg.main2",
+		"pkg.main2 -dynamic-> (pkg.D).f",
+	}, "\n")
+	if got != want {
+		t.Errorf("json edges:\n%s\nwant:\n%s", got, want)
+	}
+
+	// graphviz
+	dot := run("graphviz", "", "")
+	for _, want := range []string{
+		`subgraph "cluster_pkg" {`,
+		`label="main2"`,
+		`[style=dashed];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("graphviz output does not contain %q:\n%s", want, dot)
+		}
+	}
+
+	// graphml
+	var doc struct {
+		Nodes []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal([]byte(run("graphml", "", "")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Edges) != 3 {
+		t.Errorf("graphml: got %d edges, want 3", len(doc.Edges))
+	}
+
+	// The output is deterministic.
+	for _, format := range []string{"json", "graphviz", "graphml"} {
+		if a, b := run(format, "", ""), run(format, "", ""); a != b {
+			t.Errorf("%s output is not deterministic:\n%s\nvs:\n%s", format, a, b)
+		}
+	}
+
+	// filtering
+	for _, test := range []struct {
+		filter, roots string
+		want          []string
+	}{
+		{"", "pkg.main2", []string{
+			"pkg.main2 --> (pkg.D).f",
+		}},
+		{"^pkg$", "", []string{
+			"pkg.main --> (pkg.C).f",
+			"pkg.main --> pkg.main2",
+			"pkg.main2 --> (pkg.D).f",
+		}},
+		{"^fmt$", "", nil},
+	} {
+		var got []string
+		for _, line := range strings.Split(run("{{.Caller}} --> {{.Callee}}", test.filter, test.roots), "\n") {
+			if line != "" {
+				got = append(got, line)
+			}
+		}
+		sort.Strings(got)
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("callgraph(-filter=%q -roots=%q) = %q, want %q", test.filter, test.roots, got, test.want)
+		}
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"pkg.f", `"pkg.f"`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"π.f$1\t", "\"π.f$1\t\""}, // no \u or \t escapes
+	} {
+		if got := dotQuote(test.in); got != test.want {
+			t.Errorf("dotQuote(%q) = %s, want %s", test.in, got, test.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	gopath, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	stdout = &out
+	if err := doDiff("testdata/src", gopath, "cha", "vta", "", false, []string{"pkg"}); err != nil {
+		t.Fatal(err)
+	}
+	// vta distinguishes main->C, main2->D.
+	want := `--- cha
++++ vta
+- pkg.main --> (pkg.D).f
+- pkg.main2 --> (pkg.C).f
+`
+	if got := out.String(); got != want {
+		t.Errorf("-algo=cha -diffalgo=vta:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffRev(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+	testenv.NeedsTool(t, "git")
+
+	// Create a repository whose working tree replaces
+	// the dynamic call in main2 by a call to a new function.
+	src, err := os.ReadFile("testdata/src/pkg/pkg.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gopath, write := gitRepo(t, string(src))
+	write(strings.Replace(string(src), "\tvar i I = D(0)\n\ti.f() // dynamic call\n}", "\tmain3()\n}\n\nfunc main3() {}", 1))
+
+	var out bytes.Buffer
+	stdout = &out
+	if err := doDiff(filepath.Join(gopath, "src"), gopath, "vta", "", "HEAD", false, []string{"pkg"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- vta@HEAD
++++ vta
+- (pkg.D).f
++ pkg.main3
+- pkg.main2 --> (pkg.D).f
++ pkg.main2 --> pkg.main3
+`
+	if got := out.String(); got != want {
+		t.Errorf("-diffrev=HEAD:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A root that does not exist at the old revision is added.
+	defer func(roots string) { *rootsFlag = roots }(*rootsFlag)
+	*rootsFlag = "pkg.main3"
+	out.Reset()
+	if err := doDiff(filepath.Join(gopath, "src"), gopath, "vta", "", "HEAD", false, []string{"pkg"}); err != nil {
+		t.Fatal(err)
+	}
+	want = `--- vta@HEAD
++++ vta
++ pkg.main3
+`
+	if got := out.String(); got != want {
+		t.Errorf("-diffrev=HEAD -roots=pkg.main3:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestDiffRevClosures checks that adding a function literal does not
+// change the names by which the function literals after it are
+// compared.
+func TestDiffRevClosures(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+	testenv.NeedsTool(t, "git")
+
+	src := `package main
+
+func f() {}
+
+func g() {}
+
+func main() {
+	func() { f() }()
+}
+`
+	gopath, write := gitRepo(t, src)
+	write(strings.Replace(src, "func main() {\n", "func main() {\n\tfunc() { g() }()\n", 1))
+
+	var out bytes.Buffer
+	stdout = &out
+	if err := doDiff(filepath.Join(gopath, "src"), gopath, "static", "", "HEAD", false, []string{"pkg"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `--- static@HEAD
++++ static
++ pkg.g
++ pkg.main --> pkg.g
+`
+	if got := out.String(); got != want {
+		t.Errorf("-diffrev=HEAD:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// gitRepo creates a GOPATH containing a git repository whose
+// committed package "pkg" has the specified source. It returns the
+// GOPATH and a function that replaces the source in the working tree.
+func gitRepo(t *testing.T, src string) (string, func(string)) {
+	gopath := t.TempDir()
+	dir := filepath.Join(gopath, "src", "pkg")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	write := func(src string) {
+		if err := os.WriteFile(filepath.Join(dir, "pkg.go"), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git := func(args ...string) {
+		cmd := testenv.Command(t, "git", append([]string{"-c", "user.name=gopher", "-c", "user.email=gopher@example.com"}, args...)...)
+		cmd.Dir = gopath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	write(src)
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+	return gopath, write
+}
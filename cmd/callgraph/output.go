@@ -0,0 +1,366 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// This file defines the structured output formats (json, graphviz,
+// graphml) and the filtering of the call graph.
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"go/token"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// selectNodes returns the set of nodes of cg to be displayed: those
+// reachable from the comma-separated list of named root functions (if
+// any) that belong to a package whose path matches the filter
+// regular expression (if any). It returns nil if all nodes are to be
+// displayed.
+func selectNodes(cg *callgraph.Graph, filter, roots string) (map[*callgraph.Node]bool, error) {
+	if filter == "" && roots == "" {
+		return nil, nil
+	}
+
+	var keep map[*callgraph.Node]bool
+	if roots != "" {
+		byName := make(map[string]*callgraph.Node)
+		for fn, n := range cg.Nodes {
+			if fn != nil {
+				byName[fn.String()] = n
+			}
+		}
+		var rootNodes []*callgraph.Node
+		for _, name := range strings.Split(roots, ",") {
+			name = strings.TrimSpace(name)
+			n, ok := byName[name]
+			if !ok {
+				return nil, fmt.Errorf("no function %q in call graph", name)
+			}
+			rootNodes = append(rootNodes, n)
+		}
+		keep = reachable(rootNodes)
+	} else {
+		keep = make(map[*callgraph.Node]bool)
+		for _, n := range cg.Nodes {
+			keep[n] = true
+		}
+	}
+
+	if filter != "" {
+		rx, err := regexp.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -filter regular expression: %v", err)
+		}
+		for n := range keep {
+			if n.Func == nil || !rx.MatchString(pkgPath(n.Func)) {
+				delete(keep, n)
+			}
+		}
+	}
+	return keep, nil
+}
+
+// reachable returns the set of nodes reachable from roots.
+func reachable(roots []*callgraph.Node) map[*callgraph.Node]bool {
+	seen := make(map[*callgraph.Node]bool)
+	var visit func(n *callgraph.Node)
+	visit = func(n *callgraph.Node) {
+		if !seen[n] {
+			seen[n] = true
+			for _, e := range n.Out {
+				visit(e.Callee)
+			}
+		}
+	}
+	for _, n := range roots {
+		visit(n)
+	}
+	return seen
+}
+
+// pkgPath returns the import path of the package of fn,
+// or "" for a synthetic function that belongs to no package.
+func pkgPath(fn *ssa.Function) string {
+	if orig := fn.Origin(); orig != nil {
+		fn = orig
+	}
+	if fn.Pkg == nil {
+		return ""
+	}
+	return fn.Pkg.Pkg.Path()
+}
+
+// A graph is the selected portion of a call graph,
+// with nodes and edges in a deterministic order.
+type graph struct {
+	fset  *token.FileSet
+	nodes []*callgraph.Node
+	index map[*callgraph.Node]int // index of each node in nodes
+	edges []*callgraph.Edge
+}
+
+// makeGraph returns the portion of cg consisting of the nodes in keep
+// (or all nodes, if keep is nil) and the edges between them.
+func makeGraph(fset *token.FileSet, cg *callgraph.Graph, keep map[*callgraph.Node]bool) *graph {
+	g := &graph{fset: fset, index: make(map[*callgraph.Node]int)}
+	for fn, n := range cg.Nodes {
+		if fn != nil && (keep == nil || keep[n]) {
+			g.nodes = append(g.nodes, n)
+		}
+	}
+	sort.Slice(g.nodes, func(i, j int) bool {
+		x, y := g.nodes[i].Func, g.nodes[j].Func
+		if px, py := pkgPath(x), pkgPath(y); px != py {
+			return px < py
+		}
+		if x.Pos() != y.Pos() {
+			return x.Pos() < y.Pos()
+		}
+		return x.String() < y.String()
+	})
+	for i, n := range g.nodes {
+		g.index[n] = i
+	}
+	for _, n := range g.nodes {
+		for _, e := range n.Out {
+			if _, ok := g.index[e.Callee]; ok {
+				g.edges = append(g.edges, e)
+			}
+		}
+	}
+	sort.SliceStable(g.edges, func(i, j int) bool {
+		x, y := g.edges[i], g.edges[j]
+		if x.Caller != y.Caller {
+			return g.index[x.Caller] < g.index[y.Caller]
+		}
+		if x.Pos() != y.Pos() {
+			return x.Pos() < y.Pos()
+		}
+		return g.index[x.Callee] < g.index[y.Callee]
+	})
+	return g
+}
+
+// edgeKind returns "dynamic" or "static" according to the call site of e.
+func edgeKind(e *callgraph.Edge) string {
+	if e.Site != nil && e.Site.Common().StaticCallee() == nil {
+		return "dynamic"
+	}
+	return "static"
+}
+
+// -- JSON --
+
+// The JSON output is a single object of type jsonGraph.
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonNode struct {
+	ID      int           `json:"id"`
+	Func    string        `json:"func"`
+	Package string        `json:"package,omitempty"`
+	Pos     *jsonPosition `json:"pos,omitempty"` // declaration of the function
+}
+
+type jsonEdge struct {
+	Caller      int           `json:"caller"` // index of caller node
+	Callee      int           `json:"callee"` // index of callee node
+	Kind        string        `json:"kind"`   // "static" or "dynamic"
+	Description string        `json:"description"`
+	Pos         *jsonPosition `json:"pos,omitempty"` // call site
+}
+
+type jsonPosition struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Offset   int    `json:"offset"`
+}
+
+func (g *graph) position(pos token.Pos) *jsonPosition {
+	if !pos.IsValid() {
+		return nil
+	}
+	posn := g.fset.Position(pos)
+	return &jsonPosition{posn.Filename, posn.Line, posn.Column, posn.Offset}
+}
+
+func (g *graph) writeJSON(out io.Writer) error {
+	data := jsonGraph{
+		Nodes: []jsonNode{},
+		Edges: []jsonEdge{},
+	}
+	for i, n := range g.nodes {
+		data.Nodes = append(data.Nodes, jsonNode{
+			ID:      i,
+			Func:    n.Func.String(),
+			Package: pkgPath(n.Func),
+			Pos:     g.position(n.Func.Pos()),
+		})
+	}
+	for _, e := range g.edges {
+		data.Edges = append(data.Edges, jsonEdge{
+			Caller:      g.index[e.Caller],
+			Callee:      g.index[e.Callee],
+			Kind:        edgeKind(e),
+			Description: e.Description(),
+			Pos:         g.position(e.Pos()),
+		})
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "\t")
+	return enc.Encode(data)
+}
+
+// -- DOT --
+
+// writeDOT writes the graph in Graphviz (.dot) format, grouping the
+// functions of each package in a cluster. Dynamic calls are dashed.
+func (g *graph) writeDOT(out io.Writer) error {
+	var buf strings.Builder
+	buf.WriteString("digraph callgraph {\n")
+	buf.WriteString("\tnode [shape=box];\n")
+	for i := 0; i < len(g.nodes); {
+		// Nodes are sorted by package.
+		path := pkgPath(g.nodes[i].Func)
+		indent := "\t"
+		if path != "" {
+			fmt.Fprintf(&buf, "\tsubgraph %s {\n", dotQuote("cluster_"+path))
+			fmt.Fprintf(&buf, "\t\tlabel=%s;\n", dotQuote(path))
+			indent = "\t\t"
+		}
+		for ; i < len(g.nodes) && pkgPath(g.nodes[i].Func) == path; i++ {
+			fn := g.nodes[i].Func
+			label := fn.String()
+			if fn.Pkg != nil {
+				label = fn.RelString(fn.Pkg.Pkg)
+			}
+			fmt.Fprintf(&buf, "%sn%d [label=%s];\n", indent, i, dotQuote(label))
+		}
+		if path != "" {
+			buf.WriteString("\t}\n")
+		}
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&buf, "\tn%d -> n%d", g.index[e.Caller], g.index[e.Callee])
+		if edgeKind(e) == "dynamic" {
+			buf.WriteString(" [style=dashed]")
+		}
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+	_, err := io.WriteString(out, buf.String())
+	return err
+}
+
+// dotQuote returns s as a DOT quoted string. Unlike a Go string
+// literal, it escapes only quotation marks and backslashes: DOT
+// strings may contain any UTF-8 text, and have no \uXXXX escapes.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// -- GraphML --
+
+type graphmlDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML writes the graph in GraphML format.
+func (g *graph) writeGraphML(out io.Writer) error {
+	doc := graphmlDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphmlKey{
+			{"func", "node", "func", "string"},
+			{"package", "node", "package", "string"},
+			{"pos", "all", "pos", "string"},
+			{"kind", "edge", "kind", "string"},
+			{"description", "edge", "description", "string"},
+		},
+		Graph: graphmlGraph{ID: "callgraph", EdgeDefault: "directed"},
+	}
+	posn := func(pos token.Pos) string {
+		if !pos.IsValid() {
+			return ""
+		}
+		return g.fset.Position(pos).String()
+	}
+	for i, n := range g.nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphmlNode{
+			ID: fmt.Sprintf("n%d", i),
+			Data: []graphmlData{
+				{"func", n.Func.String()},
+				{"package", pkgPath(n.Func)},
+				{"pos", posn(n.Func.Pos())},
+			},
+		})
+	}
+	for i, e := range g.edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: fmt.Sprintf("n%d", g.index[e.Caller]),
+			Target: fmt.Sprintf("n%d", g.index[e.Callee]),
+			Data: []graphmlData{
+				{"kind", edgeKind(e)},
+				{"description", e.Description()},
+				{"pos", posn(e.Pos())},
+			},
+		})
+	}
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
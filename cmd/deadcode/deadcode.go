@@ -27,6 +27,7 @@ import (
 	"github.com/troll-zhao/tools/core/typesinternal"
 	"golang.org/x/telemetry"
 	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/kcfa"
 	"golang.org/x/tools/go/callgraph/rta"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/ssa"
@@ -39,6 +40,8 @@ var doc string
 // flags
 var (
 	testFlag = flag.Bool("test", false, "include implicit test packages and executables")
+	algoFlag = flag.String("algo", "rta", "call graph construction algorithm (rta, kcfa, kobj)")
+	kFlag    = flag.Int("k", 1, "maximum context length for the kcfa and kobj algorithms")
 	tagsFlag = flag.String("tags", "", "comma-separated list of extra build tags (see: go help buildconstraint)")
 
 	filterFlag    = flag.String("filter", "<module>", "report only packages matching this regular expression (default: module of first package)")
@@ -101,7 +104,12 @@ func main() {
 		}()
 	}
 
-	// Reject bad output options early.
+	// Reject bad options early.
+	switch *algoFlag {
+	case "rta", "kcfa", "kobj":
+	default:
+		log.Fatalf("unknown -algo %q (want rta, kcfa or kobj)", *algoFlag)
+	}
 	if *formatFlag != "" {
 		if *jsonFlag {
 			log.Fatalf("you cannot specify both -f=template and -json")
@@ -182,8 +190,30 @@ func main() {
 	})
 
 	// Compute the reachabilty from main.
-	// (Build a call graph only for -whylive.)
-	res := rta.Analyze(roots, *whyLiveFlag != "")
+	// (RTA builds a call graph only for -whylive.)
+	var (
+		reachable []*ssa.Function
+		cg        *callgraph.Graph
+	)
+	switch *algoFlag {
+	case "rta":
+		res := rta.Analyze(roots, *whyLiveFlag != "")
+		for fn := range res.Reachable {
+			reachable = append(reachable, fn)
+		}
+		cg = res.CallGraph
+
+	case "kcfa", "kobj":
+		config := &kcfa.Config{K: *kFlag, Sensitivity: kcfa.CallSite}
+		if *algoFlag == "kobj" {
+			config.Sensitivity = kcfa.Object
+		}
+		res := kcfa.Analyze(roots, config)
+		for fn := range res.Contexts {
+			reachable = append(reachable, fn)
+		}
+		cg = res.CallGraph
+	}
 
 	// Subtle: the -test flag causes us to analyze test variants
 	// such as "package p as compiled for p.test" or even "for q.test".
@@ -197,7 +227,7 @@ func main() {
 	// (We use Position not Pos to avoid assuming that files common
 	// to packages "p" and "p [p.test]" were parsed only once.)
 	reachablePosn := make(map[token.Position]bool)
-	for fn := range res.Reachable {
+	for _, fn := range reachable {
 		if fn.Pos().IsValid() || fn.Name() == "init" {
 			reachablePosn[prog.Fset.Position(fn.Pos())] = true
 		}
@@ -239,8 +269,8 @@ func main() {
 			log.Fatalf("function %s is dead code", *whyLiveFlag)
 		}
 
-		res.CallGraph.DeleteSyntheticNodes() // inline synthetic wrappers (except inits)
-		root, path := pathSearch(roots, res, targets)
+		cg.DeleteSyntheticNodes() // inline synthetic wrappers (except inits)
+		root, path := pathSearch(roots, cg, targets)
 		if root == nil {
 			// RTA doesn't add callgraph edges for reflective calls.
 			log.Fatalf("%s is reachable only through reflection", *whyLiveFlag)
@@ -455,7 +485,7 @@ func generator(file *ast.File) (string, bool) {
 
 // pathSearch returns the shortest path from one of the roots to one
 // of the targets (along with the root itself), or zero if no path was found.
-func pathSearch(roots []*ssa.Function, res *rta.Result, targets map[*ssa.Function]bool) (*callgraph.Node, []*callgraph.Edge) {
+func pathSearch(roots []*ssa.Function, cg *callgraph.Graph, targets map[*ssa.Function]bool) (*callgraph.Node, []*callgraph.Edge) {
 	// Search breadth-first (for shortest path) from the root.
 	//
 	// We don't use the virtual CallGraph.Root node as we wish to
@@ -520,7 +550,7 @@ func pathSearch(roots []*ssa.Function, res *rta.Result, targets map[*ssa.Functio
 			return nil
 		}
 		for _, rootFn := range roots {
-			root := res.CallGraph.Nodes[rootFn]
+			root := cg.Nodes[rootFn]
 			if root == nil {
 				// Missing call graph node for root.
 				// TODO(adonovan): seems like a bug in rta.
@@ -39,6 +39,16 @@ will fail to recognize that calls to a linkname-annotated function
 with no body in fact dispatch to the function named in the annotation.
 This may result in the latter function being spuriously reported as dead.
 
+The -algo flag selects the algorithm used to build the call graph.
+The default, rta, is fast and accounts for reflection. The kcfa and
+kobj algorithms perform a context-sensitive analysis of the flow of
+function values and interface values (see the -k flag and package
+golang.org/x/tools/go/callgraph/kcfa), which may reveal more dead code
+in programs that make heavy use of dynamic calls, at greater cost.
+However, they do not account for functions that are called only
+through reflection, such as exported methods of types passed to
+fmt.Println, so they may report such functions as dead.
+
 By default, the tool does not report dead functions in generated files,
 as determined by the special comment described in
 https://go.dev/s/generatedcode. Use the -generated flag to include them.
@@ -0,0 +1,381 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// This file defines the -html flag, which renders the SSA form of a
+// function as an HTML page, in the manner of the compiler's
+// GOSSAFUNC=f ssa.html file.
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"go/types"
+	"html"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// writeHTML writes to the named file, overwriting it, a page displaying
+// the functions of pkgs named name, along with their instances and
+// anonymous functions. The name may be qualified, as in "fmt.Println",
+// or relative to the package of the function, as in "(*T).String".
+func writeHTML(filename string, prog *ssa.Program, pkgs []*ssa.Package, name string) error {
+	isInitial := make(map[*ssa.Package]bool)
+	for _, pkg := range pkgs {
+		isInitial[pkg] = true
+	}
+	var fns []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Blocks != nil && htmlSelects(fn, name, isInitial) {
+			fns = append(fns, fn)
+		}
+	}
+	if len(fns) == 0 {
+		return fmt.Errorf("-html: no function named %q", name)
+	}
+	sort.Slice(fns, func(i, j int) bool {
+		x, y := fns[i], fns[j]
+		if x.Pos() != y.Pos() {
+			return x.Pos() < y.Pos()
+		}
+		return x.String() < y.String()
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, htmlHeader, html.EscapeString(name))
+	for k, fn := range fns {
+		w := &htmlWriter{buf: &buf, fset: prog.Fset, fn: fn, prefix: fmt.Sprintf("f%d-", k)}
+		w.function()
+	}
+	buf.WriteString(htmlFooter)
+
+	if err := os.WriteFile(filename, buf.Bytes(), 0666); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "dumped SSA to %s\n", filename)
+	return nil
+}
+
+// htmlSelects reports whether -html=name selects fn: whether fn, its
+// generic origin, or an enclosing function is named name and belongs
+// to one of the initial packages.
+func htmlSelects(fn *ssa.Function, name string, isInitial map[*ssa.Package]bool) bool {
+	for ; fn != nil; fn = fn.Parent() {
+		f := fn
+		if orig := fn.Origin(); orig != nil {
+			f = orig
+		}
+		if f.Pkg != nil && isInitial[f.Pkg] &&
+			(name == f.String() || name == f.RelString(f.Pkg.Pkg)) {
+			return true
+		}
+	}
+	return false
+}
+
+// An htmlWriter renders one function. The ids of its HTML elements
+// begin with prefix, so that they are unique within the page.
+type htmlWriter struct {
+	buf    *bytes.Buffer
+	fset   *token.FileSet
+	fn     *ssa.Function
+	prefix string
+
+	instrIDs map[ssa.Instruction]string // id of the element of each instruction
+	locals   map[ssa.Value]bool         // parameters, free variables and instructions of fn
+	lines    map[int]bool               // lines of source displayed
+}
+
+func (w *htmlWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(w.buf, format, args...)
+}
+
+func (w *htmlWriter) function() {
+	fn := w.fn
+	w.instrIDs = make(map[ssa.Instruction]string)
+	w.locals = make(map[ssa.Value]bool)
+	for _, p := range fn.Params {
+		w.locals[p] = true
+	}
+	for _, fv := range fn.FreeVars {
+		w.locals[fv] = true
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			w.instrIDs[instr] = fmt.Sprintf("%si%d", w.prefix, len(w.instrIDs))
+			if v, ok := instr.(ssa.Value); ok {
+				w.locals[v] = true
+			}
+		}
+	}
+
+	w.printf("<h2>%s</h2>\n", html.EscapeString(fn.String()))
+	w.printf("<p class=\"info\">")
+	if pos := fn.Pos(); pos.IsValid() {
+		w.printf("%s", html.EscapeString(w.fset.Position(pos).String()))
+	}
+	if fn.Synthetic != "" {
+		w.printf(" (%s)", html.EscapeString(fn.Synthetic))
+	}
+	w.printf("</p>\n")
+	w.printf("<table class=\"func\"><tr>\n")
+	w.printf("<th>sources</th><th>ssa</th><th>dominator tree</th>\n</tr><tr>\n")
+	w.printf("<td class=\"column\">\n")
+	w.source()
+	w.printf("</td>\n<td class=\"column\">\n")
+	w.blocks()
+	w.printf("</td>\n<td class=\"column\">\n")
+	w.domTree()
+	w.printf("</td>\n</tr></table>\n")
+}
+
+// source displays the lines of the syntax of the function.
+func (w *htmlWriter) source() {
+	w.lines = make(map[int]bool)
+	syntax := w.fn.Syntax()
+	if syntax == nil {
+		w.printf("<p class=\"info\">(no syntax)</p>\n")
+		return
+	}
+	start, end := w.fset.Position(syntax.Pos()), w.fset.Position(syntax.End())
+	data, err := os.ReadFile(start.Filename)
+	if err != nil {
+		w.printf("<p class=\"info\">%s</p>\n", html.EscapeString(err.Error()))
+		return
+	}
+	lines := strings.Split(string(data), "\n")
+	w.printf("<p class=\"info\">%s</p>\n", html.EscapeString(start.Filename))
+	w.printf("<ul class=\"source\">\n")
+	for line := start.Line; line <= end.Line && line <= len(lines); line++ {
+		w.lines[line] = true
+		w.printf("<li class=\"line\" id=\"%sL%d\"><span class=\"lineno\">%d</span>%s</li>\n",
+			w.prefix, line, line, html.EscapeString(lines[line-1]))
+	}
+	w.printf("</ul>\n")
+}
+
+// blocks displays the instructions of each block of the function.
+func (w *htmlWriter) blocks() {
+	fn := w.fn
+	var from *types.Package
+	if fn.Pkg != nil {
+		from = fn.Pkg.Pkg
+	}
+	qualifier := types.RelativeTo(from)
+
+	w.printf("<pre class=\"signature\">")
+	for _, p := range fn.Params {
+		w.printf("%s %s\n", w.value(p), html.EscapeString(types.TypeString(p.Type(), qualifier)))
+	}
+	for _, fv := range fn.FreeVars {
+		w.printf("%s %s (free)\n", w.value(fv), html.EscapeString(types.TypeString(fv.Type(), qualifier)))
+	}
+	w.printf("</pre>\n")
+
+	for _, b := range fn.Blocks {
+		w.printf("<div class=\"block\" id=\"%s\">\n", w.blockID(b))
+		w.printf("<p class=\"blockhead\">%s:", w.blockRef(b))
+		if b.Comment != "" {
+			w.printf(" <span class=\"comment\">%s</span>", html.EscapeString(b.Comment))
+		}
+		w.printf(" preds:%s succs:%s", w.blockRefs(b.Preds), w.blockRefs(b.Succs))
+		if idom := b.Idom(); idom != nil {
+			w.printf(" idom: %s", w.blockRef(idom))
+		}
+		w.printf("</p>\n<ul class=\"instrs\">\n")
+		for _, instr := range b.Instrs {
+			w.printf("<li class=\"instr\" id=\"%s\"", w.instrIDs[instr])
+			if pos := instr.Pos(); pos.IsValid() {
+				if line := w.fset.Position(pos).Line; w.lines[line] {
+					w.printf(" data-line=\"%sL%d\"", w.prefix, line)
+				}
+			}
+			w.printf(">")
+			w.instr(instr, qualifier)
+			w.printf("</li>\n")
+		}
+		w.printf("</ul>\n</div>\n")
+	}
+}
+
+// An operandMarker temporarily replaces an operand of an instruction,
+// so that its mention can be found in the text of the instruction.
+type operandMarker struct {
+	ssa.Value     // the operand, which determines the type
+	index     int // index of the operand among the markers
+}
+
+// Name returns a string that cannot occur in the text of an
+// instruction, as the operand is mentioned by its name.
+func (m operandMarker) Name() string { return "\x00" + strconv.Itoa(m.index) + "\x00" }
+
+// instr displays an instruction, in which each operand local to the
+// function is a clickable value. To locate the operands within its
+// text, the instruction is formatted with them replaced by markers.
+func (w *htmlWriter) instr(instr ssa.Instruction, qualifier types.Qualifier) {
+	if v, ok := instr.(ssa.Value); ok && v.Name() != "" {
+		w.printf("%s = ", w.value(v))
+	}
+	var operands []ssa.Value
+	ops := instr.Operands(nil)
+	for _, op := range ops {
+		if *op != nil && w.locals[*op] {
+			*op = operandMarker{*op, len(operands)}
+			operands = append(operands, (*op).(operandMarker).Value)
+		}
+	}
+	text := instr.String()
+	for _, op := range ops {
+		if m, ok := (*op).(operandMarker); ok {
+			*op = m.Value
+		}
+	}
+	// The parts of the text alternate with the indices of the operands.
+	for k, part := range strings.Split(text, "\x00") {
+		if k%2 == 0 {
+			w.printf("%s", html.EscapeString(part))
+		} else if index, err := strconv.Atoi(part); err == nil && index < len(operands) {
+			w.printf("%s", w.value(operands[index]))
+		}
+	}
+	if v, ok := instr.(ssa.Value); ok && v.Type() != nil {
+		w.printf(" <span class=\"type\">%s</span>", html.EscapeString(types.TypeString(v.Type(), qualifier)))
+	}
+}
+
+// value returns a clickable mention of the local value v, which
+// highlights its mentions and its referrers.
+func (w *htmlWriter) value(v ssa.Value) string {
+	name := v.Name()
+	var refs []string
+	if v.Referrers() != nil {
+		for _, instr := range *v.Referrers() {
+			if id, ok := w.instrIDs[instr]; ok {
+				refs = append(refs, id)
+			}
+		}
+	}
+	return fmt.Sprintf("<span class=\"value\" data-value=\"%s%s\" data-refs=\"%s\">%s</span>",
+		w.prefix, html.EscapeString(name), strings.Join(refs, " "), html.EscapeString(name))
+}
+
+func (w *htmlWriter) blockID(b *ssa.BasicBlock) string {
+	return fmt.Sprintf("%sb%d", w.prefix, b.Index)
+}
+
+// blockRef returns a link to block b.
+func (w *htmlWriter) blockRef(b *ssa.BasicBlock) string {
+	return fmt.Sprintf("<a class=\"blockref\" href=\"#%s\">b%d</a>", w.blockID(b), b.Index)
+}
+
+func (w *htmlWriter) blockRefs(blocks []*ssa.BasicBlock) string {
+	var buf strings.Builder
+	for _, b := range blocks {
+		buf.WriteString(" ")
+		buf.WriteString(w.blockRef(b))
+	}
+	return buf.String()
+}
+
+// domTree displays the dominator tree of the function, and that of its
+// recover block, if any.
+func (w *htmlWriter) domTree() {
+	var visit func(b *ssa.BasicBlock)
+	visit = func(b *ssa.BasicBlock) {
+		w.printf("<li>%s", w.blockRef(b))
+		if b.Comment != "" {
+			w.printf(" <span class=\"comment\">%s</span>", html.EscapeString(b.Comment))
+		}
+		if children := b.Dominees(); len(children) > 0 {
+			w.printf("\n<ul>\n")
+			for _, c := range children {
+				visit(c)
+			}
+			w.printf("</ul>\n")
+		}
+		w.printf("</li>\n")
+	}
+	w.printf("<ul class=\"domtree\">\n")
+	visit(w.fn.Blocks[0])
+	if r := w.fn.Recover; r != nil {
+		visit(r)
+	}
+	w.printf("</ul>\n")
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; }
+h2 { font-family: monospace; }
+.info { color: gray; font-size: 12px; }
+table.func { border-collapse: collapse; }
+th { text-align: left; border-bottom: 1px solid #ccc; }
+td.column { vertical-align: top; padding: 4px 12px; border-right: 1px solid #ccc; }
+ul.source, ul.instrs, pre.signature { font-family: monospace; list-style: none; padding-left: 0; margin: 0; }
+ul.source li { white-space: pre; }
+.lineno { display: inline-block; width: 4em; color: gray; }
+.block { margin-bottom: 8px; }
+.blockhead { font-family: monospace; margin: 4px 0; }
+ul.instrs li { padding-left: 2em; white-space: pre; }
+ul.domtree { font-family: monospace; }
+.comment, .type { color: gray; }
+.value { cursor: pointer; }
+.line, .instr { cursor: pointer; }
+.value.highlight { background-color: #ff0; }
+.instr.referrer { background-color: #cfc; }
+.line.highlight, .instr.highlight { background-color: #cdf; }
+</style>
+<script>
+// Clicking a value highlights its mentions and its referrers.
+// Clicking an instruction or a source line highlights the line and
+// the instructions attributed to it.
+document.addEventListener("click", function(e) {
+	var t = e.target;
+	if (t.dataset.value) {
+		var on = !t.classList.contains("highlight");
+		document.querySelectorAll('[data-value="' + t.dataset.value + '"]').forEach(function(x) {
+			x.classList.toggle("highlight", on);
+		});
+		t.dataset.refs.split(" ").forEach(function(id) {
+			var x = document.getElementById(id);
+			if (x) {
+				x.classList.toggle("referrer", on);
+			}
+		});
+		return;
+	}
+	var line = t.closest(".line");
+	if (!line) {
+		var instr = t.closest(".instr");
+		if (instr && instr.dataset.line) {
+			line = document.getElementById(instr.dataset.line);
+		}
+	}
+	if (line) {
+		var on = !line.classList.contains("highlight");
+		line.classList.toggle("highlight", on);
+		document.querySelectorAll('[data-line="' + line.id + '"]').forEach(function(x) {
+			x.classList.toggle("highlight", on);
+		});
+	}
+});
+</script>
+</head>
+<body>
+`
+
+const htmlFooter = `</body>
+</html>
+`
@@ -0,0 +1,139 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const htmlSrc = `package p
+
+func Apply[T any](xs []T) []T {
+	id := func(x T) T { return x }
+	var ys []T
+	for _, x := range xs {
+		ys = append(ys, id(x))
+	}
+	return ys
+}
+
+func Use() {
+	Apply([]int{1})
+	Apply([]<-chan string{nil})
+}
+
+func Other() { println("<b>") }
+`
+
+func TestWriteHTML(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(filename, []byte(htmlSrc), 0666); err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("example.com/p", "p"), []*ast.File{f}, ssa.InstantiateGenerics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := pkg.Prog
+
+	// htmlSelects selects the origin, its instances and their anonymous functions.
+	isInitial := map[*ssa.Package]bool{pkg: true}
+	var selected []string
+	for fn := range ssautil.AllFunctions(prog) {
+		if htmlSelects(fn, "Apply", isInitial) {
+			selected = append(selected, fn.String())
+		}
+	}
+	sort.Strings(selected)
+	want := []string{
+		"example.com/p.Apply",
+		"example.com/p.Apply$1",
+		"example.com/p.Apply[<-chan string]",
+		"example.com/p.Apply[<-chan string]$1",
+		"example.com/p.Apply[int]",
+		"example.com/p.Apply[int]$1",
+	}
+	if !reflect.DeepEqual(selected, want) {
+		t.Errorf("htmlSelects selected %q, want %q", selected, want)
+	}
+
+	out := filepath.Join(dir, "ssa.html")
+	if err := writeHTML(out, prog, []*ssa.Package{pkg}, "Apply"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+
+	// Names are escaped.
+	if want := "<h2>example.com/p.Apply[&lt;-chan string]</h2>"; !strings.Contains(page, want) {
+		t.Errorf("page does not contain %q", want)
+	}
+	if strings.Contains(page, "[<-chan") {
+		t.Errorf("page contains an unescaped name:\n%s", page)
+	}
+	if strings.Contains(page, "Other") {
+		t.Errorf("page contains an unselected function:\n%s", page)
+	}
+
+	// Each operand is a value whose referrers include its instruction.
+	// The parameter x of the anonymous function has only one, the
+	// return instruction, and the mentions of the parameters ys and xs
+	// must not be confused with those of x.
+	instrRx := regexp.MustCompile(`<li class="instr" id="([^"]+)"[^>]*>(.*)</li>`)
+	operandRx := regexp.MustCompile(`<span class="value" data-value="[^"]+" data-refs="([^"]*)">([^<]*)</span>`)
+	returns := 0
+	for _, m := range instrRx.FindAllStringSubmatch(page, -1) {
+		id, text := m[1], m[2]
+		if i := strings.Index(text, "</span> = "); i >= 0 {
+			text = text[i+len("</span> = "):] // the operands follow the defined value
+		}
+		for _, op := range operandRx.FindAllStringSubmatch(text, -1) {
+			refs, name := strings.Fields(op[1]), op[2]
+			if !contains(refs, id) {
+				t.Errorf("operand %s of instruction %s has data-refs %q", name, id, op[1])
+			}
+		}
+		if ops := operandRx.FindAllStringSubmatch(text, -1); strings.HasPrefix(text, "return ") && len(ops) == 1 && ops[0][2] == "x" {
+			returns++
+			if ops[0][1] != id {
+				t.Errorf("parameter x of return instruction %s has data-refs %q, want %q", id, ops[0][1], id)
+			}
+		}
+	}
+	if returns != 3 { // one in each anonymous function
+		t.Errorf("found %d return instructions of x, want 3:\n%s", returns, page)
+	}
+}
+
+func contains(list []string, x string) bool {
+	for _, y := range list {
+		if y == x {
+			return true
+		}
+	}
+	return false
+}
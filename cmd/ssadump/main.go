@@ -6,6 +6,7 @@
 package main // import "golang.org/x/tools/cmd/ssadump"
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"go/build"
@@ -32,8 +33,19 @@ var (
 The value is a sequence of zero or more more of these letters:
 R	disable [R]ecover() from panic; show interpreter crash instead.
 T	[T]race execution of the program.  Best for single-threaded programs!
+S	run goroutines one at a time under a deterministic [S]cheduler (see -seed).
 `)
 
+	seedFlag = flag.Int64("seed", 0, "seed of the deterministic scheduler (-interp=S)")
+
+	schedTraceFlag = flag.String("schedtrace", "", "write a trace of the execution under the deterministic scheduler to this file (-interp=S)")
+
+	htmlFlag = flag.String("html", "", "write the SSA form of the named function to an HTML file (see -htmlout), with its source and dominator tree")
+
+	htmlOutFlag = flag.String("htmlout", "ssa.html", "the file to which -html writes, overwriting it")
+
+	coverProfileFlag = flag.String("coverprofile", "", "write a statement coverage profile of the interpreted program to this file (-run)")
+
 	cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
 
 	args stringListValue
@@ -47,17 +59,25 @@ func init() {
 }
 
 const usage = `SSA builder and interpreter.
-Usage: ssadump [-build=[DBCSNFLG]] [-test] [-run] [-interp=[TR]] [-arg=...] package...
+Usage: ssadump [-build=[DBCSNFLG]] [-test] [-run] [-interp=[TRS]] [-seed=n] [-coverprofile=file] [-html=func [-htmlout=file]] [-arg=...] package...
 Use -help flag to display options.
 
 Examples:
 % ssadump -build=F hello.go              # dump SSA form of a single package
 % ssadump -build=F -test fmt             # dump SSA form of a package and its tests
+% ssadump -html='(*T).String' ./pkg      # render SSA form of a method as HTML in ssa.html
 % ssadump -run -interp=T hello.go        # interpret a program, with tracing
+% ssadump -run -interp=S -seed=42 prog.go # interpret a program, with a reproducible interleaving
+% ssadump -run -build=G -coverprofile=c.out prog.go # interpret a program, recording coverage
 
 The -run flag causes ssadump to build the code in a runnable form and run the first
 package named main.
 
+The -html flag renders the blocks, instructions and dominator tree of the
+named function, beside its source, together with those of its anonymous
+functions and, if built with -build=G, its generic instances. The page
+is written to the file named by -htmlout, which is overwritten.
+
 Interpretation of the standard "testing" package is no longer supported.
 `
 
@@ -94,16 +114,22 @@ func doMain() error {
 	}
 
 	var interpMode interp.Mode
+	var sched *interp.SchedulerConfig
 	for _, c := range *interpFlag {
 		switch c {
 		case 'T':
 			interpMode |= interp.EnableTracing
 		case 'R':
 			interpMode |= interp.DisableRecover
+		case 'S':
+			sched = &interp.SchedulerConfig{Seed: *seedFlag}
 		default:
 			return fmt.Errorf("unknown -interp option: '%c'", c)
 		}
 	}
+	if *schedTraceFlag != "" && sched == nil {
+		return fmt.Errorf("-schedtrace requires -interp=S")
+	}
 
 	// Profiling support.
 	if *cpuprofile != "" {
@@ -146,6 +172,15 @@ func doMain() error {
 		}
 	}
 
+	if *htmlFlag != "" {
+		for _, p := range pkgs {
+			p.Build()
+		}
+		if err := writeHTML(*htmlOutFlag, prog, pkgs, *htmlFlag); err != nil {
+			return err
+		}
+	}
+
 	if !*runFlag {
 		// Build and display only the initial packages
 		// (and synthetic wrappers).
@@ -174,10 +209,40 @@ func doMain() error {
 				build.Default.GOARCH, runtime.GOARCH)
 		}
 
+		var trace *bufio.Writer
+		if sched != nil && *schedTraceFlag != "" {
+			f, err := os.Create(*schedTraceFlag)
+			if err != nil {
+				return err
+			}
+			trace = bufio.NewWriter(f)
+			sched.Trace = trace
+		}
+
+		var cov *interp.Coverage
+		if *coverProfileFlag != "" {
+			cov = new(interp.Coverage)
+		}
+
 		// Run first main package.
 		for _, main := range ssautil.MainPackages(pkgs) {
 			fmt.Fprintf(os.Stderr, "Running: %s\n", main.Pkg.Path())
-			os.Exit(interp.Interpret(main, interpMode, sizes, main.Pkg.Path(), args))
+			if sched == nil && cov == nil {
+				os.Exit(interp.Interpret(main, interpMode, sizes, main.Pkg.Path(), args))
+			}
+			config := &interp.Config{Scheduler: sched, Coverage: cov}
+			exitCode := interp.InterpretWithConfig(main, interpMode, sizes, main.Pkg.Path(), args, config)
+			if trace != nil {
+				if err := trace.Flush(); err != nil {
+					return err
+				}
+			}
+			if cov != nil {
+				if err := writeCoverProfile(*coverProfileFlag, cov, pkgs); err != nil {
+					return err
+				}
+			}
+			os.Exit(exitCode)
 		}
 		return fmt.Errorf("no main package")
 	}
@@ -198,3 +263,16 @@ func (ss *stringListValue) Get() interface{} { return []string(*ss) }
 func (ss *stringListValue) String() string { return fmt.Sprintf("%q", *ss) }
 
 func (ss *stringListValue) Set(s string) error { *ss = append(*ss, s); return nil }
+
+// writeCoverProfile writes the coverage profile of pkgs to the named file.
+func writeCoverProfile(filename string, cov *interp.Coverage, pkgs []*ssa.Package) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := cov.WriteProfile(f, pkgs...); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysisconfig defines the analyzer configuration file
+// shared by the command-line analysis drivers (singlechecker,
+// multichecker, unitchecker) and gopls, so that the same lint
+// configuration applies in the editor and in CI.
+//
+// The configuration is a JSON file, conventionally named
+// goanalysis.json and placed in the module root:
+//
+//	{
+//		"analyzers": {
+//			"printf": {"flags": {"funcs": "Logf,Warnf"}, "severity": "error"},
+//			"shadow": {"enabled": true, "flags": {"strict": "true"}},
+//			"unusedparams": {"enabled": false}
+//		},
+//		"exclude": [
+//			{"path": "internal/gen/...", "analyzers": ["shadow"]},
+//			{"path": "*_string.go"}
+//		]
+//	}
+//
+// Each entry of "analyzers", keyed by analyzer name, may enable or
+// disable the analyzer, set the values of its flags (as if by
+// -NAME.FLAG=VALUE on the command line), and set the severity
+// ("error", "warning", "info", or "hint") of its diagnostics.
+//
+// Each entry of "exclude" suppresses the diagnostics of the named
+// analyzers (or of all analyzers, if none are named) in files whose
+// slash-separated path relative to the directory containing the
+// configuration file matches the pattern. A pattern ending in "/..."
+// matches every file beneath the directory; a pattern without a slash
+// matches the base name of a file in any directory; any other pattern
+// is matched against the entire relative path using [path.Match].
+package analysisconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FileName is the conventional name of the configuration file.
+const FileName = "goanalysis.json"
+
+// A Config is a parsed configuration file.
+type Config struct {
+	Dir       string              `json:"-"` // absolute directory containing the file, to which exclude paths are relative
+	Analyzers map[string]Analyzer `json:"analyzers,omitempty"`
+	Exclude   []Exclude           `json:"exclude,omitempty"`
+}
+
+// Analyzer holds the configuration of a single analyzer.
+type Analyzer struct {
+	Enabled  *bool             `json:"enabled,omitempty"`  // nil => driver's default
+	Flags    map[string]string `json:"flags,omitempty"`    // flag values, by flag name
+	Severity string            `json:"severity,omitempty"` // "" => driver's default
+}
+
+// An Exclude suppresses diagnostics in the files matching a path pattern.
+type Exclude struct {
+	Path      string   `json:"path"`
+	Analyzers []string `json:"analyzers,omitempty"` // empty => all analyzers
+}
+
+// Severities is the set of valid values of the Analyzer.Severity field.
+var Severities = []string{"error", "warning", "info", "hint"}
+
+// Load reads and parses the named configuration file.
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filename, data)
+}
+
+// ResolveFile returns the file name of a configuration file named on
+// the command line of a driver running in directory dir. A relative
+// name is resolved against the root directory of the module enclosing
+// dir, if any, not against dir itself, since go vet runs its analysis
+// tool in the directory of each package.
+func ResolveFile(name, dir string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return filepath.Join(d, name)
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break // no enclosing module
+		}
+		d = parent
+	}
+	return filepath.Join(dir, name)
+}
+
+// Parse parses the contents of the named configuration file.
+// Unknown fields, invalid severities, and malformed exclude
+// patterns are reported as errors.
+func Parse(filename string, data []byte) (*Config, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{Dir: filepath.Dir(abs)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	for name, a := range cfg.Analyzers {
+		if a.Severity != "" && !slices.Contains(Severities, a.Severity) {
+			return nil, fmt.Errorf("%s: analyzer %s: invalid severity %q; want one of %s",
+				filename, name, a.Severity, strings.Join(Severities, ", "))
+		}
+	}
+	for _, x := range cfg.Exclude {
+		if x.Path == "" {
+			return nil, fmt.Errorf("%s: exclude entry has no path", filename)
+		}
+		if _, err := path.Match(strings.TrimSuffix(x.Path, "/..."), ""); err != nil {
+			return nil, fmt.Errorf("%s: invalid exclude path %q: %v", filename, x.Path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Enabled reports whether the named analyzer is enabled,
+// given whether the driver enables it by default.
+// It is safe to call on a nil Config.
+func (cfg *Config) Enabled(name string, dflt bool) bool {
+	if cfg != nil {
+		if a, ok := cfg.Analyzers[name]; ok && a.Enabled != nil {
+			return *a.Enabled
+		}
+	}
+	return dflt
+}
+
+// Flags returns the flag values configured for the named analyzer.
+// It is safe to call on a nil Config.
+func (cfg *Config) Flags(name string) map[string]string {
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Analyzers[name].Flags
+}
+
+// Severity returns the severity configured for the diagnostics of
+// the named analyzer, or "" if none is configured.
+// It is safe to call on a nil Config.
+func (cfg *Config) Severity(name string) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.Analyzers[name].Severity
+}
+
+// Excluded reports whether diagnostics of the named analyzer
+// in the specified file are suppressed by an exclude entry.
+// Files outside the configuration's directory are never excluded.
+// It is safe to call on a nil Config.
+func (cfg *Config) Excluded(filename, analyzer string) bool {
+	if cfg == nil || len(cfg.Exclude) == 0 || filename == "" {
+		return false
+	}
+	rel, err := filepath.Rel(cfg.Dir, filename)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, x := range cfg.Exclude {
+		if len(x.Analyzers) > 0 && !slices.Contains(x.Analyzers, analyzer) {
+			continue
+		}
+		if matchPath(x.Path, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath reports whether the slash-separated relative file name
+// rel matches the exclude pattern.
+func matchPath(pattern, rel string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/..."); ok {
+		if dir == "." {
+			return true
+		}
+		for d := path.Dir(rel); d != "."; d = path.Dir(d) {
+			if ok, _ := path.Match(dir, d); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		rel = path.Base(rel)
+	}
+	ok, _ := path.Match(pattern, rel)
+	return ok
+}
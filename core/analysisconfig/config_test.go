@@ -0,0 +1,129 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysisconfig_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/troll-zhao/tools/core/analysisconfig"
+)
+
+const config = `{
+	"analyzers": {
+		"printf": {"flags": {"funcs": "Logf"}, "severity": "error"},
+		"shadow": {"enabled": true},
+		"unusedparams": {"enabled": false}
+	},
+	"exclude": [
+		{"path": "gen/...", "analyzers": ["shadow"]},
+		{"path": "*_string.go"},
+		{"path": "cmd/*/main.go", "analyzers": ["printf"]}
+	]
+}`
+
+func TestConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := analysisconfig.Parse(filepath.Join(dir, analysisconfig.FileName), []byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		dflt, want bool
+	}{
+		{"shadow", false, true},
+		{"unusedparams", true, false},
+		{"printf", true, true},
+		{"printf", false, false},
+		{"other", true, true},
+	} {
+		if got := cfg.Enabled(test.name, test.dflt); got != test.want {
+			t.Errorf("Enabled(%s, %t) = %t, want %t", test.name, test.dflt, got, test.want)
+		}
+	}
+
+	if got := cfg.Flags("printf")["funcs"]; got != "Logf" {
+		t.Errorf("Flags(printf)[funcs] = %q, want Logf", got)
+	}
+	if got := cfg.Severity("printf"); got != "error" {
+		t.Errorf("Severity(printf) = %q, want error", got)
+	}
+	if got := cfg.Severity("shadow"); got != "" {
+		t.Errorf("Severity(shadow) = %q, want empty", got)
+	}
+
+	for _, test := range []struct {
+		file, analyzer string
+		want           bool
+	}{
+		{"gen/x.go", "shadow", true},
+		{"gen/sub/x.go", "shadow", true},
+		{"gen/x.go", "printf", false},
+		{"generated/x.go", "shadow", false},
+		{"a/b/kind_string.go", "printf", true},
+		{"kind_string.go", "nilness", true},
+		{"cmd/tool/main.go", "printf", true},
+		{"cmd/tool/sub/main.go", "printf", false},
+		{"x.go", "printf", false},
+		{"../outside/kind_string.go", "printf", false},
+	} {
+		filename := filepath.Join(dir, filepath.FromSlash(test.file))
+		if got := cfg.Excluded(filename, test.analyzer); got != test.want {
+			t.Errorf("Excluded(%s, %s) = %t, want %t", test.file, test.analyzer, got, test.want)
+		}
+	}
+
+	// A nil Config has no effect.
+	var nilcfg *analysisconfig.Config
+	if !nilcfg.Enabled("x", true) || nilcfg.Severity("x") != "" || nilcfg.Excluded(filepath.Join(dir, "x.go"), "x") {
+		t.Errorf("nil Config is not neutral")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		config, want string
+	}{
+		{`{"analyzer": {}}`, `unknown field "analyzer"`},
+		{`{"analyzers": {"printf": {"severity": "fatal"}}}`, `invalid severity "fatal"`},
+		{`{"exclude": [{"analyzers": ["printf"]}]}`, `exclude entry has no path`},
+		{`{"exclude": [{"path": "[x"}]}`, `invalid exclude path`},
+		{`{`, `unexpected EOF`},
+	} {
+		_, err := analysisconfig.Parse(analysisconfig.FileName, []byte(test.config))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%s) = %v, want error containing %q", test.config, err, test.want)
+		}
+	}
+}
+
+func TestResolveFile(t *testing.T) {
+	root := t.TempDir()
+	pkg := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(pkg, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(t.TempDir(), "x.json")
+
+	for _, test := range []struct {
+		name, dir, want string
+	}{
+		{analysisconfig.FileName, root, filepath.Join(root, analysisconfig.FileName)},
+		{analysisconfig.FileName, pkg, filepath.Join(root, analysisconfig.FileName)},
+		{"lint/x.json", pkg, filepath.Join(root, "lint", "x.json")},
+		{abs, pkg, abs},
+	} {
+		if got := analysisconfig.ResolveFile(test.name, test.dir); got != test.want {
+			t.Errorf("ResolveFile(%q, %q) = %q, want %q", test.name, test.dir, got, test.want)
+		}
+	}
+}
@@ -497,6 +497,46 @@ func AddImport(info *types.Info, file *ast.File, pos token.Pos, pkgpath, preferr
 	}}
 }
 
+// DeleteUnusedImport returns edits to delete the import of the
+// package referred to by sel.X if sel is the only reference to it
+// in the file.
+func DeleteUnusedImport(info *types.Info, file *ast.File, sel *ast.SelectorExpr) []analysis.TextEdit {
+	id, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	pkgname, ok := info.Uses[id].(*types.PkgName)
+	if !ok {
+		return nil
+	}
+	n := 0
+	ast.Inspect(file, func(n2 ast.Node) bool {
+		if id, ok := n2.(*ast.Ident); ok && info.Uses[id] == pkgname {
+			n++
+		}
+		return true
+	})
+	if n != 1 {
+		return nil
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.ImportSpec)
+			if spec.Name != nil && info.Defs[spec.Name] == pkgname || spec.Name == nil && info.Implicits[spec] == pkgname {
+				if len(gen.Specs) == 1 && !gen.Lparen.IsValid() {
+					return []analysis.TextEdit{{Pos: gen.Pos(), End: gen.End()}}
+				}
+				return []analysis.TextEdit{{Pos: spec.Pos(), End: spec.End()}}
+			}
+		}
+	}
+	return nil
+}
+
 // importedPkgName returns the PkgName object declared by an ImportSpec.
 // TODO(adonovan): use go1.22's Info.PkgNameOf.
 func importedPkgName(info *types.Info, imp *ast.ImportSpec) (*types.PkgName, bool) {
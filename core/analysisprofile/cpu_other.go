@@ -0,0 +1,13 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package analysisprofile
+
+import "time"
+
+// processCPUTime returns zero, as CPU time
+// is not measured on this platform.
+func processCPUTime() time.Duration { return 0 }
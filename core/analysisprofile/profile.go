@@ -0,0 +1,166 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysisprofile aggregates per-analyzer performance
+// statistics for analysis drivers such as the checker and gopls.
+//
+// A driver calls [Begin] before each call to Analyzer.Run, [End]
+// immediately after it, and [Profile.Add] once it has counted the
+// facts the analyzer exported. Wall time is attributed precisely, but CPU
+// time and allocations are measured process-wide, so they are
+// attributed accurately only when the driver runs one analysis at a
+// time.
+package analysisprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Stats holds the statistics for one analyzer, aggregated across
+// all the packages to which it was applied.
+type Stats struct {
+	Analyzer    string        // analyzer name
+	Packages    int           // number of packages analyzed
+	Wall        time.Duration // wall-clock time spent in Run
+	CPU         time.Duration // process CPU time consumed during Run
+	Allocs      uint64        // number of heap objects allocated during Run
+	AllocBytes  uint64        // number of heap bytes allocated during Run
+	Facts       int           // number of facts exported
+	FactBytes   int           // size of the encoded facts exported
+	Diagnostics int           // number of diagnostics reported
+}
+
+// A Profile accumulates Stats for a set of analyzers.
+// It is safe for concurrent use.
+// The zero value is an empty profile.
+type Profile struct {
+	mu    sync.Mutex
+	stats map[string]*Stats // keyed by analyzer name
+}
+
+// A Sample records the process's resource counters at the
+// start of a call to Analyzer.Run.
+type Sample struct {
+	time       time.Time
+	cpu        time.Duration
+	allocs     uint64
+	allocBytes uint64
+}
+
+var sampleMetrics = []string{
+	"/gc/heap/allocs:objects",
+	"/gc/heap/allocs:bytes",
+}
+
+// Begin returns a Sample of the current resource counters.
+func Begin() Sample {
+	var samples [2]metrics.Sample
+	for i, name := range sampleMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples[:])
+	return Sample{
+		time:       time.Now(),
+		cpu:        processCPUTime(),
+		allocs:     uint64Value(samples[0].Value),
+		allocBytes: uint64Value(samples[1].Value),
+	}
+}
+
+func uint64Value(v metrics.Value) uint64 {
+	if v.Kind() == metrics.KindUint64 {
+		return v.Uint64()
+	}
+	return 0 // metric not supported
+}
+
+// A Span records the resources consumed by one call to Analyzer.Run.
+type Span struct {
+	wall, cpu          time.Duration
+	allocs, allocBytes uint64
+}
+
+// End returns the resources consumed since the sample start was taken.
+func End(start Sample) Span {
+	end := Begin()
+	return Span{
+		wall:       end.time.Sub(start.time),
+		cpu:        end.cpu - start.cpu,
+		allocs:     end.allocs - start.allocs,
+		allocBytes: end.allocBytes - start.allocBytes,
+	}
+}
+
+// Add records one application of the named analyzer to a package,
+// which consumed the resources of span, exported the specified number
+// and total encoded size of facts, and reported the specified number
+// of diagnostics.
+func (p *Profile) Add(analyzer string, span Span, facts, factBytes, diagnostics int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stats == nil {
+		p.stats = make(map[string]*Stats)
+	}
+	s, ok := p.stats[analyzer]
+	if !ok {
+		s = &Stats{Analyzer: analyzer}
+		p.stats[analyzer] = s
+	}
+	s.Packages++
+	s.Wall += span.wall
+	s.CPU += span.cpu
+	s.Allocs += span.allocs
+	s.AllocBytes += span.allocBytes
+	s.Facts += facts
+	s.FactBytes += factBytes
+	s.Diagnostics += diagnostics
+}
+
+// Stats returns the statistics for each analyzer in the profile,
+// in descending order of wall-clock time.
+func (p *Profile) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]Stats, 0, len(p.stats))
+	for _, s := range p.stats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Wall != stats[j].Wall {
+			return stats[i].Wall > stats[j].Wall
+		}
+		return stats[i].Analyzer < stats[j].Analyzer
+	})
+	return stats
+}
+
+// WriteJSON writes the profile to w as a JSON array of Stats
+// objects, in the order of [Profile.Stats].
+// Durations are expressed in nanoseconds.
+func (p *Profile) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(p.Stats())
+}
+
+// WriteTable writes the profile to w as a human-readable table,
+// in the order of [Profile.Stats].
+func (p *Profile) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "analyzer\tpackages\twall\tcpu\tallocs\talloc bytes\tfacts\tfact bytes\tdiagnostics\t\n")
+	for _, s := range p.Stats() {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t\n",
+			s.Analyzer, s.Packages,
+			s.Wall.Round(time.Microsecond), s.CPU.Round(time.Microsecond),
+			s.Allocs, s.AllocBytes, s.Facts, s.FactBytes, s.Diagnostics)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysisprofile_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/troll-zhao/tools/core/analysisprofile"
+)
+
+func TestProfile(t *testing.T) {
+	var p analysisprofile.Profile
+
+	start := analysisprofile.Begin()
+	time.Sleep(10 * time.Millisecond)
+	p.Add("slow", analysisprofile.End(start), 2, 100, 1)
+
+	start = analysisprofile.Begin()
+	p.Add("fast", analysisprofile.End(start), 0, 0, 3)
+	start = analysisprofile.Begin()
+	buf := make([]byte, 1<<20) // allocate
+	_ = buf
+	p.Add("fast", analysisprofile.End(start), 1, 10, 0)
+
+	stats := p.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("got %d stats, want 2", len(stats))
+	}
+	slow, fast := stats[0], stats[1]
+	if slow.Analyzer != "slow" || fast.Analyzer != "fast" {
+		t.Fatalf("got order %s, %s; want slow, fast", slow.Analyzer, fast.Analyzer)
+	}
+	if slow.Packages != 1 || slow.Facts != 2 || slow.FactBytes != 100 || slow.Diagnostics != 1 {
+		t.Errorf("slow: got %+v", slow)
+	}
+	if slow.Wall < 10*time.Millisecond {
+		t.Errorf("slow: got Wall %v, want at least 10ms", slow.Wall)
+	}
+	if fast.Packages != 2 || fast.Facts != 1 || fast.FactBytes != 10 || fast.Diagnostics != 3 {
+		t.Errorf("fast: got %+v", fast)
+	}
+
+	var out bytes.Buffer
+	if err := p.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []analysisprofile.Stats
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0] != slow || decoded[1] != fast {
+		t.Errorf("JSON round trip: got %+v, want %+v", decoded, stats)
+	}
+
+	out.Reset()
+	if err := p.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines of table, want 3:\n%s", len(lines), out.String())
+	}
+	for i, name := range []string{"analyzer", "slow", "fast"} {
+		if got := strings.Fields(lines[i])[0]; got != name {
+			t.Errorf("table line %d begins with %q, want %q", i, got, name)
+		}
+	}
+}
@@ -226,9 +226,17 @@ func (d *Decoder) Decode(read func(pkgPath string) ([]byte, error)) (*Set, error
 		if len(data) == 0 {
 			continue // no facts
 		}
+		// The data is a sequence of slices of facts (see Encode).
 		var gobFacts []gobFact
-		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&gobFacts); err != nil {
-			return nil, fmt.Errorf("decoding facts for %q: %v", imp.Path(), err)
+		dec := gob.NewDecoder(bytes.NewReader(data))
+		for {
+			var part []gobFact
+			if err := dec.Decode(&part); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("decoding facts for %q: %v", imp.Path(), err)
+			}
+			gobFacts = append(gobFacts, part...)
 		}
 		logf("decoded %d facts: %v", len(gobFacts), gobFacts)
 
@@ -269,6 +277,14 @@ func (d *Decoder) Decode(read func(pkgPath string) ([]byte, error)) (*Set, error
 // It may fail if one of the Facts could not be gob-encoded, but this is
 // a sign of a bug in an Analyzer.
 func (s *Set) Encode() []byte {
+	data, _ := s.EncodeSize()
+	return data
+}
+
+// EncodeSize is like Encode, but also returns the size of the
+// encoding of the facts about the set's package itself, as opposed to
+// those re-exported from its dependencies.
+func (s *Set) EncodeSize() (data []byte, size int) {
 	encoder := new(objectpath.Encoder)
 
 	// TODO(adonovan): opt: use a more efficient encoding
@@ -345,11 +361,25 @@ func (s *Set) Encode() []byte {
 		return false // equal
 	})
 
+	// Encode the facts about the package itself, then the others,
+	// as separate slices, so that the size of the former is known.
+	var own, others []gobFact
+	for _, gf := range gobFacts {
+		if gf.PkgPath == s.pkg.Path() {
+			own = append(own, gf)
+		} else {
+			others = append(others, gf)
+		}
+	}
 	var buf bytes.Buffer
-	if len(gobFacts) > 0 {
-		if err := gob.NewEncoder(&buf).Encode(gobFacts); err != nil {
+	enc := gob.NewEncoder(&buf)
+	for _, part := range [][]gobFact{own, others} {
+		if len(part) == 0 {
+			continue
+		}
+		if err := enc.Encode(part); err != nil {
 			// Fact encoding should never fail. Identify the culprit.
-			for _, gf := range gobFacts {
+			for _, gf := range part {
 				if err := gob.NewEncoder(io.Discard).Encode(gf); err != nil {
 					fact := gf.Fact
 					pkgpath := reflect.TypeOf(fact).Elem().PkgPath()
@@ -358,6 +388,9 @@ func (s *Set) Encode() []byte {
 				}
 			}
 		}
+		if size == 0 && len(own) > 0 {
+			size = buf.Len()
+		}
 	}
 
 	if debug {
@@ -365,7 +398,7 @@ func (s *Set) Encode() []byte {
 			s.pkg.Path(), len(gobFacts), buf.Len())
 	}
 
-	return buf.Bytes()
+	return buf.Bytes(), size
 }
 
 // String is provided only for debugging, and must not be called
@@ -267,6 +267,17 @@ func IExportShallow(fset *token.FileSet, pkg *types.Package, reportf ReportFunc)
 	return out.Bytes(), err
 }
 
+// IExportShallowAll is like IExportShallow, but it encodes every
+// package-level declaration of pkg, not only the exported ones and
+// those they depend upon, so that the package imported by
+// IImportShallow has the same members as pkg.
+func IExportShallowAll(fset *token.FileSet, pkg *types.Package, reportf ReportFunc) ([]byte, error) {
+	const bundle, shallow, all = false, true, true
+	var out bytes.Buffer
+	err := iexport(&out, fset, bundle, shallow, all, iexportVersion, []*types.Package{pkg})
+	return out.Bytes(), err
+}
+
 // IImportShallow decodes "shallow" types.Package data encoded by
 // IExportShallow in the same executable. This function cannot import data from
 // cmd/compile or gcexportdata.Write.
@@ -312,7 +323,15 @@ func IExportBundle(out io.Writer, fset *token.FileSet, pkgs []*types.Package) er
 	return iexportCommon(out, fset, bundle, shallow, iexportVersion, pkgs)
 }
 
-func iexportCommon(out io.Writer, fset *token.FileSet, bundle, shallow bool, version int, pkgs []*types.Package) (err error) {
+func iexportCommon(out io.Writer, fset *token.FileSet, bundle, shallow bool, version int, pkgs []*types.Package) error {
+	const all = false
+	return iexport(out, fset, bundle, shallow, all, version, pkgs)
+}
+
+// iexport is the common implementation of the export functions.
+// If all is set, the work queue is initialized with all declarations
+// of pkgs rather than only exported ones.
+func iexport(out io.Writer, fset *token.FileSet, bundle, shallow, all bool, version int, pkgs []*types.Package) (err error) {
 	if !debug {
 		defer func() {
 			if e := recover(); e != nil {
@@ -351,7 +370,7 @@ func iexportCommon(out io.Writer, fset *token.FileSet, bundle, shallow bool, ver
 	for _, pkg := range pkgs {
 		scope := pkg.Scope()
 		for _, name := range scope.Names() {
-			if token.IsExported(name) {
+			if all || token.IsExported(name) {
 				p.pushDecl(scope.Lookup(name))
 			}
 		}
@@ -232,3 +232,79 @@ func postTypeCheck(t *testing.T, fset *token.FileSet, pkg *types.Package) {
 		t.Errorf("%+v: expected end Position of %v here; was at %+v", wantEndPosn, obj, endPosn)
 	}
 }
+
+// TestShallowAll checks that IExportShallowAll encodes unexported
+// declarations, including those unreachable from the exported API.
+func TestShallowAll(t *testing.T) {
+	const src = `package p
+
+import "errors"
+
+type T struct{ x int }
+
+func (T) m() {}
+
+type u []T
+
+var v = errors.New("v")
+
+const c = 1
+
+func f(u) {}
+
+func F() {}
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errorsPkg := types.NewPackage("errors", "errors")
+	errorsPkg.Scope().Insert(types.NewFunc(token.NoPos, errorsPkg, "New",
+		types.NewSignatureType(nil, nil, nil,
+			types.NewTuple(types.NewParam(token.NoPos, errorsPkg, "text", types.Typ[types.String])),
+			types.NewTuple(types.NewParam(token.NoPos, errorsPkg, "", types.Universe.Lookup("error").Type())),
+			false)))
+	errorsPkg.MarkComplete()
+	conf := types.Config{Importer: importerFunc(func(string) (*types.Package, error) { return errorsPkg, nil })}
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		export func(*token.FileSet, *types.Package, gcimporter.ReportFunc) ([]byte, error)
+		want   string
+	}{
+		{gcimporter.IExportShallow, "F T"},
+		{gcimporter.IExportShallowAll, "F T c f u v"},
+	} {
+		data, err := test.export(fset, pkg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		getPackages := func(items []gcimporter.GetPackagesItem) error {
+			for i, item := range items {
+				switch item.Path {
+				case "p":
+					items[i].Pkg = types.NewPackage("p", item.Name)
+				case "errors":
+					items[i].Pkg = errorsPkg
+				default:
+					return fmt.Errorf("unexpected package %q", item.Path)
+				}
+			}
+			return nil
+		}
+		imported, err := gcimporter.IImportShallow(token.NewFileSet(), getPackages, data, "p", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(imported.Scope().Names(), " "); got != test.want {
+			t.Errorf("imported names = %q, want %q", got, test.want)
+		}
+		if obj, _, _ := types.LookupFieldOrMethod(imported.Scope().Lookup("T").Type(), false, imported, "m"); obj == nil {
+			t.Errorf("imported type T has no method m")
+		}
+	}
+}
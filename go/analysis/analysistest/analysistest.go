@@ -8,7 +8,10 @@ package analysistest
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"go/format"
+	"go/importer"
+	"go/parser"
 	"go/token"
 	"go/types"
 	"log"
@@ -133,9 +136,9 @@ type Testing interface {
 // inconsistent with the interpretation of multiple diagnostics
 // described at Diagnostic.SuggestedFixes.
 // We need to rethink the analyzer testing API to better support such
-// cases. In the meantime, users of RunWithSuggestedFixes testing
-// analyzers that offer alternative fixes are advised to put each fix
-// in a separate .go file in the testdata.
+// cases. In the meantime, analyzers that offer alternative fixes
+// should be tested with RunWithEachSuggestedFix, or should put each
+// fix in a separate .go file in the testdata.
 func RunWithSuggestedFixes(t Testing, dir string, a *analysis.Analyzer, patterns ...string) []*Result {
 	r := Run(t, dir, a, patterns...)
 
@@ -176,25 +179,7 @@ func RunWithSuggestedFixes(t Testing, dir string, a *analysis.Analyzer, patterns
 					t.Errorf("missing Diagnostic.Category for SuggestedFix without TextEdits (gopls requires the category for the name of the fix command")
 				}
 
-				for _, edit := range fix.TextEdits {
-					start, end := edit.Pos, edit.End
-					if !end.IsValid() {
-						end = start
-					}
-					// Validate the edit.
-					if start > end {
-						t.Errorf(
-							"diagnostic for analysis %v contains Suggested Fix with malformed edit: pos (%v) > end (%v)",
-							act.Pass.Analyzer.Name, start, end)
-						continue
-					}
-					file, endfile := act.Pass.Fset.File(start), act.Pass.Fset.File(end)
-					if file == nil || endfile == nil || file != endfile {
-						t.Errorf(
-							"diagnostic for analysis %v contains Suggested Fix with malformed spanning files %v and %v",
-							act.Pass.Analyzer.Name, file.Name(), endfile.Name())
-						continue
-					}
+				for file, edits := range fixEdits(t, act, &fix) {
 					if _, ok := fileContents[file]; !ok {
 						contents, err := os.ReadFile(file.Name())
 						if err != nil {
@@ -205,11 +190,7 @@ func RunWithSuggestedFixes(t Testing, dir string, a *analysis.Analyzer, patterns
 					if _, ok := fileEdits[file]; !ok {
 						fileEdits[file] = make(map[string][]diff.Edit)
 					}
-					fileEdits[file][fix.Message] = append(fileEdits[file][fix.Message], diff.Edit{
-						Start: file.Offset(start),
-						End:   file.Offset(end),
-						New:   string(edit.NewText),
-					})
+					fileEdits[file][fix.Message] = append(fileEdits[file][fix.Message], edits...)
 				}
 			}
 		}
@@ -302,6 +283,230 @@ func applyDiffsAndCompare(src, golden []byte, edits []diff.Edit, fileName string
 	return nil
 }
 
+// fixEdits validates the text edits of a suggested fix and converts
+// them to diff.Edits grouped by file. Malformed edits are reported to
+// t and omitted from the result.
+func fixEdits(t Testing, act *Result, fix *analysis.SuggestedFix) map[*token.File][]diff.Edit {
+	edits := make(map[*token.File][]diff.Edit)
+	for _, edit := range fix.TextEdits {
+		start, end := edit.Pos, edit.End
+		if !end.IsValid() {
+			end = start
+		}
+		// Validate the edit.
+		if start > end {
+			t.Errorf(
+				"diagnostic for analysis %v contains Suggested Fix with malformed edit: pos (%v) > end (%v)",
+				act.Pass.Analyzer.Name, start, end)
+			continue
+		}
+		file, endfile := act.Pass.Fset.File(start), act.Pass.Fset.File(end)
+		if file == nil || endfile == nil || file != endfile {
+			t.Errorf(
+				"diagnostic for analysis %v contains Suggested Fix with malformed spanning files %v and %v",
+				act.Pass.Analyzer.Name, file.Name(), endfile.Name())
+			continue
+		}
+		edits[file] = append(edits[file], diff.Edit{
+			Start: file.Offset(start),
+			End:   file.Offset(end),
+			New:   string(edit.NewText),
+		})
+	}
+	return edits
+}
+
+// RunWithEachSuggestedFix behaves like Run, but additionally verifies
+// each suggested fix in isolation, which makes it suitable for
+// analyzers that offer several alternative fixes for one diagnostic.
+//
+// For every fix of every diagnostic, RunWithEachSuggestedFix applies
+// that fix alone to the original sources and checks that the
+// resulting package still type-checks. It then compares each edited
+// file against a section of the txtar archive alongside it: fixes to
+// example.go are compared against the section of example.go.golden
+// named "L: message", where L is the line of the diagnostic and
+// message is SuggestedFix.Message. For example:
+//
+//	-- 5: remove double negation --
+//	package pkg
+//	...
+//
+//	-- 5: turn into single negation --
+//	package pkg
+//	...
+//
+// A section with no corresponding fix, or a fix with no corresponding
+// section, is reported as an error, as is a mismatch between the
+// fixed file and its section, which is shown as a unified diff.
+//
+// The set of fixes that a diagnostic is expected to offer may be
+// asserted in its '// want' comment; see Run.
+//
+// Fixed files are type-checked using the packages imported by the
+// original package. A fix that adds an import of a package not
+// already among its dependencies is type-checked against that
+// package's export data, which is assumed to be available from the
+// go command (as it is for the standard library).
+func RunWithEachSuggestedFix(t Testing, dir string, a *analysis.Analyzer, patterns ...string) []*Result {
+	r := Run(t, dir, a, patterns...)
+
+	for _, act := range r {
+		// Sections of each golden file, and which of them were used.
+		type section struct {
+			data []byte
+			used bool
+		}
+		goldens := make(map[*token.File]map[string]*section)
+		golden := func(file *token.File) map[string]*section {
+			sections, ok := goldens[file]
+			if !ok {
+				ar, err := txtar.ParseFile(file.Name() + ".golden")
+				if err != nil {
+					t.Errorf("error reading %s.golden: %v", file.Name(), err)
+				} else if len(ar.Comment) != 0 {
+					t.Errorf("%s.golden has leading comment; each fix must have a named section", file.Name())
+				} else {
+					sections = make(map[string]*section)
+					for _, vf := range ar.Files {
+						sections[vf.Name] = &section{data: vf.Data}
+					}
+				}
+				goldens[file] = sections
+			}
+			return sections
+		}
+
+		for _, diag := range act.Diagnostics {
+			line := act.Pass.Fset.Position(diag.Pos).Line
+			for _, fix := range diag.SuggestedFixes {
+				name := fmt.Sprintf("%d: %s", line, fix.Message)
+
+				// Apply this fix alone.
+				fixed := make(map[string][]byte)
+				for file, edits := range fixEdits(t, act, &fix) {
+					orig, err := os.ReadFile(file.Name())
+					if err != nil {
+						t.Errorf("error reading %s: %v", file.Name(), err)
+						continue
+					}
+					out, err := diff.ApplyBytes(orig, edits)
+					if err != nil {
+						t.Errorf("%s: error applying fix %q: %v", file.Name(), name, err)
+						continue
+					}
+					fixed[file.Name()] = out
+
+					sections := golden(file)
+					if sections == nil {
+						continue // error already reported
+					}
+					sect, ok := sections[name]
+					if !ok {
+						t.Errorf("no section for suggested fix %q in %s.golden", name, file.Name())
+						continue
+					}
+					sect.used = true
+					// Normalize trailing newlines between sections.
+					want := append(bytes.TrimRight(sect.data, "\n"), '\n')
+					if err := applyDiffsAndCompare(orig, want, edits, file.Name()); err != nil {
+						t.Errorf("fix %q: %s", name, err)
+					}
+				}
+
+				if len(fixed) > 0 && len(act.Pass.TypeErrors) == 0 {
+					if err := typeCheckFixed(act.Pass, fixed); err != nil {
+						posn := act.Pass.Fset.Position(diag.Pos)
+						t.Errorf("%s: suggested fix %q produces ill-typed code: %v",
+							sanitize(dir, posn.String()), name, err)
+					}
+				}
+			}
+		}
+
+		// Reject sections that match no suggested fix.
+		var surplus []string
+		for file, sections := range goldens {
+			for name, sect := range sections {
+				if !sect.used {
+					surplus = append(surplus, fmt.Sprintf("%s.golden: section %q matches no suggested fix", file.Name(), name))
+				}
+			}
+		}
+		sort.Strings(surplus)
+		for _, err := range surplus {
+			t.Errorf("%s", err)
+		}
+	}
+	return r
+}
+
+// typeCheckFixed parses and type-checks the files of the package
+// analyzed by pass, substituting the contents of fixed (which maps
+// file names to file contents) for the original files.
+func typeCheckFixed(pass *analysis.Pass, fixed map[string][]byte) error {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, f := range pass.Files {
+		filename := pass.Fset.File(f.Pos()).Name()
+		src, ok := fixed[filename]
+		if !ok {
+			var err error
+			src, err = os.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+		}
+		file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	// Use the original dependencies, so that the fixed package
+	// sees the same types as the original one.
+	deps := make(map[string]*types.Package)
+	var visit func(pkgs []*types.Package)
+	visit = func(pkgs []*types.Package) {
+		for _, pkg := range pkgs {
+			if _, ok := deps[pkg.Path()]; !ok {
+				deps[pkg.Path()] = pkg
+				visit(pkg.Imports())
+			}
+		}
+	}
+	visit(pass.Pkg.Imports())
+	var fallback types.Importer
+	imp := importerFunc(func(path string) (*types.Package, error) {
+		if pkg, ok := deps[path]; ok {
+			return pkg, nil
+		}
+		if fallback == nil {
+			fallback = importer.ForCompiler(fset, "gc", nil)
+		}
+		return fallback.Import(path)
+	})
+
+	var firstErr error
+	conf := &types.Config{
+		Importer:  imp,
+		Sizes:     pass.TypesSizes,
+		GoVersion: pass.Pkg.GoVersion(),
+		Error: func(err error) {
+			if firstErr == nil {
+				firstErr = err
+			}
+		},
+	}
+	conf.Check(pass.Pkg.Path(), fset, files, nil) // error is firstErr
+	return firstErr
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
 // Run applies an analysis to the packages denoted by the "go list" patterns.
 //
 // It loads the packages from the specified
@@ -337,6 +542,17 @@ func applyDiffsAndCompare(src, golden []byte, edits []diff.Edit, fileName string
 //
 //	// want "diag" "diag2" x:"fact1" x:"fact2" y:"fact3"
 //
+// A diagnostic expectation may be followed by a bracketed list of the
+// messages (SuggestedFix.Message, not regular expressions) of the
+// fixes that the diagnostic must offer, in any order. An empty list
+// asserts that the diagnostic offers no fixes:
+//
+//	if !!b { // want "negating a boolean twice" ["remove double negation", "turn into single negation"]
+//	x = x    // want "self-assignment" []
+//
+// A diagnostic expectation without such a list places no constraint
+// on the fixes.
+//
 // Unexpected diagnostics and facts, and unmatched expectations, are
 // reported as errors to the Testing.
 //
@@ -527,7 +743,10 @@ func check(t Testing, gopath string, pass *analysis.Pass, diagnostics []analysis
 		}
 	}
 
-	checkMessage := func(posn token.Position, kind, name, message string) {
+	// checkMessage matches a diagnostic or fact against the
+	// expectations. For a diagnostic, fixes holds the messages of
+	// its suggested fixes.
+	checkMessage := func(posn token.Position, kind, name, message string, fixes []string) {
 		posn.Filename = sanitize(gopath, posn.Filename)
 		k := key{posn.Filename, posn.Line}
 		expects := want[k]
@@ -539,6 +758,9 @@ func check(t Testing, gopath string, pass *analysis.Pass, diagnostics []analysis
 					expects[i] = expects[len(expects)-1]
 					expects = expects[:len(expects)-1]
 					want[k] = expects
+					if exp.fixes != nil {
+						checkFixes(t, posn, message, exp.fixes, fixes)
+					}
 					return
 				}
 				unmatched = append(unmatched, fmt.Sprintf("%#q", exp.rx))
@@ -556,7 +778,11 @@ func check(t Testing, gopath string, pass *analysis.Pass, diagnostics []analysis
 	for _, f := range diagnostics {
 		// TODO(matloob): Support ranges in analysistest.
 		posn := pass.Fset.Position(f.Pos)
-		checkMessage(posn, "diagnostic", "", f.Message)
+		var fixes []string
+		for _, fix := range f.SuggestedFixes {
+			fixes = append(fixes, fix.Message)
+		}
+		checkMessage(posn, "diagnostic", "", f.Message, fixes)
 	}
 
 	// Check the facts match expectations.
@@ -591,7 +817,7 @@ func check(t Testing, gopath string, pass *analysis.Pass, diagnostics []analysis
 		}
 
 		for _, fact := range facts[obj] {
-			checkMessage(posn, "fact", name, fmt.Sprint(fact))
+			checkMessage(posn, "fact", name, fmt.Sprint(fact), nil)
 		}
 	}
 
@@ -615,10 +841,41 @@ func check(t Testing, gopath string, pass *analysis.Pass, diagnostics []analysis
 	}
 }
 
+// checkFixes reports the difference between the messages of the
+// suggested fixes offered by a diagnostic (got) and those expected
+// by its '// want' comment (want).
+func checkFixes(t Testing, posn token.Position, message string, want, got []string) {
+	count := make(map[string]int)
+	for _, msg := range want {
+		count[msg]++
+	}
+	for _, msg := range got {
+		count[msg]--
+	}
+	var missing, extra []string
+	for msg, n := range count {
+		for ; n > 0; n-- {
+			missing = append(missing, strconv.Quote(msg))
+		}
+		for ; n < 0; n++ {
+			extra = append(extra, strconv.Quote(msg))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	if missing != nil {
+		t.Errorf("%v: diagnostic %q does not offer expected fixes %s", posn, message, strings.Join(missing, ", "))
+	}
+	if extra != nil {
+		t.Errorf("%v: diagnostic %q offers unexpected fixes %s", posn, message, strings.Join(extra, ", "))
+	}
+}
+
 type expectation struct {
-	kind string // either "fact" or "diagnostic"
-	name string // name of object to which fact belongs, or "package" ("fact" only)
-	rx   *regexp.Regexp
+	kind  string // either "fact" or "diagnostic"
+	name  string // name of object to which fact belongs, or "package" ("fact" only)
+	rx    *regexp.Regexp
+	fixes []string // if non-nil, messages of expected suggested fixes ("diagnostic" only)
 }
 
 func (ex expectation) String() string {
@@ -626,8 +883,8 @@ func (ex expectation) String() string {
 }
 
 // parseExpectations parses the content of a "// want ..." comment
-// and returns the expectations, a mixture of diagnostics ("rx") and
-// facts (name:"rx").
+// and returns the expectations, a mixture of diagnostics ("rx" or
+// "rx" ["fix", ...]) and facts (name:"rx").
 func parseExpectations(text string) (lineDelta int, expects []expectation, err error) {
 	var scanErr string
 	sc := new(scanner.Scanner).Init(strings.NewReader(text))
@@ -659,7 +916,28 @@ func parseExpectations(text string) (lineDelta int, expects []expectation, err e
 			if err != nil {
 				return 0, nil, err
 			}
-			expects = append(expects, expectation{"diagnostic", "", rx})
+			expects = append(expects, expectation{"diagnostic", "", rx, nil})
+
+		case '[':
+			// Messages of the fixes of the preceding diagnostic.
+			if len(expects) == 0 || expects[len(expects)-1].kind != "diagnostic" || expects[len(expects)-1].fixes != nil {
+				return 0, nil, fmt.Errorf("fix list must follow a diagnostic expectation")
+			}
+			fixes := []string{}
+			for tok = sc.Scan(); tok != ']'; tok = sc.Scan() {
+				if len(fixes) > 0 {
+					if tok != ',' {
+						return 0, nil, fmt.Errorf("got %s in fix list, want ',' or ']'", scanner.TokenString(tok))
+					}
+					tok = sc.Scan()
+				}
+				if tok != scanner.String && tok != scanner.RawString {
+					return 0, nil, fmt.Errorf("got %s in fix list, want fix message", scanner.TokenString(tok))
+				}
+				msg, _ := strconv.Unquote(sc.TokenText()) // can't fail
+				fixes = append(fixes, msg)
+			}
+			expects[len(expects)-1].fixes = fixes
 
 		case scanner.Ident:
 			name := sc.TokenText()
@@ -673,7 +951,7 @@ func parseExpectations(text string) (lineDelta int, expects []expectation, err e
 			if err != nil {
 				return 0, nil, err
 			}
-			expects = append(expects, expectation{"fact", name, rx})
+			expects = append(expects, expectation{"fact", name, rx, nil})
 
 		case scanner.EOF:
 			if scanErr != "" {
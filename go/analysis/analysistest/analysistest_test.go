@@ -6,10 +6,12 @@ package analysistest_test
 
 import (
 	"fmt"
+	"go/ast"
 	"go/token"
 	"log"
 	"os"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -204,6 +206,130 @@ func F() {} // want "say hello"`,
 	analysistest.RunWithSuggestedFixes(t, dir, noend, "a")
 }
 
+// TestEachSuggestedFix tests RunWithEachSuggestedFix and the
+// '// want' syntax for the expected set of fixes.
+func TestEachSuggestedFix(t *testing.T) {
+	testenv.NeedsTool(t, "go")
+
+	// alternatives offers two alternative fixes for each function,
+	// one of which yields ill-typed code.
+	alternatives := &analysis.Analyzer{
+		Name: "alternatives",
+		Doc:  "offers alternative fixes for each function",
+		Run: func(pass *analysis.Pass) (any, error) {
+			for _, file := range pass.Files {
+				for _, decl := range file.Decls {
+					decl, ok := decl.(*ast.FuncDecl)
+					if !ok {
+						continue
+					}
+					pass.Report(analysis.Diagnostic{
+						Pos:     decl.Name.Pos(),
+						Message: "func " + decl.Name.Name,
+						SuggestedFixes: []analysis.SuggestedFix{
+							{
+								Message: "rename",
+								TextEdits: []analysis.TextEdit{{
+									Pos:     decl.Name.End(),
+									NewText: []byte("2"),
+								}},
+							},
+							{
+								Message: "return one",
+								TextEdits: []analysis.TextEdit{{
+									Pos:     decl.Body.Pos(),
+									End:     decl.Body.End(),
+									NewText: []byte("{ return 1 }"),
+								}},
+							},
+						},
+					})
+				}
+			}
+			return nil, nil
+		},
+	}
+
+	filemap := map[string]string{
+		"a/a.go": `package a
+
+func F() {} // want "func F" ["rename", "return one"]
+
+func G() {} // want "func G" ["rename"]
+
+func H() {} // want "func H" ["rename", "return one", "inline"]
+`,
+		"a/a.go.golden": `-- 3: rename --
+package a
+
+func F2() {} // want "func F" ["rename", "return one"]
+
+func G() {} // want "func G" ["rename"]
+
+func H() {} // want "func H" ["rename", "return one", "inline"]
+
+-- 3: return one --
+package a
+
+func F() { return 1 } // want "func F" ["rename", "return one"]
+
+func G() {} // want "func G" ["rename"]
+
+func H() {} // want "func H" ["rename", "return one", "inline"]
+
+-- 5: rename --
+package a
+
+func F() {} // want "func F" ["rename", "return one"]
+
+func G3() {} // want "func G" ["rename"]
+
+func H() {} // want "func H" ["rename", "return one", "inline"]
+
+-- 7: rename --
+package a
+
+func F() {} // want "func F" ["rename", "return one"]
+
+func G() {} // want "func G" ["rename"]
+
+func H2() {} // want "func H" ["rename", "return one", "inline"]
+
+-- 7: inline --
+package a
+`,
+	}
+	dir, cleanup, err := analysistest.WriteFiles(filemap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	var got []string
+	t2 := errorfunc(func(s string) { got = append(got, s) }) // a fake *testing.T
+	analysistest.RunWithEachSuggestedFix(t2, dir, alternatives, "a")
+
+	want := []string{
+		`^a/a.go:5:6: diagnostic "func G" offers unexpected fixes "return one"$`,
+		`^a/a.go:7:6: diagnostic "func H" does not offer expected fixes "inline"$`,
+		`^a/a.go:3:6: suggested fix "3: return one" produces ill-typed code: .*too many return values`,
+		`(?s)^fix "5: rename": suggested fixes failed for .*a/a.go:.*-func G3\(\) {}.*\+func G2\(\) {}`,
+		`^no section for suggested fix "5: return one" in .*a/a.go.golden$`,
+		`^a/a.go:5:6: suggested fix "5: return one" produces ill-typed code: .*too many return values`,
+		`^no section for suggested fix "7: return one" in .*a/a.go.golden$`,
+		`^a/a.go:7:6: suggested fix "7: return one" produces ill-typed code: .*too many return values`,
+		`^.*a/a.go.golden: section "7: inline" matches no suggested fix$`,
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d errors, want %d:\n%s", len(got), len(want), strings.Join(got, "\n"))
+	}
+	for i := range min(len(got), len(want)) {
+		if !regexp.MustCompile(want[i]).MatchString(got[i]) {
+			t.Errorf("error %d: got %q, want match for %#q", i, got[i], want[i])
+		}
+	}
+}
+
 func TestModule(t *testing.T) {
 	const content = `
 Test that analysis.pass.Module is populated.
@@ -19,13 +19,15 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/troll-zhao/tools/core/analysisconfig"
 	"golang.org/x/tools/go/analysis"
 )
 
 // flags common to all {single,multi,unit}checkers.
 var (
-	JSON    = false // -json
-	Context = -1    // -c=N: if N>0, display offending line plus N lines of context
+	JSON    = false                // -json
+	Context = -1                   // -c=N: if N>0, display offending line plus N lines of context
+	Config  *analysisconfig.Config // -config=file: shared analyzer configuration, or nil
 )
 
 // Parse creates a flag for each of the analyzer's flags,
@@ -33,6 +35,12 @@ var (
 // parses the flags, then filters and returns the list of
 // analyzers enabled by flags.
 //
+// If the -config flag names an [analysisconfig] file, its analyzer
+// flag values are applied (except where the same flag appears on the
+// command line) and, in multi mode, its enabled set determines which
+// analyzers run unless some -NAME flag appears on the command line.
+// The parsed file is saved in Config for use by the driver.
+//
 // The result is intended to be passed to unitchecker.Run or checker.Run.
 // Use in unitchecker.Run will gob.Register all fact types for the returned
 // graph of analyzers but of course not the ones only reachable from
@@ -74,6 +82,7 @@ func Parse(analyzers []*analysis.Analyzer, multi bool) []*analysis.Analyzer {
 	// flags common to all checkers
 	flag.BoolVar(&JSON, "json", JSON, "emit JSON output")
 	flag.IntVar(&Context, "c", Context, `display offending line with this many lines of context`)
+	configFile := flag.String("config", "", "read analyzer flags, severities, enabled set, and excludes from this JSON file (a relative name is resolved against the module root)")
 
 	// Add shims for legacy vet flags to enable existing
 	// scripts that run vet to continue to work.
@@ -98,8 +107,24 @@ func Parse(analyzers []*analysis.Analyzer, multi bool) []*analysis.Analyzer {
 
 	everything := expand(analyzers)
 
+	if *configFile != "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg, err := analysisconfig.Load(analysisconfig.ResolveFile(*configFile, dir))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := applyConfig(cfg, everything, multi); err != nil {
+			log.Fatalf("%s: %v", *configFile, err)
+		}
+		Config = cfg
+	}
+
 	// If any -NAME flag is true,  run only those analyzers. Otherwise,
 	// if any -NAME flag is false, run all but those analyzers.
+	// Otherwise, run the analyzers enabled by the -config file.
 	if multi {
 		var hasTrue, hasFalse bool
 		for _, ts := range enabled {
@@ -126,6 +151,13 @@ func Parse(analyzers []*analysis.Analyzer, multi bool) []*analysis.Analyzer {
 				}
 			}
 			analyzers = keep
+		} else if Config != nil {
+			for _, a := range analyzers {
+				if Config.Enabled(a.Name, true) {
+					keep = append(keep, a)
+				}
+			}
+			analyzers = keep
 		}
 	}
 
@@ -143,6 +175,39 @@ func Parse(analyzers []*analysis.Analyzer, multi bool) []*analysis.Analyzer {
 	return analyzers
 }
 
+// applyConfig sets the flags of each analyzer to the values specified
+// by cfg, unless the same flag was set on the command line.
+func applyConfig(cfg *analysisconfig.Config, analyzers map[*analysis.Analyzer]bool, multi bool) error {
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for a := range analyzers {
+		for name, value := range cfg.Flags(a.Name) {
+			f := a.Flags.Lookup(name)
+			if f == nil {
+				return fmt.Errorf("analyzer %s has no flag %q", a.Name, name)
+			}
+			cmdline := name
+			if multi {
+				cmdline = a.Name + "." + name
+			}
+			if explicit[cmdline] {
+				continue // command line takes precedence
+			}
+			if err := f.Value.Set(value); err != nil {
+				return fmt.Errorf("invalid value %q for flag %s of analyzer %s: %v", value, name, a.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Excluded reports whether diagnostics of analyzer a at the specified
+// position are suppressed by an exclude entry of the -config file.
+func Excluded(fset *token.FileSet, a *analysis.Analyzer, pos token.Pos) bool {
+	return Config.Excluded(fset.Position(pos).Filename, a.Name)
+}
+
 func expand(analyzers []*analysis.Analyzer) map[*analysis.Analyzer]bool {
 	seen := make(map[*analysis.Analyzer]bool)
 	var visitAll func([]*analysis.Analyzer)
@@ -170,7 +235,7 @@ func printFlags() {
 		// flags or fix as these have no effect on unitchecker
 		// (as invoked by 'go vet').
 		switch f.Name {
-		case "debug", "cpuprofile", "memprofile", "trace", "fix":
+		case "debug", "cpuprofile", "memprofile", "trace", "fix", "profile", "workspace":
 			return
 		}
 
@@ -369,6 +434,7 @@ type JSONDiagnostic struct {
 	Category       string                   `json:"category,omitempty"`
 	Posn           string                   `json:"posn"` // e.g. "file.go:line:column"
 	Message        string                   `json:"message"`
+	Severity       string                   `json:"severity,omitempty"` // from the -config file, if any
 	SuggestedFixes []JSONSuggestedFix       `json:"suggested_fixes,omitempty"`
 	Related        []JSONRelatedInformation `json:"related,omitempty"`
 }
@@ -421,6 +487,7 @@ func (tree JSONTree) Add(fset *token.FileSet, id, name string, diags []analysis.
 				Category:       f.Category,
 				Posn:           fset.Position(f.Pos).String(),
 				Message:        f.Message,
+				Severity:       Config.Severity(name),
 				SuggestedFixes: fixes,
 				Related:        related,
 			}
@@ -42,6 +42,9 @@ func Help(progname string, analyzers []*analysis.Analyzer, args []string) {
 		fmt.Println("\nBy default all analyzers are run.")
 		fmt.Println("To select specific analyzers, use the -NAME flag for each one,")
 		fmt.Println(" or -NAME=false to run all analyzers not explicitly disabled.")
+		fmt.Println("Alternatively, use -config=goanalysis.json to select analyzers,")
+		fmt.Println(" set their flags and severities, and exclude files, in a format")
+		fmt.Println(" that is also understood by gopls.")
 
 		// Show only the core command-line flags.
 		fmt.Println("\nCore flags:")
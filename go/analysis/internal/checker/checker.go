@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/troll-zhao/tools/core/analysisinternal"
+	"github.com/troll-zhao/tools/core/analysisprofile"
 	"github.com/troll-zhao/tools/core/diff"
 	"github.com/troll-zhao/tools/core/robustio"
 	"golang.org/x/tools/go/analysis"
@@ -54,6 +55,15 @@ var (
 
 	// Fix determines whether to apply all suggested fixes.
 	Fix bool
+
+	// Profile, if set, is the format ("json" or "table") of a
+	// per-analyzer performance profile printed to the standard error.
+	Profile string
+
+	// Workspace, if set, is either a go.work file or a comma-separated
+	// list of module directories whose modules are loaded and
+	// analyzed together as a single program.
+	Workspace string
 )
 
 // RegisterFlags registers command-line flags used by the analysis driver.
@@ -69,6 +79,8 @@ func RegisterFlags() {
 	flag.BoolVar(&IncludeTests, "test", IncludeTests, "indicates whether test files should be analyzed, too")
 
 	flag.BoolVar(&Fix, "fix", false, "apply all suggested fixes")
+	flag.StringVar(&Profile, "profile", "", `print per-analyzer performance profile in this format, "json" or "table"`)
+	flag.StringVar(&Workspace, "workspace", "", "analyze the modules of this go.work file, or comma-separated list of module directories, as one program")
 }
 
 // Run loads the packages specified by args using go/packages,
@@ -78,7 +90,19 @@ func RegisterFlags() {
 // It provides most of the logic for the main functions of both the
 // singlechecker and the multi-analysis commands.
 // It returns the appropriate exit code.
+//
+// If Workspace is set, Run loads the packages of all the workspace's
+// modules in a single graph (all of them, if args is empty), so that
+// facts propagate across modules, and it finally prints a summary of
+// the results for each module to the standard error.
 func Run(args []string, analyzers []*analysis.Analyzer) (exitcode int) {
+	switch Profile {
+	case "", "json", "table":
+	default:
+		log.Printf("invalid -profile format %q; want json or table", Profile)
+		return 1
+	}
+
 	if CPUProfile != "" {
 		f, err := os.Create(CPUProfile)
 		if err != nil {
@@ -128,10 +152,21 @@ func Run(args []string, analyzers []*analysis.Analyzer) (exitcode int) {
 		log.Printf("load %s", args)
 	}
 
+	var ws *workspace
+	if Workspace != "" {
+		var err error
+		ws, err = loadWorkspace(Workspace)
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		defer ws.cleanup()
+	}
+
 	// Optimization: if the selected analyzers don't produce/consume
 	// facts, we need source only for the initial packages.
 	allSyntax := needFacts(analyzers)
-	initial, err := load(args, allSyntax)
+	initial, err := load(args, allSyntax, ws)
 	if err != nil {
 		log.Print(err)
 		return 1
@@ -163,7 +198,15 @@ func Run(args []string, analyzers []*analysis.Analyzer) (exitcode int) {
 	// are errors in the packages, this will have 0 exit
 	// code. Otherwise, we prefer to return exit code
 	// indicating diagnostics.
-	if diagExitCode := printDiagnostics(roots); diagExitCode != 0 {
+	diagExitCode := printDiagnostics(roots)
+	if ws != nil {
+		ws.printSummary(os.Stderr, roots)
+	}
+	if err := printProfile(); err != nil {
+		log.Print(err)
+		return 1
+	}
+	if diagExitCode != 0 {
 		return diagExitCode // there were diagnostics
 	}
 	return pkgsExitCode // package errors but no diagnostics
@@ -171,7 +214,9 @@ func Run(args []string, analyzers []*analysis.Analyzer) (exitcode int) {
 
 // load loads the initial packages. Returns only top-level loading
 // errors. Does not consider errors in packages.
-func load(patterns []string, allSyntax bool) ([]*packages.Package, error) {
+// If ws is non-nil, the packages are loaded in its workspace,
+// and an empty list of patterns denotes all its packages.
+func load(patterns []string, allSyntax bool, ws *workspace) ([]*packages.Package, error) {
 	mode := packages.LoadSyntax
 	if allSyntax {
 		mode = packages.LoadAllSyntax
@@ -181,6 +226,12 @@ func load(patterns []string, allSyntax bool) ([]*packages.Package, error) {
 		Mode:  mode,
 		Tests: IncludeTests,
 	}
+	if ws != nil {
+		conf.Env = append(os.Environ(), "GOWORK="+ws.gowork)
+		if len(patterns) == 0 {
+			patterns = ws.patterns()
+		}
+	}
 	initial, err := packages.Load(&conf, patterns...)
 	if err == nil && len(initial) == 0 {
 		err = fmt.Errorf("%s matched no packages", strings.Join(patterns, " "))
@@ -487,8 +538,10 @@ func diff3Conflict(path string, xlabel, ylabel string, xedits, yedits []diff.Edi
 //
 // It returns the exitcode: in plain mode, 0 for success, 1 for analysis
 // errors, and 3 for diagnostics. We avoid 2 since the flag package uses
-// it. JSON mode always succeeds at printing errors and diagnostics in a
-// structured form to stdout.
+// it. Diagnostics whose severity in the -config file is "info" or
+// "hint" are printed but do not affect the exit code. JSON mode always
+// succeeds at printing errors and diagnostics in a structured form to
+// stdout.
 func printDiagnostics(roots []*action) (exitcode int) {
 	// Print the output.
 	//
@@ -532,6 +585,7 @@ func printDiagnostics(roots []*action) (exitcode int) {
 			message string
 		}
 		seen := make(map[key]bool)
+		failed := false // some diagnostic has severity error or warning
 
 		print = func(act *action) {
 			if act.err != nil {
@@ -553,12 +607,15 @@ func printDiagnostics(roots []*action) (exitcode int) {
 					seen[k] = true
 
 					analysisflags.PrintPlain(act.pkg.Fset, diag)
+					if sev := analysisflags.Config.Severity(act.a.Name); sev != "info" && sev != "hint" {
+						failed = true
+					}
 				}
 			}
 		}
 		visitAll(roots)
 
-		if exitcode == 0 && len(seen) > 0 {
+		if exitcode == 0 && failed {
 			exitcode = 3 // successfully produced diagnostics
 		}
 	}
@@ -592,6 +649,24 @@ func printDiagnostics(roots []*action) (exitcode int) {
 	return exitcode
 }
 
+// profile accumulates the statistics printed by printProfile.
+var profile analysisprofile.Profile
+
+// printProfile prints the per-analyzer performance profile
+// to the standard error in the format specified by Profile.
+func printProfile() error {
+	if Profile != "" && !dbg('p') {
+		log.Println("Warning: CPU and allocation statistics are mostly noise; use -debug=p to disable parallelism")
+	}
+	switch Profile {
+	case "json":
+		return profile.WriteJSON(os.Stderr)
+	case "table":
+		return profile.WriteTable(os.Stderr)
+	}
+	return nil
+}
+
 // needFacts reports whether any analysis required by the specified set
 // needs facts.  If so, we must load the entire program from source.
 func needFacts(analyzers []*analysis.Analyzer) bool {
@@ -727,6 +802,13 @@ func (act *action) execOnce() {
 		module.GoVersion = mod.GoVersion
 	}
 
+	// Diagnostics in files excluded by the -config file are discarded.
+	report := func(d analysis.Diagnostic) {
+		if !analysisflags.Excluded(act.pkg.Fset, act.a, d.Pos) {
+			act.diagnostics = append(act.diagnostics, d)
+		}
+	}
+
 	// Run the analysis.
 	pass := &analysis.Pass{
 		Analyzer:     act.a,
@@ -741,7 +823,7 @@ func (act *action) execOnce() {
 		Module:       module,
 
 		ResultOf:          inputs,
-		Report:            func(d analysis.Diagnostic) { act.diagnostics = append(act.diagnostics, d) },
+		Report:            report,
 		ImportObjectFact:  act.importObjectFact,
 		ExportObjectFact:  act.exportObjectFact,
 		ImportPackageFact: act.importPackageFact,
@@ -756,7 +838,16 @@ func (act *action) execOnce() {
 	if act.pkg.IllTyped && !pass.Analyzer.RunDespiteErrors {
 		err = fmt.Errorf("analysis skipped due to errors in package")
 	} else {
+		var start analysisprofile.Sample
+		if Profile != "" {
+			start = analysisprofile.Begin()
+		}
 		act.result, err = pass.Analyzer.Run(pass)
+		if Profile != "" {
+			span := analysisprofile.End(start) // before encoding facts
+			nfacts, size := act.exportedFacts()
+			profile.Add(act.a.Name, span, nfacts, size, len(act.diagnostics))
+		}
 		if err == nil {
 			if got, want := reflect.TypeOf(act.result), pass.Analyzer.ResultType; got != want {
 				err = fmt.Errorf(
@@ -836,6 +927,29 @@ func inheritFacts(act, dep *action) {
 	}
 }
 
+// exportedFacts returns the number of facts exported by act
+// and the total size of their gob encodings.
+func (act *action) exportedFacts() (n, size int) {
+	add := func(fact analysis.Fact) {
+		n++
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(fact); err == nil {
+			size += buf.Len()
+		}
+	}
+	for key, fact := range act.objectFacts {
+		if key.obj.Pkg() == act.pkg.Types {
+			add(fact)
+		}
+	}
+	for key, fact := range act.packageFacts {
+		if key.pkg == act.pkg.Types {
+			add(fact)
+		}
+	}
+	return n, size
+}
+
 // codeFact encodes then decodes a fact,
 // just to exercise that logic.
 func codeFact(fact analysis.Fact) (analysis.Fact, error) {
@@ -5,18 +5,25 @@
 package checker_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"go/ast"
+	"io"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/troll-zhao/tools/core/analysisconfig"
+	"github.com/troll-zhao/tools/core/analysisprofile"
 	"github.com/troll-zhao/tools/core/testenv"
 	"github.com/troll-zhao/tools/core/testfiles"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/internal/analysisflags"
 	"golang.org/x/tools/go/analysis/internal/checker"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
@@ -229,6 +236,225 @@ func NewT1() *T1 { return &T1{T} }
 	}
 }
 
+// TestWorkspace tests that the modules of a workspace are analyzed
+// as one program, with facts flowing between modules.
+func TestWorkspace(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"a/go.mod": "module example.com/a\n\ngo 1.21\n",
+		"a/a.go": `package a
+
+func Bad() {}
+
+func Good() {}
+`,
+		"b/go.mod": "module example.com/b\n\ngo 1.21\n",
+		"b/b.go": `package b
+
+import "example.com/a"
+
+func F() {
+	a.Bad()
+	a.Good()
+}
+`,
+		"go.work": "go 1.21\n\nuse (\n\t./a\n\t./b\n)\n",
+	}
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	src := filepath.Join(testdata, "src")
+
+	// badcall reports calls to functions named Bad,
+	// which it learns of through facts.
+	badcall := &analysis.Analyzer{
+		Name:      "badcall",
+		Doc:       "reports calls to functions named Bad",
+		Requires:  []*analysis.Analyzer{inspect.Analyzer},
+		FactTypes: []analysis.Fact{&EmptyFact{}},
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			for _, f := range pass.Files {
+				for _, decl := range f.Decls {
+					if decl, ok := decl.(*ast.FuncDecl); ok && decl.Name.Name == "Bad" {
+						pass.ExportObjectFact(pass.TypesInfo.Defs[decl.Name], &EmptyFact{})
+					}
+				}
+			}
+			inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+			inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+				call := n.(*ast.CallExpr)
+				if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+					if obj := pass.TypesInfo.Uses[sel.Sel]; obj != nil && pass.ImportObjectFact(obj, new(EmptyFact)) {
+						pass.Reportf(call.Pos(), "call of %s", obj.Name())
+					}
+				}
+			})
+			return nil, nil
+		},
+	}
+
+	defer func() { checker.Workspace = "" }()
+	for _, spec := range []string{
+		filepath.Join(src, "go.work"),
+		filepath.Join(src, "a") + "," + filepath.Join(src, "b"),
+	} {
+		checker.Workspace = spec
+		var code int
+		out := captureStderr(t, func() {
+			code = checker.Run(nil, []*analysis.Analyzer{badcall})
+		})
+
+		if code != 3 {
+			t.Errorf("%s: got exit code %d, want 3; output:\n%s", spec, code, out)
+		}
+		for _, want := range []string{
+			`b.go:6:2: call of Bad`,
+			`(?m)^example.com/a +1 +0 +0$`,
+			`(?m)^example.com/b +1 +1 +0$`,
+			`(?m)^total +2 +1 +0$`,
+		} {
+			if !regexp.MustCompile(want).Match(out) {
+				t.Errorf("%s: output does not match %#q:\n%s", spec, want, out)
+			}
+		}
+	}
+}
+
+// TestProfile tests the -profile flag.
+func TestProfile(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"rename/test.go": `package rename
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+	testdata, cleanup, err := analysistest.WriteFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	path := filepath.Join(testdata, "src/rename/test.go")
+
+	checker.Profile = "json"
+	defer func() { checker.Profile = "" }()
+	var code int
+	out := captureStderr(t, func() {
+		code = checker.Run([]string{"file=" + path}, []*analysis.Analyzer{renameAnalyzer})
+	})
+	if code != 3 {
+		t.Errorf("got exit code %d, want 3", code)
+	}
+
+	// The profile follows the diagnostics.
+	i := bytes.IndexByte(out, '[')
+	if i < 0 {
+		t.Fatalf("no JSON profile in output:\n%s", out)
+	}
+	var stats []analysisprofile.Stats
+	if err := json.Unmarshal(out[i:], &stats); err != nil {
+		t.Fatalf("invalid JSON profile: %v\n%s", err, out)
+	}
+	got := make(map[string]analysisprofile.Stats)
+	for _, s := range stats {
+		got[s.Analyzer] = s
+	}
+	if s := got["rename"]; s.Packages != 1 || s.Diagnostics != 2 {
+		t.Errorf("rename: got %+v, want 1 package and 2 diagnostics", s)
+	}
+	if s := got["inspect"]; s.Packages != 1 || s.Diagnostics != 0 {
+		t.Errorf("inspect: got %+v, want 1 package and no diagnostics", s)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	testenv.NeedsGoPackages(t)
+
+	files := map[string]string{
+		"rename/test.go": `package rename
+
+func Foo() {
+	bar := 12
+	_ = bar
+}
+`}
+
+	for _, test := range []struct {
+		name   string
+		config string
+		code   int  // exit code
+		diags  bool // whether diagnostics are printed
+	}{
+		{"none", `{}`, 3, true},
+		{"info", `{"analyzers": {"rename": {"severity": "info"}}}`, 0, true},
+		{"exclude", `{"exclude": [{"path": "src/rename/..."}]}`, 0, false},
+		{"exclude-base", `{"exclude": [{"path": "test.go", "analyzers": ["rename"]}]}`, 0, false},
+		{"exclude-other", `{"exclude": [{"path": "*.go", "analyzers": ["other"]}]}`, 3, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Write the files afresh, in case an earlier test left -fix enabled.
+			testdata, cleanup, err := analysistest.WriteFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cleanup()
+			path := filepath.Join(testdata, "src/rename/test.go")
+
+			cfg, err := analysisconfig.Parse(filepath.Join(testdata, analysisconfig.FileName), []byte(test.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			analysisflags.Config = cfg
+			defer func() { analysisflags.Config = nil }()
+
+			var code int
+			out := captureStderr(t, func() {
+				code = checker.Run([]string{"file=" + path}, []*analysis.Analyzer{renameAnalyzer})
+			})
+			if code != test.code {
+				t.Errorf("got exit code %d, want %d", code, test.code)
+			}
+			if got := bytes.Contains(out, []byte("renaming")); got != test.diags {
+				t.Errorf("diagnostics printed: got %t, want %t; output:\n%s", got, test.diags, out)
+			}
+		})
+	}
+}
+
+// captureStderr calls f and returns what it writes to os.Stderr.
+func captureStderr(t *testing.T, f func()) []byte {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drain the pipe concurrently lest f block on a full buffer.
+	type result struct {
+		out []byte
+		err error
+	}
+	done := make(chan result)
+	go func() {
+		out, err := io.ReadAll(r)
+		done <- result{out, err}
+	}()
+	stderr := os.Stderr
+	os.Stderr = w
+	f()
+	os.Stderr = stderr
+	w.Close()
+	res := <-done
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	return res.out
+}
+
 type EmptyFact struct{}
 
 func (f *EmptyFact) AFact() {}
@@ -0,0 +1,181 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"go/version"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/mod/modfile"
+)
+
+// A workspace is a set of modules that are loaded and analyzed
+// together as a single program, so that facts flow across module
+// boundaries exactly as they do within a module.
+type workspace struct {
+	gowork  string   // go.work file (possibly synthesized) passed to the go command as GOWORK
+	dirs    []string // absolute module root directories, in go.work order
+	modules []string // module paths, parallel to dirs
+	cleanup func()   // deletes the synthesized go.work file, if any
+}
+
+// loadWorkspace returns the workspace denoted by spec, which is
+// either the name of a go.work file or a comma-separated list of
+// module root directories. In the latter case, it synthesizes a
+// temporary go.work file that uses each of the modules.
+func loadWorkspace(spec string) (*workspace, error) {
+	ws := &workspace{cleanup: func() {}}
+
+	if info, err := os.Stat(spec); err == nil && info.Mode().IsRegular() {
+		// go.work file
+		gowork, err := filepath.Abs(spec)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(gowork)
+		if err != nil {
+			return nil, err
+		}
+		wf, err := modfile.ParseWork(gowork, data, nil)
+		if err != nil {
+			return nil, err
+		}
+		ws.gowork = gowork
+		for _, use := range wf.Use {
+			dir := use.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(filepath.Dir(gowork), dir)
+			}
+			ws.dirs = append(ws.dirs, dir)
+		}
+	} else {
+		// list of module directories
+		for _, dir := range strings.Split(spec, ",") {
+			if dir == "" {
+				continue
+			}
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				return nil, err
+			}
+			ws.dirs = append(ws.dirs, dir)
+		}
+	}
+	if len(ws.dirs) == 0 {
+		return nil, fmt.Errorf("workspace %s contains no modules", spec)
+	}
+
+	// Read the module path and Go version of each module.
+	goVersion := "1.18" // the first version to support go.work files
+	for _, dir := range ws.dirs {
+		gomod := filepath.Join(dir, "go.mod")
+		data, err := os.ReadFile(gomod)
+		if err != nil {
+			return nil, err
+		}
+		mf, err := modfile.ParseLax(gomod, data, nil)
+		if err != nil {
+			return nil, err
+		}
+		if mf.Module == nil {
+			return nil, fmt.Errorf("%s: no module declaration", gomod)
+		}
+		ws.modules = append(ws.modules, mf.Module.Mod.Path)
+		if mf.Go != nil && version.Compare("go"+mf.Go.Version, "go"+goVersion) > 0 {
+			goVersion = mf.Go.Version
+		}
+	}
+
+	if ws.gowork == "" {
+		// Synthesize a go.work file. Its go version must be
+		// at least that of each of the modules it uses.
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "go %s\n\nuse (\n", goVersion)
+		for _, dir := range ws.dirs {
+			fmt.Fprintf(&buf, "\t%s\n", modfile.AutoQuote(dir))
+		}
+		fmt.Fprintf(&buf, ")\n")
+
+		tmpdir, err := os.MkdirTemp("", "checker-workspace")
+		if err != nil {
+			return nil, err
+		}
+		ws.cleanup = func() { os.RemoveAll(tmpdir) }
+		ws.gowork = filepath.Join(tmpdir, "go.work")
+		if err := os.WriteFile(ws.gowork, buf.Bytes(), 0666); err != nil {
+			ws.cleanup()
+			return nil, err
+		}
+	}
+	return ws, nil
+}
+
+// patterns returns the go list patterns that match all packages of
+// all modules of the workspace.
+func (ws *workspace) patterns() []string {
+	patterns := make([]string, len(ws.modules))
+	for i, mod := range ws.modules {
+		patterns[i] = mod + "/..."
+	}
+	return patterns
+}
+
+// printSummary prints to w a table summarizing, for each module of
+// the workspace, the number of root packages analyzed, the number of
+// diagnostics reported in them, and the number of failed analyses.
+func (ws *workspace) printSummary(w io.Writer, roots []*action) {
+	type summary struct {
+		pkgs   map[string]bool // PkgPath of each root package
+		diags  map[string]bool // each distinct diagnostic, by position and message
+		errors int
+	}
+	summaries := make(map[string]*summary)
+	for _, mod := range ws.modules {
+		summaries[mod] = &summary{
+			pkgs:  make(map[string]bool),
+			diags: make(map[string]bool),
+		}
+	}
+	for _, act := range roots {
+		if act.pkg.Module == nil {
+			continue
+		}
+		s, ok := summaries[act.pkg.Module.Path]
+		if !ok {
+			continue // not a workspace module
+		}
+		s.pkgs[act.pkg.PkgPath] = true
+		if act.err != nil {
+			s.errors++
+			continue
+		}
+		for _, diag := range act.diagnostics {
+			// De-duplicate diagnostics in files that
+			// belong to several packages, such as foo
+			// and foo.test, as printDiagnostics does.
+			posn := act.pkg.Fset.Position(diag.Pos)
+			s.diags[fmt.Sprintf("%s\t%s\t%s", posn, act.a.Name, diag.Message)] = true
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "module\tpackages\tdiagnostics\terrors\n")
+	var npkgs, ndiags, nerrors int
+	for _, mod := range ws.modules {
+		s := summaries[mod]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", mod, len(s.pkgs), len(s.diags), s.errors)
+		npkgs += len(s.pkgs)
+		ndiags += len(s.diags)
+		nerrors += s.errors
+	}
+	fmt.Fprintf(tw, "total\t%d\t%d\t%d\n", npkgs, ndiags, nerrors)
+	tw.Flush()
+}
@@ -35,7 +35,7 @@ func Main(analyzers ...*analysis.Analyzer) {
 	analyzers = analysisflags.Parse(analyzers, true)
 
 	args := flag.Args()
-	if len(args) == 0 {
+	if len(args) == 0 && checker.Workspace == "" {
 		fmt.Fprintf(os.Stderr, `%[1]s is a tool for static analysis of Go programs.
 
 Usage: %[1]s [-flag] [package]
@@ -46,7 +46,7 @@ Run '%[1]s help' for more detail,
 		os.Exit(1)
 	}
 
-	if args[0] == "help" {
+	if len(args) > 0 && args[0] == "help" {
 		analysisflags.Help(progname, analyzers, args[1:])
 		os.Exit(0)
 	}
@@ -0,0 +1,407 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package callgraph is an analysis that provides an interprocedural
+// call graph of the current package, and summaries of the callees of
+// functions in its dependencies. By itself, it does not report any
+// diagnostics.
+//
+// Call edges are computed from the SSA form of the package (see
+// [buildssa]) using Class Hierarchy Analysis (CHA): a static call has
+// a single callee; a dynamic call through an interface may dispatch
+// to the method of any concrete type visible to the package (that
+// is, declared in it or in one of its transitive dependencies) that
+// implements the interface; and a dynamic call of a function value
+// may call any function of the package with the same signature.
+// Because the analysis is modular, it cannot see the types and
+// functions of packages that depend on the current one.
+//
+// For each function whose facts are visible to importing packages
+// (exported functions, and methods), the analysis exports a
+// [Summary] fact recording its callees, so that an analyzer that
+// requires this one can follow calls across package boundaries using
+// [Result.Callees], in the same modular way as other facts, and with
+// the same drivers, including unitchecker.
+package callgraph
+
+import (
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/objectpath"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name:       "callgraph",
+	Doc:        "build an interprocedural call graph",
+	URL:        "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/callgraph",
+	Run:        run,
+	ResultType: reflect.TypeOf(new(Result)),
+	FactTypes:  []analysis.Fact{new(Summary)},
+	Requires:   []*analysis.Analyzer{buildssa.Analyzer},
+}
+
+// A Summary is a fact that records the callees of a function: the
+// functions of other packages, and the summarized functions of its
+// own package, that it may call directly or through a chain of calls
+// to unsummarized functions (such as unexported functions and
+// function literals) of its own package.
+type Summary struct {
+	Callees []Callee // sorted by Name
+}
+
+func (*Summary) AFact() {}
+
+func (s *Summary) String() string {
+	if len(s.Callees) == 0 {
+		return "calls nothing"
+	}
+	names := make([]string, len(s.Callees))
+	for i, c := range s.Callees {
+		names[i] = c.Name
+	}
+	return "calls " + strings.Join(names, ", ")
+}
+
+// A Callee identifies a function in a Summary.
+type Callee struct {
+	PkgPath string          // path of the function's package
+	Path    objectpath.Path // path of the function within its package
+	Name    string          // for display, as by types.Func.FullName
+}
+
+// A Result holds the call graph of the current package and
+// the summaries of the functions of its dependencies.
+type Result struct {
+	// Graph is the call graph of the functions of the current
+	// package (including function literals, and the synthetic
+	// wrappers they call), with edges to their direct callees in
+	// any package. Its root node is nil.
+	Graph *callgraph.Graph
+
+	pkgs      map[string]*types.Package // transitive imports, keyed by path
+	summaries map[*types.Func]*Summary
+}
+
+// Callees returns the callees of the specified function, as recorded
+// by its Summary, or nil if it has no summary. The function may
+// belong to the current package, if it is summarized, or to any of
+// its dependencies. Callees whose packages are not among the
+// dependencies of the current package are omitted.
+func (r *Result) Callees(fn *types.Func) []*types.Func {
+	summary, ok := r.summaries[fn.Origin()]
+	if !ok {
+		return nil
+	}
+	var callees []*types.Func
+	for _, c := range summary.Callees {
+		pkg, ok := r.pkgs[c.PkgPath]
+		if !ok {
+			continue // not a dependency
+		}
+		if obj, err := objectpath.Object(pkg, c.Path); err == nil {
+			if fn, ok := obj.(*types.Func); ok {
+				callees = append(callees, fn)
+			}
+		}
+	}
+	return callees
+}
+
+// Summary returns the summary of the specified function,
+// or nil if it has none.
+func (r *Result) Summary(fn *types.Func) *Summary {
+	return r.summaries[fn.Origin()]
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ssainfo := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	prog := ssainfo.Pkg.Prog
+
+	// Enumerate the functions of this package,
+	// including function literals.
+	var fns []*ssa.Function
+	seen := make(map[*ssa.Function]bool)
+	var addAnons func(fn *ssa.Function)
+	addAnons = func(fn *ssa.Function) {
+		if !seen[fn] {
+			seen[fn] = true
+			fns = append(fns, fn)
+			for _, anon := range fn.AnonFuncs {
+				addAnons(anon)
+			}
+		}
+	}
+	for _, fn := range ssainfo.SrcFuncs {
+		addAnons(fn)
+	}
+
+	pkgs := importClosure(pass.Pkg)
+	calleesOf := lazyCallees(prog, pass.Pkg, pass.TypesInfo, pkgs, fns)
+
+	// Build the call graph, including the edges
+	// of the synthetic wrappers called by fns.
+	cg := callgraph.New(nil)
+	queue := append([]*ssa.Function(nil), fns...)
+	for len(queue) > 0 {
+		fn := queue[0]
+		queue = queue[1:]
+		fnode := cg.CreateNode(fn)
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if site, ok := instr.(ssa.CallInstruction); ok {
+					for _, g := range calleesOf(site) {
+						if g.Synthetic != "" && g.Blocks != nil && !seen[g] {
+							seen[g] = true
+							queue = append(queue, g)
+						}
+						callgraph.AddEdge(fnode, site, cg.CreateNode(g))
+					}
+				}
+			}
+		}
+	}
+
+	// Summarize and export the callees of each function
+	// whose facts are visible downstream.
+	//
+	// For each summarized function, we traverse the graph
+	// through the unsummarized functions of this package.
+	for _, fn := range fns {
+		obj := summarized(pass.Pkg, fn)
+		if obj == nil {
+			continue
+		}
+		var callees []Callee
+		visited := map[*callgraph.Node]bool{cg.Nodes[fn]: true}
+		var visit func(n *callgraph.Node)
+		visit = func(n *callgraph.Node) {
+			for _, e := range n.Out {
+				g := e.Callee
+				if visited[g] {
+					continue
+				}
+				visited[g] = true
+				if callee := funcObject(g.Func); callee != nil && (callee.Pkg() != pass.Pkg || summarized(pass.Pkg, g.Func) != nil) {
+					if c, ok := makeCallee(callee); ok {
+						callees = append(callees, c)
+					}
+				} else if seen[g.Func] {
+					visit(g) // an unsummarized function of this package, or a wrapper
+				}
+			}
+		}
+		visit(cg.Nodes[fn])
+		sort.Slice(callees, func(i, j int) bool { return callees[i].Name < callees[j].Name })
+		pass.ExportObjectFact(obj, &Summary{Callees: dedup(callees)})
+	}
+
+	// Gather the summaries of this package and its dependencies.
+	summaries := make(map[*types.Func]*Summary)
+	for _, fact := range pass.AllObjectFacts() {
+		if fn, ok := fact.Object.(*types.Func); ok {
+			summaries[fn] = fact.Fact.(*Summary)
+		}
+	}
+
+	return &Result{
+		Graph:     cg,
+		pkgs:      pkgs,
+		summaries: summaries,
+	}, nil
+}
+
+// summarized returns the object of fn if fn is a function of pkg
+// whose facts are visible to importing packages (an exported
+// package-level function, or a method of a package-level type),
+// or nil otherwise.
+func summarized(pkg *types.Package, fn *ssa.Function) *types.Func {
+	if fn.Parent() != nil || fn.Synthetic != "" {
+		return nil // function literal, or wrapper
+	}
+	obj := funcObject(fn)
+	if obj == nil || obj.Pkg() != pkg {
+		return nil
+	}
+	if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+		// A method of a local type is not visible downstream.
+		named := receiverNamed(recv.Type())
+		if named == nil || named.Obj().Parent() != pkg.Scope() {
+			return nil
+		}
+		return obj
+	}
+	if !obj.Exported() {
+		return nil
+	}
+	return obj
+}
+
+// funcObject returns the declared function or method of which fn
+// is the SSA form or an instantiation, or nil if there is none.
+func funcObject(fn *ssa.Function) *types.Func {
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	obj, _ := fn.Object().(*types.Func)
+	return obj
+}
+
+// makeCallee returns the Callee that identifies fn.
+func makeCallee(fn *types.Func) (Callee, bool) {
+	path, err := objectpath.For(fn)
+	if err != nil {
+		return Callee{}, false // e.g. a method of a local type
+	}
+	return Callee{
+		PkgPath: fn.Pkg().Path(),
+		Path:    path,
+		Name:    fn.FullName(),
+	}, true
+}
+
+// dedup removes adjacent duplicates from a sorted list of callees.
+func dedup(callees []Callee) []Callee {
+	var out []Callee
+	for i, c := range callees {
+		if i == 0 || c != callees[i-1] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// importClosure returns the transitive closure of the
+// packages imported by pkg, including pkg, keyed by path.
+func importClosure(pkg *types.Package) map[string]*types.Package {
+	pkgs := make(map[string]*types.Package)
+	var visit func(pkg *types.Package)
+	visit = func(pkg *types.Package) {
+		if _, ok := pkgs[pkg.Path()]; !ok {
+			pkgs[pkg.Path()] = pkg
+			for _, imp := range pkg.Imports() {
+				visit(imp)
+			}
+		}
+	}
+	visit(pkg)
+	return pkgs
+}
+
+// lazyCallees returns a function that maps a call site in one of
+// the functions fns of package pkg to its callees, as computed by
+// Class Hierarchy Analysis over the concrete types visible to pkg.
+// The resulting function is not concurrency safe.
+func lazyCallees(prog *ssa.Program, pkg *types.Package, info *types.Info, pkgs map[string]*types.Package, fns []*ssa.Function) func(site ssa.CallInstruction) []*ssa.Function {
+	// funcsBySig contains the functions of this package, keyed by
+	// signature. It is the effective set of address-taken functions
+	// used to resolve a dynamic call of a particular signature.
+	var funcsBySig typeutil.Map // value is []*ssa.Function
+	for _, fn := range fns {
+		if fn.Signature.Recv() == nil && fn.Synthetic == "" && fn.TypeParams().Len() == 0 {
+			funcs, _ := funcsBySig.At(fn.Signature).([]*ssa.Function)
+			funcs = append(funcs, fn)
+			funcsBySig.Set(fn.Signature, funcs)
+		}
+	}
+
+	// Gather the visible concrete types: the package-level types
+	// of pkg and its dependencies, and the local types of pkg.
+	var concrete []types.Type
+	addType := func(obj types.Object) {
+		if tname, ok := obj.(*types.TypeName); ok && !tname.IsAlias() {
+			if named, ok := tname.Type().(*types.Named); ok &&
+				named.TypeParams().Len() == 0 && !types.IsInterface(named) {
+				concrete = append(concrete, named, types.NewPointer(named))
+			}
+		}
+	}
+	paths := make([]string, 0, len(pkgs))
+	for path := range pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths) // for determinism
+	for _, path := range paths {
+		scope := pkgs[path].Scope()
+		for _, name := range scope.Names() {
+			addType(scope.Lookup(name))
+		}
+	}
+	for _, obj := range info.Defs {
+		if obj != nil && obj.Parent() != pkg.Scope() && obj.Parent() != nil {
+			addType(obj) // local type
+		}
+	}
+
+	// methodsByID indexes the methods of the concrete types by ID.
+	//
+	// We must key by ID, not name, for correct resolution of interface
+	// calls to a type with two (unexported) methods spelled the same but
+	// from different packages.
+	methodsByID := make(map[string][]*types.Selection)
+	for _, T := range concrete {
+		mset := prog.MethodSets.MethodSet(T)
+		for i := 0; i < mset.Len(); i++ {
+			sel := mset.At(i)
+			id := sel.Obj().Id()
+			methodsByID[id] = append(methodsByID[id], sel)
+		}
+	}
+
+	// An imethod represents an interface method I.m.
+	type imethod struct {
+		I  *types.Interface
+		id string
+	}
+	methodsMemo := make(map[imethod][]*ssa.Function)
+	lookupMethods := func(I *types.Interface, m *types.Func) []*ssa.Function {
+		id := m.Id()
+		methods, ok := methodsMemo[imethod{I, id}]
+		if !ok {
+			for _, sel := range methodsByID[id] {
+				if types.Implements(sel.Recv(), I) {
+					if fn := prog.MethodValue(sel); fn != nil {
+						methods = append(methods, fn)
+					}
+				}
+			}
+			methodsMemo[imethod{I, id}] = methods
+		}
+		return methods
+	}
+
+	return func(site ssa.CallInstruction) []*ssa.Function {
+		call := site.Common()
+		if call.IsInvoke() {
+			tiface, ok := call.Value.Type().Underlying().(*types.Interface)
+			if !ok {
+				return nil // method call on a type parameter
+			}
+			return lookupMethods(tiface, call.Method)
+		} else if g := call.StaticCallee(); g != nil {
+			return []*ssa.Function{g}
+		} else if _, ok := call.Value.(*ssa.Builtin); !ok {
+			fns, _ := funcsBySig.At(call.Signature()).([]*ssa.Function)
+			return fns
+		}
+		return nil
+	}
+}
+
+// receiverNamed returns the named type N of a
+// method receiver of type N or *N, or nil.
+func receiverNamed(T types.Type) *types.Named {
+	if p, ok := T.(*types.Pointer); ok {
+		T = p.Elem()
+	}
+	named, _ := T.(*types.Named)
+	return named
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package callgraph_test
+
+import (
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/callgraph"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, callgraph.Analyzer, "a", "b")
+
+	// Follow the summaries from a.F into package b.
+	for _, result := range results {
+		if result.Pass.Pkg.Path() != "a" {
+			continue
+		}
+		res := result.Result.(*callgraph.Result)
+		if res.Graph == nil || len(res.Graph.Nodes) == 0 {
+			t.Errorf("empty call graph")
+		}
+
+		F := result.Pass.Pkg.Scope().Lookup("F").(*types.Func)
+		var G *types.Func
+		for _, callee := range res.Callees(F) {
+			if callee.Name() == "G" {
+				G = callee
+			}
+		}
+		if G == nil {
+			t.Fatalf("Callees(a.F) = %v, does not include b.G", res.Callees(F))
+		}
+		callees := res.Callees(G)
+		if len(callees) != 1 || callees[0].FullName() != "b.H" {
+			t.Errorf("Callees(b.G) = %v, want [b.H]", callees)
+		}
+	}
+}
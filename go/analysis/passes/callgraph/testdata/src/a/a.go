@@ -0,0 +1,17 @@
+package a
+
+import "b"
+
+func F() { // want F:`calls \(b.T\).M, b.G, b.Generic, b.H`
+	b.G()
+	var i b.I = b.T{}
+	i.M()
+	func() { b.Generic(1) }()
+	local()
+}
+
+func local() { b.H() }
+
+type U struct{}
+
+func (U) m() { F() } // want m:"calls a.F"
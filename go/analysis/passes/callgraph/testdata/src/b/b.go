@@ -0,0 +1,19 @@
+package b
+
+type I interface{ M() }
+
+type T struct{}
+
+func (T) M() { H() } // want M:"calls b.H"
+
+func G() { helper() } // want G:"calls b.H"
+
+func helper() { H() }
+
+func H() {} // want H:"calls nothing"
+
+type embed struct{ T }
+
+var _ I = embed{}
+
+func Generic[E any](e E) { H() } // want Generic:"calls b.H"
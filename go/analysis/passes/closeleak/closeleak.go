@@ -0,0 +1,484 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package closeleak
+
+import (
+	_ "embed"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/analysis/passes/internal/ssautil"
+	"golang.org/x/tools/go/ssa"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "closeleak",
+	Doc:       analysisutil.MustExtractDoc(doc, "closeleak"),
+	URL:       "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/closeleak",
+	Run:       run,
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer, ctrlflow.Analyzer},
+	FactTypes: []analysis.Fact{new(resourceFact)},
+}
+
+var constructors string // -constructors flag
+
+func init() {
+	Analyzer.Flags.StringVar(&constructors, "constructors", "",
+		"comma-separated list of additional functions whose results must be closed")
+}
+
+// wellKnown is the set of standard functions that open a resource,
+// keyed by types.Func.FullName.
+var wellKnown = map[string]bool{
+	"os.Create":     true,
+	"os.CreateTemp": true,
+	"os.Open":       true,
+	"os.OpenFile":   true,
+
+	"net.Dial":                            true,
+	"net.DialTimeout":                     true,
+	"net.Listen":                          true,
+	"(*net.Dialer).Dial":                  true,
+	"(*net.Dialer).DialContext":           true,
+	"(*net.ListenConfig).Listen":          true,
+	"net/http.Get":                        true,
+	"net/http.Head":                       true,
+	"net/http.Post":                       true,
+	"net/http.PostForm":                   true,
+	"(*net/http.Client).Do":               true,
+	"(*net/http.Client).Get":              true,
+	"(*net/http.Client).Head":             true,
+	"(*net/http.Client).Post":             true,
+	"(*net/http.Client).PostForm":         true,
+	"(*database/sql.DB).Conn":             true,
+	"(*database/sql.DB).Prepare":          true,
+	"(*database/sql.DB).Query":            true,
+	"(*database/sql.Conn).Query":          true,
+	"(*database/sql.Tx).Prepare":          true,
+	"(*database/sql.Tx).Query":            true,
+	"(*database/sql.Stmt).Query":          true,
+	"(*database/sql.DB).PrepareContext":   true,
+	"(*database/sql.DB).QueryContext":     true,
+	"(*database/sql.Conn).PrepareContext": true,
+	"(*database/sql.Conn).QueryContext":   true,
+	"(*database/sql.Tx).PrepareContext":   true,
+	"(*database/sql.Tx).QueryContext":     true,
+	"(*database/sql.Stmt).QueryContext":   true,
+}
+
+// A resourceFact records how a function deals with resources.
+// Parameter indices include the receiver, if any, as in
+// ssa.Function.Params.
+type resourceFact struct {
+	Opens int   // 1 + index of the result that is a newly opened resource; 0 => none
+	Owns  []int // indices of parameters whose resources the function takes ownership of
+}
+
+func (*resourceFact) AFact() {}
+
+func (f *resourceFact) String() string {
+	var parts []string
+	if f.Opens > 0 {
+		parts = append(parts, fmt.Sprintf("opens=%d", f.Opens-1))
+	}
+	if len(f.Owns) > 0 {
+		parts = append(parts, fmt.Sprintf("owns=%v", f.Owns))
+	}
+	return "resource(" + strings.Join(parts, " ") + ")"
+}
+
+type checker struct {
+	pass  *analysis.Pass
+	cfgs  *ctrlflow.CFGs
+	extra map[string]bool               // from -constructors flag
+	facts map[*types.Func]*resourceFact // facts for functions of this package
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	c := &checker{
+		pass:  pass,
+		cfgs:  pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs),
+		extra: make(map[string]bool),
+		facts: make(map[*types.Func]*resourceFact),
+	}
+	for _, name := range strings.Split(constructors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			c.extra[name] = true
+		}
+	}
+
+	// Compute facts for the functions of this package. Facts may
+	// depend on each other, so iterate until they no longer grow.
+	for changed := true; changed; {
+		changed = false
+		for _, fn := range ssainput.SrcFuncs {
+			obj, ok := fn.Object().(*types.Func)
+			if !ok {
+				continue
+			}
+			fact := c.summarize(fn)
+			if old := c.facts[obj]; old == nil || fact.Opens != old.Opens || !slices.Equal(fact.Owns, old.Owns) {
+				c.facts[obj] = fact
+				changed = true
+			}
+		}
+	}
+	for obj, fact := range c.facts {
+		if fact.Opens > 0 || len(fact.Owns) > 0 {
+			pass.ExportObjectFact(obj, fact)
+		}
+	}
+
+	for _, fn := range ssainput.SrcFuncs {
+		c.check(fn)
+	}
+	return nil, nil
+}
+
+// summarize computes the resource fact for fn.
+func (c *checker) summarize(fn *ssa.Function) *resourceFact {
+	fact := new(resourceFact)
+	for i, param := range fn.Params {
+		if i == 0 && fn.Signature.Recv() != nil {
+			continue // a method does not take ownership of its receiver
+		}
+		t := param.Type()
+		if (isResource(t) || types.IsInterface(t)) && len(c.disposals(aliases(param))) > 0 {
+			fact.Owns = append(fact.Owns, i)
+		}
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			index, res, _ := c.opens(call)
+			if index < 0 {
+				continue
+			}
+			for v := range aliases(res...) {
+				for _, ref := range *v.Referrers() {
+					if ret, ok := ref.(*ssa.Return); ok {
+						for j, result := range ret.Results {
+							if result == v && fact.Opens == 0 {
+								fact.Opens = 1 + j
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return fact
+}
+
+// check reports the resources opened by fn that are not closed on
+// some path.
+func (c *checker) check(fn *ssa.Function) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			call, ok := instr.(*ssa.Call)
+			if !ok {
+				continue
+			}
+			index, res, errs := c.opens(call)
+			if index < 0 || len(res) == 0 {
+				continue // the result is discarded; unusedresult's concern
+			}
+			resources := aliases(res...)
+			ret := c.leak(call, resources, errs, c.disposals(resources))
+			if ret == nil {
+				continue
+			}
+			callee := call.Call.StaticCallee().Object().(*types.Func)
+			t := call.Type()
+			if tuple, ok := t.(*types.Tuple); ok {
+				t = tuple.At(index).Type()
+			}
+			what := "the " + types.TypeString(t, types.RelativeTo(c.pass.Pkg))
+			if isResponse(t) {
+				what = "the body of the response"
+			}
+			diag := analysis.Diagnostic{
+				Pos:     call.Pos(),
+				Message: fmt.Sprintf("%s returned by %s is not closed on all paths", what, funcName(c.pass.Pkg, callee)),
+			}
+			if call := ssautil.CallExpr(fn, call.Pos()); call != nil {
+				diag.Pos, diag.End = call.Pos(), call.End()
+			}
+			if ret.Pos().IsValid() {
+				diag.Related = []analysis.RelatedInformation{{
+					Pos:     ret.Pos(),
+					Message: "this return leaks it",
+				}}
+			}
+			c.pass.Report(diag)
+		}
+	}
+}
+
+// opens reports whether call opens a resource. If so, it returns
+// the index of the result that is the resource, the values that
+// denote it, and the values that denote the call's error result.
+// Otherwise it returns -1.
+func (c *checker) opens(call *ssa.Call) (index int, res []ssa.Value, errs map[ssa.Value]bool) {
+	callee := call.Call.StaticCallee()
+	if callee == nil {
+		return -1, nil, nil
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok {
+		return -1, nil, nil
+	}
+	index = -1
+	if name := obj.FullName(); wellKnown[name] || c.extra[name] {
+		results := callee.Signature.Results()
+		for i := 0; i < results.Len(); i++ {
+			if isResource(results.At(i).Type()) {
+				index = i
+				break
+			}
+		}
+	} else if fact := c.fact(obj); fact != nil && fact.Opens > 0 {
+		index = fact.Opens - 1
+	}
+	if index < 0 {
+		return -1, nil, nil
+	}
+
+	if _, ok := call.Type().(*types.Tuple); !ok {
+		return index, []ssa.Value{call}, nil
+	}
+	errs = make(map[ssa.Value]bool)
+	for _, ref := range *call.Referrers() {
+		if extract, ok := ref.(*ssa.Extract); ok {
+			if extract.Index == index {
+				res = append(res, extract)
+			} else if types.Identical(extract.Type(), errorType) {
+				errs[extract] = true
+			}
+		}
+	}
+	return index, res, errs
+}
+
+// fact returns the resource fact for fn, or nil if there is none.
+func (c *checker) fact(fn *types.Func) *resourceFact {
+	if fn.Pkg() == c.pass.Pkg {
+		return c.facts[fn]
+	}
+	fact := new(resourceFact)
+	if c.pass.ImportObjectFact(fn, fact) {
+		return fact
+	}
+	return nil
+}
+
+// aliases returns the set of values that denote the same resource as
+// one of the roots: conversions of it, φ-nodes that merge it, and, for
+// an *http.Response, loads of its Body field.
+func aliases(roots ...ssa.Value) map[ssa.Value]bool {
+	set := make(map[ssa.Value]bool)
+	var add func(v ssa.Value)
+	add = func(v ssa.Value) {
+		if set[v] {
+			return
+		}
+		set[v] = true
+		for _, ref := range *v.Referrers() {
+			switch ref := ref.(type) {
+			case *ssa.ChangeType, *ssa.ChangeInterface, *ssa.MakeInterface, *ssa.Phi, *ssa.TypeAssert:
+				add(ref.(ssa.Value))
+			case *ssa.Extract:
+				if ref.Index == 0 { // v, ok := x.(T)
+					add(ref)
+				}
+			case *ssa.FieldAddr:
+				if isResponse(v.Type()) && ssautil.Field(ref.X.Type(), ref.Field).Name() == "Body" {
+					for _, load := range *ref.Referrers() {
+						if load, ok := load.(*ssa.UnOp); ok && load.Op == token.MUL {
+							add(load)
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, root := range roots {
+		add(root)
+	}
+	return set
+}
+
+// disposals returns the set of instructions that close or hand off
+// one of the specified values.
+func (c *checker) disposals(values map[ssa.Value]bool) map[ssa.Instruction]bool {
+	set := make(map[ssa.Instruction]bool)
+	for v := range values {
+		refs := v.Referrers()
+		if refs == nil {
+			continue
+		}
+		for _, ref := range *refs {
+			if c.disposes(v, ref) {
+				set[ref] = true
+			}
+		}
+	}
+	return set
+}
+
+// disposes reports whether instr closes or hands off the resource v.
+func (c *checker) disposes(v ssa.Value, instr ssa.Instruction) bool {
+	switch instr := instr.(type) {
+	case *ssa.Return, *ssa.Send, *ssa.MapUpdate, *ssa.MakeClosure, *ssa.Go:
+		return true
+
+	case *ssa.Store:
+		return instr.Val == v
+
+	case ssa.CallInstruction: // *ssa.Call or *ssa.Defer
+		common := instr.Common()
+		if common.IsInvoke() {
+			if common.Value == v {
+				return common.Method.Name() == "Close"
+			}
+			return true // passed to a dynamic callee
+		}
+		if _, ok := common.Value.(*ssa.Builtin); ok {
+			return false
+		}
+		callee := common.StaticCallee()
+		if callee == nil {
+			return common.Value != v // passed to a dynamic callee
+		}
+		for i, arg := range common.Args {
+			if arg != v {
+				continue
+			}
+			if i == 0 && callee.Signature.Recv() != nil {
+				return callee.Name() == "Close"
+			}
+			obj, ok := callee.Object().(*types.Func)
+			if !ok {
+				return true // passed to a function literal
+			}
+			if fact := c.fact(obj); fact != nil && slices.Contains(fact.Owns, i) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leak returns the first return instruction found to be reachable
+// from the call that opens a resource without passing through one
+// of its disposals, or nil if the resource does not leak.
+func (c *checker) leak(call *ssa.Call, resources, errs map[ssa.Value]bool, disposals map[ssa.Instruction]bool) *ssa.Return {
+	// pruned reports whether the resource is nil
+	// on the specified successor of the if instruction.
+	pruned := func(instr *ssa.If, succ int) bool {
+		cond, ok := instr.Cond.(*ssa.BinOp)
+		if !ok || cond.Op != token.EQL && cond.Op != token.NEQ {
+			return false
+		}
+		x, y := cond.X, cond.Y
+		if isNil(x) {
+			x, y = y, x
+		}
+		if !isNil(y) {
+			return false
+		}
+		eq := succ == 0 && cond.Op == token.EQL || succ == 1 && cond.Op == token.NEQ
+		return errs[x] && !eq || resources[x] && eq
+	}
+
+	seen := make(map[*ssa.BasicBlock]bool)
+	var visit func(b *ssa.BasicBlock, instrs []ssa.Instruction) *ssa.Return
+	visit = func(b *ssa.BasicBlock, instrs []ssa.Instruction) *ssa.Return {
+		for _, instr := range instrs {
+			if disposals[instr] || ssautil.NoReturn(c.cfgs, instr) {
+				return nil
+			}
+			switch instr := instr.(type) {
+			case *ssa.Return:
+				return instr
+			case *ssa.If:
+				for i, succ := range b.Succs {
+					if !pruned(instr, i) && !seen[succ] {
+						seen[succ] = true
+						if ret := visit(succ, succ.Instrs); ret != nil {
+							return ret
+						}
+					}
+				}
+			case *ssa.Jump:
+				if succ := b.Succs[0]; !seen[succ] {
+					seen[succ] = true
+					return visit(succ, succ.Instrs)
+				}
+			}
+		}
+		return nil // panic, or a loop back to a visited block
+	}
+	b := call.Block()
+	seen[b] = true
+	i := slices.Index(b.Instrs, ssa.Instruction(call))
+	return visit(b, b.Instrs[i+1:])
+}
+
+var (
+	errorType  = types.Universe.Lookup("error").Type()
+	closerType = types.NewInterfaceType([]*types.Func{
+		types.NewFunc(token.NoPos, nil, "Close", types.NewSignatureType(nil, nil, nil, nil,
+			types.NewTuple(types.NewParam(token.NoPos, nil, "", errorType)), false)),
+	}, nil).Complete()
+)
+
+// isResource reports whether values of type t must be closed.
+func isResource(t types.Type) bool {
+	return types.Implements(t, closerType) || isResponse(t)
+}
+
+// isResponse reports whether t is *net/http.Response,
+// whose Body must be closed.
+func isResponse(t types.Type) bool {
+	ptr, ok := t.Underlying().(*types.Pointer)
+	return ok && analysisutil.IsNamedType(ptr.Elem(), "net/http", "Response")
+}
+
+func isNil(v ssa.Value) bool {
+	k, ok := v.(*ssa.Const)
+	return ok && k.IsNil()
+}
+
+// funcName returns the name of fn, qualified relative to pkg.
+func funcName(pkg *types.Package, fn *types.Func) string {
+	qual := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		return fmt.Sprintf("(%s).%s", types.TypeString(recv.Type(), qual), fn.Name())
+	}
+	if fn.Pkg() == pkg {
+		return fn.Name()
+	}
+	return fn.Pkg().Name() + "." + fn.Name()
+}
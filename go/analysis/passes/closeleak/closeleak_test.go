@@ -0,0 +1,24 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package closeleak_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/closeleak"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, closeleak.Analyzer, "a", "b")
+}
+
+func TestConstructors(t *testing.T) {
+	testdata := analysistest.TestData()
+	closeleak.Analyzer.Flags.Set("constructors", "c.Dial")
+	defer closeleak.Analyzer.Flags.Set("constructors", "")
+	analysistest.Run(t, testdata, closeleak.Analyzer, "c")
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package closeleak defines an Analyzer that checks that resources
+// such as files, database rows, and HTTP response bodies are closed.
+//
+// # Analyzer closeleak
+//
+// closeleak: check that opened resources are closed
+//
+// The closeleak analyzer reports calls to functions that open a
+// resource, that is, a value of a type with a Close method, such as
+// [os.Open], [database/sql.DB.Query], or [net/http.Get] (whose
+// response Body must be closed), when there is a path from the call
+// to a return from the enclosing function along which the resource
+// is neither closed nor handed off. For example:
+//
+//	f, err := os.Open(name)
+//	if err != nil {
+//		return err
+//	}
+//	if !valid(name) {
+//		return errInvalid // f is not closed
+//	}
+//	defer f.Close()
+//
+// A resource is handed off, and no longer the responsibility of the
+// function, when it is returned, stored in a variable, field, map,
+// or channel, captured by a function literal, passed to a goroutine
+// or to a dynamically called function, or passed to a function that
+// takes ownership of it. Paths on which the call's error result, or
+// the resource itself, is found to be nil are not considered, nor
+// are paths that end in a panic or in a call to a function such as
+// os.Exit or log.Fatal that does not return.
+//
+// The analyzer records two kinds of facts about the functions it
+// analyzes: a function that returns a newly opened resource is
+// itself treated as opening one, and a function that closes or hands
+// off a resource passed to it takes ownership of it. This allows the
+// analyzer to see through helper functions in any package.
+//
+// The set of functions known to open resources includes the standard
+// library functions listed above and similar ones in the os, net,
+// net/http, and database/sql packages. The -constructors flag adds to
+// it a comma-separated list of functions and methods written as
+// qualified names, for example
+//
+//	-constructors=example.com/db.Open,(*example.com/db.Conn).Query
+package closeleak
@@ -0,0 +1,167 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import (
+	"b"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+)
+
+var errInvalid = errors.New("invalid")
+
+func valid(string) bool
+
+func leak(name string) error {
+	f, err := os.Open(name) // want `the \*os.File returned by os.Open is not closed on all paths`
+	if err != nil {
+		return err
+	}
+	if !valid(name) {
+		return errInvalid
+	}
+	defer f.Close()
+	return nil
+}
+
+func closed(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return nil
+}
+
+func closedNoDefer(name string) error {
+	f, err := os.Create(name)
+	if err == nil {
+		_, err = f.WriteString("hello")
+		f.Close()
+	}
+	return err
+}
+
+func returned(name string) (*os.File, error) { // want returned:`resource\(opens=0\)`
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func wrapped(name string) error {
+	f, err := returned(name) // want `the \*os.File returned by returned is not closed on all paths`
+	if err != nil {
+		return err
+	}
+	_ = f.Name()
+	return nil
+}
+
+type holder struct{ r io.Reader }
+
+func stored(h *holder, name string) {
+	f, _ := os.Open(name)
+	h.r = f
+}
+
+func captured(name string) {
+	f, _ := os.Open(name)
+	defer func() { f.Close() }()
+}
+
+func nilChecked(name string) {
+	f, _ := os.Open(name)
+	if f == nil {
+		return
+	}
+	f.Close()
+}
+
+func fatal(name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !valid(name) {
+		log.Fatal(errInvalid) // ok: does not return
+	}
+	f.Close()
+}
+
+func panics(name string) {
+	f, _ := os.Open(name)
+	if !valid(name) {
+		panic(name)
+	}
+	f.Close()
+}
+
+func body(url string) ([]byte, error) {
+	resp, err := http.Get(url) // want `the body of the response returned by http.Get is not closed on all paths`
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func bodyClosed(c *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func rows(db *sql.DB) (n int) {
+	rows, err := db.Query("SELECT 1") // want `the \*database/sql.Rows returned by \(\*sql.DB\).Query is not closed on all paths`
+	if err != nil {
+		return 0
+	}
+	for rows.Next() {
+		n++
+	}
+	return n
+}
+
+func ownership(name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	b.Consume(f)
+}
+
+func notOwnership(name string) {
+	f, err := os.Open(name) // want `not closed on all paths`
+	if err != nil {
+		return
+	}
+	b.Inspect(f)
+}
+
+func helper(name string) error {
+	f, err := b.OpenConfig(name) // want `the \*os.File returned by b.OpenConfig is not closed`
+	if err != nil {
+		return err
+	}
+	b.Inspect(f)
+	return nil
+}
+
+func localOwner(f *os.File) { // want localOwner:`resource\(owns=\[0\]\)`
+	defer f.Close()
+}
+
+func useLocalOwner(name string) {
+	f, _ := os.Open(name)
+	localOwner(f)
+}
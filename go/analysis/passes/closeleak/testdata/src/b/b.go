@@ -0,0 +1,26 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+import (
+	"io"
+	"os"
+)
+
+// OpenConfig opens a resource that its caller must close.
+func OpenConfig(name string) (*os.File, error) { // want OpenConfig:`resource\(opens=0\)`
+	return os.Open(name + ".cfg")
+}
+
+// Consume reads r and closes it.
+func Consume(r io.ReadCloser) { // want Consume:`resource\(owns=\[0\]\)`
+	defer r.Close()
+	io.ReadAll(r)
+}
+
+// Inspect reads f but does not close it.
+func Inspect(f *os.File) {
+	f.Stat()
+}
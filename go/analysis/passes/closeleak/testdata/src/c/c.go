@@ -0,0 +1,21 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package c
+
+type Conn struct{}
+
+func (*Conn) Close() error { return nil }
+
+func Dial() *Conn { return new(Conn) }
+
+func _() {
+	c := Dial() // want `the \*Conn returned by Dial is not closed on all paths`
+	_ = c
+}
+
+func _() {
+	c := Dial()
+	c.Close()
+}
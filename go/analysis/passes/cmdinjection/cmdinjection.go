@@ -0,0 +1,62 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmdinjection
+
+import (
+	_ "embed"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/analysis/passes/internal/taint"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "cmdinjection",
+	Doc:       analysisutil.MustExtractDoc(doc, "cmdinjection"),
+	URL:       "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/cmdinjection",
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer},
+	FactTypes: []analysis.Fact{new(taintFact)},
+	Run:       run,
+}
+
+// A taintFact summarizes the taint flows of a function.
+type taintFact struct{ taint.Summary }
+
+func (*taintFact) AFact() {}
+
+var config = taint.Config{
+	Sources:    taint.HTTPRequestSources(),
+	Sinks:      make(taint.Set),
+	Sanitizers: make(taint.Set),
+	Message:    "untrusted input is passed to %s",
+	NewFact:    func() taint.Fact { return new(taintFact) },
+}
+
+func init() {
+	for _, sink := range []string{
+		"os/exec.Command",
+		"os/exec.CommandContext:name",
+		"os/exec.CommandContext:arg",
+		"os.StartProcess:name",
+		"os.StartProcess:argv",
+		"syscall.Exec:argv0",
+		"syscall.Exec:argv",
+		"syscall.StartProcess:argv0",
+		"syscall.StartProcess:argv",
+	} {
+		config.Sinks[sink] = true
+	}
+	Analyzer.Flags.Var(&config.Sources, "sources", "comma-separated list of functions and fields that are sources of untrusted input")
+	Analyzer.Flags.Var(&config.Sinks, "sinks", "comma-separated list of functions whose parameters must not receive untrusted input")
+	Analyzer.Flags.Var(&config.Sanitizers, "sanitizers", "comma-separated list of functions whose results are trusted")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	return nil, taint.Run(pass, &config)
+}
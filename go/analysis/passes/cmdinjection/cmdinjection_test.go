@@ -0,0 +1,24 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmdinjection_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/cmdinjection"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, cmdinjection.Analyzer, "a", "b")
+}
+
+func TestSanitizers(t *testing.T) {
+	testdata := analysistest.TestData()
+	cmdinjection.Analyzer.Flags.Set("sanitizers", "c.Allowed")
+	defer cmdinjection.Analyzer.Flags.Set("sanitizers", "")
+	analysistest.Run(t, testdata, cmdinjection.Analyzer, "c")
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cmdinjection defines an Analyzer that reports commands
+// run with untrusted input.
+//
+// # Analyzer cmdinjection
+//
+// cmdinjection: check for commands run with untrusted input
+//
+// The cmdinjection analyzer reports calls to os/exec.Command and
+// related functions whose program name or arguments are derived from
+// untrusted input:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		cmd := exec.Command("sh", "-c", "convert "+r.FormValue("file"))
+//		...
+//	}
+//
+// An attacker who controls the input may run arbitrary commands, or
+// pass unexpected options to the intended command. Validate such
+// input against a list of permitted values before use.
+//
+// Untrusted input is any data obtained from the fields and methods of
+// an *http.Request. The analysis follows the flow of data through
+// assignments, string operations, and calls, including calls to
+// helper functions in other packages. Values of numeric and boolean
+// types, such as the results of strconv.Atoi, are considered safe.
+//
+// The -sources, -sinks, and -sanitizers flags each replace the
+// corresponding comma-separated list of functions, using the naming
+// scheme of types.Func.FullName, for example "os/exec.Command". A
+// struct field used as a source is named by its package path, type,
+// and field, for example "net/http.Request.URL". A sink "name:param"
+// applies only to the named parameter of the function.
+package cmdinjection
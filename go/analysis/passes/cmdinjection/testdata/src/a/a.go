@@ -0,0 +1,45 @@
+package a
+
+import (
+	"b"
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+func direct(w http.ResponseWriter, r *http.Request) { // want direct:`p1->os/exec.Command`
+	exec.Command("convert", r.FormValue("file")) // want `untrusted input is passed to os/exec.Command`
+}
+
+func shell(w http.ResponseWriter, r *http.Request) { // want shell:`p1->os/exec.CommandContext`
+	line := "ls " + r.URL.Path
+	exec.CommandContext(context.Background(), "sh", "-c", line) // want `untrusted input is passed to os/exec.CommandContext`
+}
+
+func args(r *http.Request) { // want args:`p0->os/exec.Command`
+	argv := []string{"-n"}
+	argv = append(argv, r.URL.Query()["name"]...)
+	exec.Command("grep", argv...) // want `untrusted input is passed to os/exec.Command`
+}
+
+func process(r *http.Request) { // want process:`p0->os.StartProcess`
+	os.StartProcess("/bin/echo", []string{"echo", r.UserAgent()}, nil) // want `untrusted input is passed to os.StartProcess`
+}
+
+func helpers(r *http.Request) { // want helpers:`p0->os/exec.Command`
+	b.Shell("echo " + r.Referer()) // want `untrusted input is passed to os/exec.Command \(via call to Shell\)`
+	b.Run("echo", "a", r.Host)     // want `untrusted input is passed to os/exec.Command \(via call to Run\)`
+	b.Tool([]string{r.RemoteAddr}) // want `untrusted input is passed to os/exec.Command \(via call to Tool\)`
+	b.Run("echo", b.Quote(r.Host)) // want `untrusted input is passed to os/exec.Command \(via call to Run\)`
+}
+
+func safe(r *http.Request) {
+	n, _ := strconv.Atoi(r.FormValue("n"))
+	exec.Command("seq", strconv.Itoa(n))
+	exec.Command("ls", os.Args[1:]...)
+	if r.FormValue("verbose") != "" {
+		exec.Command("ls", "-l")
+	}
+}
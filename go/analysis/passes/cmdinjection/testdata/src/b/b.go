@@ -0,0 +1,26 @@
+package b
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Shell runs a command line using the shell.
+func Shell(line string) error { // want Shell:`p0->os/exec.Command`
+	return exec.Command("sh", "-c", line).Run()
+}
+
+// Run runs the named program with the specified arguments.
+func Run(name string, args ...string) error { // want Run:`p0->os/exec.Command p1->os/exec.Command`
+	return exec.Command(name, args...).Run()
+}
+
+// Tool runs a fixed program with the specified arguments.
+func Tool(args []string) *exec.Cmd { // want Tool:`p0->os/exec.Command`
+	return exec.Command("tool", append([]string{"-v"}, args...)...)
+}
+
+// Quote quotes s for the shell.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,22 @@
+package c
+
+import (
+	"net/http"
+	"os/exec"
+)
+
+var allowed = map[string]string{"list": "ls", "date": "date"}
+
+// Allowed returns the program for the named command,
+// or "true" if it is not permitted.
+func Allowed(name string) string {
+	if prog, ok := allowed[name]; ok {
+		return prog
+	}
+	return "true"
+}
+
+func handler(w http.ResponseWriter, r *http.Request) { // want handler:`p1->os/exec.Command`
+	exec.Command(Allowed(r.FormValue("cmd")))
+	exec.Command(r.FormValue("cmd")) // want `untrusted input is passed to os/exec.Command`
+}
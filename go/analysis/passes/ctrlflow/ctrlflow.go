@@ -44,7 +44,8 @@ type CFGs struct {
 	defs      map[*ast.Ident]types.Object // from Pass.TypesInfo.Defs
 	funcDecls map[*types.Func]*declInfo
 	funcLits  map[*ast.FuncLit]*litInfo
-	pass      *analysis.Pass // transient; nil after construction
+	noReturn  map[*types.Func]bool // functions known not to return
+	pass      *analysis.Pass       // transient; nil after construction
 }
 
 // CFGs has two maps: funcDecls for named functions and funcLits for
@@ -80,6 +81,14 @@ func (c *CFGs) FuncLit(lit *ast.FuncLit) *cfg.CFG {
 	return c.funcLits[lit].cfg
 }
 
+// NoReturn reports whether the specified function cannot return.
+// It is defined for at least all functions declared in the current
+// package and all functions that appear as the static callee of a
+// call in the current package, even if imported from a dependency.
+func (c *CFGs) NoReturn(fn *types.Func) bool {
+	return c.noReturn[fn]
+}
+
 func run(pass *analysis.Pass) (interface{}, error) {
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 
@@ -118,6 +127,7 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		defs:      pass.TypesInfo.Defs,
 		funcDecls: funcDecls,
 		funcLits:  funcLits,
+		noReturn:  make(map[*types.Func]bool),
 		pass:      pass,
 	}
 
@@ -171,6 +181,7 @@ func (c *CFGs) buildDecl(fn *types.Func, di *declInfo) {
 			}
 		}
 		if di.noReturn {
+			c.noReturn[fn] = true
 			c.pass.ExportObjectFact(fn, new(noReturn))
 		}
 
@@ -206,7 +217,11 @@ func (c *CFGs) callMayReturn(call *ast.CallExpr) (r bool) {
 
 	// Not declared in this package.
 	// Is there a fact from another package?
-	return !c.pass.ImportObjectFact(fn, new(noReturn))
+	if c.pass.ImportObjectFact(fn, new(noReturn)) {
+		c.noReturn[fn] = true
+		return false
+	}
+	return true
 }
 
 var panicBuiltin = types.Universe.Lookup("panic").(*types.Builtin)
@@ -6,6 +6,7 @@ package ctrlflow_test
 
 import (
 	"go/ast"
+	"go/types"
 	"testing"
 
 	"golang.org/x/tools/go/analysis/analysistest"
@@ -27,6 +28,13 @@ func Test(t *testing.T) {
 					t.Errorf("%s: no CFG for func %s",
 						result.Pass.Fset.Position(decl.Pos()), decl.Name.Name)
 				}
+
+				// NoReturn must agree with the exported facts.
+				fn := result.Pass.TypesInfo.Defs[decl.Name].(*types.Func)
+				if got, want := cfgs.NoReturn(fn), len(result.Facts[fn]) > 0; got != want {
+					t.Errorf("%s: NoReturn(%s) = %t, want %t",
+						result.Pass.Fset.Position(decl.Pos()), decl.Name.Name, got, want)
+				}
 			}
 		}
 	}
@@ -0,0 +1,47 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package errorwrap defines an Analyzer that checks that errors are
+// wrapped with %w and inspected with errors.Is and errors.As.
+//
+// # Analyzer errorwrap
+//
+// errorwrap: check that errors are wrapped with %w and inspected with errors.Is and errors.As
+//
+// Since Go 1.13, an error may wrap another error, and the functions
+// errors.Is and errors.As examine the entire chain of wrapped errors.
+// This analyzer reports code that defeats that mechanism:
+//
+// A comparison such as err == ErrNotFound, in which one operand is a
+// package-level variable of error type (a "sentinel" error), does not
+// match an error that wraps the sentinel. The analyzer suggests a fix
+// to use errors.Is(err, ErrNotFound) instead. Comparisons with io.EOF
+// are not reported, since the io.Reader contract requires that Read
+// return io.EOF itself, not an error wrapping it.
+//
+// A type switch on an error value, such as
+//
+//	switch err.(type) {
+//	case *fs.PathError:
+//		...
+//	}
+//
+// does not match an error that wraps a *fs.PathError; use errors.As.
+//
+// A call to fmt.Errorf that formats an error operand with %v or %s
+// discards the error's identity, so that callers cannot examine it
+// using errors.Is or errors.As. The analyzer suggests a fix to use
+// %w instead, when the format is a string literal and the change does
+// not give the call more than one %w verb in a file whose Go version
+// is older than go1.20.
+//
+// Prior to go1.20, fmt.Errorf supported only one %w verb per call, so
+// the analyzer also reports calls with several %w verbs in files whose
+// Go version (from the go.mod file or a //go:build constraint) is
+// older than go1.20.
+//
+// Comparisons and type switches within Is and As methods, which
+// implement the matching logic of errors.Is and errors.As, are not
+// reported.
+package errorwrap
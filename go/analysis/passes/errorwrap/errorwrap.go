@@ -0,0 +1,331 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errorwrap
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"github.com/troll-zhao/tools/core/versions"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "errorwrap",
+	Doc:      analysisutil.MustExtractDoc(doc, "errorwrap"),
+	URL:      "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/errorwrap",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func run(pass *analysis.Pass) (any, error) {
+	switch pass.Pkg.Path() {
+	case "errors", "errors_test":
+		// These packages implement the wrapping mechanism.
+		return nil, nil
+	}
+
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.BinaryExpr)(nil),
+		(*ast.CallExpr)(nil),
+		(*ast.TypeSwitchStmt)(nil),
+	}
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		file := stack[0].(*ast.File)
+		switch n := n.(type) {
+		case *ast.BinaryExpr:
+			if !inMatchMethod(stack) {
+				checkComparison(pass, file, n)
+			}
+		case *ast.TypeSwitchStmt:
+			if !inMatchMethod(stack) {
+				checkTypeSwitch(pass, n)
+			}
+		case *ast.CallExpr:
+			checkErrorf(pass, file, n)
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// inMatchMethod reports whether the innermost node of the stack is
+// within the declaration of an Is or As method, whose job is to
+// compare errors directly.
+func inMatchMethod(stack []ast.Node) bool {
+	if len(stack) > 1 {
+		if decl, ok := stack[1].(*ast.FuncDecl); ok && decl.Recv != nil {
+			return decl.Name.Name == "Is" || decl.Name.Name == "As"
+		}
+	}
+	return false
+}
+
+// checkComparison reports comparisons err == ErrSentinel and
+// err != ErrSentinel, and suggests a call to errors.Is instead.
+func checkComparison(pass *analysis.Pass, file *ast.File, e *ast.BinaryExpr) {
+	if e.Op != token.EQL && e.Op != token.NEQ {
+		return
+	}
+	info := pass.TypesInfo
+	x, y := e.X, e.Y
+	if sentinel(info, y) == nil {
+		x, y = y, x
+	}
+	v := sentinel(info, y)
+	if v == nil || !isErrorInterface(info.TypeOf(x)) {
+		return
+	}
+	if v.Pkg().Path() == "io" && v.Name() == "EOF" {
+		return // Read must return io.EOF unwrapped
+	}
+
+	name, edits := analysisinternal.AddImport(info, file, e.Pos(), "errors", "errors")
+	not := ""
+	if e.Op == token.NEQ {
+		not = "!"
+	}
+	call := fmt.Sprintf("%s%s.Is(%s, %s)", not, name,
+		analysisutil.Format(pass.Fset, x),
+		analysisutil.Format(pass.Fset, y))
+	pass.Report(analysis.Diagnostic{
+		Pos:     e.Pos(),
+		End:     e.End(),
+		Message: fmt.Sprintf("comparison with %s using %s does not match wrapped errors; use errors.Is", v.Name(), e.Op),
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message: "Use errors.Is",
+			TextEdits: append(edits, analysis.TextEdit{
+				Pos:     e.Pos(),
+				End:     e.End(),
+				NewText: []byte(call),
+			}),
+		}},
+	})
+}
+
+// sentinel returns the package-level variable of error type
+// denoted by e, or nil if e does not denote one.
+func sentinel(info *types.Info, e ast.Expr) *types.Var {
+	var id *ast.Ident
+	switch e := ast.Unparen(e).(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	default:
+		return nil
+	}
+	v, ok := info.Uses[id].(*types.Var)
+	if !ok || v.Pkg() == nil || v.Parent() != v.Pkg().Scope() {
+		return nil // not a package-level variable
+	}
+	if !types.Implements(v.Type(), errorType) {
+		return nil
+	}
+	return v
+}
+
+// isErrorInterface reports whether t is an interface type
+// that implements error, and thus may hold a wrapping error.
+func isErrorInterface(t types.Type) bool {
+	return t != nil && types.IsInterface(t) && types.Implements(t, errorType)
+}
+
+// checkTypeSwitch reports type switches on error values that
+// have cases for specific error types.
+func checkTypeSwitch(pass *analysis.Pass, stmt *ast.TypeSwitchStmt) {
+	var assert *ast.TypeAssertExpr
+	switch s := stmt.Assign.(type) {
+	case *ast.ExprStmt:
+		assert, _ = s.X.(*ast.TypeAssertExpr)
+	case *ast.AssignStmt:
+		if len(s.Rhs) == 1 {
+			assert, _ = s.Rhs[0].(*ast.TypeAssertExpr)
+		}
+	}
+	if assert == nil || !isErrorInterface(pass.TypesInfo.TypeOf(assert.X)) {
+		return
+	}
+	for _, clause := range stmt.Body.List {
+		for _, expr := range clause.(*ast.CaseClause).List {
+			tv, ok := pass.TypesInfo.Types[expr]
+			if ok && tv.IsType() && !types.Identical(tv.Type, types.Universe.Lookup("error").Type()) {
+				pass.ReportRangef(assert.X, "type switch on error %s does not match wrapped errors; use errors.As",
+					analysisutil.Format(pass.Fset, assert.X))
+				return
+			}
+		}
+	}
+}
+
+// checkErrorf reports calls to fmt.Errorf that format an error
+// operand without %w, and calls with several %w verbs in files
+// older than go1.20.
+func checkErrorf(pass *analysis.Pass, file *ast.File, call *ast.CallExpr) {
+	info := pass.TypesInfo
+	fn := typeutil.StaticCallee(info, call)
+	if !analysisutil.IsFunctionNamed(fn, "fmt", "Errorf") || len(call.Args) == 0 || call.Ellipsis.IsValid() {
+		return
+	}
+	tv := info.Types[call.Args[0]]
+	if tv.Value == nil || tv.Value.Kind() != constant.String {
+		return
+	}
+	format := constant.StringVal(tv.Value)
+	verbs := parseVerbs(format)
+	args := call.Args[1:]
+
+	nwrap := 0
+	for _, v := range verbs {
+		if v.verb == 'w' {
+			nwrap++
+		}
+	}
+	version := versions.FileVersion(info, file)
+	multiOK := versions.AtLeast(version, versions.Go1_20)
+	if nwrap > 1 && !multiOK {
+		pass.ReportRangef(call, "fmt.Errorf call has more than one %%w verb, which requires go1.20 or later (file is %s)", version)
+		return
+	}
+	if nwrap > 0 && !multiOK {
+		return // the call cannot wrap another error
+	}
+
+	// Find the non-wrapping verbs applied to error operands.
+	var nonwrap []formatVerb
+	for _, v := range verbs {
+		if (v.verb == 'v' || v.verb == 's') && v.arg < len(args) {
+			t := info.TypeOf(args[v.arg])
+			if t != nil && !types.Identical(t, types.Typ[types.UntypedNil]) && types.Implements(t, errorType) {
+				nonwrap = append(nonwrap, v)
+			}
+		}
+	}
+
+	// A fix is possible only if the verb's text appears verbatim
+	// in the format literal, and applying each fix would not
+	// yield multiple %w verbs in an old file.
+	lit, _ := ast.Unparen(call.Args[0]).(*ast.BasicLit)
+	verbatim := lit != nil && lit.Kind == token.STRING && lit.Value[1:len(lit.Value)-1] == format
+	canFix := multiOK || len(nonwrap) == 1
+
+	for _, v := range nonwrap {
+		arg := args[v.arg]
+		diag := analysis.Diagnostic{
+			Pos:     arg.Pos(),
+			End:     arg.End(),
+			Message: fmt.Sprintf("fmt.Errorf formats error operand %s with %%%c; use %%w to wrap it", analysisutil.Format(pass.Fset, arg), v.verb),
+		}
+		if verbatim && canFix && v.plain {
+			pos := lit.Pos() + 1 + token.Pos(v.start)
+			diag.SuggestedFixes = []analysis.SuggestedFix{{
+				Message: fmt.Sprintf("Use %%w instead of %%%c", v.verb),
+				TextEdits: []analysis.TextEdit{{
+					Pos:     pos,
+					End:     pos + token.Pos(v.end-v.start),
+					NewText: []byte("%w"),
+				}},
+			}}
+		}
+		pass.Report(diag)
+	}
+}
+
+// A formatVerb describes a formatting directive such as %v in a
+// format string.
+type formatVerb struct {
+	start, end int  // byte offsets of the directive, including '%'
+	verb       rune // the verb letter
+	arg        int  // index of the operand, after the format
+	plain      bool // directive has no flags, width, precision, or index
+}
+
+// parseVerbs returns the formatting directives of a fmt format
+// string, other than %%. Malformed directives are reported by the
+// printf checker; here they are parsed on a best-effort basis.
+func parseVerbs(format string) []formatVerb {
+	var (
+		verbs  []formatVerb
+		argNum int
+	)
+	for i := 0; i < len(format); {
+		if format[i] != '%' {
+			i++
+			continue
+		}
+		start := i
+		plain := true
+		i++
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			plain = false
+			i++
+		}
+		// index parses an explicit argument index [n].
+		index := func() {
+			if i < len(format) && format[i] == '[' {
+				plain = false
+				if j := strings.IndexByte(format[i:], ']'); j > 0 {
+					if n, err := strconv.Atoi(format[i+1 : i+j]); err == nil && n > 0 {
+						argNum = n - 1
+					}
+					i += j + 1
+				}
+			}
+		}
+		// number parses a width or precision.
+		number := func() {
+			index()
+			if i < len(format) && format[i] == '*' {
+				plain = false
+				argNum++
+				i++
+				return
+			}
+			for i < len(format) && '0' <= format[i] && format[i] <= '9' {
+				plain = false
+				i++
+			}
+		}
+		number()
+		if i < len(format) && format[i] == '.' {
+			plain = false
+			i++
+			number()
+		}
+		index()
+		if i == len(format) {
+			break
+		}
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		i += size
+		if verb == '%' {
+			continue
+		}
+		verbs = append(verbs, formatVerb{start, i, verb, argNum, plain})
+		argNum++
+	}
+	return verbs
+}
@@ -0,0 +1,19 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errorwrap_test
+
+import (
+	"testing"
+
+	"github.com/troll-zhao/tools/core/testenv"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/errorwrap"
+)
+
+func Test(t *testing.T) {
+	testenv.NeedsGo1Point(t, 22) // per-file Go versions
+
+	analysistest.RunWithEachSuggestedFix(t, analysistest.TestData(), errorwrap.Analyzer, "example.com/a", "example.com/old")
+}
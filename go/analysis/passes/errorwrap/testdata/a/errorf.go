@@ -0,0 +1,20 @@
+package a
+
+import "fmt"
+
+func errorf(err error, e *Error, s string) {
+	_ = fmt.Errorf("read: %v", err)       // want `fmt.Errorf formats error operand err with %v; use %w to wrap it`
+	_ = fmt.Errorf("read %s: %s", s, err) // want `fmt.Errorf formats error operand err with %s`
+	_ = fmt.Errorf(`%v, %v`,
+		err, // want `operand err with %v`
+		e)   // want `operand e with %v`
+	_ = fmt.Errorf("%w: %v", err, e)      // want `operand e with %v`
+	_ = fmt.Errorf("%+v", err)            // want `operand err with %v`
+	_ = fmt.Errorf("%[1]v %[1]d", err)    // want `operand err with %v`
+	_ = fmt.Errorf("quoted \"%v\"", err)  // want `operand err with %v`
+	_ = fmt.Errorf("%q %v", err, nil)     // ok: %q, nil
+	_ = fmt.Errorf("%d%% %v", 1, s)       // ok: not an error
+	_ = fmt.Errorf("%w", err)             // ok
+	_ = fmt.Errorf("%w, %w", err, e)      // ok: go1.22
+	_ = fmt.Errorf(s, err)                // ok: not a constant
+}
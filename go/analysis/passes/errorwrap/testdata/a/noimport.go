@@ -0,0 +1,7 @@
+package a
+
+import "io/fs"
+
+func noimport(err error) bool {
+	return err == fs.ErrExist // want `comparison with ErrExist`
+}
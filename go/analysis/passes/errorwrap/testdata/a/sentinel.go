@@ -0,0 +1,36 @@
+package a
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Error struct{}
+
+func (*Error) Error() string { return "error" }
+
+var ErrPtr = new(Error)
+
+func sentinel(err error) bool {
+	if err == ErrNotFound { // want `comparison with ErrNotFound using == does not match wrapped errors; use errors.Is`
+		return true
+	}
+	if fs.ErrNotExist != err { // want `comparison with ErrNotExist using != does not match wrapped errors; use errors.Is`
+		return false
+	}
+	_ = err == nil                // ok: nil
+	_ = err == io.EOF             // ok: io.EOF is never wrapped
+	_ = ErrNotFound == ErrPtr     // want `comparison with ErrPtr using == does not match wrapped errors`
+	var e *Error
+	_ = e == ErrPtr // ok: concrete type cannot wrap
+	local := errors.New("local")
+	return err == local // ok: not a package-level variable
+}
+
+// Is implements the matching logic of errors.Is.
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound // ok: within Is method
+}
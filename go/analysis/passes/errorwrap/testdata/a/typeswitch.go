@@ -0,0 +1,37 @@
+package a
+
+import (
+	"io/fs"
+	"net"
+)
+
+func typeswitch(err error) {
+	switch err.(type) { // want `type switch on error err does not match wrapped errors; use errors.As`
+	case *fs.PathError:
+	}
+	switch err := err.(type) { // want `type switch on error err does not match wrapped errors`
+	case nil:
+	case net.Error:
+		_ = err
+	}
+	switch err.(type) { // ok: no specific error types
+	case nil, error:
+	}
+	var x any
+	switch x.(type) { // ok: not an error
+	case *fs.PathError:
+	}
+}
+
+// As implements the matching logic of errors.As.
+func (e *Error) As(target any) bool {
+	switch target.(type) {
+	case **Error:
+		return true
+	}
+	var err error = e
+	switch err.(type) { // ok: within As method
+	case *fs.PathError:
+	}
+	return false
+}
@@ -0,0 +1,5 @@
+// The old module declares go1.19, which permits only one %w verb
+// per call to fmt.Errorf.
+module example.com/old
+
+go 1.19
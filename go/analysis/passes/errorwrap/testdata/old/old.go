@@ -0,0 +1,10 @@
+package old
+
+import "fmt"
+
+func old(err, err2 error) {
+	_ = fmt.Errorf("%w, %w", err, err2) // want `fmt.Errorf call has more than one %w verb, which requires go1.20 or later \(file is go1.19\)`
+	_ = fmt.Errorf("%w, %v", err, err2) // ok: cannot wrap both
+	_ = fmt.Errorf("%v, %v", err, err2) // want `operand err with %v` `operand err2 with %v`
+	_ = fmt.Errorf("op: %v", err)       // want `operand err with %v`
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package goroutineleak defines an Analyzer that reports goroutines
+// that may block forever on a channel operation.
+//
+// # Analyzer goroutineleak
+//
+// goroutineleak: check for goroutines blocked forever on channel operations
+//
+// A goroutine blocked on a channel operation that can never proceed is
+// never garbage collected, nor is anything it references. The
+// goroutineleak analyzer reports two common forms of this mistake.
+//
+// First, a goroutine that sends its result on an unbuffered channel
+// blocks forever if the function that started it returns without
+// receiving, for example because another case of a select statement
+// was chosen first:
+//
+//	ch := make(chan result)
+//	go func() {
+//		ch <- compute() // blocks forever if ctx is done first
+//	}()
+//	select {
+//	case r := <-ch:
+//		return r, nil
+//	case <-ctx.Done():
+//		return result{}, ctx.Err()
+//	}
+//
+// or because an early error return skips the receive. Giving the
+// channel a buffer of one element allows the send to complete.
+//
+// Second, a goroutine that ranges over a channel blocks forever once
+// the values are exhausted, unless the channel is closed. The analyzer
+// reports such goroutines when the function that started them may
+// return without closing the channel.
+//
+// The analyzer considers only channels created by the function that
+// starts the goroutine, and that are used only by that function and
+// the goroutine, including functions of the same package they call
+// statically; a channel that is stored in memory other than a local
+// variable, returned, or passed to a dynamic call or to a function of
+// another package is assumed to be used correctly elsewhere.
+//
+// A goroutine started in a loop is not reported if the function later
+// receives from the channel in a loop with the same bound, on every
+// iteration, as in this fan-in:
+//
+//	for i := 0; i < n; i++ {
+//		go func() { ch <- work(i) }()
+//	}
+//	for i := 0; i < n; i++ {
+//		sum += <-ch
+//	}
+package goroutineleak
@@ -0,0 +1,519 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package goroutineleak
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/analysis/passes/internal/ssautil"
+	"golang.org/x/tools/go/ssa"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "goroutineleak",
+	Doc:      analysisutil.MustExtractDoc(doc, "goroutineleak"),
+	URL:      "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/goroutineleak",
+	Requires: []*analysis.Analyzer{buildssa.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+type checker struct {
+	pass *analysis.Pass
+	cfgs *ctrlflow.CFGs
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	c := &checker{
+		pass: pass,
+		cfgs: pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs),
+	}
+	for _, fn := range ssainput.SrcFuncs {
+		var gos []*ssa.Go
+		var chans []*ssa.MakeChan
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.Go:
+					gos = append(gos, instr)
+				case *ssa.MakeChan:
+					chans = append(chans, instr)
+				}
+			}
+		}
+		if len(gos) == 0 || len(chans) == 0 {
+			continue
+		}
+		for _, ch := range chans {
+			c.checkChan(fn, ch, gos)
+		}
+	}
+	return nil, nil
+}
+
+// chanUses records the operations on a channel and its aliases.
+type chanUses struct {
+	name      string                // name of a variable holding the channel
+	sends     []ssa.Instruction     // *ssa.Send or *ssa.Select
+	recvs     []ssa.Instruction     // *ssa.UnOp or *ssa.Select
+	recvCases map[*ssa.Select][]int // indices of receive cases of each select
+	closes    []ssa.CallInstruction // calls to close
+	escapes   bool                  // channel may be used elsewhere
+	seen      map[ssa.Value]bool    // aliases of the channel
+}
+
+// checkChan reports goroutines started by fn that may block forever
+// on the channel created by mc.
+func (c *checker) checkChan(fn *ssa.Function, mc *ssa.MakeChan, gos []*ssa.Go) {
+	u := &chanUses{
+		recvCases: make(map[*ssa.Select][]int),
+		seen:      make(map[ssa.Value]bool),
+	}
+	c.visit(u, mc)
+	if u.escapes {
+		return
+	}
+	if u.name == "" {
+		u.name = "channel"
+	}
+
+	// Find the goroutine that performs each operation.
+	// Operations in other functions are not supported.
+	reach := make(map[*ssa.Go]map[*ssa.Function]bool)
+	for _, g := range gos {
+		reach[g] = c.reachable(g)
+	}
+	owner := func(instr ssa.Instruction) (*ssa.Go, bool) {
+		if instr.Parent() == fn {
+			return nil, true
+		}
+		for _, g := range gos {
+			if reach[g][instr.Parent()] {
+				return g, true
+			}
+		}
+		return nil, false
+	}
+	// only returns the go statement whose goroutine performs all the
+	// instructions, or nil if any is performed elsewhere.
+	only := func(instrs []ssa.Instruction) *ssa.Go {
+		var g *ssa.Go
+		for _, instr := range instrs {
+			h, ok := owner(instr)
+			if !ok || h == nil || g != nil && h != g {
+				return nil
+			}
+			g = h
+		}
+		return g
+	}
+	local := func(instrs []ssa.Instruction) bool {
+		for _, instr := range instrs {
+			if instr.Parent() != fn {
+				return false
+			}
+		}
+		return true
+	}
+	var closes []ssa.Instruction
+	for _, call := range u.closes {
+		closes = append(closes, call)
+	}
+
+	// A goroutine sends on an unbuffered channel that fn may not receive from?
+	if g := only(u.sends); g != nil && local(u.recvs) && isZero(mc.Size) {
+		barrier := func(instr ssa.Instruction) bool {
+			if sel, ok := instr.(*ssa.Select); ok {
+				return sel.Blocking && len(u.recvCases[sel]) == len(sel.States)
+			}
+			return contains(u.recvs, instr)
+		}
+		barrierBlocks := make(map[*ssa.BasicBlock]bool)
+		for sel, cases := range u.recvCases {
+			for _, i := range cases {
+				if b := caseBlock(sel, i); b != nil {
+					barrierBlocks[b] = true
+				}
+			}
+		}
+		// A receive loop that runs once for each goroutine started
+		// by a loop receives from all of them when it completes.
+		for _, recv := range u.recvs {
+			if done := loopExit(g, recv); done != nil {
+				barrierBlocks[done] = true
+			}
+		}
+		if ret := c.unguardedReturn(g, barrier, barrierBlocks); ret != nil {
+			c.report(u.sends[0].Pos(), ret, fn,
+				"goroutine may block forever sending on unbuffered channel %s: %s may return without receiving from it",
+				"returns without receiving from %s", u.name)
+			return
+		}
+	}
+
+	// A goroutine ranges over a channel that fn may not close?
+	if g := only(u.recvs); g != nil && local(u.sends) && local(closes) {
+		var ranges []ssa.Instruction
+		for _, instr := range u.recvs {
+			if recv, ok := instr.(*ssa.UnOp); ok && recv.CommaOk && recv.Block().Comment == "rangechan.loop" {
+				ranges = append(ranges, instr)
+			}
+		}
+		if len(ranges) == 0 {
+			return
+		}
+		for _, call := range u.closes {
+			if _, ok := call.(*ssa.Defer); ok {
+				return // closed on every path
+			}
+		}
+		barrier := func(instr ssa.Instruction) bool {
+			return contains(closes, instr)
+		}
+		if ret := c.unguardedReturn(g, barrier, nil); ret != nil {
+			c.report(ranges[0].Pos(), ret, fn,
+				"goroutine may block forever ranging over channel %s: %s may return without closing it",
+				"returns without closing %s", u.name)
+		}
+	}
+}
+
+// visit records the uses of v, an alias of a channel.
+func (c *checker) visit(u *chanUses, v ssa.Value) {
+	if u.escapes || u.seen[v] {
+		return
+	}
+	u.seen[v] = true
+	if u.name == "" {
+		switch v := v.(type) {
+		case *ssa.Alloc:
+			u.name = v.Comment
+		case *ssa.Parameter:
+			u.name = v.Name()
+		case *ssa.FreeVar:
+			u.name = v.Name()
+		}
+	}
+	for _, ref := range *v.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Send:
+			if ref.Chan != v {
+				u.escapes = true // channel of channels
+			}
+			u.sends = append(u.sends, ref)
+
+		case *ssa.UnOp:
+			switch ref.Op {
+			case token.ARROW:
+				u.recvs = append(u.recvs, ref)
+			case token.MUL: // load from variable
+				c.visit(u, ref)
+			default:
+				u.escapes = true
+			}
+
+		case *ssa.Store:
+			if ref.Val == v {
+				switch ref.Addr.(type) {
+				case *ssa.Alloc, *ssa.FreeVar:
+					c.visit(u, ref.Addr)
+				default:
+					u.escapes = true
+				}
+			}
+
+		case *ssa.Select:
+			for i, st := range ref.States {
+				if st.Chan != v {
+					continue
+				}
+				if st.Dir == types.RecvOnly {
+					u.recvs = append(u.recvs, ref)
+					u.recvCases[ref] = append(u.recvCases[ref], i)
+				} else {
+					u.sends = append(u.sends, ref)
+				}
+			}
+
+		case *ssa.MakeClosure:
+			fn := ref.Fn.(*ssa.Function)
+			for i, b := range ref.Bindings {
+				if b == v {
+					c.visit(u, fn.FreeVars[i])
+				}
+			}
+
+		case *ssa.Phi, *ssa.ChangeType:
+			c.visit(u, ref.(ssa.Value))
+
+		case ssa.CallInstruction:
+			c.visitCall(u, v, ref)
+
+		case *ssa.DebugRef:
+			// ignore
+
+		default:
+			u.escapes = true
+		}
+	}
+}
+
+// visitCall records the uses of v, an alias of a channel, by a call.
+func (c *checker) visitCall(u *chanUses, v ssa.Value, call ssa.CallInstruction) {
+	common := call.Common()
+	if b, ok := common.Value.(*ssa.Builtin); ok {
+		switch b.Name() {
+		case "close":
+			u.closes = append(u.closes, call)
+		case "len", "cap":
+		default:
+			u.escapes = true
+		}
+		return
+	}
+	if common.Value == v || common.IsInvoke() {
+		u.escapes = true
+		return
+	}
+	callee := common.StaticCallee()
+	if callee == nil || !c.inPackage(callee) || callee.Blocks == nil || len(callee.Params) != len(common.Args) {
+		u.escapes = true // dynamic call, or call to another package
+		return
+	}
+	for i, arg := range common.Args {
+		if arg == v {
+			c.visit(u, callee.Params[i])
+		}
+	}
+}
+
+// inPackage reports whether fn belongs to the package being analyzed.
+func (c *checker) inPackage(fn *ssa.Function) bool {
+	return fn.Pkg != nil && fn.Pkg.Pkg == c.pass.Pkg
+}
+
+// reachable returns the set of functions of the current package that
+// the goroutine started by g may call through static calls.
+func (c *checker) reachable(g *ssa.Go) map[*ssa.Function]bool {
+	reach := make(map[*ssa.Function]bool)
+	var visit func(fn *ssa.Function)
+	visit = func(fn *ssa.Function) {
+		if fn == nil || reach[fn] || !c.inPackage(fn) {
+			return
+		}
+		reach[fn] = true
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if call, ok := instr.(ssa.CallInstruction); ok {
+					visit(call.Common().StaticCallee())
+				}
+			}
+		}
+	}
+	visit(g.Call.StaticCallee())
+	return reach
+}
+
+// unguardedReturn returns a return statement that is reachable from g
+// without executing a barrier instruction or entering a barrier block,
+// or nil if there is none.
+func (c *checker) unguardedReturn(g *ssa.Go, barrier func(ssa.Instruction) bool, barrierBlocks map[*ssa.BasicBlock]bool) *ssa.Return {
+	seen := make(map[*ssa.BasicBlock]bool)
+	var search func(b *ssa.BasicBlock, instrs []ssa.Instruction) *ssa.Return
+	search = func(b *ssa.BasicBlock, instrs []ssa.Instruction) *ssa.Return {
+		for _, instr := range instrs {
+			if barrier(instr) {
+				return nil
+			}
+			switch instr := instr.(type) {
+			case *ssa.Return:
+				return instr
+			case *ssa.Panic:
+				return nil
+			}
+			if ssautil.NoReturn(c.cfgs, instr) {
+				return nil
+			}
+		}
+		for _, succ := range b.Succs {
+			if !seen[succ] && !barrierBlocks[succ] {
+				seen[succ] = true
+				if ret := search(succ, succ.Instrs); ret != nil {
+					return ret
+				}
+			}
+		}
+		return nil
+	}
+	b := g.Block()
+	for i, instr := range b.Instrs {
+		if instr == g {
+			return search(b, b.Instrs[i+1:])
+		}
+	}
+	return nil
+}
+
+// report reports a diagnostic at pos, with related information
+// at the return statement ret of fn if it has a position.
+func (c *checker) report(pos token.Pos, ret *ssa.Return, fn *ssa.Function, format, related, name string) {
+	diag := analysis.Diagnostic{
+		Pos:     pos,
+		Message: fmt.Sprintf(format, name, funcName(fn)),
+	}
+	retPos := ret.Pos()
+	if !retPos.IsValid() {
+		// implicit return at end of function body
+		switch syntax := fn.Syntax().(type) {
+		case *ast.FuncDecl:
+			retPos = syntax.Body.Rbrace
+		case *ast.FuncLit:
+			retPos = syntax.Body.Rbrace
+		}
+	}
+	if retPos.IsValid() {
+		diag.Related = []analysis.RelatedInformation{{
+			Pos:     retPos,
+			Message: fmt.Sprintf(related, name),
+		}}
+	}
+	c.pass.Report(diag)
+}
+
+// caseBlock returns the block that begins the body of the specified
+// case of a select statement, or nil if it cannot be found.
+func caseBlock(sel *ssa.Select, i int) *ssa.BasicBlock {
+	for _, ref := range *sel.Referrers() {
+		idx, ok := ref.(*ssa.Extract)
+		if !ok || idx.Index != 0 {
+			continue
+		}
+		for _, ref := range *idx.Referrers() {
+			cmp, ok := ref.(*ssa.BinOp)
+			if !ok || cmp.Op != token.EQL {
+				continue
+			}
+			if k, ok := cmp.Y.(*ssa.Const); ok && k.Value != nil && k.Int64() == int64(i) {
+				for _, ref := range *cmp.Referrers() {
+					if cond, ok := ref.(*ssa.If); ok {
+						return cond.Block().Succs[0]
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// loopExit returns the block at which the loop containing recv, a
+// receive, exits, provided that it runs once for each iteration of
+// the loop containing g: both loops have the same bound, recv is
+// executed on every iteration, and the loop has no other exit.
+// Otherwise it returns nil.
+func loopExit(g *ssa.Go, recv ssa.Instruction) *ssa.BasicBlock {
+	if _, ok := recv.(*ssa.UnOp); !ok {
+		return nil
+	}
+	gloop, rloop := loopHeader(g.Block()), loopHeader(recv.Block())
+	if gloop == nil || rloop == nil || gloop == rloop {
+		return nil
+	}
+	x, y := loopCond(gloop), loopCond(rloop)
+	if x == nil || y == nil || x.Op != y.Op || !sameValue(x.Y, y.Y) {
+		return nil
+	}
+	for _, pred := range rloop.Preds {
+		if rloop.Dominates(pred) && !recv.Block().Dominates(pred) {
+			return nil // an iteration may skip the receive
+		}
+	}
+	done := rloop.Succs[1]
+	if len(done.Preds) != 1 {
+		return nil // the loop may exit early
+	}
+	return done
+}
+
+// loopHeader returns the header of the innermost loop whose body
+// contains b, or nil if there is none.
+func loopHeader(b *ssa.BasicBlock) *ssa.BasicBlock {
+	for ; b != nil; b = b.Idom() {
+		if h := b.Idom(); h != nil && strings.HasSuffix(h.Comment, ".loop") && len(h.Succs) == 2 && h.Succs[0] == b {
+			return h
+		}
+	}
+	return nil
+}
+
+// loopCond returns the comparison that controls the loop with the
+// specified header, or nil if it is not a comparison.
+func loopCond(header *ssa.BasicBlock) *ssa.BinOp {
+	if cond, ok := header.Instrs[len(header.Instrs)-1].(*ssa.If); ok {
+		cmp, _ := cond.Cond.(*ssa.BinOp)
+		return cmp
+	}
+	return nil
+}
+
+// sameValue reports whether x and y are the same value: identical,
+// equal constants, or the lengths of the same value.
+func sameValue(x, y ssa.Value) bool {
+	if x == y {
+		return true
+	}
+	switch x := x.(type) {
+	case *ssa.Const:
+		y, ok := y.(*ssa.Const)
+		return ok && x.Value != nil && y.Value != nil &&
+			types.Identical(x.Type(), y.Type()) &&
+			constant.Compare(x.Value, token.EQL, y.Value)
+	case *ssa.Call:
+		y, ok := y.(*ssa.Call)
+		if !ok {
+			return false
+		}
+		bx, ok1 := x.Call.Value.(*ssa.Builtin)
+		by, ok2 := y.Call.Value.(*ssa.Builtin)
+		return ok1 && ok2 && bx.Name() == "len" && by.Name() == "len" &&
+			sameValue(x.Call.Args[0], y.Call.Args[0])
+	}
+	return false
+}
+
+// isZero reports whether the size of a channel is zero.
+func isZero(size ssa.Value) bool {
+	k, ok := size.(*ssa.Const)
+	return ok && k.Value != nil && constant.Sign(k.Value) == 0
+}
+
+// funcName returns the name of fn for use in a diagnostic.
+func funcName(fn *ssa.Function) string {
+	if fn.Parent() != nil {
+		return "the enclosing function literal"
+	}
+	return fn.Name()
+}
+
+func contains(instrs []ssa.Instruction, x ssa.Instruction) bool {
+	for _, instr := range instrs {
+		if instr == x {
+			return true
+		}
+	}
+	return false
+}
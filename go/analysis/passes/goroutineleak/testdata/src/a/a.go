@@ -0,0 +1,246 @@
+package a
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+type result struct{ n int }
+
+func compute() result { return result{} }
+
+func selectDone(ctx context.Context) (result, error) {
+	ch := make(chan result)
+	go func() {
+		ch <- compute() // want `goroutine may block forever sending on unbuffered channel ch: selectDone may return without receiving from it`
+	}()
+	select {
+	case r := <-ch:
+		return r, nil
+	case <-ctx.Done():
+		return result{}, ctx.Err()
+	}
+}
+
+func selectTimeout() (result, error) {
+	ch := make(chan result, 0)
+	go func() {
+		ch <- compute() // want `goroutine may block forever sending on unbuffered channel ch`
+	}()
+	select {
+	case r := <-ch:
+		return r, nil
+	case <-time.After(time.Second):
+		return result{}, errors.New("timeout")
+	}
+}
+
+func earlyReturn(check func() error) (result, error) {
+	ch := make(chan result)
+	go func() {
+		ch <- compute() // want `goroutine may block forever sending on unbuffered channel ch: earlyReturn may return without receiving from it`
+	}()
+	if err := check(); err != nil {
+		return result{}, err
+	}
+	return <-ch, nil
+}
+
+func neverReceived() {
+	done := make(chan bool)
+	go func() {
+		compute()
+		done <- true // want `goroutine may block forever sending on unbuffered channel done`
+	}()
+}
+
+func helper(ch chan result) {
+	ch <- compute() // want `goroutine may block forever sending on unbuffered channel ch: namedFunc may return without receiving from it`
+}
+
+func namedFunc(ctx context.Context) result {
+	ch := make(chan result)
+	go helper(ch)
+	select {
+	case r := <-ch:
+		return r
+	case <-ctx.Done():
+		return result{}
+	}
+}
+
+// Correct uses.
+
+func buffered(ctx context.Context) (result, error) {
+	ch := make(chan result, 1)
+	go func() {
+		ch <- compute()
+	}()
+	select {
+	case r := <-ch:
+		return r, nil
+	case <-ctx.Done():
+		return result{}, ctx.Err()
+	}
+}
+
+func alwaysReceived(check func() error) (result, error) {
+	ch := make(chan result)
+	go func() {
+		ch <- compute()
+	}()
+	r := <-ch
+	if err := check(); err != nil {
+		return result{}, err
+	}
+	return r, nil
+}
+
+func fatal(check func() error) result {
+	ch := make(chan result)
+	go func() {
+		ch <- compute()
+	}()
+	if err := check(); err != nil {
+		log.Fatal(err)
+	}
+	return <-ch
+}
+
+var global chan result
+
+func escapes() {
+	ch := make(chan result)
+	global = ch
+	go func() {
+		ch <- compute()
+	}()
+}
+
+func returned() chan result {
+	ch := make(chan result)
+	go func() {
+		ch <- compute()
+	}()
+	return ch
+}
+
+// Ranging over channels.
+
+func neverClosed(items []int) {
+	ch := make(chan int)
+	go func() {
+		for x := range ch { // want `goroutine may block forever ranging over channel ch: neverClosed may return without closing it`
+			_ = x
+		}
+	}()
+	for _, x := range items {
+		ch <- x
+	}
+}
+
+func closedOnSomePaths(items []int) error {
+	ch := make(chan int)
+	go func() {
+		for x := range ch { // want `goroutine may block forever ranging over channel ch: closedOnSomePaths may return without closing it`
+			_ = x
+		}
+	}()
+	for _, x := range items {
+		if x < 0 {
+			return errors.New("negative")
+		}
+		ch <- x
+	}
+	close(ch)
+	return nil
+}
+
+func closed(items []int) error {
+	ch := make(chan int)
+	go func() {
+		for x := range ch {
+			_ = x
+		}
+	}()
+	for _, x := range items {
+		if x < 0 {
+			close(ch)
+			return errors.New("negative")
+		}
+		ch <- x
+	}
+	close(ch)
+	return nil
+}
+
+func deferClose(items []int) error {
+	ch := make(chan int)
+	defer close(ch)
+	go func() {
+		for x := range ch {
+			_ = x
+		}
+	}()
+	for _, x := range items {
+		if x < 0 {
+			return errors.New("negative")
+		}
+		ch <- x
+	}
+	return nil
+}
+
+func work(i int) int { return i }
+
+func fanIn(n int) int {
+	ch := make(chan int)
+	for i := 0; i < n; i++ {
+		go func() { ch <- work(i) }()
+	}
+	sum := 0
+	for i := 0; i < n; i++ {
+		sum += <-ch
+	}
+	return sum
+}
+
+func fanInRange(items []int) int {
+	ch := make(chan int)
+	for _, x := range items {
+		go func() { ch <- work(x) }()
+	}
+	sum := 0
+	for range items {
+		sum += <-ch
+	}
+	return sum
+}
+
+func fanInBreak(n int) int {
+	ch := make(chan int)
+	for i := 0; i < n; i++ {
+		go func() { ch <- work(i) }() // want `goroutine may block forever sending on unbuffered channel ch: fanInBreak may return without receiving from it`
+	}
+	sum := 0
+	for i := 0; i < n; i++ {
+		if sum += <-ch; sum > 100 {
+			break
+		}
+	}
+	return sum
+}
+
+func fanInFewer(n int) int {
+	ch := make(chan int)
+	for i := 0; i < n; i++ {
+		go func() { ch <- work(i) }() // want `goroutine may block forever sending on unbuffered channel ch: fanInFewer may return without receiving from it`
+	}
+	sum := 0
+	for i := 0; i < n-1; i++ {
+		sum += <-ch
+	}
+	return sum
+}
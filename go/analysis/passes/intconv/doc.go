@@ -0,0 +1,42 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package intconv defines an Analyzer that checks for integer
+// conversions that may truncate their operand or change its sign.
+//
+// # Analyzer intconv
+//
+// intconv: check for integer conversions that may lose information
+//
+// A conversion between integer types whose range of values does not
+// include that of the operand's type silently wraps the operand: for
+// example, int32(x) of an int64 x greater than math.MaxInt32 yields a
+// negative number, and uint(x) of a negative int x yields a large
+// positive one. The intconv analyzer reports such narrowing
+// conversions unless the operand is known to be in range.
+//
+// The analyzer computes a range of possible values of the operand from
+// its definition, for example a constant, a bitwise AND with a
+// constant mask, a remainder by a constant, a right shift, or a
+// conversion from a smaller type, and refines it using comparisons
+// that dominate the conversion:
+//
+//	if n < 0 || n > math.MaxUint16 {
+//		return errRange
+//	}
+//	port := uint16(n) // ok
+//
+// The results of strconv.ParseInt and strconv.ParseUint with a
+// constant bitSize argument are known to fit in a value of that size,
+// so the following is not reported:
+//
+//	n, err := strconv.ParseInt(s, 10, 32)
+//	...
+//	return int32(n), nil // ok
+//
+// When the -boundscheck flag is set and the operand is a variable,
+// the analyzer suggests a fix to insert a bounds check before the
+// statement containing the conversion, which panics if the operand is
+// out of range.
+package intconv
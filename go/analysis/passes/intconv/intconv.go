@@ -0,0 +1,579 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intconv
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/ssa"
+)
+
+//go:embed doc.go
+var doc string
+
+var boundsCheckFix bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&boundsCheckFix, "boundscheck", false, "suggest fixes that insert a bounds check, which panics if the operand is out of range, before the conversion")
+}
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "intconv",
+	Doc:      analysisutil.MustExtractDoc(doc, "intconv"),
+	URL:      "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/intconv",
+	Requires: []*analysis.Analyzer{buildssa.Analyzer},
+	Run:      run,
+}
+
+// maxDepth bounds the depth of the recursive computation of ranges.
+const maxDepth = 8
+
+type checker struct {
+	pass     *analysis.Pass
+	visiting map[ssa.Value]bool // Phi nodes being visited
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	c := &checker{pass: pass, visiting: make(map[ssa.Value]bool)}
+	for _, fn := range ssainput.SrcFuncs {
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if conv, ok := instr.(*ssa.Convert); ok && conv.Pos().IsValid() {
+					c.checkConvert(conv)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// An interval is a closed range [lo, hi] of integers.
+type interval struct {
+	lo, hi *big.Int
+}
+
+func (x interval) contains(y interval) bool {
+	return x.lo.Cmp(y.lo) <= 0 && y.hi.Cmp(x.hi) <= 0
+}
+
+// intersect returns the intersection of x and y,
+// or x if they do not intersect.
+func (x interval) intersect(y interval) interval {
+	lo, hi := x.lo, x.hi
+	if y.lo.Cmp(lo) > 0 {
+		lo = y.lo
+	}
+	if y.hi.Cmp(hi) < 0 {
+		hi = y.hi
+	}
+	if lo.Cmp(hi) > 0 {
+		return x // unreachable code
+	}
+	return interval{lo, hi}
+}
+
+func (x interval) union(y interval) interval {
+	lo, hi := x.lo, x.hi
+	if y.lo.Cmp(lo) < 0 {
+		lo = y.lo
+	}
+	if y.hi.Cmp(hi) > 0 {
+		hi = y.hi
+	}
+	return interval{lo, hi}
+}
+
+// typeRange returns the range of values of integer type t.
+func (c *checker) typeRange(t types.Type) (interval, bool) {
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 {
+		return interval{}, false
+	}
+	bits := uint(8 * c.pass.TypesSizes.Sizeof(basic))
+	return bitRange(bits, basic.Info()&types.IsUnsigned == 0), true
+}
+
+// bitRange returns the range of values of an integer of the
+// specified number of bits.
+func bitRange(bits uint, signed bool) interval {
+	one := big.NewInt(1)
+	if signed {
+		hi := new(big.Int).Lsh(one, bits-1)
+		lo := new(big.Int).Neg(hi)
+		return interval{lo, hi.Sub(hi, one)}
+	}
+	hi := new(big.Int).Lsh(one, bits)
+	return interval{new(big.Int), hi.Sub(hi, one)}
+}
+
+// checkConvert reports a conversion between integer types
+// that may not preserve the value of its operand.
+func (c *checker) checkConvert(conv *ssa.Convert) {
+	src, ok1 := c.typeRange(conv.X.Type())
+	dst, ok2 := c.typeRange(conv.Type())
+	if !ok1 || !ok2 || dst.contains(src) {
+		return
+	}
+	known := c.bounds(conv.X, conv.Block(), 0)
+	if dst.contains(known) {
+		return
+	}
+
+	file := c.file(conv.Pos())
+	if file == nil {
+		return
+	}
+	path, _ := astutil.PathEnclosingInterval(file, conv.Pos(), conv.Pos())
+	if len(path) == 0 {
+		return
+	}
+	call, ok := path[0].(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return
+	}
+	arg := call.Args[0]
+	what := "truncate"
+	if c.pass.TypesSizes.Sizeof(conv.Type()) >= c.pass.TypesSizes.Sizeof(conv.X.Type()) {
+		what = "change the sign of"
+	}
+	qual := types.RelativeTo(c.pass.Pkg)
+	diag := analysis.Diagnostic{
+		Pos: call.Pos(),
+		End: call.End(),
+		Message: fmt.Sprintf("conversion from %s to %s may %s %s",
+			types.TypeString(conv.X.Type(), qual), types.TypeString(conv.Type(), qual),
+			what, analysisutil.Format(c.pass.Fset, arg)),
+	}
+	if boundsCheckFix {
+		if fix, ok := c.boundsCheck(file, path, arg, conv.Type(), known, dst); ok {
+			diag.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+	}
+	c.pass.Report(diag)
+}
+
+// bounds returns the range of possible values of v at the start of
+// block at.
+func (c *checker) bounds(v ssa.Value, at *ssa.BasicBlock, depth int) interval {
+	iv, _ := c.typeRange(v.Type())
+	if depth < maxDepth {
+		iv = iv.intersect(c.defBounds(v, at, depth+1))
+	}
+
+	// Refine the range using the comparisons that dominate the
+	// block: if the sole predecessor of a dominating block ends
+	// with an if statement, its condition is known on entry.
+	for b := at; b != nil; b = b.Idom() {
+		if len(b.Preds) != 1 {
+			continue
+		}
+		pred := b.Preds[0]
+		cond, ok := pred.Instrs[len(pred.Instrs)-1].(*ssa.If)
+		if !ok || pred.Succs[0] == pred.Succs[1] {
+			continue
+		}
+		if cmp, ok := cond.Cond.(*ssa.BinOp); ok {
+			iv = refine(iv, cmp, v, b == pred.Succs[0])
+		}
+	}
+	return iv
+}
+
+// defBounds returns the range of possible values of v implied by its
+// definition.
+func (c *checker) defBounds(v ssa.Value, at *ssa.BasicBlock, depth int) interval {
+	full, _ := c.typeRange(v.Type())
+	switch v := v.(type) {
+	case *ssa.Const:
+		if x := constInt(v); x != nil {
+			return interval{x, x}
+		}
+
+	case *ssa.Convert:
+		if _, ok := c.typeRange(v.X.Type()); ok {
+			if x := c.bounds(v.X, at, depth); full.contains(x) {
+				return x
+			}
+		}
+
+	case *ssa.BinOp:
+		x := c.bounds(v.X, at, depth)
+		var y interval
+		if v.Op != token.SHL && v.Op != token.SHR {
+			y = c.bounds(v.Y, at, depth)
+		}
+		if r, ok := binop(v.Op, x, y, constInt(v.Y)); ok && full.contains(r) {
+			return r
+		}
+
+	case *ssa.Extract:
+		if v.Index == 0 {
+			if call, ok := v.Tuple.(*ssa.Call); ok {
+				if r, ok := c.parseBounds(call); ok {
+					return r
+				}
+			}
+		}
+
+	case *ssa.Call:
+		if b, ok := v.Call.Value.(*ssa.Builtin); ok && (b.Name() == "len" || b.Name() == "cap") {
+			return interval{new(big.Int), full.hi}
+		}
+
+	case *ssa.Phi:
+		if c.visiting[v] {
+			break
+		}
+		c.visiting[v] = true
+		defer delete(c.visiting, v)
+		// An edge that increments or decrements the value of
+		// the φ-node, as in a loop, extends the range of the
+		// other edges upward or downward without limit.
+		var (
+			r          interval
+			ok         bool
+			incr, decr bool
+		)
+		for i, edge := range v.Edges {
+			if op, ok := edge.(*ssa.BinOp); ok && op.X == v {
+				if k := constInt(op.Y); k != nil && k.Sign() > 0 {
+					switch op.Op {
+					case token.ADD:
+						incr = true
+						continue
+					case token.SUB:
+						decr = true
+						continue
+					}
+				}
+			}
+			x := c.bounds(edge, v.Block().Preds[i], depth)
+			if ok {
+				r = r.union(x)
+			} else {
+				r, ok = x, true
+			}
+		}
+		if ok {
+			if incr {
+				r.hi = full.hi
+			}
+			if decr {
+				r.lo = full.lo
+			}
+			return r
+		}
+	}
+	return full
+}
+
+// binop returns the range of x op y, if it can be computed.
+// k is the value of the constant operand y, or nil.
+func binop(op token.Token, x, y interval, k *big.Int) (interval, bool) {
+	switch op {
+	case token.ADD:
+		return interval{new(big.Int).Add(x.lo, y.lo), new(big.Int).Add(x.hi, y.hi)}, true
+	case token.SUB:
+		return interval{new(big.Int).Sub(x.lo, y.hi), new(big.Int).Sub(x.hi, y.lo)}, true
+	case token.AND:
+		// The result is non-negative and no greater than
+		// any non-negative operand.
+		switch {
+		case x.lo.Sign() >= 0 && y.lo.Sign() >= 0:
+			hi := x.hi
+			if y.hi.Cmp(hi) < 0 {
+				hi = y.hi
+			}
+			return interval{new(big.Int), hi}, true
+		case x.lo.Sign() >= 0:
+			return interval{new(big.Int), x.hi}, true
+		case y.lo.Sign() >= 0:
+			return interval{new(big.Int), y.hi}, true
+		}
+	case token.REM:
+		if k != nil && k.Sign() != 0 {
+			m := new(big.Int).Abs(k)
+			m.Sub(m, big.NewInt(1))
+			if x.lo.Sign() >= 0 {
+				return interval{new(big.Int), m}, true
+			}
+			return interval{new(big.Int).Neg(m), m}, true
+		}
+	case token.QUO:
+		if k != nil && k.Sign() > 0 {
+			return interval{new(big.Int).Quo(x.lo, k), new(big.Int).Quo(x.hi, k)}, true
+		}
+	case token.SHR:
+		if k != nil && k.IsUint64() && k.Uint64() < 1024 {
+			n := uint(k.Uint64())
+			return interval{new(big.Int).Rsh(x.lo, n), new(big.Int).Rsh(x.hi, n)}, true
+		}
+	}
+	return interval{}, false
+}
+
+// refine returns the range iv of value v, refined by the knowledge
+// that the comparison cmp has the specified outcome.
+func refine(iv interval, cmp *ssa.BinOp, v ssa.Value, outcome bool) interval {
+	op := cmp.Op
+	var k *big.Int
+	switch {
+	case cmp.X == v:
+		k = constInt(cmp.Y)
+	case cmp.Y == v:
+		k = constInt(cmp.X)
+		switch op { // k op v => v op' k
+		case token.LSS:
+			op = token.GTR
+		case token.LEQ:
+			op = token.GEQ
+		case token.GTR:
+			op = token.LSS
+		case token.GEQ:
+			op = token.LEQ
+		}
+	}
+	if k == nil {
+		return iv
+	}
+	if !outcome {
+		switch op {
+		case token.LSS:
+			op = token.GEQ
+		case token.LEQ:
+			op = token.GTR
+		case token.GTR:
+			op = token.LEQ
+		case token.GEQ:
+			op = token.LSS
+		case token.EQL:
+			op = token.NEQ
+		case token.NEQ:
+			op = token.EQL
+		}
+	}
+	one := big.NewInt(1)
+	switch op {
+	case token.LSS:
+		return iv.intersect(interval{iv.lo, new(big.Int).Sub(k, one)})
+	case token.LEQ:
+		return iv.intersect(interval{iv.lo, k})
+	case token.GTR:
+		return iv.intersect(interval{new(big.Int).Add(k, one), iv.hi})
+	case token.GEQ:
+		return iv.intersect(interval{k, iv.hi})
+	case token.EQL:
+		return iv.intersect(interval{k, k})
+	}
+	return iv
+}
+
+// parseBounds returns the range of the integer result of a call to
+// strconv.ParseInt or strconv.ParseUint with a constant bitSize.
+func (c *checker) parseBounds(call *ssa.Call) (interval, bool) {
+	callee := call.Call.StaticCallee()
+	if callee == nil || len(call.Call.Args) != 3 {
+		return interval{}, false
+	}
+	obj, ok := callee.Object().(*types.Func)
+	if !ok || obj.Pkg() == nil || obj.Pkg().Path() != "strconv" {
+		return interval{}, false
+	}
+	signed := obj.Name() == "ParseInt"
+	if !signed && obj.Name() != "ParseUint" {
+		return interval{}, false
+	}
+	bits := constInt(call.Call.Args[2])
+	if bits == nil || !bits.IsUint64() || bits.Uint64() > 64 {
+		return interval{}, false
+	}
+	n := uint(bits.Uint64())
+	if n == 0 {
+		n = uint(8 * c.pass.TypesSizes.Sizeof(types.Typ[types.Int]))
+	}
+	return bitRange(n, signed), true
+}
+
+// constInt returns the value of v if it is an integer constant,
+// or nil.
+func constInt(v ssa.Value) *big.Int {
+	k, ok := v.(*ssa.Const)
+	if !ok || k.Value == nil || k.Value.Kind() != constant.Int {
+		return nil
+	}
+	x, _ := new(big.Int).SetString(k.Value.ExactString(), 10)
+	return x
+}
+
+// file returns the file containing pos, or nil.
+func (c *checker) file(pos token.Pos) *ast.File {
+	for _, f := range c.pass.Files {
+		if f.Pos() <= pos && pos < f.End() {
+			return f
+		}
+	}
+	return nil
+}
+
+// boundsCheck returns a fix that inserts a check that the operand
+// arg of a conversion, whose range is known, is within the range dst
+// of the type t. The check is inserted before the statement enclosing
+// the conversion, whose syntax is path[0], provided that statement
+// always evaluates the conversion, that arg is a variable, and that
+// the variable is in scope before the statement.
+func (c *checker) boundsCheck(file *ast.File, path []ast.Node, arg ast.Expr, t types.Type, known, dst interval) (analysis.SuggestedFix, bool) {
+	if !isVariable(arg) {
+		return analysis.SuggestedFix{}, false
+	}
+	stmt := enclosingStmt(path)
+	if stmt == nil || c.declaredWithin(arg, stmt) {
+		return analysis.SuggestedFix{}, false
+	}
+	min, max, ok := limits(t.Underlying().(*types.Basic).Kind())
+	if !ok {
+		return analysis.SuggestedFix{}, false
+	}
+
+	math, edits := analysisinternal.AddImport(c.pass.TypesInfo, file, stmt.Pos(), "math", "math")
+	text := analysisutil.Format(c.pass.Fset, arg)
+	var conds []string
+	if known.lo.Cmp(dst.lo) < 0 {
+		if min == "" {
+			conds = append(conds, text+" < 0")
+		} else {
+			conds = append(conds, fmt.Sprintf("%s < %s.%s", text, math, min))
+		}
+	}
+	if known.hi.Cmp(dst.hi) > 0 {
+		conds = append(conds, fmt.Sprintf("%s > %s.%s", text, math, max))
+	} else if min == "" {
+		edits = nil // math is not needed
+	}
+
+	indent := strings.Repeat("\t", c.pass.Fset.Position(stmt.Pos()).Column-1)
+	msg := strconv.Quote(fmt.Sprintf("%s out of range for %s", text, types.TypeString(t, types.RelativeTo(c.pass.Pkg))))
+	check := fmt.Sprintf("if %s {\n%s\tpanic(%s)\n%s}\n%s", strings.Join(conds, " || "), indent, msg, indent, indent)
+	return analysis.SuggestedFix{
+		Message: "Insert bounds check",
+		TextEdits: append(edits, analysis.TextEdit{
+			Pos:     stmt.Pos(),
+			End:     stmt.Pos(),
+			NewText: []byte(check),
+		}),
+	}, true
+}
+
+// declaredWithin reports whether e refers to a variable declared
+// within stmt, for example in the Init statement of an if statement,
+// which is not in scope before stmt.
+func (c *checker) declaredWithin(e ast.Expr, stmt ast.Stmt) bool {
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if obj := c.pass.TypesInfo.Uses[id]; obj != nil && stmt.Pos() <= obj.Pos() && obj.Pos() < stmt.End() {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// limits returns the names of the constants of package math that
+// are the minimum and maximum values of the integer type of the
+// specified kind. The minimum is "" for unsigned types.
+func limits(kind types.BasicKind) (min, max string, ok bool) {
+	switch kind {
+	case types.Int:
+		return "MinInt", "MaxInt", true
+	case types.Int8:
+		return "MinInt8", "MaxInt8", true
+	case types.Int16:
+		return "MinInt16", "MaxInt16", true
+	case types.Int32:
+		return "MinInt32", "MaxInt32", true
+	case types.Int64:
+		return "MinInt64", "MaxInt64", true
+	case types.Uint:
+		return "", "MaxUint", true
+	case types.Uint8:
+		return "", "MaxUint8", true
+	case types.Uint16:
+		return "", "MaxUint16", true
+	case types.Uint32:
+		return "", "MaxUint32", true
+	case types.Uint64:
+		return "", "MaxUint64", true
+	}
+	return "", "", false
+}
+
+// isVariable reports whether e is a variable or a field selection
+// from one, which may be evaluated again without side effects.
+func isVariable(e ast.Expr) bool {
+	switch e := ast.Unparen(e).(type) {
+	case *ast.Ident:
+		return true
+	case *ast.SelectorExpr:
+		return isVariable(e.X)
+	}
+	return false
+}
+
+// enclosingStmt returns the statement of a block that encloses the
+// innermost node of path, provided the statement always evaluates
+// that node; otherwise it returns nil.
+func enclosingStmt(path []ast.Node) ast.Stmt {
+	for i := 1; i < len(path); i++ {
+		child, parent := path[i-1], path[i]
+		switch parent := parent.(type) {
+		case *ast.FuncLit:
+			return nil
+		case *ast.BinaryExpr:
+			if parent.Op == token.LAND || parent.Op == token.LOR {
+				if child == parent.Y {
+					return nil // conditionally evaluated
+				}
+			}
+		case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+			if stmt, ok := child.(ast.Stmt); ok {
+				return stmt
+			}
+			return nil
+		case *ast.IfStmt:
+			if child != parent.Init && child != parent.Cond {
+				return nil
+			}
+		case *ast.SwitchStmt:
+			if child != parent.Init && child != parent.Tag {
+				return nil
+			}
+		case *ast.TypeSwitchStmt:
+			if child != parent.Init && child != parent.Assign {
+				return nil
+			}
+		case *ast.AssignStmt, *ast.DeclStmt, *ast.ExprStmt, *ast.GenDecl,
+			*ast.ReturnStmt, *ast.SendStmt, *ast.ValueSpec, *ast.IncDecStmt:
+			// always evaluated
+		case ast.Stmt:
+			return nil // e.g. for, range, select, go, defer
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package intconv_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/intconv"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, intconv.Analyzer, "a")
+}
+
+func TestBoundsCheck(t *testing.T) {
+	testdata := analysistest.TestData()
+	intconv.Analyzer.Flags.Set("boundscheck", "true")
+	defer intconv.Analyzer.Flags.Set("boundscheck", "false")
+	analysistest.RunWithEachSuggestedFix(t, testdata, intconv.Analyzer, "a")
+}
@@ -0,0 +1,93 @@
+package a
+
+import (
+	"math"
+	"strconv"
+)
+
+type Port uint16
+
+func narrow(x int64) int32 {
+	return int32(x) // want `conversion from int64 to int32 may truncate x`
+}
+
+func sign(n int, u uint) (uint, int) {
+	a := uint(n) // want `conversion from int to uint may change the sign of n`
+	b := int(u)  // want `conversion from uint to int may change the sign of u`
+	return a, b
+}
+
+func named(n int) Port {
+	return Port(n) // want `conversion from int to Port may truncate n`
+}
+
+func field(p struct{ n int64 }) int16 {
+	v := int16(p.n) // want `conversion from int64 to int16 may truncate p.n`
+	return v
+}
+
+func noFix(ok bool, x int64, xs []int64, next func() int64) {
+	if ok && int32(x) > 0 { // want `conversion from int64 to int32 may truncate x`
+	}
+	if y := next(); int32(y) > 0 { // want `conversion from int64 to int32 may truncate y`
+	}
+	_ = int32(xs[0])                // want `conversion from int64 to int32 may truncate xs\[0\]`
+	for i := int32(x); i > 0; i-- { // want `conversion from int64 to int32 may truncate x`
+	}
+}
+
+func lengths(s []byte) (int32, uint) {
+	return int32(len(s)), uint(len(s)) // want `conversion from int to int32 may truncate len\(s\)`
+}
+
+func parse(s string) (int32, int32, error) {
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	m, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int32(n), int32(m), nil // want `conversion from int64 to int32 may truncate m`
+}
+
+// Conversions of values known to be in range.
+
+func guarded(x int64) int32 {
+	if x < math.MinInt32 || x > math.MaxInt32 {
+		return 0
+	}
+	return int32(x)
+}
+
+func guardedAnd(n int) Port {
+	if n >= 0 && n <= math.MaxUint16 {
+		return Port(n)
+	}
+	return 0
+}
+
+func nonNegative(n int) uint {
+	if n < 0 {
+		panic(n)
+	}
+	return uint(n)
+}
+
+func masks(x uint64, y int) (byte, uint8, int8, int8) {
+	return byte(x & 0xff), uint8(x >> 56), int8(y % 100), int8(y / (1 << 58))
+}
+
+func widening(x int32, y uint8) (int64, int, uint32) {
+	return int64(x), int(y), uint32(y)
+}
+
+func loop(s string) {
+	for i := 0; i < 100; i++ {
+		_ = int8(i)
+	}
+	for i := 10; i > 0; i-- {
+		_ = uint8(i)
+	}
+}
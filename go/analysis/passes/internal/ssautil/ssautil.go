@@ -0,0 +1,55 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ssautil defines helpers shared by the analyzers that use SSA
+// form. It is separate from analysisutil so that analyzers that do not
+// use SSA do not depend on it.
+package ssautil
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/ssa"
+)
+
+// CallExpr returns the call expression in the syntax of fn whose left
+// parenthesis is at lparen, or nil if there is none, for example
+// because fn has no syntax.
+func CallExpr(fn *ssa.Function, lparen token.Pos) *ast.CallExpr {
+	var found *ast.CallExpr
+	if fn != nil && fn.Syntax() != nil {
+		ast.Inspect(fn.Syntax(), func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok && call.Lparen == lparen {
+				found = call
+			}
+			return found == nil
+		})
+	}
+	return found
+}
+
+// Field returns the index'th field of the struct type t, or of the
+// struct type to which t points.
+func Field(t types.Type, index int) *types.Var {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	return t.Underlying().(*types.Struct).Field(index)
+}
+
+// NoReturn reports whether instr is a static call to a function that
+// never returns, such as os.Exit, according to cfgs.
+func NoReturn(cfgs *ctrlflow.CFGs, instr ssa.Instruction) bool {
+	if call, ok := instr.(*ssa.Call); ok {
+		if callee := call.Call.StaticCallee(); callee != nil {
+			if obj, ok := callee.Object().(*types.Func); ok {
+				return cfgs.NoReturn(obj)
+			}
+		}
+	}
+	return false
+}
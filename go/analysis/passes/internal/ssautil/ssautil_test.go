@@ -0,0 +1,73 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssautil_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/passes/internal/ssautil"
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestHelpers(t *testing.T) {
+	src := `package p
+
+type S struct{ a, b int }
+
+func f(s *S) int { return g(s.b) + g(0) }
+
+func g(int) int
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []*ast.File{file}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+	pkg, err := new(types.Config).Check("p", fset, files, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssapkg := ssa.NewProgram(fset, 0).CreatePackage(pkg, files, info, false)
+	ssapkg.Build()
+	f := ssapkg.Func("f")
+
+	var calls, fields int
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Call:
+				calls++
+				call := ssautil.CallExpr(f, instr.Pos())
+				if call == nil || call.Lparen != instr.Pos() {
+					t.Errorf("CallExpr(%s) = %v", instr, call)
+				}
+			case *ssa.FieldAddr:
+				fields++
+				if got := ssautil.Field(instr.X.Type(), instr.Field).Name(); got != "b" {
+					t.Errorf("Field(%s) = %s, want b", instr, got)
+				}
+			}
+		}
+	}
+	if calls != 2 || fields != 1 {
+		t.Errorf("found %d calls and %d field selections, want 2 and 1", calls, fields)
+	}
+	if call := ssautil.CallExpr(ssapkg.Func("g"), f.Pos()); call != nil {
+		t.Errorf("CallExpr found %s in a function without syntax", types.ExprString(call))
+	}
+}
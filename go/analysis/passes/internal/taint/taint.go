@@ -0,0 +1,542 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package taint defines a taint-flow analysis over SSA form, used by
+// analyzers that report untrusted data reaching sensitive functions.
+//
+// An analyzer is described by a Config listing sources of untrusted
+// data, sinks that must not receive it, and sanitizers whose results
+// are trusted. Within a function, taint flows through every value
+// computed from a tainted operand, through stores to memory, and
+// from the arguments of a call to its results and to the memory
+// reachable from its pointer arguments. Values of numeric and boolean
+// types are never tainted, nor are the results of sanitizers and of
+// the sinks themselves.
+//
+// Each function is summarized by a fact recording whether its results
+// are derived from a source and which of its parameters flow to a
+// sink, so that flows through helper functions are detected across
+// package boundaries.
+package taint
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/internal/ssautil"
+	"golang.org/x/tools/go/ssa"
+)
+
+// A Config describes a taint analysis.
+//
+// Functions and methods are named as by types.Func.FullName, for
+// example "os.Getenv" or "(*net/http.Request).FormValue"; struct
+// fields are named by the package path, type, and field, for example
+// "net/http.Request.URL". A source or sink is not considered as such
+// within the package that declares it.
+type Config struct {
+	// Sources are the functions whose results are untrusted,
+	// and the struct fields whose values are untrusted.
+	Sources Set
+
+	// Sinks are the functions that must not receive untrusted
+	// arguments. An element "name:param" restricts the sink to the
+	// named parameter; an element "name" applies to all parameters.
+	Sinks Set
+
+	// Sanitizers are the functions whose results are trusted
+	// regardless of their arguments.
+	Sanitizers Set
+
+	// Message is the format of a diagnostic; its operand is the
+	// name of the sink.
+	Message string
+
+	// NewFact returns a new fact of the analyzer's fact type.
+	NewFact func() Fact
+}
+
+// A Set is a set of names. It implements flag.Value as a
+// comma-separated list.
+type Set map[string]bool
+
+func (s *Set) String() string {
+	var items []string
+	for item := range *s {
+		items = append(items, item)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func (s *Set) Set(value string) error {
+	m := make(Set) // clobber previous value
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			m[name] = true
+		}
+	}
+	*s = m
+	return nil
+}
+
+// HTTPRequestSources returns a new set of the sources of untrusted
+// input from an *http.Request: its fields, and the methods that
+// return data supplied by the client.
+func HTTPRequestSources() Set {
+	s := make(Set)
+	for _, field := range []string{
+		"Body", "Form", "Header", "Host", "Method", "MultipartForm",
+		"PostForm", "RemoteAddr", "RequestURI", "Trailer", "URL",
+	} {
+		s["net/http.Request."+field] = true
+	}
+	for _, method := range []string{
+		"BasicAuth", "Cookie", "Cookies", "CookiesNamed", "FormFile",
+		"FormValue", "MultipartReader", "PathValue", "PostFormValue",
+		"Referer", "UserAgent",
+	} {
+		s["(*net/http.Request)."+method] = true
+	}
+	return s
+}
+
+// A Fact is the fact type of a taint analyzer. Since a fact type may
+// belong to only one analyzer, each analyzer declares its own type,
+// a struct that embeds Summary.
+type Fact interface {
+	analysis.Fact
+	summary() *Summary
+}
+
+// A Summary describes the taint flows of a function.
+type Summary struct {
+	Source bool       // results may be derived from a source
+	Sinks  []SinkFlow // parameters that flow to a sink
+}
+
+// A SinkFlow records that a parameter flows to a sink. Free
+// variables of a function literal are numbered after its parameters.
+type SinkFlow struct {
+	Param int
+	Sink  string
+}
+
+func (s *Summary) summary() *Summary { return s }
+
+func (s *Summary) String() string {
+	var parts []string
+	if s.Source {
+		parts = append(parts, "source")
+	}
+	for _, sink := range s.Sinks {
+		parts = append(parts, fmt.Sprintf("p%d->%s", sink.Param, sink.Sink))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *Summary) empty() bool { return !s.Source && len(s.Sinks) == 0 }
+
+// Run performs the taint analysis described by config, reporting
+// diagnostics and exporting facts. The analyzer must require
+// buildssa.Analyzer and declare the fact type returned by
+// config.NewFact.
+func Run(pass *analysis.Pass, config *Config) error {
+	c := &checker{
+		pass:      pass,
+		config:    config,
+		sinks:     make(map[string][]string),
+		summaries: make(map[*ssa.Function]*Summary),
+		reported:  make(map[ssa.CallInstruction]bool),
+	}
+	// Build the sink table in a deterministic order, in which a sink
+	// naming a whole function precedes (and so takes precedence
+	// over) those naming its parameters.
+	names := make([]string, 0, len(config.Sinks))
+	for sink := range config.Sinks {
+		names = append(names, sink)
+	}
+	sort.Strings(names)
+	for _, sink := range names {
+		name, param, ok := strings.Cut(sink, ":")
+		if params, seen := c.sinks[name]; seen && params == nil {
+			continue // all parameters are sinks
+		}
+		if ok {
+			c.sinks[name] = append(c.sinks[name], param)
+		} else {
+			c.sinks[name] = nil
+		}
+	}
+	for _, fn := range pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA).SrcFuncs {
+		s := c.summarize(fn)
+		if obj, ok := fn.Object().(*types.Func); ok && !s.empty() {
+			fact := config.NewFact()
+			*fact.summary() = *s
+			pass.ExportObjectFact(obj, fact)
+		}
+	}
+	return nil
+}
+
+type checker struct {
+	pass      *analysis.Pass
+	config    *Config
+	sinks     map[string][]string // function name => parameter names (nil => all)
+	summaries map[*ssa.Function]*Summary
+	reported  map[ssa.CallInstruction]bool
+}
+
+// labels is the set of origins of the taint of a value:
+// bit 0 denotes a source, and bit i+1 denotes parameter i.
+type labels uint64
+
+const sourceLabel labels = 1
+
+func paramLabel(i int) labels {
+	if i >= 63 {
+		return 0 // too many parameters
+	}
+	return 1 << (i + 1)
+}
+
+// summarize computes the summary of fn, memoizing it, and reports
+// flows from sources to sinks within fn. A recursive call for a
+// function being summarized returns an empty summary.
+func (c *checker) summarize(fn *ssa.Function) *Summary {
+	if s, ok := c.summaries[fn]; ok {
+		return s
+	}
+	s := new(Summary)
+	c.summaries[fn] = s
+	if fn.Blocks == nil {
+		return s
+	}
+
+	// Compute the taint of each value, iterating to a fixed point.
+	taint := make(map[ssa.Value]labels)
+	for i, param := range fn.Params {
+		add(taint, param, paramLabel(i))
+	}
+	for i, fv := range fn.FreeVars {
+		add(taint, fv, paramLabel(len(fn.Params)+i))
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				if c.transfer(instr, taint) {
+					changed = true
+				}
+			}
+		}
+	}
+
+	// Record the flows to results and to sinks.
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Return:
+				for _, res := range instr.Results {
+					if taint[res]&sourceLabel != 0 {
+						s.Source = true
+					}
+				}
+			case ssa.CallInstruction:
+				c.checkCall(fn, instr, taint, s)
+			}
+		}
+	}
+	sort.Slice(s.Sinks, func(i, j int) bool {
+		x, y := s.Sinks[i], s.Sinks[j]
+		if x.Param != y.Param {
+			return x.Param < y.Param
+		}
+		return x.Sink < y.Sink
+	})
+	return s
+}
+
+// add adds l to the taint of v, and reports whether it changed.
+func add(taint map[ssa.Value]labels, v ssa.Value, l labels) bool {
+	if l == 0 || !mayCarry(v.Type()) {
+		return false
+	}
+	old := taint[v]
+	taint[v] = old | l
+	return taint[v] != old
+}
+
+// mayCarry reports whether a value of type t may carry untrusted
+// data. Numbers and booleans may not.
+func mayCarry(t types.Type) bool {
+	if basic, ok := t.Underlying().(*types.Basic); ok {
+		return basic.Info()&types.IsString != 0 || basic.Kind() == types.UnsafePointer
+	}
+	return true
+}
+
+// transfer propagates taint through instr, and reports whether the
+// taint of any value changed.
+func (c *checker) transfer(instr ssa.Instruction, taint map[ssa.Value]labels) bool {
+	switch instr := instr.(type) {
+	case *ssa.Call:
+		common := instr.Common()
+		if c.isSink(calleeFunc(common)) {
+			// Untrusted input is reported where it reaches the sink,
+			// and not followed into the sink's results.
+			return false
+		}
+		l := c.callTaint(common, taint)
+		changed := add(taint, instr, l)
+		// The call may store its arguments in memory
+		// reachable from its pointer arguments.
+		if l := c.argTaint(common, taint); l != 0 {
+			for _, arg := range common.Args {
+				if _, ok := arg.Type().Underlying().(*types.Pointer); ok {
+					changed = add(taint, root(arg), l) || changed
+				}
+			}
+		}
+		return changed
+
+	case *ssa.Store:
+		return add(taint, root(instr.Addr), taint[instr.Val])
+
+	case *ssa.MapUpdate:
+		return add(taint, root(instr.Map), taint[instr.Key]|taint[instr.Value])
+
+	case *ssa.Send:
+		return add(taint, instr.Chan, taint[instr.X])
+
+	case *ssa.FieldAddr:
+		l := taint[instr.X]
+		if c.isSource(fieldName(instr.X.Type(), instr.Field)) {
+			l |= sourceLabel
+		}
+		return add(taint, instr, l)
+
+	case *ssa.Field:
+		l := taint[instr.X]
+		if c.isSource(fieldName(instr.X.Type(), instr.Field)) {
+			l |= sourceLabel
+		}
+		return add(taint, instr, l)
+
+	default:
+		// Any other value is tainted by its operands.
+		v, ok := instr.(ssa.Value)
+		if !ok {
+			return false
+		}
+		var l labels
+		for _, op := range instr.Operands(nil) {
+			if *op != nil {
+				l |= taint[*op]
+			}
+		}
+		return add(taint, v, l)
+	}
+}
+
+// root returns the variable or value whose memory contains the
+// location addressed by v.
+func root(v ssa.Value) ssa.Value {
+	for {
+		switch x := v.(type) {
+		case *ssa.FieldAddr:
+			v = x.X
+		case *ssa.IndexAddr:
+			v = x.X
+		case *ssa.Slice:
+			v = x.X
+		default:
+			return v
+		}
+	}
+}
+
+// argTaint returns the union of the taint of the arguments of a call,
+// including the receiver or function value.
+func (c *checker) argTaint(common *ssa.CallCommon, taint map[ssa.Value]labels) labels {
+	l := taint[common.Value]
+	for _, arg := range common.Args {
+		l |= taint[arg]
+	}
+	return l
+}
+
+// callTaint returns the taint of the result of a call.
+func (c *checker) callTaint(common *ssa.CallCommon, taint map[ssa.Value]labels) labels {
+	obj := calleeFunc(common)
+	if obj != nil && c.config.Sanitizers[obj.FullName()] {
+		return 0
+	}
+	l := c.argTaint(common, taint)
+	if obj != nil && c.isSource(obj.FullName()) {
+		l |= sourceLabel
+	}
+	if fn := common.StaticCallee(); fn != nil {
+		if s := c.calleeSummary(fn); s != nil && s.Source {
+			l |= sourceLabel
+		}
+	}
+	return l
+}
+
+// isSource reports whether the named function or field is a source
+// for the current package.
+func (c *checker) isSource(name string) bool {
+	return name != "" && c.config.Sources[name] && !declaredIn(name, c.pass.Pkg)
+}
+
+// isSink reports whether fn is a sink for the current package.
+func (c *checker) isSink(fn *types.Func) bool {
+	if fn == nil {
+		return false
+	}
+	name := fn.FullName()
+	_, ok := c.sinks[name]
+	return ok && !declaredIn(name, c.pass.Pkg)
+}
+
+// declaredIn reports whether the function or field of the specified
+// name belongs to pkg.
+func declaredIn(name string, pkg *types.Package) bool {
+	name = strings.TrimLeft(name, "(*")
+	path := pkg.Path()
+	return strings.HasPrefix(name, path) && len(name) > len(path) && name[len(path)] == '.'
+}
+
+// calleeSummary returns the summary of fn, computed for functions of
+// the current package and imported as a fact for others, or nil.
+func (c *checker) calleeSummary(fn *ssa.Function) *Summary {
+	if fn.Pkg != nil && fn.Pkg.Pkg == c.pass.Pkg {
+		return c.summarize(fn)
+	}
+	if obj, ok := fn.Object().(*types.Func); ok {
+		fact := c.config.NewFact()
+		if c.pass.ImportObjectFact(obj, fact) {
+			return fact.summary()
+		}
+	}
+	return nil
+}
+
+// checkCall records the flows of tainted arguments of call to sinks,
+// either directly or within the called function, reporting those
+// from sources.
+func (c *checker) checkCall(fn *ssa.Function, call ssa.CallInstruction, taint map[ssa.Value]labels, s *Summary) {
+	common := call.Common()
+	if obj := calleeFunc(common); obj != nil {
+		if name := obj.FullName(); c.isSink(obj) {
+			params := c.sinks[name]
+			sig := obj.Type().(*types.Signature)
+			offset := 0
+			if sig.Recv() != nil && !common.IsInvoke() {
+				offset = 1 // receiver is the first argument
+			}
+			for i := 0; i < sig.Params().Len(); i++ {
+				if offset+i < len(common.Args) && (params == nil || contains(params, sig.Params().At(i).Name())) {
+					c.sink(call, taint[common.Args[offset+i]], name, nil, s)
+				}
+			}
+		}
+	}
+	if callee := common.StaticCallee(); callee != nil {
+		if cs := c.calleeSummary(callee); cs != nil {
+			for _, flow := range cs.Sinks {
+				var arg ssa.Value
+				if flow.Param < len(common.Args) {
+					arg = common.Args[flow.Param]
+				} else if mc, ok := common.Value.(*ssa.MakeClosure); ok && flow.Param-len(callee.Params) < len(mc.Bindings) {
+					arg = mc.Bindings[flow.Param-len(callee.Params)]
+				}
+				if arg != nil {
+					c.sink(call, taint[arg], flow.Sink, callee, s)
+				}
+			}
+		}
+	}
+}
+
+// sink records the flow of a value with taint l to the named sink,
+// reporting it if it is from a source. If via is non-nil, the flow is
+// within that called function.
+func (c *checker) sink(call ssa.CallInstruction, l labels, name string, via *ssa.Function, s *Summary) {
+	if l&sourceLabel != 0 && !c.reported[call] {
+		c.reported[call] = true
+		pos, end := call.Pos(), call.Pos()
+		if expr := ssautil.CallExpr(call.Parent(), pos); expr != nil {
+			pos, end = expr.Pos(), expr.End()
+		}
+		if pos.IsValid() {
+			msg := fmt.Sprintf(c.config.Message, name)
+			if via != nil && via.Parent() != nil {
+				msg += " (via call to function literal)"
+			} else if via != nil {
+				msg += fmt.Sprintf(" (via call to %s)", via.Name())
+			}
+			c.pass.Report(analysis.Diagnostic{Pos: pos, End: end, Message: msg})
+		}
+	}
+	for i := 0; i < 63; i++ {
+		if l&paramLabel(i) != 0 {
+			flow := SinkFlow{Param: i, Sink: name}
+			if !containsFlow(s.Sinks, flow) {
+				s.Sinks = append(s.Sinks, flow)
+			}
+		}
+	}
+}
+
+// calleeFunc returns the function or abstract method called by
+// common, or nil.
+func calleeFunc(common *ssa.CallCommon) *types.Func {
+	if common.IsInvoke() {
+		return common.Method
+	}
+	if fn := common.StaticCallee(); fn != nil {
+		obj, _ := fn.Object().(*types.Func)
+		return obj
+	}
+	return nil
+}
+
+// fieldName returns the name of the specified field of the struct
+// type t (or *t) in the form "path.Type.Field", or "" if t is not a
+// named type.
+func fieldName(t types.Type, field int) string {
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return ""
+	}
+	obj := named.Obj()
+	return obj.Pkg().Path() + "." + obj.Name() + "." + ssautil.Field(named, field).Name()
+}
+
+func contains(list []string, x string) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFlow(list []SinkFlow, x SinkFlow) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
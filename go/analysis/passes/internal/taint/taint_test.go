@@ -0,0 +1,45 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package taint_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/internal/taint"
+)
+
+// testFact is the fact type of testAnalyzer.
+type testFact struct{ taint.Summary }
+
+func (*testFact) AFact() {}
+
+var testConfig = taint.Config{
+	Sources:    taint.Set{"src.Source": true, "src.Request.Data": true},
+	Sinks:      taint.Set{"src.Sink": true, "src.Exec:cmd": true, "src.Run": true, "src.Run:cmd": true},
+	Sanitizers: taint.Set{"src.Clean": true},
+	Message:    "tainted data reaches %s",
+	NewFact:    func() taint.Fact { return new(testFact) },
+}
+
+var testAnalyzer = &analysis.Analyzer{
+	Name:      "testtaint",
+	Doc:       "test taint analysis",
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer},
+	FactTypes: []analysis.Fact{new(testFact)},
+	Run: func(pass *analysis.Pass) (interface{}, error) {
+		return nil, taint.Run(pass, &testConfig)
+	},
+}
+
+// Test checks the propagation of taint from sources to sinks through
+// fields, closures, and calls between functions, within a package
+// and across packages, and its interruption by sanitizers.
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, testAnalyzer, "a", "b")
+}
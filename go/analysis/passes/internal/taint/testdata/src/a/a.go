@@ -0,0 +1,88 @@
+package a
+
+import "src"
+
+func direct() {
+	src.Sink(src.Source()) // want "tainted data reaches src.Sink"
+}
+
+func sanitized() {
+	src.Sink(src.Clean(src.Source()))
+}
+
+func numbers() {
+	n := len(src.Source())
+	src.Sink(string(rune(n)))
+}
+
+func param() {
+	src.Exec(src.Source(), "ls")
+	src.Exec("/", src.Source()) // want "tainted data reaches src.Exec"
+	src.Run(src.Source(), "ls") // want "tainted data reaches src.Run"
+}
+
+// Fields.
+
+func field(r *src.Request) { // want field:"p0->src.Sink"
+	src.Sink(r.Data) // want "tainted data reaches src.Sink"
+	src.Sink(r.ID)
+}
+
+func fieldValue(r src.Request) { // want fieldValue:"p0->src.Sink"
+	src.Sink(r.Data) // want "tainted data reaches src.Sink"
+}
+
+type box struct{ s string }
+
+func store() {
+	var b box
+	b.s = src.Source()
+	src.Sink(b.s) // want "tainted data reaches src.Sink"
+}
+
+func storeSlice() {
+	xs := make([]string, 1)
+	xs[0] = src.Source()
+	src.Sink(xs[0]) // want "tainted data reaches src.Sink"
+}
+
+// Closures.
+
+func closureResult() {
+	f := func() string { return src.Source() }
+	src.Sink(f()) // want "tainted data reaches src.Sink"
+}
+
+func closureCapture() {
+	s := src.Source()
+	func() { // want "tainted data reaches src.Sink \\(via call to function literal\\)"
+		src.Sink(s)
+	}()
+}
+
+func closureSink() {
+	s := src.Source()
+	f := func(x string) { src.Sink(x) }
+	f(s) // want "tainted data reaches src.Sink \\(via call to function literal\\)"
+}
+
+// Calls between functions.
+
+func Get() string { return src.Source() } // want Get:"source"
+
+func Put(x string) { src.Sink(x) } // want Put:"p0->src.Sink"
+
+func viaGet() {
+	src.Sink(Get()) // want "tainted data reaches src.Sink"
+}
+
+func viaPut() {
+	Put(src.Source()) // want "tainted data reaches src.Sink \\(via call to Put\\)"
+}
+
+func recursive(x string, n int) string {
+	if n == 0 {
+		return x
+	}
+	return recursive(x, n-1)
+}
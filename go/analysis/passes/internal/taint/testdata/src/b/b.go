@@ -0,0 +1,16 @@
+package b
+
+import (
+	"a"
+	"src"
+)
+
+func crossPackage() {
+	a.Put(a.Get()) // want "tainted data reaches src.Sink \\(via call to Put\\)"
+}
+
+func crossPackageSanitized() {
+	a.Put(src.Clean(src.Source()))
+}
+
+func Forward(x string) { a.Put(x) } // want Forward:"p0->src.Sink"
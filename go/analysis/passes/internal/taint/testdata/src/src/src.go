@@ -0,0 +1,27 @@
+package src
+
+// Source returns untrusted data.
+func Source() string { return "" }
+
+// Sink must not receive untrusted data.
+func Sink(s string) {}
+
+// Exec must not receive an untrusted cmd; its dir is trusted.
+func Exec(dir, cmd string) {}
+
+// Run must not receive untrusted data in any parameter, even though
+// its cmd is also listed separately.
+func Run(dir, cmd string) {}
+
+// Clean returns a trusted copy of s.
+func Clean(s string) string { return s }
+
+// Request has an untrusted Data field.
+type Request struct {
+	Data string
+	ID   string
+}
+
+func local() {
+	Sink(Source()) // sources and sinks are not such within their own package
+}
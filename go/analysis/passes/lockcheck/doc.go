@@ -0,0 +1,56 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lockcheck defines an Analyzer that checks the use of
+// sync.Mutex and sync.RWMutex locks.
+//
+// # Analyzer lockcheck
+//
+// lockcheck: check that locks are released and not held while blocking
+//
+// The lockcheck analyzer reports two kinds of mistake in the use of
+// sync.Mutex and sync.RWMutex locks (and other sync.Locker values).
+//
+// First, it reports a call to Lock or RLock when the function returns
+// with the lock still held on some paths but not others, typically
+// because an early return forgets to call Unlock:
+//
+//	mu.Lock()
+//	if cache[key] != nil {
+//		return cache[key] // mu is still locked
+//	}
+//	...
+//	mu.Unlock()
+//
+// A deferred call to Unlock releases the lock on every path. A
+// function that returns with the lock held on every path is assumed
+// to acquire it on behalf of its caller, if the lock is reachable
+// from a parameter or package-level variable; otherwise, as for a
+// local mutex, the caller cannot release it, and the analyzer
+// reports that the function returns with the lock held. An exported
+// function that returns with a lock held is also reported unless its
+// name begins with Lock or RLock or its doc comment says that it
+// acquires or locks something, since callers in other packages would
+// not otherwise know to release the lock.
+//
+// Second, it reports operations that may block indefinitely while a
+// lock is held, delaying every other goroutine that needs the lock:
+// channel sends and receives, select statements without a default
+// case, calls to time.Sleep, and network operations such as dialing,
+// accepting, reading, writing, and HTTP requests.
+//
+// The analyzer records facts about functions that lock or unlock
+// mutexes reachable from their parameters (including the receiver)
+// or package-level variables, so that helper functions such as
+//
+//	func (c *Cache) lock() { c.mu.Lock() }
+//
+// are understood by their callers in any package. Function literals
+// called directly or deferred are handled similarly.
+//
+// The analysis is path-insensitive: a lock acquired under some
+// condition is considered held on all paths that follow, so a
+// function that locks and unlocks under the same condition may be
+// reported.
+package lockcheck
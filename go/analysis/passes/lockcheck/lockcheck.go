@@ -0,0 +1,662 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lockcheck
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/analysis/passes/internal/ssautil"
+	"golang.org/x/tools/go/ssa"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "lockcheck",
+	Doc:       analysisutil.MustExtractDoc(doc, "lockcheck"),
+	URL:       "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/lockcheck",
+	Run:       run,
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer, ctrlflow.Analyzer},
+	FactTypes: []analysis.Fact{new(lockFact)},
+}
+
+// A lockFact summarizes the locks that a function acquires or
+// releases on behalf of its caller.
+type lockFact struct {
+	Effects []effect
+}
+
+// An effect is the acquisition or release of a lock by a function.
+// The lock is identified by its root, a parameter ("p0"), free
+// variable ("f0"), or package-level variable of the function's
+// package ("g:name"), and a path of field selections from it.
+type effect struct {
+	Root    string
+	Path    string
+	Read    bool // RLock or RUnlock
+	Acquire bool // Lock or RLock; otherwise Unlock or RUnlock
+}
+
+func (*lockFact) AFact() {}
+
+func (f *lockFact) String() string {
+	var parts []string
+	for _, e := range f.Effects {
+		parts = append(parts, e.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func (e effect) String() string {
+	name := "Unlock"
+	if e.Acquire {
+		name = "Lock"
+	}
+	if e.Read {
+		name = "R" + name
+	}
+	return fmt.Sprintf("%s(%s%s)", name, e.Root, e.Path)
+}
+
+// A lockKey identifies a lock within a function: a root value (an
+// ssa.Value, or a *types.Var for a package-level variable) and a path
+// of field selections from it.
+type lockKey struct {
+	root any
+	path string
+	read bool
+}
+
+// A state is the set of locks that may be held at a point in a
+// function, and the set of locks released by deferred calls.
+type state struct {
+	held     map[lockKey]ssa.Instruction // lock => acquiring instruction
+	deferred map[lockKey]bool
+}
+
+func newState() *state {
+	return &state{
+		held:     make(map[lockKey]ssa.Instruction),
+		deferred: make(map[lockKey]bool),
+	}
+}
+
+func (s *state) copy() *state {
+	t := newState()
+	for k, v := range s.held {
+		t.held[k] = v
+	}
+	for k := range s.deferred {
+		t.deferred[k] = true
+	}
+	return t
+}
+
+// merge adds the locks of t to s, and reports whether s changed.
+func (s *state) merge(t *state) bool {
+	changed := false
+	for k, v := range t.held {
+		if _, ok := s.held[k]; !ok {
+			s.held[k] = v
+			changed = true
+		}
+	}
+	for k := range t.deferred {
+		if !s.deferred[k] {
+			s.deferred[k] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+type checker struct {
+	pass    *analysis.Pass
+	cfgs    *ctrlflow.CFGs
+	results map[*ssa.Function]*result
+}
+
+// A result holds the effects and diagnostics of a function.
+type result struct {
+	effects []effect
+	diags   []analysis.Diagnostic
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	c := &checker{
+		pass:    pass,
+		cfgs:    pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs),
+		results: make(map[*ssa.Function]*result),
+	}
+	for _, fn := range ssainput.SrcFuncs {
+		res := c.analyze(fn)
+		if obj, ok := fn.Object().(*types.Func); ok && len(res.effects) > 0 {
+			pass.ExportObjectFact(obj, &lockFact{Effects: res.effects})
+		}
+		for _, diag := range res.diags {
+			pass.Report(diag)
+		}
+	}
+	return nil, nil
+}
+
+// A funcAnalysis holds the state of the analysis of one function.
+type funcAnalysis struct {
+	c        *checker
+	fn       *ssa.Function
+	final    bool // second pass: record returns and report
+	res      *result
+	returns  []map[lockKey]ssa.Instruction // locks held at each return
+	released map[lockKey]bool              // locks released but not acquired
+	reported map[ssa.Instruction]bool
+}
+
+// analyze computes the result for fn, memoizing it.
+// A recursive call for a function being analyzed returns
+// an empty result.
+func (c *checker) analyze(fn *ssa.Function) *result {
+	if res, ok := c.results[fn]; ok {
+		return res
+	}
+	res := new(result)
+	c.results[fn] = res
+	if fn.Blocks == nil {
+		return res
+	}
+	fa := &funcAnalysis{
+		c:        c,
+		fn:       fn,
+		res:      res,
+		released: make(map[lockKey]bool),
+		reported: make(map[ssa.Instruction]bool),
+	}
+
+	// Compute the state on entry to each block,
+	// iterating to a fixed point.
+	in := make([]*state, len(fn.Blocks))
+	in[0] = newState()
+	work := []*ssa.BasicBlock{fn.Blocks[0]}
+	for len(work) > 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		out := fa.transfer(b, in[b.Index].copy())
+		if out == nil {
+			continue // block does not continue
+		}
+		for _, succ := range b.Succs {
+			if in[succ.Index] == nil {
+				in[succ.Index] = out.copy()
+				work = append(work, succ)
+			} else if in[succ.Index].merge(out) {
+				work = append(work, succ)
+			}
+		}
+	}
+
+	// Visit each reachable block again, reporting blocking
+	// operations and recording the locks held at each return.
+	fa.final = true
+	for _, b := range fn.Blocks {
+		if in[b.Index] != nil {
+			fa.transfer(b, in[b.Index].copy())
+		}
+	}
+	fa.summarize()
+	return res
+}
+
+// transfer applies the instructions of block b to state s. It
+// returns the state on exit from the block, or nil if the block does
+// not continue to its successors.
+func (fa *funcAnalysis) transfer(b *ssa.BasicBlock, s *state) *state {
+	for _, instr := range b.Instrs {
+		switch instr := instr.(type) {
+		case *ssa.Call:
+			if ssautil.NoReturn(fa.c.cfgs, instr) {
+				return nil
+			}
+			fa.call(instr, instr.Common(), s, false)
+		case *ssa.Defer:
+			fa.call(instr, instr.Common(), s, true)
+		case *ssa.Send:
+			fa.blocking(instr, instr.Pos(), "channel send", s)
+		case *ssa.UnOp:
+			if instr.Op == token.ARROW {
+				fa.blocking(instr, instr.Pos(), "channel receive", s)
+			}
+		case *ssa.Select:
+			if instr.Blocking {
+				fa.blocking(instr, instr.Pos(), "select statement", s)
+			}
+		case *ssa.Return:
+			if fa.final {
+				held := make(map[lockKey]ssa.Instruction)
+				for k, v := range s.held {
+					if !s.deferred[k] {
+						held[k] = v
+					}
+				}
+				fa.returns = append(fa.returns, held)
+			}
+		case *ssa.Panic:
+			return nil
+		}
+	}
+	return s
+}
+
+// call applies the effects on locks of a call or deferred call.
+func (fa *funcAnalysis) call(instr ssa.Instruction, common *ssa.CallCommon, s *state, deferred bool) {
+	// Direct lock operation?
+	if op, recv := lockOp(common); op != "" {
+		if key, ok := fa.key(recv); ok {
+			key.read = strings.HasPrefix(op, "R")
+			fa.apply(instr, s, key, strings.HasSuffix(op, "Lock") && !strings.HasSuffix(op, "Unlock"), deferred)
+		}
+		return
+	}
+
+	if !deferred {
+		if desc := blockingCall(common); desc != "" {
+			pos := instr.Pos()
+			if call := ssautil.CallExpr(fa.fn, pos); call != nil {
+				pos = call.Pos()
+			}
+			fa.blocking(instr, pos, "call to "+desc, s)
+		}
+	}
+
+	// Effects of a function that locks or unlocks on our behalf.
+	callee := common.StaticCallee()
+	if callee == nil {
+		return
+	}
+	var effects []effect
+	if callee.Pkg != nil && callee.Pkg.Pkg == fa.c.pass.Pkg {
+		effects = fa.c.analyze(callee).effects
+	} else if obj, ok := callee.Object().(*types.Func); ok {
+		var fact lockFact
+		if fa.c.pass.ImportObjectFact(obj, &fact) {
+			effects = fact.Effects
+		}
+	}
+	// Apply releases before acquisitions, so that a function that
+	// temporarily releases a lock held by its caller has no effect.
+	for _, acquire := range [...]bool{false, true} {
+		for _, e := range effects {
+			if e.Acquire == acquire {
+				if key, ok := fa.effectKey(common, callee, e); ok {
+					fa.apply(instr, s, key, acquire, deferred)
+				}
+			}
+		}
+	}
+}
+
+// apply applies the acquisition or release of a lock to state s.
+func (fa *funcAnalysis) apply(instr ssa.Instruction, s *state, key lockKey, acquire, deferred bool) {
+	_, held := s.held[key]
+	switch {
+	case deferred && acquire:
+		return // a deferred Lock has no effect on the remainder of the function
+	case deferred:
+		s.deferred[key] = true
+	case acquire:
+		if !held {
+			s.held[key] = instr
+		}
+		return
+	case held:
+		delete(s.held, key)
+		return
+	}
+	if !held && fa.final {
+		fa.released[key] = true
+	}
+}
+
+// blocking reports a potentially blocking operation
+// performed while a lock is held.
+func (fa *funcAnalysis) blocking(instr ssa.Instruction, pos token.Pos, desc string, s *state) {
+	if !fa.final || len(s.held) == 0 || fa.reported[instr] || !pos.IsValid() {
+		return
+	}
+	fa.reported[instr] = true
+	var names []string
+	for k := range s.held {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	verb := "is"
+	if len(names) > 1 {
+		verb = "are"
+	}
+	fa.res.diags = append(fa.res.diags, analysis.Diagnostic{
+		Pos:     pos,
+		Message: fmt.Sprintf("%s while %s %s locked", desc, strings.Join(names, ", "), verb),
+	})
+}
+
+// summarize reports locks held on some paths to a return but not
+// others, and computes the effects of the function on the locks
+// of its caller.
+func (fa *funcAnalysis) summarize() {
+	if len(fa.returns) == 0 {
+		return
+	}
+
+	// Find the locks acquired by this function that are
+	// held on at least one return path, and count them.
+	count := make(map[lockKey]int)
+	acquired := make(map[lockKey]ssa.Instruction)
+	for _, held := range fa.returns {
+		for k, instr := range held {
+			count[k]++
+			if old, ok := acquired[k]; !ok || instr.Pos() < old.Pos() {
+				acquired[k] = instr
+			}
+		}
+	}
+	keys := make([]lockKey, 0, len(acquired))
+	for k := range acquired {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return acquired[keys[i]].Pos() < acquired[keys[j]].Pos() })
+
+	for _, k := range keys {
+		msg := "%s is locked here but not unlocked on all paths"
+		if count[k] == len(fa.returns) {
+			// Held on every path: acquired on behalf of the caller,
+			// if the caller can name the lock.
+			if e, ok := fa.encode(k); ok {
+				e.Acquire = true
+				fa.res.effects = append(fa.res.effects, e)
+				// A lock that was released and reacquired
+				// is returned in the state it was received.
+				if fa.released[k] || !fa.unannouncedAcquire() {
+					continue
+				}
+				msg = "%s is locked here and still locked when exported function " + fa.fn.Name() + " returns"
+			} else {
+				msg = "%s is locked here and still locked when the function returns"
+			}
+		}
+		instr := acquired[k]
+		if _, ok := instr.(*ssa.Defer); ok {
+			continue
+		}
+		pos := instr.Pos()
+		if call := ssautil.CallExpr(fa.fn, pos); call != nil {
+			pos = call.Pos()
+		}
+		if pos.IsValid() {
+			fa.res.diags = append(fa.res.diags, analysis.Diagnostic{
+				Pos:     pos,
+				Message: fmt.Sprintf(msg, k),
+			})
+		}
+	}
+
+	for k := range fa.released {
+		if e, ok := fa.encode(k); ok {
+			fa.res.effects = append(fa.res.effects, e)
+		}
+	}
+	sort.Slice(fa.res.effects, func(i, j int) bool {
+		return fa.res.effects[i].String() < fa.res.effects[j].String()
+	})
+	sort.Slice(fa.res.diags, func(i, j int) bool {
+		return fa.res.diags[i].Pos < fa.res.diags[j].Pos
+	})
+}
+
+// unannouncedAcquire reports whether fa.fn, which returns with a lock
+// held on every path, is an exported function whose callers in other
+// packages have no reason to expect it: its name does not begin with
+// Lock or RLock, and its doc comment does not say that it acquires or
+// locks anything.
+func (fa *funcAnalysis) unannouncedAcquire() bool {
+	obj, ok := fa.fn.Object().(*types.Func)
+	if !ok || !obj.Exported() {
+		return false
+	}
+	if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := t.(*types.Named); ok && !named.Obj().Exported() {
+			return false
+		}
+	}
+	if strings.HasPrefix(obj.Name(), "Lock") || strings.HasPrefix(obj.Name(), "RLock") {
+		return false
+	}
+	if decl, ok := fa.fn.Syntax().(*ast.FuncDecl); ok && decl.Doc != nil {
+		for _, word := range strings.Fields(strings.ToLower(decl.Doc.Text())) {
+			switch strings.Trim(word, ".,;:()") {
+			case "acquires", "locks":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// key returns the key of the lock addressed by v.
+func (fa *funcAnalysis) key(v ssa.Value) (lockKey, bool) {
+	path := ""
+	for {
+		switch x := v.(type) {
+		case *ssa.FieldAddr:
+			path = "." + ssautil.Field(x.X.Type(), x.Field).Name() + path
+			v = x.X
+		case *ssa.UnOp:
+			if x.Op != token.MUL {
+				return lockKey{}, false
+			}
+			path = "*" + path
+			v = x.X
+		case *ssa.MakeInterface:
+			v = x.X
+		case *ssa.ChangeType:
+			v = x.X
+		case *ssa.Global:
+			return lockKey{root: x.Object(), path: path}, true
+		case *ssa.Const:
+			return lockKey{}, false
+		default:
+			return lockKey{root: v, path: path}, true
+		}
+	}
+}
+
+// encode returns the effect that describes k in terms of the
+// parameters, free variables, or package-level variables of the
+// function, if possible.
+func (fa *funcAnalysis) encode(k lockKey) (effect, bool) {
+	e := effect{Path: k.path, Read: k.read}
+	switch root := k.root.(type) {
+	case *ssa.Parameter:
+		for i, param := range fa.fn.Params {
+			if param == root {
+				e.Root = "p" + strconv.Itoa(i)
+				return e, true
+			}
+		}
+	case *ssa.FreeVar:
+		for i, fv := range fa.fn.FreeVars {
+			if fv == root {
+				e.Root = "f" + strconv.Itoa(i)
+				return e, true
+			}
+		}
+	case *types.Var:
+		if root.Pkg() == fa.c.pass.Pkg {
+			e.Root = "g:" + root.Name()
+			return e, true
+		}
+	}
+	return e, false
+}
+
+// effectKey returns the key, in the calling function,
+// of the lock described by an effect of the callee.
+func (fa *funcAnalysis) effectKey(common *ssa.CallCommon, callee *ssa.Function, e effect) (lockKey, bool) {
+	var (
+		key lockKey
+		ok  bool
+	)
+	switch {
+	case strings.HasPrefix(e.Root, "p"):
+		if i, err := strconv.Atoi(e.Root[1:]); err == nil && i < len(common.Args) {
+			key, ok = fa.key(common.Args[i])
+		}
+	case strings.HasPrefix(e.Root, "f"):
+		if mc, isClosure := common.Value.(*ssa.MakeClosure); isClosure {
+			if i, err := strconv.Atoi(e.Root[1:]); err == nil && i < len(mc.Bindings) {
+				key, ok = fa.key(mc.Bindings[i])
+			}
+		}
+	case strings.HasPrefix(e.Root, "g:"):
+		if obj, isFunc := callee.Object().(*types.Func); isFunc && obj.Pkg() != nil {
+			if v, isVar := obj.Pkg().Scope().Lookup(e.Root[len("g:"):]).(*types.Var); isVar {
+				key, ok = lockKey{root: v}, true
+			}
+		}
+	}
+	key.path += e.Path
+	key.read = e.Read
+	return key, ok
+}
+
+func (k lockKey) String() string {
+	var name string
+	switch root := k.root.(type) {
+	case *ssa.Parameter:
+		name = root.Name()
+	case *ssa.FreeVar:
+		name = root.Name()
+	case *ssa.Alloc:
+		name = root.Comment
+	case *types.Var:
+		name = root.Name()
+	default:
+		name = "lock"
+	}
+	return name + strings.ReplaceAll(k.path, "*", "")
+}
+
+// lockOp returns the name of the locking method (Lock, Unlock, RLock,
+// RUnlock) called by common and the address of its lock, or "" if
+// common is not a call of a locking method.
+func lockOp(common *ssa.CallCommon) (string, ssa.Value) {
+	if common.IsInvoke() {
+		if name := common.Method.Name(); name == "Lock" || name == "Unlock" {
+			if analysisutil.IsNamedType(common.Value.Type(), "sync", "Locker") {
+				return name, common.Value
+			}
+		}
+		return "", nil
+	}
+	callee := common.StaticCallee()
+	if callee == nil || callee.Signature.Recv() == nil {
+		return "", nil
+	}
+	ptr, ok := callee.Signature.Recv().Type().(*types.Pointer)
+	if !ok || !analysisutil.IsNamedType(ptr.Elem(), "sync", "Mutex", "RWMutex") {
+		return "", nil
+	}
+	switch name := callee.Name(); name {
+	case "Lock", "Unlock", "RLock", "RUnlock":
+		return name, common.Args[0]
+	}
+	return "", nil
+}
+
+// blockingCall returns a description of the function called by
+// common if it may block indefinitely, or "" otherwise.
+func blockingCall(common *ssa.CallCommon) string {
+	var fn *types.Func
+	if common.IsInvoke() {
+		fn = common.Method
+	} else if callee := common.StaticCallee(); callee != nil {
+		fn, _ = callee.Object().(*types.Func)
+	}
+	if fn == nil || fn.Pkg() == nil {
+		return ""
+	}
+	name := fn.Name()
+	var recv string // name of receiver type, or of interface for abstract methods
+	if sig := fn.Type().(*types.Signature); sig.Recv() != nil {
+		t := sig.Recv().Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := types.Unalias(t).(*types.Named); ok {
+			recv = named.Obj().Name()
+		}
+	}
+	if common.IsInvoke() {
+		if named, ok := types.Unalias(common.Value.Type()).(*types.Named); ok {
+			recv = named.Obj().Name()
+		}
+	}
+	hasPrefix := func(prefixes ...string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+	var blocks bool
+	switch fn.Pkg().Path() {
+	case "time":
+		blocks = recv == "" && name == "Sleep"
+	case "net":
+		switch {
+		case recv == "":
+			blocks = hasPrefix("Dial", "Listen", "Lookup")
+		case strings.HasSuffix(recv, "Conn"):
+			blocks = hasPrefix("Read", "Write")
+		case strings.HasSuffix(recv, "Listener"):
+			blocks = hasPrefix("Accept")
+		case recv == "Dialer":
+			blocks = hasPrefix("Dial")
+		case recv == "Resolver":
+			blocks = hasPrefix("Lookup")
+		}
+	case "net/http":
+		switch recv {
+		case "":
+			blocks = name == "Get" || name == "Head" || name == "Post" || name == "PostForm"
+		case "Client":
+			blocks = name == "Do" || name == "Get" || name == "Head" || name == "Post" || name == "PostForm"
+		case "RoundTripper":
+			blocks = name == "RoundTrip"
+		}
+	}
+	if !blocks {
+		return ""
+	}
+	if recv != "" {
+		return fmt.Sprintf("%s.%s.%s", fn.Pkg().Name(), recv, name)
+	}
+	return fmt.Sprintf("%s.%s", fn.Pkg().Name(), name)
+}
@@ -0,0 +1,211 @@
+package a
+
+import (
+	"b"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type T struct {
+	mu    sync.Mutex
+	cache map[string]int
+	ch    chan int
+}
+
+func (t *T) earlyReturn(key string) int {
+	t.mu.Lock() // want `t.mu is locked here but not unlocked on all paths`
+	if v, ok := t.cache[key]; ok {
+		return v
+	}
+	v := len(key)
+	t.cache[key] = v
+	t.mu.Unlock()
+	return v
+}
+
+func (t *T) deferred(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if v, ok := t.cache[key]; ok {
+		return v
+	}
+	return 0
+}
+
+func (t *T) deferredClosure(key string) int {
+	t.mu.Lock()
+	defer func() {
+		t.mu.Unlock()
+	}()
+	if v, ok := t.cache[key]; ok {
+		return v
+	}
+	return 0
+}
+
+func (t *T) balanced(key string) int {
+	t.mu.Lock()
+	if v, ok := t.cache[key]; ok {
+		t.mu.Unlock()
+		return v
+	}
+	t.mu.Unlock()
+	return 0
+}
+
+func (t *T) exit(key string) int {
+	t.mu.Lock()
+	if _, ok := t.cache[key]; !ok {
+		os.Exit(1)
+	}
+	t.mu.Unlock()
+	return 0
+}
+
+func (t *T) panics(key string) int {
+	t.mu.Lock()
+	if _, ok := t.cache[key]; !ok {
+		panic(key)
+	}
+	t.mu.Unlock()
+	return 0
+}
+
+func (t *T) blocking(conn net.Conn, buf []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ch <- 1                     // want `channel send while t.mu is locked`
+	<-t.ch                        // want `channel receive while t.mu is locked`
+	time.Sleep(time.Second)       // want `call to time.Sleep while t.mu is locked`
+	conn.Read(buf)                // want `call to net.Conn.Read while t.mu is locked`
+	http.Get("http://golang.org") // want `call to http.Get while t.mu is locked`
+	select {                      // want `select statement while t.mu is locked`
+	case <-t.ch:
+	case t.ch <- 2:
+	}
+	select {
+	case v := <-t.ch:
+		_ = v
+	default:
+	}
+}
+
+func (t *T) notBlocking() {
+	t.mu.Lock()
+	t.cache["x"] = 1
+	t.mu.Unlock()
+	t.ch <- 1
+	time.Sleep(time.Second)
+}
+
+func local() {
+	var mu sync.Mutex
+	ch := make(chan int)
+	mu.Lock()
+	ch <- 1 // want `channel send while mu is locked`
+	mu.Unlock()
+}
+
+func localHeld(ch chan int) {
+	var mu sync.Mutex
+	mu.Lock() // want `mu is locked here and still locked when the function returns`
+	ch <- 1   // want `channel send while mu is locked`
+}
+
+func twoLocks(a, b *sync.Mutex, ch chan int) {
+	a.Lock()
+	b.Lock()
+	ch <- 1 // want `channel send while a, b are locked`
+	b.Unlock()
+	a.Unlock()
+}
+
+func locker(l sync.Locker, ch chan int) {
+	l.Lock()
+	ch <- 1 // want `channel send while l is locked`
+	l.Unlock()
+}
+
+func rlock(mu *sync.RWMutex, ch chan int, ok bool) {
+	mu.RLock() // want `mu is locked here but not unlocked on all paths`
+	if ok {
+		return
+	}
+	<-ch // want `channel receive while mu is locked`
+	mu.RUnlock()
+}
+
+// Helpers in this package.
+
+func (t *T) lock() { // want lock:"Lock\\(p0.mu\\)"
+	t.mu.Lock()
+}
+
+func (t *T) unlock() { // want unlock:"Unlock\\(p0.mu\\)"
+	t.mu.Unlock()
+}
+
+func (t *T) viaHelpers(key string) int {
+	t.lock() // want `t.mu is locked here but not unlocked on all paths`
+	if v, ok := t.cache[key]; ok {
+		return v
+	}
+	<-t.ch // want `channel receive while t.mu is locked`
+	t.unlock()
+	return 0
+}
+
+func (t *T) viaDeferredHelper() {
+	t.lock()
+	defer t.unlock()
+	t.ch <- 1 // want `channel send while t.mu is locked`
+}
+
+// Helpers in another package.
+
+func imported(c *b.Cache, ch chan int, ok bool) {
+	c.Lock() // want `c.mu is locked here but not unlocked on all paths`
+	if ok {
+		return
+	}
+	ch <- 1 // want `channel send while c.mu is locked`
+	c.Unlock()
+}
+
+func importedGlobal(ch chan int) {
+	b.LockGlobal()
+	defer b.UnlockGlobal()
+	ch <- 1 // want `channel send while global is locked`
+}
+
+func unlocked(mu *sync.Mutex, ch chan int) {
+	mu.Lock()
+	b.Unlocked(mu, func() {
+		ch <- 1
+	})
+	ch <- 2 // want `channel send while mu is locked`
+	mu.Unlock()
+}
+
+// Function literals.
+
+func closure(ch chan int) {
+	var mu sync.Mutex
+	lock := func() {
+		mu.Lock()
+	}
+	lock()
+	ch <- 1 // want `channel send while mu is locked`
+	mu.Unlock()
+}
+
+func goroutine(mu *sync.Mutex, ch chan int) {
+	mu.Lock()
+	go func() {
+		ch <- 1
+	}()
+	mu.Unlock()
+}
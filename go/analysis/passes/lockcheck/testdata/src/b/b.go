@@ -0,0 +1,59 @@
+package b
+
+import "sync"
+
+type Cache struct {
+	mu   sync.RWMutex
+	data map[string]string
+}
+
+func (c *Cache) Lock() { // want Lock:"Lock\\(p0.mu\\)"
+	c.mu.Lock()
+}
+
+func (c *Cache) Unlock() { // want Unlock:"Unlock\\(p0.mu\\)"
+	c.mu.Unlock()
+}
+
+func (c *Cache) RLock() { // want RLock:"RLock\\(p0.mu\\)"
+	c.mu.RLock()
+}
+
+func (c *Cache) RUnlock() { // want RUnlock:"RUnlock\\(p0.mu\\)"
+	c.mu.RUnlock()
+}
+
+var global sync.Mutex
+
+func LockGlobal() { // want LockGlobal:"Lock\\(g:global\\)"
+	global.Lock()
+}
+
+func UnlockGlobal() { // want UnlockGlobal:"Unlock\\(g:global\\)"
+	global.Unlock()
+}
+
+// Exported functions that return with a lock held must say so.
+
+func (c *Cache) Begin() { // want Begin:"Lock\\(p0.mu\\)"
+	c.mu.Lock() // want `c.mu is locked here and still locked when exported function Begin returns`
+}
+
+// Acquire acquires c.mu on behalf of its caller.
+func (c *Cache) Acquire() { // want Acquire:"Lock\\(p0.mu\\)"
+	c.mu.Lock()
+}
+
+// Balanced functions have no effect on their callers.
+func (c *Cache) Get(key string) string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.data[key]
+}
+
+// Unlocked releases the lock while f runs.
+func Unlocked(mu *sync.Mutex, f func()) { // want Unlocked:"Lock\\(p0\\) Unlock\\(p0\\)"
+	mu.Unlock()
+	f()
+	mu.Lock()
+}
@@ -0,0 +1,42 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lostcontext defines an Analyzer that checks for failure to
+// propagate an available context.Context.
+//
+// # Analyzer lostcontext
+//
+// lostcontext: check that an available context is propagated
+//
+// A function that receives a context.Context parameter, or an
+// *http.Request whose Context method provides one, should pass that
+// context on to the operations it performs, so that their deadlines
+// and cancellation follow those of the caller. This analyzer reports
+// two ways in which the context is lost.
+//
+// The first is a call to context.Background or context.TODO where a
+// context is available, for example:
+//
+//	func handle(ctx context.Context, id string) error {
+//		return store.Delete(context.Background(), id) // should use ctx
+//	}
+//
+// The second is a call to a function or method F when a variant of F,
+// named FContext or FWithContext, accepts a context.Context as its
+// first parameter followed by the parameters of F, for example:
+//
+//	func lookup(ctx context.Context, db *sql.DB, q string) (*sql.Rows, error) {
+//		return db.Query(q) // should use db.QueryContext(ctx, q)
+//	}
+//
+// In both cases the analyzer offers a fix that uses the available
+// context instead.
+//
+// The context is available within the function that declares the
+// parameter and within function literals nested in it, except those
+// started by a go statement, since a goroutine may outlive the
+// request that the context describes. An assignment such as
+// ctx = context.Background(), which typically replaces a nil context,
+// is not reported.
+package lostcontext
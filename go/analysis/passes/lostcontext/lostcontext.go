@@ -0,0 +1,243 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lostcontext
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "lostcontext",
+	Doc:      analysisutil.MustExtractDoc(doc, "lostcontext"),
+	URL:      "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/lostcontext",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	// Fast path: a context parameter requires one of these packages.
+	if pass.Pkg.Path() == "context" ||
+		!analysisutil.Imports(pass.Pkg, "context") && !analysisutil.Imports(pass.Pkg, "net/http") {
+		return nil, nil
+	}
+
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.CallExpr)(nil),
+	}
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		fn, _ := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if fn == nil {
+			return true
+		}
+		file := stack[0].(*ast.File)
+		if analysisutil.IsFunctionNamed(fn, "context", "Background", "TODO") {
+			ctx := enclosingContext(pass.TypesInfo, file, stack, call.Pos())
+			if ctx != "" && !assignsContext(stack, call, ctx) {
+				edits := []analysis.TextEdit{{
+					Pos:     call.Pos(),
+					End:     call.End(),
+					NewText: []byte(ctx),
+				}}
+				// The call may be the file's only use of package context.
+				if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+					edits = append(edits, analysisinternal.DeleteUnusedImport(pass.TypesInfo, file, sel)...)
+				}
+				pass.Report(analysis.Diagnostic{
+					Pos:     call.Pos(),
+					End:     call.End(),
+					Message: fmt.Sprintf("call to context.%s discards the available context; use %s", fn.Name(), ctx),
+					SuggestedFixes: []analysis.SuggestedFix{{
+						Message:   fmt.Sprintf("Use %s", ctx),
+						TextEdits: edits,
+					}},
+				})
+			}
+			return true
+		}
+		if variant := contextVariant(pass.TypesInfo, call, fn); variant != nil {
+			ctx := enclosingContext(pass.TypesInfo, file, stack, call.Pos())
+			if ctx != "" {
+				reportVariant(pass, call, fn, variant, ctx)
+			}
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// reportVariant reports a call to fn that could instead call its
+// context-accepting variant, passing ctx.
+func reportVariant(pass *analysis.Pass, call *ast.CallExpr, fn, variant *types.Func, ctx string) {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	}
+	diag := analysis.Diagnostic{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		Message: fmt.Sprintf("%s does not use the available context; use %s(%s, ...)", fn.Name(), variant.Name(), ctx),
+	}
+	if id != nil {
+		// Insert the context as the first argument.
+		pos, text := call.Rparen, ctx
+		if len(call.Args) > 0 {
+			pos, text = call.Args[0].Pos(), ctx+", "
+		}
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: fmt.Sprintf("Use %s", variant.Name()),
+			TextEdits: []analysis.TextEdit{
+				{Pos: id.Pos(), End: id.End(), NewText: []byte(variant.Name())},
+				{Pos: pos, End: pos, NewText: []byte(text)},
+			},
+		}}
+	}
+	pass.Report(diag)
+}
+
+// enclosingContext returns the expression, such as "ctx" or
+// "req.Context()", that denotes the context available at pos in the
+// innermost function on the stack, or "" if none is available.
+func enclosingContext(info *types.Info, file *ast.File, stack []ast.Node, pos token.Pos) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		var ftype *ast.FuncType
+		switch n := stack[i].(type) {
+		case *ast.FuncDecl:
+			ftype = n.Type
+		case *ast.FuncLit:
+			ftype = n.Type
+		default:
+			continue
+		}
+		if ctx := paramContext(info, file, ftype, pos); ctx != "" {
+			return ctx
+		}
+		// A goroutine may outlive the enclosing function's context.
+		if i >= 2 {
+			if call, ok := stack[i-1].(*ast.CallExpr); ok && call.Fun == stack[i] {
+				if _, ok := stack[i-2].(*ast.GoStmt); ok {
+					return ""
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// paramContext returns the expression that denotes the context
+// provided by a parameter of the function type, or "" if there is
+// none. A context.Context parameter is preferred over an
+// *http.Request. Parameters shadowed at pos are ignored.
+func paramContext(info *types.Info, file *ast.File, ftype *ast.FuncType, pos token.Pos) string {
+	scope := info.Scopes[file].Innermost(pos)
+	if scope == nil {
+		return ""
+	}
+	var req string
+	for _, field := range ftype.Params.List {
+		for _, name := range field.Names {
+			v, ok := info.Defs[name].(*types.Var)
+			if !ok || name.Name == "_" {
+				continue
+			}
+			if _, obj := scope.LookupParent(name.Name, pos); obj != v {
+				continue // shadowed
+			}
+			if isContext(v.Type()) {
+				return name.Name
+			}
+			if ptr, ok := v.Type().(*types.Pointer); ok && req == "" &&
+				analysisutil.IsNamedType(ptr.Elem(), "net/http", "Request") {
+				req = name.Name + ".Context()"
+			}
+		}
+	}
+	return req
+}
+
+// assignsContext reports whether call is the operand of an
+// assignment to the variable ctx, as in ctx = context.Background().
+func assignsContext(stack []ast.Node, call *ast.CallExpr, ctx string) bool {
+	if assign, ok := stack[len(stack)-2].(*ast.AssignStmt); ok && len(assign.Lhs) == len(assign.Rhs) {
+		for i, rhs := range assign.Rhs {
+			if id, ok := assign.Lhs[i].(*ast.Ident); ok && rhs == call && id.Name == ctx {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contextVariant returns the variant of the function or method fn
+// called by call that accepts a context.Context followed by the
+// parameters of fn, such as QueryContext for Query, or nil if there
+// is none.
+func contextVariant(info *types.Info, call *ast.CallExpr, fn *types.Func) *types.Func {
+	sig := fn.Type().(*types.Signature)
+	if fn.Pkg() == nil || sig.TypeParams() != nil {
+		return nil
+	}
+	for _, suffix := range [...]string{"Context", "WithContext"} {
+		name := fn.Name() + suffix
+		var obj types.Object
+		if sig.Recv() != nil {
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return nil
+			}
+			if selection, ok := info.Selections[sel]; ok {
+				obj, _, _ = types.LookupFieldOrMethod(selection.Recv(), true, fn.Pkg(), name)
+			}
+		} else {
+			obj = fn.Pkg().Scope().Lookup(name)
+		}
+		if variant, ok := obj.(*types.Func); ok && isVariant(sig, variant.Type().(*types.Signature)) {
+			return variant
+		}
+	}
+	return nil
+}
+
+// isVariant reports whether vsig is the signature sig with a
+// leading context.Context parameter.
+func isVariant(sig, vsig *types.Signature) bool {
+	params, vparams := sig.Params(), vsig.Params()
+	if vparams.Len() != params.Len()+1 ||
+		vsig.Variadic() != sig.Variadic() ||
+		!isContext(vparams.At(0).Type()) {
+		return false
+	}
+	for i := 0; i < params.Len(); i++ {
+		if !types.Identical(params.At(i).Type(), vparams.At(i+1).Type()) {
+			return false
+		}
+	}
+	return types.Identical(sig.Results(), vsig.Results())
+}
+
+func isContext(t types.Type) bool {
+	return analysisutil.IsNamedType(t, "context", "Context")
+}
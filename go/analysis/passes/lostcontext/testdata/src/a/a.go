@@ -0,0 +1,70 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os/exec"
+)
+
+func use(context.Context) {}
+
+func background(ctx context.Context) {
+	use(context.Background()) // want `call to context.Background discards the available context; use ctx`
+	use(context.TODO())       // want `call to context.TODO discards the available context; use ctx`
+	func() {
+		use(context.Background()) // want `use ctx`
+	}()
+	go func() {
+		use(context.Background()) // ok: goroutine may outlive ctx
+	}()
+	{
+		ctx := 1
+		_ = ctx
+		use(context.Background()) // ok: ctx is shadowed
+	}
+}
+
+func request(w http.ResponseWriter, req *http.Request) {
+	use(context.Background()) // want `use req.Context\(\)`
+}
+
+func both(req *http.Request, ctx context.Context) {
+	use(context.Background()) // want `use ctx`
+}
+
+func nilContext(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background() // ok: replaces nil context
+	}
+	use(ctx)
+}
+
+func none(_ context.Context) {
+	use(context.Background()) // ok: no named context
+}
+
+func variants(ctx context.Context, db *sql.DB) {
+	db.Query("SELECT 1", 2)         // want `Query does not use the available context; use QueryContext\(ctx, ...\)`
+	db.Ping()                       // want `use PingContext\(ctx, ...\)`
+	exec.Command("ls")              // want `use CommandContext\(ctx, ...\)`
+	http.NewRequest("GET", "", nil) // want `use NewRequestWithContext\(ctx, ...\)`
+	db.QueryContext(ctx, "SELECT 1")
+	db.Close() // ok: no variant
+	find("x")  // want `use findContext\(ctx, ...\)`
+	lookup(1)  // ok: variant has a different signature
+}
+
+func noContext(db *sql.DB) {
+	db.Query("SELECT 1") // ok: no context available
+	_ = context.Background()
+}
+
+func find(string)                          {}
+func findContext(context.Context, string)  {}
+func lookup(int)                           {}
+func lookupContext(context.Context, int64) {}
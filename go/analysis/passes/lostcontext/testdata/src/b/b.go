@@ -0,0 +1,15 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+import (
+	"context"
+	"net/http"
+)
+
+// The fix removes the import of context, which would be unused.
+func handle(req *http.Request) *http.Request {
+	return req.WithContext(context.TODO()) // want `use req.Context\(\)`
+}
@@ -0,0 +1,40 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sqlinjection defines an Analyzer that reports SQL queries
+// built from untrusted input.
+//
+// # Analyzer sqlinjection
+//
+// sqlinjection: check for SQL queries built from untrusted input
+//
+// The sqlinjection analyzer reports calls to database/sql methods
+// such as Query and Exec whose query string is derived, typically by
+// string concatenation or formatting, from untrusted input:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		name := r.FormValue("name")
+//		rows, err := db.Query("SELECT * FROM users WHERE name = '" + name + "'")
+//		...
+//	}
+//
+// An attacker who controls the input may change the meaning of the
+// query. Pass untrusted values as arguments for placeholders instead:
+//
+//	rows, err := db.Query("SELECT * FROM users WHERE name = ?", name)
+//
+// Untrusted input is any data obtained from the fields and methods of
+// an *http.Request. The analysis follows the flow of data through
+// assignments, string operations, and calls, including calls to
+// helper functions in other packages. Values of numeric and boolean
+// types, such as the results of strconv.Atoi, are considered safe.
+//
+// The -sources, -sinks, and -sanitizers flags each replace the
+// corresponding comma-separated list of functions, using the naming
+// scheme of types.Func.FullName, for example
+// "(*database/sql.DB).Query:query". A struct field used as a source
+// is named by its package path, type, and field, for example
+// "net/http.Request.URL". A sink "name:param" applies only to the
+// named parameter of the function.
+package sqlinjection
@@ -0,0 +1,59 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqlinjection
+
+import (
+	_ "embed"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/analysis/passes/internal/analysisutil"
+	"golang.org/x/tools/go/analysis/passes/internal/taint"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "sqlinjection",
+	Doc:       analysisutil.MustExtractDoc(doc, "sqlinjection"),
+	URL:       "https://pkg.go.dev/golang.org/x/tools/go/analysis/passes/sqlinjection",
+	Requires:  []*analysis.Analyzer{buildssa.Analyzer},
+	FactTypes: []analysis.Fact{new(taintFact)},
+	Run:       run,
+}
+
+// A taintFact summarizes the taint flows of a function.
+type taintFact struct{ taint.Summary }
+
+func (*taintFact) AFact() {}
+
+var config = taint.Config{
+	Sources:    taint.HTTPRequestSources(),
+	Sinks:      make(taint.Set),
+	Sanitizers: make(taint.Set),
+	Message:    "query built from untrusted input is passed to %s",
+	NewFact:    func() taint.Fact { return new(taintFact) },
+}
+
+func init() {
+	for _, typ := range []string{"DB", "Tx", "Conn"} {
+		for _, method := range []string{
+			"Exec", "ExecContext",
+			"Prepare", "PrepareContext",
+			"Query", "QueryContext",
+			"QueryRow", "QueryRowContext",
+		} {
+			config.Sinks["(*database/sql."+typ+")."+method+":query"] = true
+		}
+	}
+	Analyzer.Flags.Var(&config.Sources, "sources", "comma-separated list of functions and fields that are sources of untrusted input")
+	Analyzer.Flags.Var(&config.Sinks, "sinks", "comma-separated list of functions whose query parameters must not receive untrusted input")
+	Analyzer.Flags.Var(&config.Sanitizers, "sanitizers", "comma-separated list of functions whose results are trusted")
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	return nil, taint.Run(pass, &config)
+}
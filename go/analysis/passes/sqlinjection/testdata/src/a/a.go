@@ -0,0 +1,65 @@
+package a
+
+import (
+	"b"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var db *sql.DB
+
+func concat(w http.ResponseWriter, r *http.Request) { // want concat:`p1->\(\*database/sql.DB\).Query`
+	name := r.FormValue("name")
+	db.Query("SELECT * FROM users WHERE name = '" + name + "'") // want `query built from untrusted input is passed to \(\*database/sql.DB\).Query`
+}
+
+func format(w http.ResponseWriter, r *http.Request) { // want format:`p1->\(\*database/sql.DB\).ExecContext`
+	q := fmt.Sprintf("DELETE FROM users WHERE id = %s", r.URL.Query().Get("id"))
+	db.ExecContext(context.Background(), q) // want `query built from untrusted input is passed to \(\*database/sql.DB\).ExecContext`
+}
+
+func builder(tx *sql.Tx, r *http.Request) { // want builder:`p1->\(\*database/sql.Tx\).QueryRow`
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM t WHERE x = ")
+	sb.WriteString(r.Header.Get("X-Id"))
+	tx.QueryRow(sb.String()) // want `query built from untrusted input is passed to \(\*database/sql.Tx\).QueryRow`
+}
+
+func placeholder(w http.ResponseWriter, r *http.Request) {
+	db.Query("SELECT * FROM users WHERE name = ?", r.FormValue("name"))
+}
+
+func number(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.FormValue("id"))
+	db.Query("SELECT * FROM users WHERE id = " + strconv.Itoa(id))
+}
+
+func constant() {
+	table := "users"
+	db.Query("SELECT * FROM " + table)
+}
+
+func helpers(w http.ResponseWriter, r *http.Request) { // want helpers:`p1->\(\*database/sql.DB\).Query`
+	b.Lookup(db, r.FormValue("name")) // want `query built from untrusted input is passed to \(\*database/sql.DB\).Query \(via call to Lookup\)`
+	b.SafeLookup(db, r.FormValue("name"))
+	db.Query("SELECT * FROM users WHERE name = '" + b.Name(r) + "'") // want `query built from untrusted input`
+}
+
+func local(r *http.Request) { // want local:`p0->\(\*database/sql.DB\).Query`
+	byName(r.PostFormValue("name")) // want `query built from untrusted input is passed to \(\*database/sql.DB\).Query \(via call to byName\)`
+}
+
+func byName(name string) { // want byName:`p0->\(\*database/sql.DB\).Query`
+	db.Query("SELECT * FROM users WHERE name = '" + name + "'")
+}
+
+func closure(r *http.Request) { // want closure:`p0->\(\*database/sql.DB\).Query`
+	name := r.FormValue("name")
+	func() { // want `query built from untrusted input is passed to \(\*database/sql.DB\).Query \(via call to function literal\)`
+		db.Query("SELECT * FROM users WHERE name = '" + name + "'")
+	}()
+}
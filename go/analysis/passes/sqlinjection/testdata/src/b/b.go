@@ -0,0 +1,22 @@
+package b
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+)
+
+// Name returns the untrusted name parameter of a request.
+func Name(r *http.Request) string { // want Name:"source"
+	return strings.TrimSpace(r.URL.Query().Get("name"))
+}
+
+// Lookup queries the users table for the named user.
+func Lookup(db *sql.DB, name string) (*sql.Rows, error) { // want Lookup:`p1->\(\*database/sql.DB\).Query`
+	return db.Query("SELECT id FROM users WHERE name = '" + name + "'")
+}
+
+// SafeLookup queries the users table using a placeholder.
+func SafeLookup(db *sql.DB, name string) (*sql.Rows, error) {
+	return db.Query("SELECT id FROM users WHERE name = ?", name)
+}
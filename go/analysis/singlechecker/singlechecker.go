@@ -62,7 +62,7 @@ func Main(a *analysis.Analyzer) {
 	analyzers = analysisflags.Parse(analyzers, false)
 
 	args := flag.Args()
-	if len(args) == 0 {
+	if len(args) == 0 && checker.Workspace == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
@@ -366,6 +366,12 @@ func run(fset *token.FileSet, cfg *Config, analyzers []*analysis.Analyzer) ([]re
 				GoVersion: cfg.GoVersion,
 			}
 
+			// Diagnostics in files excluded by the -config file are discarded.
+			report := func(d analysis.Diagnostic) {
+				if !analysisflags.Excluded(fset, a, d.Pos) {
+					act.diagnostics = append(act.diagnostics, d)
+				}
+			}
 			pass := &analysis.Pass{
 				Analyzer:          a,
 				Fset:              fset,
@@ -377,7 +383,7 @@ func run(fset *token.FileSet, cfg *Config, analyzers []*analysis.Analyzer) ([]re
 				TypesSizes:        tc.Sizes,
 				TypeErrors:        nil, // unitchecker doesn't RunDespiteErrors
 				ResultOf:          inputs,
-				Report:            func(d analysis.Diagnostic) { act.diagnostics = append(act.diagnostics, d) },
+				Report:            report,
 				ImportObjectFact:  facts.ImportObjectFact,
 				ExportObjectFact:  facts.ExportObjectFact,
 				AllObjectFacts:    func() []analysis.ObjectFact { return facts.AllObjectFacts(factFilter) },
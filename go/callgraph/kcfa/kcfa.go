@@ -0,0 +1,823 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kcfa computes the call graph of a Go program using a
+// context-sensitive inclusion-based analysis of the flow of function
+// values and dynamic types.
+//
+// The algorithms in the sibling packages static, cha, rta and vta are
+// context-insensitive: every call to a function merges the values
+// flowing into and out of it with those of all other calls. When a
+// program passes different function values or interface values
+// through a common helper, such as an identity function, a
+// constructor, or a method of an adapter type, their callees appear
+// to flow to every caller of the helper. This package analyzes each
+// function separately in each of its calling contexts, up to a
+// configurable bound, so that values flowing through distinct
+// contexts are not merged.
+//
+// Two kinds of context are supported (see [Sensitivity]):
+//
+//   - With call-site sensitivity, or k-CFA (Shivers, 1991. Control-flow
+//     analysis of higher-order languages), a context is the sequence of
+//     the k most recent call sites on the call stack.
+//
+//   - With object sensitivity, or k-obj (Milanova, Rountev and Ryder,
+//     2005. Parameterized object sensitivity for points-to analysis
+//     for Java), the context of a dynamic call is the object that
+//     was called: the site at which its concrete value was converted
+//     to an interface, or at which its closure was created, preceded
+//     by the context in which that occurred. Static calls are analyzed
+//     in the context of their caller.
+//
+// The analysis tracks two kinds of abstract object: function values
+// (functions, closures and bound methods), and concrete types held
+// by interfaces. Each SSA register of interface, function, struct or
+// array type is represented, in each context, by a node holding the
+// set of objects it may contain; inclusion constraints between nodes
+// are generated from the instructions of each reachable function and
+// solved to a fixed point, adding call edges and analyzing callees in
+// new contexts as new objects reach the operands of dynamic calls.
+//
+// Memory is abstracted by type, as in VTA: all values of a given
+// interface or function type stored in variables, fields, elements,
+// maps, or channels are represented by a single context-insensitive
+// node. So the analysis distinguishes calling contexts, not heap
+// locations.
+//
+// The analysis is whole-program: it starts from a set of root
+// functions, typically the main and init functions of the program,
+// and analyzes only functions reachable from them. Like rta, it does
+// not account for calls made by reflection or by assembly code, nor
+// for functions called only by them. Unsafe conversions are treated
+// like ordinary conversions. Function bodies must be built with the
+// [ssa.InstantiateGenerics] mode flag, so that calls to generic
+// functions are calls to their instantiations.
+//
+// The cost of the analysis is bounded by [Config.MaxContexts]: once a
+// function has been analyzed in that many contexts, calls to it in
+// further contexts are analyzed in the empty context, which merges
+// them as a context-insensitive analysis would. The empty context is
+// not subject to the bound, so a function may be analyzed in one more
+// context than MaxContexts.
+package kcfa // import "golang.org/x/tools/go/callgraph/kcfa"
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/container/intsets"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Sensitivity specifies the kind of context that distinguishes the
+// analyses of a function.
+type Sensitivity int
+
+const (
+	CallSite Sensitivity = iota // k-CFA: contexts are sequences of call sites
+	Object                      // k-obj: contexts are sequences of allocation sites
+)
+
+func (s Sensitivity) String() string {
+	switch s {
+	case CallSite:
+		return "CallSite"
+	case Object:
+		return "Object"
+	}
+	return fmt.Sprintf("Sensitivity(%d)", int(s))
+}
+
+// DefaultMaxContexts is the number of contexts in which a function is
+// analyzed if Config.MaxContexts is zero.
+const DefaultMaxContexts = 64
+
+// A Config specifies the parameters of the analysis.
+type Config struct {
+	// K is the maximum length of a context.
+	// With K=0 the analysis is context-insensitive.
+	K int
+
+	Sensitivity Sensitivity
+
+	// MaxContexts is the maximum number of contexts in which each
+	// function is analyzed before further calls to it fall back to
+	// the empty context, which is not counted: a function may thus
+	// be analyzed in up to MaxContexts+1 contexts. If zero,
+	// DefaultMaxContexts is used.
+	MaxContexts int
+}
+
+// A Result holds the results of the analysis.
+type Result struct {
+	// CallGraph is the discovered call graph. Its Root is the
+	// first root function. It does not include edges for calls
+	// made via reflection.
+	CallGraph *callgraph.Graph
+
+	// Contexts maps each reachable function to the number of
+	// distinct contexts in which it was analyzed.
+	Contexts map[*ssa.Function]int
+}
+
+// Analyze computes a context-sensitive call graph of the program
+// containing the specified root functions, which must be one or more
+// entrypoints (main and init functions) of a complete SSA program,
+// with function bodies for all dependencies. It returns nil if no
+// roots were specified.
+func Analyze(roots []*ssa.Function, config *Config) *Result {
+	if len(roots) == 0 {
+		return nil
+	}
+	maxContexts := config.MaxContexts
+	if maxContexts <= 0 {
+		maxContexts = DefaultMaxContexts
+	}
+	a := &analysis{
+		config:      *config,
+		maxContexts: maxContexts,
+		prog:        roots[0].Prog,
+		result: &Result{
+			CallGraph: callgraph.New(roots[0]),
+			Contexts:  make(map[*ssa.Function]int),
+		},
+		contexts:  make(map[contextKey]*context),
+		objects:   make(map[object]int),
+		locals:    make(map[localKey]nodeid),
+		results:   make(map[resultKey]nodeid),
+		funcNodes: make(map[*ssa.Function]nodeid),
+		reached:   make(map[resultKey]bool),
+		cgEdges:   make(map[cgEdge]bool),
+		panicNode: -1,
+	}
+	hasher := typeutil.MakeHasher()
+	a.canon.SetHasher(hasher)
+	a.heap.SetHasher(hasher)
+	a.leafCache.SetHasher(hasher)
+
+	for _, root := range roots {
+		a.result.CallGraph.CreateNode(root)
+		a.reach(root, nil)
+	}
+	a.solve()
+	return a.result
+}
+
+// -- contexts --
+
+// A context is an interned sequence of sites, most recent first.
+// The empty context is represented by nil.
+type context struct {
+	site   ssa.Instruction // a CallInstruction, MakeInterface, or MakeClosure
+	parent *context
+	depth  int
+}
+
+type contextKey struct {
+	site   ssa.Instruction
+	parent *context
+}
+
+func (c *context) String() string {
+	var sites []string
+	for ; c != nil; c = c.parent {
+		fn := c.site.Parent()
+		sites = append(sites, fmt.Sprintf("%s@%s", fn, fn.Prog.Fset.Position(c.site.Pos())))
+	}
+	return "[" + strings.Join(sites, " ") + "]"
+}
+
+// push returns the context consisting of site followed by the first
+// k-1 sites of ctx.
+func (a *analysis) push(site ssa.Instruction, ctx *context, k int) *context {
+	if k <= 0 {
+		return nil
+	}
+	sites := []ssa.Instruction{site}
+	for c := ctx; c != nil && len(sites) < k; c = c.parent {
+		sites = append(sites, c.site)
+	}
+	var result *context
+	for i := len(sites) - 1; i >= 0; i-- {
+		key := contextKey{sites[i], result}
+		c, ok := a.contexts[key]
+		if !ok {
+			depth := 1
+			if result != nil {
+				depth = result.depth + 1
+			}
+			c = &context{site: sites[i], parent: result, depth: depth}
+			a.contexts[key] = c
+		}
+		result = c
+	}
+	return result
+}
+
+// truncate returns the first k sites of ctx.
+func (a *analysis) truncate(ctx *context, k int) *context {
+	if ctx == nil || ctx.depth <= k {
+		return ctx
+	}
+	if k <= 0 {
+		return nil
+	}
+	return a.push(ctx.site, ctx.parent, k)
+}
+
+// -- objects --
+
+// An object is an abstract value that may be held by a register:
+// either a function value or a concrete type held by an interface.
+type object struct {
+	fn   *ssa.Function   // the function, for a function value
+	typ  types.Type      // the dynamic type (canonical), for a value in an interface
+	site ssa.Instruction // the MakeClosure or MakeInterface instruction, if any
+	ctx  *context        // the heap context in which site was executed
+}
+
+func (o *object) String() string {
+	var s string
+	if o.fn != nil {
+		s = o.fn.String()
+	} else {
+		s = o.typ.String()
+	}
+	if o.site != nil {
+		s += fmt.Sprintf("@%s%s", o.site.Parent().Prog.Fset.Position(o.site.Pos()), o.ctx)
+	}
+	return s
+}
+
+// objectID returns the index of the interned object o.
+func (a *analysis) objectID(o object) int {
+	id, ok := a.objects[o]
+	if !ok {
+		id = len(a.objectList)
+		a.objects[o] = id
+		a.objectList = append(a.objectList, &object{o.fn, o.typ, o.site, o.ctx})
+	}
+	return id
+}
+
+// heapContext returns the context of objects allocated in ctx.
+func (a *analysis) heapContext(ctx *context) *context {
+	if a.config.Sensitivity == Object {
+		return a.truncate(ctx, a.config.K-1)
+	}
+	return nil
+}
+
+// -- nodes --
+
+type nodeid int
+
+// A node represents the set of objects that a register (in a given
+// context) or a class of memory locations may hold.
+type node struct {
+	typ      types.Type // objects are admitted only if compatible with typ
+	pts      intsets.Sparse
+	delta    intsets.Sparse // objects not yet propagated
+	succs    []nodeid
+	succSet  map[nodeid]bool
+	watchers []func(obj int) // called for each new object
+}
+
+type localKey struct {
+	v     ssa.Value
+	ctx   *context
+	index int // component of a tuple
+}
+
+type resultKey struct {
+	fn  *ssa.Function
+	ctx *context
+	i   int
+}
+
+type cgEdge struct {
+	caller *ssa.Function
+	site   ssa.CallInstruction
+	callee *ssa.Function
+}
+
+// Working state of the analysis.
+type analysis struct {
+	config      Config
+	maxContexts int
+	prog        *ssa.Program
+	result      *Result
+
+	contexts   map[contextKey]*context
+	objects    map[object]int
+	objectList []*object
+
+	nodes     []*node
+	locals    map[localKey]nodeid
+	results   map[resultKey]nodeid
+	funcNodes map[*ssa.Function]nodeid // constant nodes for functions used as values
+	heap      typeutil.Map             // maps each leaf type to its memory node
+	panicNode nodeid
+
+	canon     typeutil.Map // canonical types
+	leafCache typeutil.Map // maps each type to its []types.Type leaves
+
+	reached  map[resultKey]bool // (fn, ctx) pairs already analyzed; i is unused
+	cgEdges  map[cgEdge]bool
+	worklist []nodeid
+}
+
+// canonical returns the canonical type identical to t.
+func (a *analysis) canonical(t types.Type) types.Type {
+	if c := a.canon.At(t); c != nil {
+		return c.(types.Type)
+	}
+	a.canon.Set(t, t)
+	return t
+}
+
+// leaves returns the interface and function types of the values held
+// by a value of type t, other than those reachable through pointers,
+// slices, maps, and channels, which are held in memory. The result is
+// empty if values of type t cannot hold objects.
+func (a *analysis) leaves(t types.Type) []types.Type {
+	if l := a.leafCache.At(t); l != nil {
+		return l.([]types.Type)
+	}
+	var leaves []types.Type
+	seen := make(map[types.Type]bool)
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		switch u := t.Underlying().(type) {
+		case *types.Interface, *types.Signature:
+			t = a.canonical(t)
+			if !seen[t] {
+				seen[t] = true
+				leaves = append(leaves, t)
+			}
+		case *types.Struct:
+			for i := 0; i < u.NumFields(); i++ {
+				visit(u.Field(i).Type())
+			}
+		case *types.Array:
+			visit(u.Elem())
+		}
+	}
+	visit(t)
+	if leaves == nil {
+		leaves = []types.Type{}
+	}
+	a.leafCache.Set(t, leaves)
+	return leaves
+}
+
+// admits reports whether a value of type t may hold object o.
+func (a *analysis) admits(t types.Type, o *object) bool {
+	for _, leaf := range a.leaves(t) {
+		switch u := leaf.Underlying().(type) {
+		case *types.Interface:
+			// An interface may hold a function value as the
+			// payload of a value of named function type.
+			if o.fn != nil || types.Implements(o.typ, u) {
+				return true
+			}
+		case *types.Signature:
+			if o.fn != nil && types.Identical(o.fn.Signature, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newNode returns a new node for values of type t,
+// or -1 if values of type t cannot hold objects.
+func (a *analysis) newNode(t types.Type) nodeid {
+	if len(a.leaves(t)) == 0 {
+		return -1
+	}
+	a.nodes = append(a.nodes, &node{typ: t})
+	return nodeid(len(a.nodes) - 1)
+}
+
+// valueNode returns the node for SSA value v in context ctx,
+// or -1 if v cannot hold objects.
+func (a *analysis) valueNode(v ssa.Value, ctx *context) nodeid {
+	switch v := v.(type) {
+	case *ssa.Function:
+		id, ok := a.funcNodes[v]
+		if !ok {
+			id = a.newNode(v.Signature)
+			a.funcNodes[v] = id
+			a.addObject(id, a.objectID(object{fn: v}))
+		}
+		return id
+	case *ssa.Const, *ssa.Global, *ssa.Builtin:
+		return -1 // constants hold no objects; globals are pointers
+	}
+	return a.local(v, ctx, 0, v.Type())
+}
+
+// tupleNode returns the node for component i of tuple-typed value v.
+func (a *analysis) tupleNode(v ssa.Value, ctx *context, i int) nodeid {
+	return a.local(v, ctx, i, v.Type().(*types.Tuple).At(i).Type())
+}
+
+func (a *analysis) local(v ssa.Value, ctx *context, index int, t types.Type) nodeid {
+	key := localKey{v, ctx, index}
+	id, ok := a.locals[key]
+	if !ok {
+		id = a.newNode(t)
+		a.locals[key] = id
+	}
+	return id
+}
+
+// resultNode returns the node for the ith result of fn in ctx.
+func (a *analysis) resultNode(fn *ssa.Function, ctx *context, i int) nodeid {
+	key := resultKey{fn, ctx, i}
+	id, ok := a.results[key]
+	if !ok {
+		id = a.newNode(fn.Signature.Results().At(i).Type())
+		a.results[key] = id
+	}
+	return id
+}
+
+// heapNode returns the memory node for values of leaf type t.
+func (a *analysis) heapNode(t types.Type) nodeid {
+	if id := a.heap.At(t); id != nil {
+		return id.(nodeid)
+	}
+	id := a.newNode(t)
+	a.heap.Set(t, id)
+	return id
+}
+
+// store adds constraints for storing the value of node n,
+// of type t, to memory.
+func (a *analysis) store(n nodeid, t types.Type) {
+	if n >= 0 {
+		for _, leaf := range a.leaves(t) {
+			a.addEdge(n, a.heapNode(leaf))
+		}
+	}
+}
+
+// load adds constraints for loading a value of type t from memory
+// into node n.
+func (a *analysis) load(n nodeid, t types.Type) {
+	if n >= 0 {
+		for _, leaf := range a.leaves(t) {
+			a.addEdge(a.heapNode(leaf), n)
+		}
+	}
+}
+
+// addObject adds object obj to node n, if admissible.
+func (a *analysis) addObject(n nodeid, obj int) {
+	if n < 0 {
+		return
+	}
+	nd := a.nodes[n]
+	if !a.admits(nd.typ, a.objectList[obj]) || !nd.pts.Insert(obj) {
+		return
+	}
+	if nd.delta.IsEmpty() {
+		a.worklist = append(a.worklist, n)
+	}
+	nd.delta.Insert(obj)
+}
+
+// addEdge adds the constraint that the objects of src flow to dst.
+func (a *analysis) addEdge(src, dst nodeid) {
+	if src < 0 || dst < 0 || src == dst {
+		return
+	}
+	nd := a.nodes[src]
+	if nd.succSet == nil {
+		nd.succSet = make(map[nodeid]bool)
+	}
+	if nd.succSet[dst] {
+		return
+	}
+	nd.succSet[dst] = true
+	nd.succs = append(nd.succs, dst)
+	for _, obj := range nd.pts.AppendTo(nil) {
+		a.addObject(dst, obj)
+	}
+}
+
+// watch arranges for f to be called for each object of node n.
+func (a *analysis) watch(n nodeid, f func(obj int)) {
+	if n < 0 {
+		return
+	}
+	nd := a.nodes[n]
+	nd.watchers = append(nd.watchers, f)
+	for _, obj := range nd.pts.AppendTo(nil) {
+		f(obj)
+	}
+}
+
+// solve propagates objects along edges until a fixed point is reached.
+func (a *analysis) solve() {
+	var objs []int
+	for len(a.worklist) > 0 {
+		n := a.worklist[len(a.worklist)-1]
+		a.worklist = a.worklist[:len(a.worklist)-1]
+		nd := a.nodes[n]
+		objs = nd.delta.AppendTo(objs[:0])
+		nd.delta.Clear()
+		for _, obj := range objs {
+			for _, succ := range nd.succs {
+				a.addObject(succ, obj)
+			}
+			for i := 0; i < len(nd.watchers); i++ { // (watchers may grow)
+				nd.watchers[i](obj)
+			}
+		}
+	}
+}
+
+// -- constraint generation --
+
+// reach ensures that fn is analyzed in context ctx.
+func (a *analysis) reach(fn *ssa.Function, ctx *context) {
+	key := resultKey{fn: fn, ctx: ctx}
+	if a.reached[key] {
+		return
+	}
+	a.reached[key] = true
+	a.result.Contexts[fn]++
+
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			a.genInstr(instr, ctx)
+		}
+	}
+}
+
+// genInstr generates the constraints for instruction instr of a
+// function analyzed in context ctx.
+func (a *analysis) genInstr(instr ssa.Instruction, ctx *context) {
+	node := func(v ssa.Value) nodeid { return a.valueNode(v, ctx) }
+
+	switch instr := instr.(type) {
+	case *ssa.Store:
+		a.store(node(instr.Val), instr.Val.Type())
+
+	case *ssa.UnOp:
+		switch instr.Op {
+		case token.MUL:
+			a.load(node(instr), instr.Type())
+		case token.ARROW:
+			if instr.CommaOk {
+				a.load(a.tupleNode(instr, ctx, 0), instr.X.Type().Underlying().(*types.Chan).Elem())
+			} else {
+				a.load(node(instr), instr.Type())
+			}
+		}
+
+	case *ssa.MapUpdate:
+		a.store(node(instr.Key), instr.Key.Type())
+		a.store(node(instr.Value), instr.Value.Type())
+
+	case *ssa.Lookup:
+		if instr.CommaOk {
+			a.load(a.tupleNode(instr, ctx, 0), instr.Type().(*types.Tuple).At(0).Type())
+		} else {
+			a.load(node(instr), instr.Type())
+		}
+
+	case *ssa.Send:
+		a.store(node(instr.X), instr.X.Type())
+
+	case *ssa.Select:
+		recv := 2 // index of first received value in result tuple
+		for _, st := range instr.States {
+			if st.Send != nil {
+				a.store(node(st.Send), st.Send.Type())
+			} else {
+				a.load(a.tupleNode(instr, ctx, recv), instr.Type().(*types.Tuple).At(recv).Type())
+				recv++
+			}
+		}
+
+	case *ssa.Next:
+		if !instr.IsString {
+			tuple := instr.Type().(*types.Tuple)
+			a.load(a.tupleNode(instr, ctx, 1), tuple.At(1).Type())
+			a.load(a.tupleNode(instr, ctx, 2), tuple.At(2).Type())
+		}
+
+	case *ssa.Extract:
+		a.addEdge(a.tupleNode(instr.Tuple, ctx, instr.Index), node(instr))
+
+	case *ssa.Field:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.Index:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.Phi:
+		for _, edge := range instr.Edges {
+			a.addEdge(node(edge), node(instr))
+		}
+
+	case *ssa.ChangeType:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.ChangeInterface:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.Convert:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.MultiConvert:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.MakeInterface:
+		T := a.canonical(instr.X.Type())
+		a.addObject(node(instr), a.objectID(object{typ: T, site: instr, ctx: a.heapContext(ctx)}))
+		a.addEdge(node(instr.X), node(instr)) // payload
+
+	case *ssa.TypeAssert:
+		if instr.CommaOk {
+			a.addEdge(node(instr.X), a.tupleNode(instr, ctx, 0))
+		} else {
+			a.addEdge(node(instr.X), node(instr))
+		}
+
+	case *ssa.MakeClosure:
+		fn := instr.Fn.(*ssa.Function)
+		a.addObject(node(instr), a.objectID(object{fn: fn, site: instr, ctx: ctx}))
+
+	case *ssa.Return:
+		fn := instr.Parent()
+		for i, r := range instr.Results {
+			a.addEdge(node(r), a.resultNode(fn, ctx, i))
+		}
+
+	case *ssa.Panic:
+		a.addEdge(node(instr.X), a.getPanicNode())
+
+	case ssa.CallInstruction:
+		a.genCall(instr, ctx)
+	}
+}
+
+// getPanicNode returns the node for values passed to panic.
+func (a *analysis) getPanicNode() nodeid {
+	if a.panicNode < 0 {
+		a.panicNode = a.newNode(types.NewInterfaceType(nil, nil).Complete())
+	}
+	return a.panicNode
+}
+
+// genCall generates the constraints for call site in context ctx.
+func (a *analysis) genCall(site ssa.CallInstruction, ctx *context) {
+	common := site.Common()
+	switch callee := common.Value.(type) {
+	case *ssa.Builtin:
+		if callee.Name() == "recover" && site.Value() != nil {
+			a.addEdge(a.getPanicNode(), a.valueNode(site.Value(), ctx))
+		}
+		return
+
+	case *ssa.Function:
+		a.call(site, ctx, callee, nil)
+		return
+	}
+
+	if common.IsInvoke() {
+		recv := a.valueNode(common.Value, ctx)
+		a.watch(recv, func(obj int) {
+			o := a.objectList[obj]
+			if o.typ == nil || types.IsInterface(o.typ) {
+				return // a function payload, or not yet concrete
+			}
+			if callee := a.lookupMethod(o.typ, common.Method); callee != nil {
+				a.call(site, ctx, callee, o)
+			}
+		})
+		return
+	}
+
+	// Dynamic call of a function value.
+	a.watch(a.valueNode(common.Value, ctx), func(obj int) {
+		o := a.objectList[obj]
+		if o.fn != nil && types.Identical(o.fn.Signature, common.Signature()) {
+			a.call(site, ctx, o.fn, o)
+		}
+	})
+}
+
+// lookupMethod returns the concrete method of type T
+// that implements interface method m, or nil if none.
+func (a *analysis) lookupMethod(T types.Type, m *types.Func) *ssa.Function {
+	sel := a.prog.MethodSets.MethodSet(T).Lookup(m.Pkg(), m.Name())
+	if sel == nil {
+		return nil
+	}
+	return a.prog.MethodValue(sel)
+}
+
+// calleeContext returns the context in which a call from site, in
+// context ctx, to callee via object obj (nil for a static call) is
+// analyzed.
+func (a *analysis) calleeContext(site ssa.CallInstruction, ctx *context, callee *ssa.Function, obj *object) *context {
+	var calleeCtx *context
+	switch a.config.Sensitivity {
+	case CallSite:
+		calleeCtx = a.push(site, ctx, a.config.K)
+	case Object:
+		if obj != nil && obj.site != nil {
+			calleeCtx = a.push(obj.site, obj.ctx, a.config.K)
+		} else {
+			calleeCtx = ctx
+		}
+	}
+	if calleeCtx != nil && !a.reached[resultKey{fn: callee, ctx: calleeCtx}] && a.result.Contexts[callee] >= a.maxContexts {
+		calleeCtx = nil // budget exhausted
+	}
+	return calleeCtx
+}
+
+// call generates the constraints for a call from site, in context
+// ctx, to callee via object obj (nil for a static call).
+func (a *analysis) call(site ssa.CallInstruction, ctx *context, callee *ssa.Function, obj *object) {
+	edge := cgEdge{site.Parent(), site, callee}
+	if !a.cgEdges[edge] {
+		a.cgEdges[edge] = true
+		cg := a.result.CallGraph
+		callgraph.AddEdge(cg.CreateNode(edge.caller), site, cg.CreateNode(callee))
+	}
+
+	common := site.Common()
+	args := common.Args
+	var argNodes []nodeid
+	if common.IsInvoke() {
+		argNodes = append(argNodes, a.valueNode(common.Value, ctx))
+	}
+	for _, arg := range args {
+		argNodes = append(argNodes, a.valueNode(arg, ctx))
+	}
+	v := site.Value()
+	nresults := callee.Signature.Results().Len()
+
+	if callee.Blocks == nil {
+		// Function without a body (e.g. assembly):
+		// assume it may store its arguments to memory,
+		// and load its results from memory.
+		a.reach(callee, nil)
+		for i, n := range argNodes {
+			if i < len(callee.Params) {
+				a.store(n, callee.Params[i].Type())
+			} else if n >= 0 {
+				a.store(n, a.nodes[n].typ)
+			}
+		}
+		if v != nil {
+			if nresults == 1 {
+				a.load(a.valueNode(v, ctx), v.Type())
+			} else {
+				for i := 0; i < nresults; i++ {
+					a.load(a.tupleNode(v, ctx, i), callee.Signature.Results().At(i).Type())
+				}
+			}
+		}
+		return
+	}
+
+	calleeCtx := a.calleeContext(site, ctx, callee, obj)
+	a.reach(callee, calleeCtx)
+
+	for i, n := range argNodes {
+		if i < len(callee.Params) {
+			a.addEdge(n, a.valueNode(callee.Params[i], calleeCtx))
+		}
+	}
+	if obj != nil {
+		if mc, ok := obj.site.(*ssa.MakeClosure); ok {
+			for i, b := range mc.Bindings {
+				a.addEdge(a.valueNode(b, obj.ctx), a.valueNode(callee.FreeVars[i], calleeCtx))
+			}
+		}
+	}
+	if v != nil {
+		if nresults == 1 {
+			a.addEdge(a.resultNode(callee, calleeCtx, 0), a.valueNode(v, ctx))
+		} else {
+			for i := 0; i < nresults; i++ {
+				a.addEdge(a.resultNode(callee, calleeCtx, i), a.tupleNode(v, ctx, i))
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// No testdata on Android.
+
+//go:build !android
+// +build !android
+
+package kcfa_test
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/troll-zhao/tools/core/testfiles"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/kcfa"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/txtar"
+)
+
+// TestKCFA runs the analysis on each testdata/*.txtar file, using
+// the configuration in the archive's comment, and compares the
+// results with the expectations expressed in the WANT comment.
+func TestKCFA(t *testing.T) {
+	archives, err := filepath.Glob("testdata/*.txtar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, archive := range archives {
+		t.Run(archive, func(t *testing.T) {
+			ar, err := txtar.ParseFile(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config, err := parseConfig(string(ar.Comment))
+			if err != nil {
+				t.Fatalf("%s: %v", archive, err)
+			}
+
+			pkgs := testfiles.LoadPackages(t, ar, "./...")
+			prog, spkgs := ssautil.Packages(pkgs, ssa.SanityCheckFunctions|ssa.InstantiateGenerics)
+			prog.Build()
+			mainPkg := spkgs[0]
+
+			res := kcfa.Analyze([]*ssa.Function{
+				mainPkg.Func("main"),
+				mainPkg.Func("init"),
+			}, config)
+
+			check(t, pkgs[0].Syntax[0], mainPkg, res)
+		})
+	}
+}
+
+// parseConfig parses a configuration of the form
+// "k=1 sensitivity=callsite maxcontexts=1".
+func parseConfig(s string) (*kcfa.Config, error) {
+	config := new(kcfa.Config)
+	for _, field := range strings.Fields(s) {
+		name, value, _ := strings.Cut(field, "=")
+		switch name {
+		case "k", "maxcontexts":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			if name == "k" {
+				config.K = n
+			} else {
+				config.MaxContexts = n
+			}
+		case "sensitivity":
+			switch value {
+			case "callsite":
+				config.Sensitivity = kcfa.CallSite
+			case "object":
+				config.Sensitivity = kcfa.Object
+			default:
+				return nil, fmt.Errorf("invalid sensitivity %q", value)
+			}
+		default:
+			return nil, fmt.Errorf("invalid config field %q", field)
+		}
+	}
+	return config, nil
+}
+
+// check tests the analysis results against the test expectations
+// defined by a comment starting with a line "WANT:".
+//
+// The rest of the comment consists of lines of the following forms:
+//
+//	edge      <func> --kind--> <func>	# call graph edge
+//	reachable <func>			# reachable function
+//	contexts  <func> <n>			# number of contexts
+//
+// Each line asserts that an element is found in the given set, or, if
+// the line is preceded by "!", that it is not in the set.
+//
+// Functions are notated as if by ssa.Function.String.
+func check(t *testing.T, f *ast.File, pkg *ssa.Package, res *kcfa.Result) {
+	tokFile := pkg.Prog.Fset.File(f.Pos())
+
+	// Find the WANT comment.
+	var want string
+	for _, c := range f.Comments {
+		text := strings.TrimSpace(c.Text())
+		if t := strings.TrimPrefix(text, "WANT:\n"); t != text {
+			want = t
+			break
+		}
+	}
+	if want == "" {
+		t.Fatalf("No WANT: comment in %s", tokFile.Name())
+	}
+
+	// Parse the comment into string-to-sense maps.
+	var (
+		wantEdge      = make(map[string]bool)
+		wantReachable = make(map[string]bool)
+		wantContexts  = make(map[string]bool)
+	)
+	for _, line := range strings.Split(want, "\n") {
+		line := strings.TrimSpace(line)
+		if line == "" {
+			continue // skip blanks
+		}
+
+		// A leading "!" negates the assertion.
+		sense := true
+		if rest := strings.TrimPrefix(line, "!"); rest != line {
+			sense = false
+			line = strings.TrimSpace(rest)
+		}
+
+		var want map[string]bool
+		kind := strings.Fields(line)[0]
+		switch kind {
+		case "edge":
+			want = wantEdge
+		case "reachable":
+			want = wantReachable
+		case "contexts":
+			want = wantContexts
+		default:
+			t.Fatalf("%s: invalid assertion: %q", tokFile.Name(), line)
+		}
+		want[strings.TrimSpace(line[len(kind):])] = sense
+	}
+
+	type stringset = map[string]bool // (sets: values are true)
+
+	// compare checks that got matches each assertion of the form
+	// (str, sense) in want.
+	compare := func(kind string, got stringset, want map[string]bool) {
+		ok := true
+		for str, sense := range want {
+			if got[str] != sense {
+				ok = false
+				if sense {
+					t.Errorf("missing %s %q", kind, str)
+				} else {
+					t.Errorf("unwanted %s %q", kind, str)
+				}
+			}
+		}
+
+		// Print the actual output in expectation form.
+		if !ok {
+			var strs []string
+			for s := range got {
+				strs = append(strs, s)
+			}
+			sort.Strings(strs)
+			var buf strings.Builder
+			for _, str := range strs {
+				fmt.Fprintf(&buf, "%s %s\n", kind, str)
+			}
+			t.Errorf("got:\n%s", &buf)
+		}
+	}
+
+	// Check call graph edges.
+	{
+		got := make(stringset)
+		callgraph.GraphVisitEdges(res.CallGraph, func(e *callgraph.Edge) error {
+			edge := fmt.Sprintf("%s --%s--> %s",
+				e.Caller.Func.RelString(pkg.Pkg),
+				e.Description(),
+				e.Callee.Func.RelString(pkg.Pkg))
+			got[edge] = true
+			return nil
+		})
+		compare("edge", got, wantEdge)
+	}
+
+	// Check reachable functions and their numbers of contexts.
+	{
+		reachable := make(stringset)
+		contexts := make(stringset)
+		for f, n := range res.Contexts {
+			reachable[f.RelString(pkg.Pkg)] = true
+			contexts[fmt.Sprintf("%s %d", f.RelString(pkg.Pkg), n)] = true
+		}
+		compare("reachable", reachable, wantReachable)
+		compare("contexts", contexts, wantContexts)
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/container/intsets"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// A Result holds the results of the analysis.
+type Result struct {
+	// CallGraph is the discovered call graph. Its Root is the
+	// first root function. It does not include edges for calls
+	// made via reflection.
+	CallGraph *callgraph.Graph
+
+	a *analysis
+}
+
+// Analyze computes the points-to sets of the program containing
+// the specified root functions, which must be one or more entrypoints
+// (main and init functions) of a complete SSA program, with function
+// bodies for all dependencies. It returns nil if no roots were
+// specified.
+func Analyze(roots []*ssa.Function) *Result {
+	if len(roots) == 0 {
+		return nil
+	}
+	a := newAnalysis(roots[0].Prog)
+	a.result = &Result{
+		CallGraph: callgraph.New(roots[0]),
+		a:         a,
+	}
+	for _, root := range roots {
+		a.result.CallGraph.CreateNode(root)
+		a.reach(root)
+	}
+
+	// Functions and globals not mentioned by reachable code still
+	// point to their own objects. Create their nodes now, so that
+	// queries need not modify the result.
+	for fn := range ssautil.AllFunctions(a.prog) {
+		a.valueNode(fn)
+	}
+	for _, pkg := range a.prog.AllPackages() {
+		for _, mem := range pkg.Members {
+			if g, ok := mem.(*ssa.Global); ok {
+				a.valueNode(g)
+			}
+		}
+	}
+	a.solve()
+	return a.result
+}
+
+// PointsTo returns the set of locations to which the SSA value v may
+// point. The result is empty if v is not of pointer-like type (see
+// [CanPoint]) or does not belong to a reachable function.
+//
+// For a value of interface type, the locations are the tagged
+// objects created by conversions to interface types, whose dynamic
+// types are reported by [PointsToSet.DynamicTypes].
+//
+// PointsTo does not modify the result, so it may be called
+// concurrently, as may the methods of the sets it returns.
+func (r *Result) PointsTo(v ssa.Value) PointsToSet {
+	if !CanPoint(v.Type()) {
+		return PointsToSet{r.a, nil}
+	}
+	id, ok := r.a.values[v]
+	if !ok {
+		return PointsToSet{r.a, nil}
+	}
+	return PointsToSet{r.a, &r.a.nodes[id].pts}
+}
+
+// MayAlias reports whether the pointer-like values x and y may point
+// to the same location.
+func (r *Result) MayAlias(x, y ssa.Value) bool {
+	return r.PointsTo(x).Intersects(r.PointsTo(y))
+}
+
+// CanPoint reports whether a value of type T is pointer-like, that
+// is, whether it may point to a location, and thus have a non-empty
+// points-to set.
+func CanPoint(T types.Type) bool {
+	switch T := T.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature, *types.Interface:
+		return true
+	case *types.Basic:
+		return T.Kind() == types.UnsafePointer
+	}
+	return false
+}
+
+// A PointsToSet is a set of locations to which a pointer-like value
+// may point.
+type PointsToSet struct {
+	a   *analysis
+	pts *intsets.Sparse // set of nodeids; nil => empty
+}
+
+// Labels returns the labels of the locations in the set,
+// in an arbitrary but deterministic order.
+func (s PointsToSet) Labels() []*Label {
+	if s.pts == nil {
+		return nil
+	}
+	var labels []*Label
+	for _, id := range s.pts.AppendTo(nil) {
+		labels = append(labels, s.a.label(nodeid(id)))
+	}
+	return labels
+}
+
+// Intersects reports whether this set and y have a location in
+// common, that is, whether pointers with these points-to sets may
+// alias.
+func (s PointsToSet) Intersects(y PointsToSet) bool {
+	if s.pts == nil || y.pts == nil {
+		return false
+	}
+	return s.pts.Intersects(y.pts)
+}
+
+// DynamicTypes returns the dynamic types of the tagged objects in
+// the set, which is the points-to set of a value of interface type.
+//
+// The result maps each dynamic type T to a PointsToSet: if T is
+// pointer-like, the set of locations to which the values of type T
+// held by the interface may point; otherwise the empty set.
+func (s PointsToSet) DynamicTypes() *typeutil.Map {
+	var m typeutil.Map // not s.a.hasher, which is not safe for concurrent use
+	if s.pts == nil {
+		return &m
+	}
+	for _, id := range s.pts.AppendTo(nil) {
+		obj := s.a.nodes[id].obj
+		if !obj.tagged || nodeid(id) != obj.start {
+			continue
+		}
+		pts, _ := m.At(obj.typ).(PointsToSet)
+		if CanPoint(obj.typ) {
+			if pts.pts == nil {
+				pts = PointsToSet{s.a, new(intsets.Sparse)}
+			}
+			pts.pts.UnionWith(&s.a.nodes[obj.start].pts)
+		}
+		m.Set(obj.typ, pts)
+	}
+	return &m
+}
+
+func (s PointsToSet) String() string {
+	var buf strings.Builder
+	buf.WriteByte('[')
+	for i, l := range s.Labels() {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(l.String())
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// A Label denotes a location: an abstract object, such as the
+// variables allocated by an instruction, and a path to a field or
+// element within it.
+type Label struct {
+	obj  *object
+	path string
+	fset *token.FileSet
+}
+
+// Value returns the allocation site of the object: one of
+// [*ssa.Alloc], [*ssa.MakeSlice], [*ssa.MakeMap], [*ssa.MakeChan],
+// [*ssa.MakeInterface], [*ssa.MakeClosure], [*ssa.Function],
+// [*ssa.Global], or a [*ssa.Call] to append or to a function whose
+// body is not available.
+func (l *Label) Value() ssa.Value { return l.obj.site }
+
+// Path returns the path from the start of the object to the
+// location, such as ".f[*].g", or "" for the object itself. All the
+// elements of an array, slice or channel are denoted "[*]", and the
+// keys and values of a map "[*].key" and "[*].value". An object and
+// its first field, element or key are the same location, whose path
+// is "".
+func (l *Label) Path() string { return l.path }
+
+// Pos returns the position of the allocation site, if known. The
+// position of a closure is that of its function literal.
+func (l *Label) Pos() token.Pos {
+	if pos := l.obj.site.Pos(); pos.IsValid() || l.obj.fn == nil {
+		return pos
+	}
+	return l.obj.fn.Pos()
+}
+
+// String returns a description of the location, such as
+// "x.f@main.go:12:2" for field f of local variable x, or
+// "makemap[*].value@main.go:20:7" for the values of a map.
+func (l *Label) String() string {
+	var s string
+	switch v := l.obj.site.(type) {
+	case *ssa.Function, *ssa.Global:
+		return v.String() + l.path
+	case *ssa.Alloc:
+		s = v.Comment
+		if s == "" {
+			s = "alloc"
+		}
+	case *ssa.MakeSlice:
+		s = "makeslice"
+	case *ssa.MakeMap:
+		s = "makemap"
+	case *ssa.MakeChan:
+		s = "makechan"
+	case *ssa.MakeInterface:
+		s = fmt.Sprintf("makeinterface:%s", v.X.Type())
+	case *ssa.MakeClosure:
+		s = "makeclosure"
+	case *ssa.Call:
+		s = v.Call.Value.Name()
+	default:
+		s = v.Name()
+	}
+	return fmt.Sprintf("%s%s@%s", s, l.path, l.fset.Position(l.Pos()))
+}
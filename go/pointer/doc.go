@@ -0,0 +1,87 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package pointer implements a whole-program, inclusion-based
+(Andersen-style) points-to analysis of Go programs in SSA form.
+
+Given the root functions of a program, typically its main and init
+functions, [Analyze] computes for every SSA value of pointer-like type
+(pointer, slice, map, channel, function, interface, or unsafe.Pointer)
+in every reachable function a conservative approximation of the set
+of memory locations to which it may point at run time. The result
+answers questions such as "may these two pointers alias?" (see
+[Result.MayAlias] and [PointsToSet.Intersects]), "which dynamic types
+may this interface hold?" (see [PointsToSet.DynamicTypes]), and "which
+allocation sites may this channel refer to?" (see [PointsToSet.Labels]).
+It also computes a call graph, as a by-product of discovering the
+callees of dynamic calls.
+
+# Abstraction
+
+Each abstract object, or allocation site, represents all the
+variables allocated by a single instruction: a local variable that
+escapes (*ssa.Alloc), a call to new, a composite literal, make of a
+slice, map or channel, a call to append, or the conversion of a
+concrete value to an interface (*ssa.MakeInterface), which allocates
+a "tagged" object holding the dynamic type and value. Each global
+variable and each function is also an object. Objects are identified
+by their site alone: the analysis is context-insensitive, so the
+objects allocated by all calls of a function are merged.
+
+The analysis is field-sensitive: each field of a struct is a
+distinct location, so pointers to different fields of the same object
+do not alias, and a value loaded from one field does not include
+values stored to another. All elements of an array, slice, map, or
+channel are represented by a single location, as are all keys of a
+map. A location is described by a [Label], which denotes an object
+and a path within it, such as "x.f[*].g".
+
+The analysis is flow-insensitive: it assumes that the instructions
+of each reachable function may execute in any order, any number of
+times.
+
+# Generics
+
+Function bodies should be built with the [ssa.InstantiateGenerics]
+mode flag, so that each call to a generic function is a call to a
+fully instantiated function whose values have concrete types. Without
+it, calls are made through instantiation wrappers to the generic
+function body, in which a value of type parameter type is treated as
+a single pointer-like value; values of struct and array types that
+flow through such bodies may be lost, and type assertions on values
+of type parameter type may fail to match.
+
+# Limitations
+
+The analysis is sound, that is, its points-to sets include all
+locations to which a value may point in any execution, only for
+programs that do not use reflection, unsafe, or functions implemented
+in assembly. Such programs are handled by the following approximations.
+
+Functions whose bodies are not available, such as those implemented in
+assembly or provided by the runtime, are assumed to have no effect on
+pointers: each pointer-like result of a call to such a function points
+to a fresh object of the appropriate type allocated at the call site
+(or to nothing, if it is an interface or function). The pointer
+functions of package sync/atomic are modeled exactly.
+
+The functions of package reflect are not analyzed. Instead, the
+values passed to them escape: every location reachable from a value
+passed to reflect may be reached by any reflect.Value or reflect.Type.
+If the program calls a method of reflect.Value that stores values
+(such as Set, SetMapIndex, or Send), escaped values may be stored in
+any escaped location; and if it calls reflect.Value.Call, every
+escaped function and every method of every escaped dynamic type may
+be called with escaped arguments. Objects allocated by reflection,
+such as by reflect.New or reflect.MakeFunc, are not represented.
+
+A conversion between unsafe.Pointer and another pointer type preserves
+the locations to which a pointer points, but pointer arithmetic is
+not modeled: the result of unsafe.Add points to the same locations as
+its operand, and pointers converted to uintptr and back are lost.
+Reinterpreting the memory of an object as a value of a different type
+with a larger or differently arranged set of fields may lose values.
+*/
+package pointer // import "golang.org/x/tools/go/pointer"
@@ -0,0 +1,381 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines the constraint generation phase.
+
+import (
+	"go/token"
+	"go/types"
+
+	"github.com/troll-zhao/tools/core/typeparams"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// valueNode returns the first node of the block for SSA value v.
+func (a *analysis) valueNode(v ssa.Value) nodeid {
+	id, ok := a.values[v]
+	if ok {
+		return id
+	}
+	id = a.newBlock(v.Type())
+	a.values[v] = id
+
+	// Functions and globals are constant pointers to objects.
+	switch v := v.(type) {
+	case *ssa.Function:
+		obj := a.newObject(a.flatten(v.Signature), v.Signature, v)
+		obj.fn = v
+		a.addLoc(id, obj.start)
+	case *ssa.Global:
+		T := v.Type().(*types.Pointer).Elem()
+		a.addLoc(id, a.newObject(a.flatten(T), T, v).start)
+	}
+	return id
+}
+
+// resultNode returns the first node of the block for the results of fn.
+func (a *analysis) resultNode(fn *ssa.Function) nodeid {
+	id, ok := a.results[fn]
+	if !ok {
+		id = a.newBlock(fn.Signature.Results())
+		a.results[fn] = id
+	}
+	return id
+}
+
+// alloc allocates a new object at site, whose contents have the
+// specified fields, and adds it to the points-to set of site.
+func (a *analysis) alloc(site ssa.Value, fields []*field, typ types.Type) *object {
+	obj := a.newObject(fields, typ, site)
+	a.addLoc(a.valueNode(site), obj.start)
+	return obj
+}
+
+// reach ensures that the constraints for fn have been generated.
+func (a *analysis) reach(fn *ssa.Function) {
+	if a.reached[fn] {
+		return
+	}
+	a.reached[fn] = true
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			a.genInstr(instr)
+		}
+	}
+}
+
+// genInstr generates the constraints for instruction instr.
+func (a *analysis) genInstr(instr ssa.Instruction) {
+	node := a.valueNode
+	sizeof := func(v ssa.Value) int { return a.sizeof(v.Type()) }
+
+	switch instr := instr.(type) {
+	case *ssa.Alloc:
+		T := instr.Type().(*types.Pointer).Elem()
+		a.alloc(instr, a.flatten(T), T)
+
+	case *ssa.MakeSlice:
+		elem := typeparams.CoreType(instr.Type()).(*types.Slice).Elem()
+		a.alloc(instr, a.prefix("[*]", elem), elem)
+
+	case *ssa.MakeMap:
+		m := typeparams.CoreType(instr.Type()).(*types.Map)
+		fields := append(a.prefix("[*].key", m.Key()), a.prefix("[*].value", m.Elem())...)
+		a.alloc(instr, fields, m)
+
+	case *ssa.MakeChan:
+		elem := typeparams.CoreType(instr.Type()).(*types.Chan).Elem()
+		a.alloc(instr, a.prefix("[*]", elem), elem)
+
+	case *ssa.MakeInterface:
+		T := instr.X.Type()
+		obj := a.alloc(instr, a.flatten(T), T)
+		obj.tagged = true
+		a.copy(node(instr.X), obj.start, obj.size)
+
+	case *ssa.MakeClosure:
+		fn := instr.Fn.(*ssa.Function)
+		obj := a.alloc(instr, a.flatten(fn.Signature), fn.Signature)
+		obj.fn = fn
+		for i, b := range instr.Bindings {
+			a.copy(node(b), node(fn.FreeVars[i]), sizeof(b))
+		}
+
+	case *ssa.Store:
+		a.store(node(instr.Addr), node(instr.Val), 0, sizeof(instr.Val))
+
+	case *ssa.UnOp:
+		switch instr.Op {
+		case token.MUL:
+			a.load(node(instr), node(instr.X), 0, sizeof(instr))
+		case token.ARROW:
+			elem := typeparams.CoreType(instr.X.Type()).(*types.Chan).Elem()
+			a.load(node(instr), node(instr.X), 0, a.sizeof(elem))
+		}
+
+	case *ssa.FieldAddr:
+		S := typeparams.CoreType(instr.X.Type()).(*types.Pointer).Elem()
+		a.offsetAddr(node(instr), node(instr.X), a.offsetOf(S, instr.Field))
+
+	case *ssa.Field:
+		offset := a.offsetOf(instr.X.Type(), instr.Field)
+		if offset+sizeof(instr) <= sizeof(instr.X) { // (false for a type parameter)
+			a.copy(node(instr.X)+nodeid(offset), node(instr), sizeof(instr))
+		}
+
+	case *ssa.IndexAddr:
+		// All elements are represented by the first.
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.Index:
+		if _, ok := instr.X.Type().Underlying().(*types.Array); ok {
+			a.copy(node(instr.X), node(instr), sizeof(instr))
+		}
+
+	case *ssa.Slice:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.SliceToArrayPointer:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.MapUpdate:
+		m := typeparams.CoreType(instr.Map.Type()).(*types.Map)
+		a.store(node(instr.Map), node(instr.Key), 0, a.sizeof(m.Key()))
+		a.store(node(instr.Map), node(instr.Value), a.sizeof(m.Key()), a.sizeof(m.Elem()))
+
+	case *ssa.Lookup:
+		if m, ok := typeparams.CoreType(instr.X.Type()).(*types.Map); ok {
+			// The result of a comma-ok lookup is a tuple whose
+			// first component is the value.
+			a.load(node(instr), node(instr.X), a.sizeof(m.Key()), a.sizeof(m.Elem()))
+		}
+
+	case *ssa.Next:
+		if !instr.IsString {
+			x := instr.Iter.(*ssa.Range).X
+			m := typeparams.CoreType(x.Type()).(*types.Map)
+			tuple := instr.Type().(*types.Tuple)
+			if isValid(tuple.At(1).Type()) {
+				a.load(node(instr)+nodeid(a.tupleOffset(tuple, 1)), node(x), 0, a.sizeof(m.Key()))
+			}
+			if isValid(tuple.At(2).Type()) {
+				a.load(node(instr)+nodeid(a.tupleOffset(tuple, 2)), node(x), a.sizeof(m.Key()), a.sizeof(m.Elem()))
+			}
+		}
+
+	case *ssa.Send:
+		a.store(node(instr.Chan), node(instr.X), 0, sizeof(instr.X))
+
+	case *ssa.Select:
+		tuple := instr.Type().(*types.Tuple)
+		recv := 2 // index of first received value in result tuple
+		for _, st := range instr.States {
+			if st.Send != nil {
+				a.store(node(st.Chan), node(st.Send), 0, sizeof(st.Send))
+			} else {
+				offset := a.tupleOffset(tuple, recv)
+				a.load(node(instr)+nodeid(offset), node(st.Chan), 0, a.sizeof(tuple.At(recv).Type()))
+				recv++
+			}
+		}
+
+	case *ssa.Extract:
+		tuple := instr.Tuple.Type().(*types.Tuple)
+		offset := a.tupleOffset(tuple, instr.Index)
+		a.copy(node(instr.Tuple)+nodeid(offset), node(instr), sizeof(instr))
+
+	case *ssa.Phi:
+		for _, edge := range instr.Edges {
+			a.copy(node(edge), node(instr), sizeof(instr))
+		}
+
+	case *ssa.ChangeType:
+		a.copy(node(instr.X), node(instr), sizeof(instr))
+
+	case *ssa.ChangeInterface:
+		a.addEdge(node(instr.X), node(instr))
+
+	case *ssa.Convert:
+		// Conversions between unsafe.Pointer and other pointer
+		// types preserve the locations; others yield no pointers.
+		if CanPoint(instr.X.Type()) && CanPoint(instr.Type()) {
+			a.addEdge(node(instr.X), node(instr))
+		}
+
+	case *ssa.MultiConvert:
+		if CanPoint(instr.X.Type()) && CanPoint(instr.Type()) {
+			a.addEdge(node(instr.X), node(instr))
+		}
+
+	case *ssa.TypeAssert:
+		a.typeAssert(instr)
+
+	case *ssa.Return:
+		fn := instr.Parent()
+		results := fn.Signature.Results()
+		for i, r := range instr.Results {
+			offset := a.tupleOffset(results, i)
+			a.copy(node(r), a.resultNode(fn)+nodeid(offset), sizeof(r))
+		}
+
+	case *ssa.Panic:
+		a.addEdge(node(instr.X), a.panicNode)
+
+	case ssa.CallInstruction:
+		a.genCall(instr)
+	}
+}
+
+// isValid reports whether t is not the invalid type, used by SSA
+// for components of a tuple that are not needed.
+func isValid(t types.Type) bool {
+	return t != types.Typ[types.Invalid]
+}
+
+// typeAssert generates the constraints for a type assertion.
+func (a *analysis) typeAssert(instr *ssa.TypeAssert) {
+	dst := a.valueNode(instr) // (the first component, if CommaOk)
+	if I, ok := instr.AssertedType.Underlying().(*types.Interface); ok {
+		a.watch(a.valueNode(instr.X), func(loc nodeid) {
+			obj := a.nodes[loc].obj
+			if obj.tagged && loc == obj.start && types.Implements(obj.typ, I) {
+				a.addLoc(dst, loc)
+			}
+		})
+		return
+	}
+	T := instr.AssertedType
+	a.watch(a.valueNode(instr.X), func(loc nodeid) {
+		obj := a.nodes[loc].obj
+		if obj.tagged && loc == obj.start && types.Identical(obj.typ, T) {
+			a.copy(obj.start, dst, obj.size)
+		}
+	})
+}
+
+// genCall generates the constraints for a call site.
+func (a *analysis) genCall(site ssa.CallInstruction) {
+	common := site.Common()
+	switch callee := common.Value.(type) {
+	case *ssa.Builtin:
+		a.genBuiltin(site, callee)
+		return
+
+	case *ssa.Function:
+		a.call(site, callee, -1)
+		return
+	}
+
+	if common.IsInvoke() {
+		a.watch(a.valueNode(common.Value), func(loc nodeid) {
+			obj := a.nodes[loc].obj
+			if !obj.tagged || loc != obj.start {
+				return
+			}
+			if callee := a.lookupMethod(obj.typ, common.Method); callee != nil {
+				a.call(site, callee, obj.start)
+			}
+		})
+		return
+	}
+
+	// Dynamic call of a function value.
+	a.watch(a.valueNode(common.Value), func(loc nodeid) {
+		obj := a.nodes[loc].obj
+		if obj.fn != nil && loc == obj.start && types.Identical(obj.fn.Signature, common.Signature()) {
+			a.call(site, obj.fn, -1)
+		}
+	})
+}
+
+// lookupMethod returns the concrete method of type T
+// that implements interface method m, or nil if none.
+func (a *analysis) lookupMethod(T types.Type, m *types.Func) *ssa.Function {
+	sel := a.prog.MethodSets.MethodSet(T).Lookup(m.Pkg(), m.Name())
+	if sel == nil {
+		return nil
+	}
+	return a.prog.MethodValue(sel)
+}
+
+// call generates the constraints for a call from site to callee. If
+// the call is a dynamic method call, recv is the first node of the
+// receiver value held by the tagged object; otherwise it is -1.
+func (a *analysis) call(site ssa.CallInstruction, callee *ssa.Function, recv nodeid) {
+	edge := cgEdge{site, callee}
+	if !a.cgEdges[edge] {
+		a.cgEdges[edge] = true
+		cg := a.result.CallGraph
+		callgraph.AddEdge(cg.CreateNode(site.Parent()), site, cg.CreateNode(callee))
+	}
+
+	common := site.Common()
+	var args []block
+	if recv >= 0 {
+		args = append(args, block{recv, a.nodes[recv].obj.size})
+	}
+	for _, v := range common.Args {
+		args = append(args, block{a.valueNode(v), a.sizeof(v.Type())})
+	}
+
+	if callee.Blocks == nil || isReflect(callee) {
+		a.external(site, callee, args)
+		return
+	}
+
+	a.reach(callee)
+	for i, arg := range args {
+		if i < len(callee.Params) {
+			param := callee.Params[i]
+			a.copy(arg.start, a.valueNode(param), min(arg.size, a.sizeof(param.Type())))
+		}
+	}
+	if v := site.Value(); v != nil {
+		a.copy(a.resultNode(callee), a.valueNode(v), min(a.sizeof(v.Type()), a.sizeof(callee.Signature.Results())))
+	}
+}
+
+// genBuiltin generates the constraints for a call to a built-in function.
+func (a *analysis) genBuiltin(site ssa.CallInstruction, fn *ssa.Builtin) {
+	v := site.Value()
+	if v == nil {
+		return // (go or defer)
+	}
+	args := site.Common().Args
+	switch fn.Name() {
+	case "append":
+		// append(x, y...) may return x, or a new array
+		// containing the elements of x and y.
+		dst := a.valueNode(v)
+		a.addEdge(a.valueNode(args[0]), dst)
+		if len(args) > 1 {
+			elem := typeparams.CoreType(v.Type()).(*types.Slice).Elem()
+			obj := a.alloc(v, a.prefix("[*]", elem), elem)
+			a.load(obj.start, a.valueNode(args[0]), 0, obj.size)
+			if _, ok := typeparams.CoreType(args[1].Type()).(*types.Slice); ok {
+				a.load(obj.start, a.valueNode(args[1]), 0, obj.size)
+			}
+		}
+
+	case "copy":
+		elem := typeparams.CoreType(args[0].Type()).(*types.Slice).Elem()
+		if _, ok := typeparams.CoreType(args[1].Type()).(*types.Slice); ok {
+			tmp := a.newBlock(elem)
+			a.load(tmp, a.valueNode(args[1]), 0, a.sizeof(elem))
+			a.store(a.valueNode(args[0]), tmp, 0, a.sizeof(elem))
+		}
+
+	case "recover":
+		a.addEdge(a.panicNode, a.valueNode(v))
+
+	case "ssa:wrapnilchk", "Add", "Slice", "SliceData":
+		// These return (a pointer derived from) their first operand.
+		if CanPoint(v.Type()) {
+			a.addEdge(a.valueNode(args[0]), a.valueNode(v))
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// No testdata on Android.
+
+//go:build !android
+// +build !android
+
+package pointer_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/troll-zhao/tools/core/testfiles"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/txtar"
+)
+
+// TestPointer runs the analysis on each testdata/*.txtar file and
+// checks the results against the expectations expressed by comments
+// on calls to the print built-in function:
+//
+//	print(x)    // @pointsto l1 | l2	# labels of the points-to set of x
+//	print(x)    // @types T1 | T2	# dynamic types of interface x
+//	print(x, y) // @alias yes	# whether x and y may alias (yes or no)
+//
+// An empty list of labels or types denotes the empty set. Labels are
+// notated as if by Label.String, but with only the line number of
+// the position.
+func TestPointer(t *testing.T) {
+	archives, err := filepath.Glob("testdata/*.txtar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, archive := range archives {
+		t.Run(filepath.Base(archive), func(t *testing.T) {
+			ar, err := txtar.ParseFile(archive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkgs := testfiles.LoadPackages(t, ar, "./...")
+			prog, spkgs := ssautil.AllPackages(pkgs, ssa.SanityCheckFunctions|ssa.InstantiateGenerics)
+			prog.Build()
+			mainPkg := spkgs[0]
+
+			res := pointer.Analyze([]*ssa.Function{
+				mainPkg.Func("main"),
+				mainPkg.Func("init"),
+			})
+
+			notes := make(map[token.Position]string)
+			for _, f := range pkgs[0].Syntax {
+				annotations(prog.Fset, f, notes)
+			}
+			checked := make(map[token.Position]bool)
+			for fn := range ssautil.AllFunctions(prog) {
+				if fn.Pkg != mainPkg && fn.Origin() == nil {
+					continue // not in the main package
+				}
+				if fn.TypeParams().Len() > len(fn.TypeArgs()) {
+					continue // generic; only its instances are analyzed
+				}
+				for _, b := range fn.Blocks {
+					for _, instr := range b.Instrs {
+						call, ok := instr.(*ssa.Call)
+						if !ok {
+							continue
+						}
+						if builtin, ok := call.Call.Value.(*ssa.Builtin); !ok || builtin.Name() != "print" {
+							continue
+						}
+						posn := prog.Fset.Position(call.Pos())
+						posn = token.Position{Filename: posn.Filename, Line: posn.Line}
+						note, ok := notes[posn]
+						if !ok {
+							t.Errorf("%s: print call has no annotation", posn)
+							continue
+						}
+						check(t, res, mainPkg.Pkg, posn, note, call.Call.Args)
+						checked[posn] = true
+					}
+				}
+			}
+			if len(checked) != len(notes) {
+				t.Errorf("checked %d annotations, want %d", len(checked), len(notes))
+			}
+		})
+	}
+}
+
+var annotationRx = regexp.MustCompile(`@(pointsto|types|alias)\b(.*)`)
+
+// annotations adds the annotation comments of file f to notes,
+// indexed by the file and line.
+func annotations(fset *token.FileSet, f *ast.File, notes map[token.Position]string) {
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if m := annotationRx.FindString(c.Text); m != "" {
+				posn := fset.Position(c.Pos())
+				notes[token.Position{Filename: posn.Filename, Line: posn.Line}] = m
+			}
+		}
+	}
+}
+
+var posnRx = regexp.MustCompile(`@[^@]*:(\d+):\d+$`)
+
+// check tests one annotation against the results for args.
+func check(t *testing.T, res *pointer.Result, pkg *types.Package, posn token.Position, note string, args []ssa.Value) {
+	kind, list, _ := strings.Cut(note[1:], " ")
+	var want []string
+	for _, s := range strings.Split(list, "|") {
+		if s := strings.TrimSpace(s); s != "" {
+			want = append(want, s)
+		}
+	}
+	sort.Strings(want)
+
+	var got []string
+	switch kind {
+	case "pointsto":
+		for _, l := range res.PointsTo(args[0]).Labels() {
+			got = append(got, posnRx.ReplaceAllString(l.String(), "@$1"))
+		}
+
+	case "types":
+		res.PointsTo(args[0]).DynamicTypes().Iterate(func(T types.Type, _ any) {
+			got = append(got, types.TypeString(T, types.RelativeTo(pkg)))
+		})
+
+	case "alias":
+		if res.MayAlias(args[0], args[1]) {
+			got = append(got, "yes")
+		} else {
+			got = append(got, "no")
+		}
+	}
+	sort.Strings(got)
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s:%d: @%s: got %s, want %s", filepath.Base(posn.Filename), posn.Line, kind,
+			strings.Join(got, " | "), strings.Join(want, " | "))
+	}
+}
+
+// TestConcurrentQueries checks that queries do not modify the result:
+// the points-to sets of functions and globals not mentioned by
+// reachable code are computed by Analyze, and concurrent queries
+// agree with sequential ones. Run with -race.
+func TestConcurrentQueries(t *testing.T) {
+	ar := txtar.Parse([]byte(`
+-- go.mod --
+module example.com
+go 1.18
+
+-- main.go --
+package main
+
+var used, unused *int
+
+func f() {}
+
+func g() {}
+
+func main() {
+	used = new(int)
+	var h func() = f
+	h()
+	var x interface{} = &used
+	_ = x
+}
+`))
+	pkgs := testfiles.LoadPackages(t, ar, "./...")
+	prog, spkgs := ssautil.AllPackages(pkgs, ssa.SanityCheckFunctions|ssa.InstantiateGenerics)
+	prog.Build()
+	mainPkg := spkgs[0]
+	res := pointer.Analyze([]*ssa.Function{mainPkg.Func("main"), mainPkg.Func("init")})
+
+	var values []ssa.Value
+	for _, name := range []string{"f", "g", "main"} {
+		values = append(values, mainPkg.Func(name))
+	}
+	for _, name := range []string{"used", "unused"} {
+		values = append(values, mainPkg.Var(name))
+	}
+	for _, b := range mainPkg.Func("main").Blocks {
+		for _, instr := range b.Instrs {
+			if v, ok := instr.(ssa.Value); ok && pointer.CanPoint(v.Type()) {
+				values = append(values, v)
+			}
+		}
+	}
+	query := func(v ssa.Value) string {
+		pts := res.PointsTo(v)
+		var dynamic []string
+		pts.DynamicTypes().Iterate(func(T types.Type, _ any) {
+			dynamic = append(dynamic, T.String())
+		})
+		sort.Strings(dynamic)
+		return pts.String() + " " + strings.Join(dynamic, " | ")
+	}
+	want := make([]string, len(values))
+	for i, v := range values {
+		want[i] = query(v)
+	}
+	for _, name := range []string{"g", "unused"} {
+		if got := res.PointsTo(mainPkg.Members[name].(ssa.Value)); len(got.Labels()) != 1 {
+			t.Errorf("PointsTo(%s) = %s, want its own object", name, got)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, v := range values {
+				if got := query(v); got != want[i] {
+					t.Errorf("concurrent PointsTo(%s) = %s, want %s", v.Name(), got, want[i])
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines the model of functions whose bodies are not
+// analyzed: those without bodies, and those of package reflect.
+//
+// Values passed to reflect escape: they flow into a single node,
+// a.escaped, as do the values of all locations reachable from escaped
+// locations. The results of reflect functions are drawn from the
+// escaped node.
+
+import (
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A block is a contiguous sequence of nodes holding a value.
+type block struct {
+	start nodeid
+	size  int
+}
+
+// isReflect reports whether fn belongs to one of the packages that
+// implement reflection, which are not analyzed.
+func isReflect(fn *ssa.Function) bool {
+	if orig := fn.Origin(); orig != nil {
+		fn = orig
+	}
+	if fn.Pkg == nil {
+		return false
+	}
+	switch fn.Pkg.Pkg.Path() {
+	case "reflect", "internal/reflectlite":
+		return true
+	}
+	return false
+}
+
+// external generates the constraints for a call from site to callee,
+// a function whose body is not analyzed, with the specified arguments.
+func (a *analysis) external(site ssa.CallInstruction, callee *ssa.Function, args []block) {
+	if !isReflect(callee) {
+		// A function without a body is assumed to return pointers
+		// to fresh objects, unless it is an atomic intrinsic.
+		if !a.atomic(site, callee, args) {
+			a.fresh(site)
+		}
+		return
+	}
+
+	for _, arg := range args {
+		a.escapeBlock(arg.start, arg.size)
+	}
+	if v := site.Value(); v != nil {
+		a.unescapeBlock(a.valueNode(v), a.sizeof(v.Type()))
+	}
+	switch name := callee.Name(); {
+	case strings.HasPrefix(name, "Set"), name == "Copy", name == "Send", name == "TrySend":
+		a.enableReflectSet()
+	case name == "Call", name == "CallSlice":
+		a.enableReflectCall()
+	}
+}
+
+// fresh adds to each pointer-like component of the result of call
+// site, if any, a fresh object of the appropriate type.
+func (a *analysis) fresh(site ssa.CallInstruction) {
+	if v := site.Value(); v != nil {
+		id := a.valueNode(v)
+		for i, f := range a.flatten(v.Type()) {
+			if obj := a.freshObject(v, f.typ); obj != nil {
+				a.addLoc(id+nodeid(i), obj.start)
+			}
+		}
+	}
+}
+
+// freshObject returns a new object allocated by the call v to a
+// function whose body is not analyzed, to which a result of type t
+// may point, or nil if its type is not known.
+func (a *analysis) freshObject(v ssa.Value, t types.Type) *object {
+	switch t := t.Underlying().(type) {
+	case *types.Pointer:
+		return a.newObject(a.flatten(t.Elem()), t.Elem(), v)
+	case *types.Slice:
+		return a.newObject(a.prefix("[*]", t.Elem()), t.Elem(), v)
+	case *types.Chan:
+		return a.newObject(a.prefix("[*]", t.Elem()), t.Elem(), v)
+	case *types.Map:
+		fields := append(a.prefix("[*].key", t.Key()), a.prefix("[*].value", t.Elem())...)
+		return a.newObject(fields, t, v)
+	}
+	return nil // function, interface, or unsafe.Pointer
+}
+
+// atomic generates the constraints for a call to one of the pointer
+// functions of package sync/atomic, and reports whether it did so.
+func (a *analysis) atomic(site ssa.CallInstruction, callee *ssa.Function, args []block) bool {
+	if callee.Pkg == nil || callee.Pkg.Pkg.Path() != "sync/atomic" {
+		return false
+	}
+	switch callee.Name() {
+	case "LoadPointer": // (addr) val
+		if v := site.Value(); v != nil {
+			a.load(a.valueNode(v), args[0].start, 0, 1)
+		}
+	case "StorePointer": // (addr, val)
+		a.store(args[0].start, args[1].start, 0, 1)
+	case "SwapPointer": // (addr, new) old
+		if v := site.Value(); v != nil {
+			a.load(a.valueNode(v), args[0].start, 0, 1)
+		}
+		a.store(args[0].start, args[1].start, 0, 1)
+	case "CompareAndSwapPointer": // (addr, old, new) swapped
+		a.store(args[0].start, args[2].start, 0, 1)
+	default:
+		return false
+	}
+	return true
+}
+
+// escapeBlock adds the pointer-like nodes of a block to the escaped node.
+func (a *analysis) escapeBlock(start nodeid, size int) {
+	for i := 0; i < size; i++ {
+		if CanPoint(a.nodes[start+nodeid(i)].typ) {
+			a.addEdge(start+nodeid(i), a.escaped)
+		}
+	}
+}
+
+// escape is called for each location loc that escapes. All the
+// locations of its object escape too, as do the values they hold.
+func (a *analysis) escape(loc nodeid) {
+	obj := a.nodes[loc].obj
+	if a.escapedObjects[obj] {
+		return
+	}
+	a.escapedObjects[obj] = true
+	a.escapedList = append(a.escapedList, obj)
+	a.escapeBlock(obj.start, obj.size)
+	if a.reflectSet {
+		a.unescapeBlock(obj.start, obj.size)
+	}
+	if a.reflectCall {
+		a.reflectCallObject(obj)
+	}
+}
+
+// unescapeBlock adds the escaped node to the pointer-like nodes of a block.
+func (a *analysis) unescapeBlock(start nodeid, size int) {
+	for i := 0; i < size; i++ {
+		if CanPoint(a.nodes[start+nodeid(i)].typ) {
+			a.addEdge(a.escaped, start+nodeid(i))
+		}
+	}
+}
+
+// enableReflectSet records that the program may store values through
+// reflection: any escaped value may be stored in any escaped location.
+func (a *analysis) enableReflectSet() {
+	if a.reflectSet {
+		return
+	}
+	a.reflectSet = true
+	for _, obj := range a.escapedList {
+		a.unescapeBlock(obj.start, obj.size)
+	}
+}
+
+// enableReflectCall records that the program may make calls through
+// reflection: any escaped function or method of an escaped dynamic
+// type may be called with escaped arguments.
+func (a *analysis) enableReflectCall() {
+	if a.reflectCall {
+		return
+	}
+	a.reflectCall = true
+	for _, obj := range a.escapedList {
+		a.reflectCallObject(obj)
+	}
+}
+
+// reflectCallObject generates the constraints for reflective calls
+// of the function or methods of an escaped object.
+func (a *analysis) reflectCallObject(obj *object) {
+	if obj.fn != nil {
+		a.reflectiveCall(obj.fn)
+	}
+	if obj.tagged {
+		mset := a.prog.MethodSets.MethodSet(obj.typ)
+		for i := 0; i < mset.Len(); i++ {
+			if fn := a.prog.MethodValue(mset.At(i)); fn != nil {
+				a.reflectiveCall(fn)
+			}
+		}
+	}
+}
+
+// reflectiveCall generates the constraints for a call of fn by
+// reflection, whose arguments are escaped values, and whose results
+// escape.
+func (a *analysis) reflectiveCall(fn *ssa.Function) {
+	if a.reflective[fn] {
+		return
+	}
+	a.reflective[fn] = true
+	if fn.Blocks == nil || isReflect(fn) {
+		return
+	}
+	a.result.CallGraph.CreateNode(fn)
+	a.reach(fn)
+	for _, p := range fn.Params {
+		a.unescapeBlock(a.valueNode(p), a.sizeof(p.Type()))
+	}
+	a.escapeBlock(a.resultNode(fn), a.sizeof(fn.Signature.Results()))
+}
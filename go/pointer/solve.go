@@ -0,0 +1,340 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pointer
+
+// This file defines the nodes and objects of the constraint graph,
+// and the solver.
+
+import (
+	"fmt"
+	"go/types"
+
+	"github.com/troll-zhao/tools/core/typeparams"
+	"golang.org/x/tools/container/intsets"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+type nodeid int
+
+// A node represents a location holding a single scalar value: either
+// a component of an SSA register, or a field or element of an object.
+// Its points-to set contains the nodeids of the locations to which
+// the value may point.
+type node struct {
+	obj      *object    // the object containing this node, if any
+	typ      types.Type // type of the value held by the node
+	pts      intsets.Sparse
+	delta    intsets.Sparse // locations not yet propagated
+	succs    []nodeid
+	succSet  map[nodeid]bool
+	watchers []func(loc nodeid) // called for each new location
+}
+
+// An object is an abstract memory object, represented by a contiguous
+// block of nodes, one for each field of the flattened type of its
+// contents (see flatten).
+type object struct {
+	start  nodeid
+	size   int
+	typ    types.Type    // the type of the contents; for a tagged object, the dynamic type
+	tagged bool          // a tagged object, created by MakeInterface, to which interfaces point
+	fn     *ssa.Function // the function, for a function object
+	site   ssa.Value     // the allocation site
+	fields []*field      // the flattened fields of the contents
+}
+
+// A field is a component of the flattened form of a type.
+type field struct {
+	typ  types.Type // type of the scalar field
+	path string     // path from the start of the value, e.g. ".f[*]"
+}
+
+// Working state of the analysis.
+type analysis struct {
+	prog   *ssa.Program
+	result *Result
+	hasher typeutil.Hasher
+
+	nodes     []*node
+	values    map[ssa.Value]nodeid     // first node of each value
+	results   map[*ssa.Function]nodeid // first node of the result tuple of each function
+	flattened typeutil.Map             // maps each type to its []*field
+	reached   map[*ssa.Function]bool   // functions whose constraints have been generated
+	cgEdges   map[cgEdge]bool          // call graph edges already added
+	panicNode nodeid                   // values passed to panic
+	worklist  []nodeid
+
+	// Model of reflection and external functions; see reflect.go.
+	escaped        nodeid                 // values that escape into reflection
+	escapedObjects map[*object]bool       // objects whose locations have escaped
+	escapedList    []*object              // escapedObjects, in order
+	reflectSet     bool                   // the program stores through reflection
+	reflectCall    bool                   // the program calls through reflection
+	reflective     map[*ssa.Function]bool // functions called through reflection
+}
+
+type cgEdge struct {
+	site   ssa.CallInstruction
+	callee *ssa.Function
+}
+
+func newAnalysis(prog *ssa.Program) *analysis {
+	a := &analysis{
+		prog:           prog,
+		hasher:         typeutil.MakeHasher(),
+		values:         make(map[ssa.Value]nodeid),
+		results:        make(map[*ssa.Function]nodeid),
+		reached:        make(map[*ssa.Function]bool),
+		cgEdges:        make(map[cgEdge]bool),
+		escapedObjects: make(map[*object]bool),
+		reflective:     make(map[*ssa.Function]bool),
+	}
+	a.flattened.SetHasher(a.hasher)
+	eface := types.NewInterfaceType(nil, nil).Complete()
+	a.panicNode = a.newBlock(eface)
+	a.escaped = a.newBlock(types.Typ[types.UnsafePointer])
+	a.watch(a.escaped, a.escape)
+	return a
+}
+
+// flatten returns the scalar fields of a value of type t, in order:
+// a struct is flattened to the fields of its fields, an array to the
+// fields of its element, and a tuple to the fields of its components.
+// Any other type, including a struct with no fields, has a single
+// field.
+func (a *analysis) flatten(t types.Type) []*field {
+	if fields := a.flattened.At(t); fields != nil {
+		return fields.([]*field)
+	}
+	var fields []*field
+	switch u := t.(type) {
+	case *types.Tuple:
+		for i := 0; i < u.Len(); i++ {
+			for _, f := range a.flatten(u.At(i).Type()) {
+				fields = append(fields, &field{f.typ, fmt.Sprintf("#%d%s", i, f.path)})
+			}
+		}
+	default:
+		switch u := t.Underlying().(type) {
+		case *types.Struct:
+			for i := 0; i < u.NumFields(); i++ {
+				for _, f := range a.flatten(u.Field(i).Type()) {
+					fields = append(fields, &field{f.typ, "." + u.Field(i).Name() + f.path})
+				}
+			}
+		case *types.Array:
+			for _, f := range a.flatten(u.Elem()) {
+				fields = append(fields, &field{f.typ, "[*]" + f.path})
+			}
+		}
+	}
+	if len(fields) == 0 {
+		fields = []*field{{typ: t}}
+	}
+	a.flattened.Set(t, fields)
+	return fields
+}
+
+// sizeof returns the number of nodes of a value of type t.
+func (a *analysis) sizeof(t types.Type) int { return len(a.flatten(t)) }
+
+// offsetOf returns the offset of field i of struct type t.
+func (a *analysis) offsetOf(t types.Type, i int) int {
+	s := typeparams.CoreType(t).(*types.Struct)
+	offset := 0
+	for j := 0; j < i; j++ {
+		offset += a.sizeof(s.Field(j).Type())
+	}
+	return offset
+}
+
+// tupleOffset returns the offset of component i of tuple t.
+func (a *analysis) tupleOffset(t *types.Tuple, i int) int {
+	offset := 0
+	for j := 0; j < i; j++ {
+		offset += a.sizeof(t.At(j).Type())
+	}
+	return offset
+}
+
+// newBlock returns the first of a new block of nodes for a value of
+// type t, which is not part of an object.
+func (a *analysis) newBlock(t types.Type) nodeid {
+	start := nodeid(len(a.nodes))
+	for _, f := range a.flatten(t) {
+		a.nodes = append(a.nodes, &node{typ: f.typ})
+	}
+	return start
+}
+
+// newObject returns a new object allocated at site, whose contents
+// have the specified fields.
+func (a *analysis) newObject(fields []*field, typ types.Type, site ssa.Value) *object {
+	obj := &object{
+		start:  nodeid(len(a.nodes)),
+		size:   len(fields),
+		typ:    typ,
+		site:   site,
+		fields: fields,
+	}
+	for _, f := range fields {
+		a.nodes = append(a.nodes, &node{obj: obj, typ: f.typ})
+	}
+	return obj
+}
+
+// prefix returns the fields of type t, each prefixed by path.
+func (a *analysis) prefix(path string, t types.Type) []*field {
+	var fields []*field
+	for _, f := range a.flatten(t) {
+		fields = append(fields, &field{f.typ, path + f.path})
+	}
+	return fields
+}
+
+// label returns the label of location id, which must belong to an object.
+func (a *analysis) label(id nodeid) *Label {
+	obj := a.nodes[id].obj
+	var path string
+	if id > obj.start {
+		path = obj.fields[id-obj.start].path
+	}
+	return &Label{obj: obj, path: path, fset: a.prog.Fset}
+}
+
+// inBounds reports whether the size nodes at the given offset from
+// location loc lie within the object containing loc.
+func (a *analysis) inBounds(loc nodeid, offset, size int) bool {
+	obj := a.nodes[loc].obj
+	return obj != nil && offset >= 0 && loc+nodeid(offset+size) <= obj.start+nodeid(obj.size)
+}
+
+// addLoc adds location loc to the points-to set of node n.
+func (a *analysis) addLoc(n, loc nodeid) {
+	nd := a.nodes[n]
+	if !nd.pts.Insert(int(loc)) {
+		return
+	}
+	if nd.delta.IsEmpty() {
+		a.worklist = append(a.worklist, n)
+	}
+	nd.delta.Insert(int(loc))
+}
+
+// addLocs adds the locations in set to the points-to set of node n.
+func (a *analysis) addLocs(n nodeid, set *intsets.Sparse) {
+	nd := a.nodes[n]
+	var diff intsets.Sparse
+	diff.Difference(set, &nd.pts)
+	if diff.IsEmpty() {
+		return
+	}
+	nd.pts.UnionWith(&diff)
+	if nd.delta.IsEmpty() {
+		a.worklist = append(a.worklist, n)
+	}
+	nd.delta.UnionWith(&diff)
+}
+
+// addEdge adds the constraint pts(dst) ⊇ pts(src).
+func (a *analysis) addEdge(src, dst nodeid) {
+	if src == dst {
+		return
+	}
+	nd := a.nodes[src]
+	if nd.succSet == nil {
+		nd.succSet = make(map[nodeid]bool)
+	}
+	if nd.succSet[dst] {
+		return
+	}
+	nd.succSet[dst] = true
+	nd.succs = append(nd.succs, dst)
+	a.addLocs(dst, &nd.pts)
+}
+
+// copy adds the constraints that the size nodes starting at dst
+// include the points-to sets of the corresponding nodes at src.
+func (a *analysis) copy(src, dst nodeid, size int) {
+	for i := 0; i < size; i++ {
+		if CanPoint(a.nodes[dst+nodeid(i)].typ) {
+			a.addEdge(src+nodeid(i), dst+nodeid(i))
+		}
+	}
+}
+
+// watch arranges for f to be called for each location in the
+// points-to set of node n.
+func (a *analysis) watch(n nodeid, f func(loc nodeid)) {
+	nd := a.nodes[n]
+	nd.watchers = append(nd.watchers, f)
+	for _, loc := range nd.pts.AppendTo(nil) {
+		f(nodeid(loc))
+	}
+}
+
+// load adds the constraint that the size nodes starting at dst
+// include the values of the nodes at offset from each location to
+// which src points, as for dst = *(src+offset).
+func (a *analysis) load(dst, src nodeid, offset, size int) {
+	a.watch(src, func(loc nodeid) {
+		if a.inBounds(loc, offset, size) {
+			a.copy(loc+nodeid(offset), dst, size)
+		}
+	})
+}
+
+// store adds the constraint that the nodes at offset from each
+// location to which dst points include the values of the size nodes
+// starting at src, as for *(dst+offset) = src.
+func (a *analysis) store(dst, src nodeid, offset, size int) {
+	a.watch(dst, func(loc nodeid) {
+		if a.inBounds(loc, offset, size) {
+			a.copy(src, loc+nodeid(offset), size)
+		}
+	})
+}
+
+// offsetAddr adds the constraint that dst points to the location at
+// offset from each location to which src points, as for
+// dst = &src.f.
+func (a *analysis) offsetAddr(dst, src nodeid, offset int) {
+	if offset == 0 {
+		a.addEdge(src, dst)
+		return
+	}
+	a.watch(src, func(loc nodeid) {
+		if a.inBounds(loc, offset, 1) {
+			a.addLoc(dst, loc+nodeid(offset))
+		}
+	})
+}
+
+// solve propagates locations along edges until a fixed point is reached.
+func (a *analysis) solve() {
+	var (
+		delta intsets.Sparse
+		locs  []int
+	)
+	for len(a.worklist) > 0 {
+		n := a.worklist[len(a.worklist)-1]
+		a.worklist = a.worklist[:len(a.worklist)-1]
+		nd := a.nodes[n]
+		delta.Copy(&nd.delta)
+		nd.delta.Clear()
+		for i := 0; i < len(nd.succs); i++ { // (succs may grow)
+			a.addLocs(nd.succs[i], &delta)
+		}
+		if len(nd.watchers) > 0 {
+			locs = delta.AppendTo(locs[:0])
+			for _, loc := range locs {
+				for i := 0; i < len(nd.watchers); i++ { // (watchers may grow)
+					nd.watchers[i](nodeid(loc))
+				}
+			}
+		}
+	}
+}
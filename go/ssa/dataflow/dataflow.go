@@ -0,0 +1,258 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dataflow provides a framework for monotone dataflow
+// analysis over the control-flow graph of an SSA function.
+//
+// A client describes an analysis by an [Analysis] value, which
+// specifies the direction of the analysis, a [Lattice] of facts,
+// and a transfer function that describes the effect of each
+// instruction on a fact. [Solve] computes the least fixed point of
+// the resulting equations and returns a [Result] holding the facts
+// that hold at the start and end of each basic block.
+//
+// Blocks are visited in an order derived from the dominator tree of
+// the function (see [ssa.Function.DomPreorder]), so that in the
+// common case of a reducible control-flow graph each block is
+// visited after the blocks that flow into it, and few iterations are
+// needed to reach the fixed point.
+//
+// The examples and tests of this package contain implementations of
+// three classic analyses: reaching definitions, liveness of SSA
+// values, and constant propagation.
+package dataflow
+
+import "golang.org/x/tools/go/ssa"
+
+// A Direction specifies the direction in which facts flow through
+// the control-flow graph.
+type Direction int
+
+const (
+	// Forward analyses propagate facts from the entry block to its
+	// successors, and through each block in instruction order.
+	Forward Direction = iota
+
+	// Backward analyses propagate facts from the exit blocks to their
+	// predecessors, and through each block in reverse order.
+	Backward
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Forward:
+		return "Forward"
+	case Backward:
+		return "Backward"
+	}
+	return "Direction(?)"
+}
+
+// A Lattice describes the domain of facts of an analysis, of type F.
+//
+// Join must compute the least upper bound of two facts, and Bottom
+// must be its identity element. To ensure termination, the lattice
+// must have no infinite ascending chains, and the transfer functions
+// of the analysis must be monotone.
+//
+// Facts are treated as values: implementations of Join, and the
+// transfer functions of an [Analysis], must not modify their
+// arguments, though they may return one of them unchanged.
+type Lattice[F any] interface {
+	Bottom() F
+	Join(x, y F) F
+	Equal(x, y F) bool
+}
+
+// An Analysis describes a dataflow analysis whose facts are of type F.
+type Analysis[F any] struct {
+	Direction Direction
+	Lattice   Lattice[F]
+
+	// Boundary is the fact that holds on entry to the function (for
+	// a forward analysis) or on exit from it (for a backward one).
+	// It is joined into the input of the entry and recover blocks,
+	// or of the blocks without successors, respectively.
+	Boundary F
+
+	// Transfer returns the fact that holds after instr, given the
+	// fact that holds before it, where "before" and "after" are
+	// relative to the direction of the analysis.
+	Transfer func(instr ssa.Instruction, fact F) F
+
+	// Edge, if non-nil, returns the fact that flows along the
+	// control-flow edge from block "from" to block "to", given the
+	// fact at the end of "from". As with Transfer, the edge is
+	// relative to the direction of the analysis: for a backward
+	// analysis, "from" is the successor.
+	//
+	// Edge functions are useful for refining facts along the
+	// branches of a conditional, and for handling φ-nodes, whose
+	// operands flow along a single incoming edge.
+	Edge func(from, to *ssa.BasicBlock, fact F) F
+}
+
+// A Result holds the solution of a dataflow analysis of a function.
+type Result[F any] struct {
+	analysis *Analysis[F]
+	fn       *ssa.Function
+	in, out  []F // facts at start and end of each block, indexed by Index
+}
+
+// Func returns the function that was analyzed.
+func (r *Result[F]) Func() *ssa.Function { return r.fn }
+
+// In returns the fact that holds at the start of block b.
+//
+// The start of the block is in program order regardless of the
+// direction of the analysis: for a backward analysis, In is the
+// result of applying the transfer functions to the block.
+func (r *Result[F]) In(b *ssa.BasicBlock) F { return r.in[b.Index] }
+
+// Out returns the fact that holds at the end of block b.
+func (r *Result[F]) Out(b *ssa.BasicBlock) F { return r.out[b.Index] }
+
+// Before returns the fact that holds immediately before instr, in
+// program order. It is computed by applying the transfer functions
+// of the instructions of the block, so its cost is proportional to
+// the length of the block.
+func (r *Result[F]) Before(instr ssa.Instruction) F {
+	b := instr.Block()
+	if r.analysis.Direction == Forward {
+		fact := r.in[b.Index]
+		for _, x := range b.Instrs {
+			if x == instr {
+				break
+			}
+			fact = r.analysis.Transfer(x, fact)
+		}
+		return fact
+	}
+	fact := r.out[b.Index]
+	for i := len(b.Instrs) - 1; i >= 0; i-- {
+		fact = r.analysis.Transfer(b.Instrs[i], fact)
+		if b.Instrs[i] == instr {
+			break
+		}
+	}
+	return fact
+}
+
+// After returns the fact that holds immediately after instr, in
+// program order.
+func (r *Result[F]) After(instr ssa.Instruction) F {
+	b := instr.Block()
+	if r.analysis.Direction == Forward {
+		fact := r.in[b.Index]
+		for _, x := range b.Instrs {
+			fact = r.analysis.Transfer(x, fact)
+			if x == instr {
+				break
+			}
+		}
+		return fact
+	}
+	fact := r.out[b.Index]
+	for i := len(b.Instrs) - 1; i >= 0; i-- {
+		if b.Instrs[i] == instr {
+			break
+		}
+		fact = r.analysis.Transfer(b.Instrs[i], fact)
+	}
+	return fact
+}
+
+// Solve computes the least solution of the dataflow equations of
+// analysis a for function fn, which must have been built.
+//
+// Solve iterates over the blocks of fn until no fact changes. A
+// forward analysis visits blocks in preorder of the dominator tree,
+// and a backward analysis in the reverse of that order.
+func Solve[F any](fn *ssa.Function, a *Analysis[F]) *Result[F] {
+	lat := a.Lattice
+	n := len(fn.Blocks)
+	r := &Result[F]{
+		analysis: a,
+		fn:       fn,
+		in:       make([]F, n),
+		out:      make([]F, n),
+	}
+	for i := 0; i < n; i++ {
+		r.in[i] = lat.Bottom()
+		r.out[i] = lat.Bottom()
+	}
+	if n == 0 {
+		return r // external function
+	}
+
+	order := fn.DomPreorder()
+	if a.Direction == Backward {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+
+	edge := func(from, to *ssa.BasicBlock, fact F) F {
+		if a.Edge != nil {
+			fact = a.Edge(from, to, fact)
+		}
+		return fact
+	}
+
+	// Every block is visited at least once; thereafter a block is
+	// revisited only when the output of one of its neighbors changes.
+	pending := make([]bool, n)
+	for i := range pending {
+		pending[i] = true
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, b := range order {
+			if !pending[b.Index] {
+				continue
+			}
+			pending[b.Index] = false
+
+			fact := lat.Bottom()
+			if a.Direction == Forward {
+				if b.Index == 0 || b == fn.Recover {
+					fact = lat.Join(fact, a.Boundary)
+				}
+				for _, pred := range b.Preds {
+					fact = lat.Join(fact, edge(pred, b, r.out[pred.Index]))
+				}
+				r.in[b.Index] = fact
+				for _, instr := range b.Instrs {
+					fact = a.Transfer(instr, fact)
+				}
+				if !lat.Equal(fact, r.out[b.Index]) {
+					r.out[b.Index] = fact
+					for _, succ := range b.Succs {
+						pending[succ.Index] = true
+					}
+					changed = true
+				}
+			} else {
+				if len(b.Succs) == 0 {
+					fact = lat.Join(fact, a.Boundary)
+				}
+				for _, succ := range b.Succs {
+					fact = lat.Join(fact, edge(succ, b, r.in[succ.Index]))
+				}
+				r.out[b.Index] = fact
+				for i := len(b.Instrs) - 1; i >= 0; i-- {
+					fact = a.Transfer(b.Instrs[i], fact)
+				}
+				if !lat.Equal(fact, r.in[b.Index]) {
+					r.in[b.Index] = fact
+					for _, pred := range b.Preds {
+						pending[pred.Index] = true
+					}
+					changed = true
+				}
+			}
+		}
+	}
+	return r
+}
@@ -0,0 +1,411 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dataflow_test
+
+import (
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/dataflow"
+)
+
+// -- reaching definitions --
+
+// reachingDefinitions returns a forward analysis that computes the
+// set of stores to local variables that may reach each point of a
+// function. It is meaningful only for functions built in
+// [ssa.NaiveForm], in which local variables are not lifted to
+// registers.
+func reachingDefinitions() *dataflow.Analysis[set[*ssa.Store]] {
+	return &dataflow.Analysis[set[*ssa.Store]]{
+		Direction: dataflow.Forward,
+		Lattice:   setLattice[*ssa.Store]{},
+		Transfer: func(instr ssa.Instruction, defs set[*ssa.Store]) set[*ssa.Store] {
+			store, ok := instr.(*ssa.Store)
+			if !ok {
+				return defs
+			}
+			if _, ok := store.Addr.(*ssa.Alloc); !ok {
+				return defs // not a local variable
+			}
+			out := make(set[*ssa.Store])
+			for def := range defs {
+				if def.Addr != store.Addr {
+					out[def] = true
+				}
+			}
+			out[store] = true
+			return out
+		},
+	}
+}
+
+func TestReachingDefinitions(t *testing.T) {
+	const src = `package p
+
+func f(cond bool) int {
+	x := 1
+	if cond {
+		x = 2
+	}
+	return x
+}
+`
+	fn, err := buildFunc(src, "f", ssa.NaiveForm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dataflow.Solve(fn, reachingDefinitions())
+
+	// stores returns the values stored to variable x by the
+	// definitions that reach a point, in sorted order.
+	stores := func(defs set[*ssa.Store]) string {
+		var vals []string
+		for def := range defs {
+			if def.Addr.(*ssa.Alloc).Comment == "x" {
+				vals = append(vals, def.Val.Name())
+			}
+		}
+		sort.Strings(vals)
+		return strings.Join(vals, " ")
+	}
+
+	var checked int
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Store:
+				// Each store to x supersedes the previous ones.
+				if instr.Addr.(*ssa.Alloc).Comment == "x" {
+					if got, want := stores(result.After(instr)), instr.Val.Name(); got != want {
+						t.Errorf("after %s: got stores [%s], want [%s]", instr, got, want)
+					}
+					checked++
+				}
+			case *ssa.UnOp:
+				// Both stores reach the load of x in the return statement.
+				if instr.Op == token.MUL && instr.X.(*ssa.Alloc).Comment == "x" {
+					if got, want := stores(result.Before(instr)), "1:int 2:int"; got != want {
+						t.Errorf("before %s: got stores [%s], want [%s]", instr, got, want)
+					}
+					checked++
+				}
+			}
+		}
+	}
+	if checked != 3 {
+		t.Errorf("checked %d instructions, want 3", checked)
+	}
+}
+
+// -- liveness --
+
+func TestLiveness(t *testing.T) {
+	const src = `package p
+
+func f(a, b int) int {
+	x := a * 2
+	for i := 0; i < b; i++ {
+		x += i
+	}
+	return x
+}
+`
+	fn, err := buildFunc(src, "f", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dataflow.Solve(fn, liveness())
+
+	a, b := fn.Params[0], fn.Params[1]
+	entry := fn.Blocks[0]
+	if live := result.In(entry); !live[a] || !live[b] {
+		t.Errorf("live-in of entry block = %v, want a and b", live)
+	}
+	// a is dead once x has been computed; b is live throughout the loop.
+	for _, blk := range fn.Blocks[1:] {
+		if live := result.In(blk); live[a] {
+			t.Errorf("a is live at start of block %d (%s)", blk.Index, blk.Comment)
+		}
+		if live := result.In(blk); blk.Comment != "for.done" && !live[b] {
+			t.Errorf("b is not live at start of block %d (%s)", blk.Index, blk.Comment)
+		}
+	}
+	if live := result.Out(entry); live[a] {
+		t.Errorf("a is live at end of entry block")
+	}
+}
+
+// -- constant propagation --
+
+// A constFact records the constant values of SSA registers at some
+// point of a function, and whether the point is reachable.
+//
+// A register that is absent from vals is undefined; one whose value
+// in vals is nil is not a constant.
+type constFact struct {
+	reachable bool
+	vals      map[ssa.Value]constant.Value
+}
+
+// value returns the constant value of v, or nil if it is unknown.
+func (f constFact) value(v ssa.Value) constant.Value {
+	if c, ok := v.(*ssa.Const); ok {
+		return c.Value
+	}
+	return f.vals[v]
+}
+
+// with returns a copy of f in which v has value c.
+func (f constFact) with(v ssa.Value, c constant.Value) constFact {
+	vals := make(map[ssa.Value]constant.Value, len(f.vals)+1)
+	for k, x := range f.vals {
+		vals[k] = x
+	}
+	vals[v] = c
+	return constFact{reachable: true, vals: vals}
+}
+
+type constLattice struct{}
+
+func (constLattice) Bottom() constFact { return constFact{} }
+
+func (constLattice) Join(x, y constFact) constFact {
+	if !x.reachable {
+		return y
+	}
+	if !y.reachable {
+		return x
+	}
+	vals := make(map[ssa.Value]constant.Value)
+	for v, c := range x.vals {
+		vals[v] = c
+	}
+	for v, c := range y.vals {
+		if d, ok := vals[v]; ok && !sameConst(c, d) {
+			c = nil
+		}
+		vals[v] = c
+	}
+	return constFact{reachable: true, vals: vals}
+}
+
+func (constLattice) Equal(x, y constFact) bool {
+	if x.reachable != y.reachable || len(x.vals) != len(y.vals) {
+		return false
+	}
+	for v, c := range x.vals {
+		d, ok := y.vals[v]
+		if !ok || (c == nil) != (d == nil) || c != nil && !sameConst(c, d) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameConst(x, y constant.Value) bool {
+	return x != nil && y != nil && x.Kind() == y.Kind() && constant.Compare(x, token.EQL, y)
+}
+
+// constantPropagation returns a forward analysis that computes the
+// constant values of the numeric registers of a function.
+//
+// Its Edge function prunes the branches of conditionals whose
+// condition is constant, and assigns φ-nodes the value of their
+// operand along each edge, so that a φ-node is constant if all its
+// reachable operands agree. This makes it equivalent to the sparse
+// conditional constant propagation algorithm of Wegman and Zadeck.
+func constantPropagation() *dataflow.Analysis[constFact] {
+	return &dataflow.Analysis[constFact]{
+		Direction: dataflow.Forward,
+		Lattice:   constLattice{},
+		Boundary:  constFact{reachable: true},
+		Transfer: func(instr ssa.Instruction, f constFact) constFact {
+			if !f.reachable {
+				return f
+			}
+			switch instr := instr.(type) {
+			case *ssa.BinOp:
+				return f.with(instr, foldBinOp(instr, f.value(instr.X), f.value(instr.Y)))
+			case *ssa.UnOp:
+				var c constant.Value
+				if x := f.value(instr.X); x != nil && instr.Op != token.MUL && instr.Op != token.ARROW {
+					c = checkRange(constant.UnaryOp(instr.Op, x, 0), instr.Type())
+				}
+				return f.with(instr, c)
+			}
+			return f
+		},
+		Edge: func(from, to *ssa.BasicBlock, f constFact) constFact {
+			if !f.reachable {
+				return f
+			}
+			if cond, ok := from.Instrs[len(from.Instrs)-1].(*ssa.If); ok {
+				if c := f.value(cond.Cond); c != nil && c.Kind() == constant.Bool {
+					taken := from.Succs[1]
+					if constant.BoolVal(c) {
+						taken = from.Succs[0]
+					}
+					if to != taken {
+						return constFact{} // unreachable
+					}
+				}
+			}
+			// The φ-nodes of a block are evaluated in parallel,
+			// so their operands are looked up in the original fact.
+			out := f
+			for i, pred := range to.Preds {
+				if pred != from {
+					continue
+				}
+				for _, instr := range to.Instrs {
+					phi, ok := instr.(*ssa.Phi)
+					if !ok {
+						break
+					}
+					out = out.with(phi, f.value(phi.Edges[i]))
+				}
+			}
+			return out
+		},
+	}
+}
+
+// foldBinOp returns the constant value of a binary operation
+// applied to x and y, or nil if it is not a known constant.
+func foldBinOp(e *ssa.BinOp, x, y constant.Value) constant.Value {
+	if x == nil || y == nil {
+		return nil
+	}
+	switch e.Op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		return constant.MakeBool(constant.Compare(x, e.Op, y))
+	case token.SHL, token.SHR:
+		s, ok := constant.Uint64Val(y)
+		if !ok || s >= 64 || x.Kind() != constant.Int {
+			return nil
+		}
+		return checkRange(constant.Shift(x, e.Op, uint(s)), e.Type())
+	case token.QUO, token.REM:
+		if constant.Sign(y) == 0 {
+			return nil // division by zero panics
+		}
+		op := e.Op
+		if op == token.QUO && x.Kind() == constant.Int {
+			op = token.QUO_ASSIGN // integer division
+		}
+		return checkRange(constant.BinaryOp(x, op, y), e.Type())
+	case token.AND_NOT:
+		return checkRange(constant.BinaryOp(x, token.AND_NOT, y), e.Type())
+	}
+	return checkRange(constant.BinaryOp(x, e.Op, y), e.Type())
+}
+
+// checkRange returns c if it is representable by an integer type t,
+// and nil if the operation that produced it may have overflowed.
+// Results of other types are returned unchanged.
+func checkRange(c constant.Value, t types.Type) constant.Value {
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 {
+		return c
+	}
+	if c.Kind() != constant.Int {
+		return nil
+	}
+	size := 8 * types.SizesFor("gc", "amd64").Sizeof(basic)
+	if basic.Info()&types.IsUnsigned != 0 {
+		u, exact := constant.Uint64Val(c)
+		if !exact || size < 64 && u >= 1<<size {
+			return nil
+		}
+		return c
+	}
+	i, exact := constant.Int64Val(c)
+	if !exact || size < 64 && (i < -1<<(size-1) || i >= 1<<(size-1)) {
+		return nil
+	}
+	return c
+}
+
+func TestConstantPropagation(t *testing.T) {
+	const src = `package p
+
+func f() int {
+	x := 3
+	y := x * 4
+	if y > 10 {
+		return y
+	}
+	return 0
+}
+
+func g(n int) int {
+	k := 1
+	i := 0
+	for i < n {
+		k = k * 1
+		i++
+	}
+	return k + i
+}
+
+func h() int8 {
+	x := int8(100)
+	return x + x
+}
+`
+	for _, test := range []struct {
+		name string
+		want []string // constant value of each returned expression
+	}{
+		{"f", []string{"12"}},    // the return 0 statement is unreachable
+		{"g", []string{"<nil>"}}, // i is not a constant
+		{"h", []string{"<nil>"}}, // x + x overflows
+	} {
+		fn, err := buildFunc(src, test.name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := dataflow.Solve(fn, constantPropagation())
+
+		var got []string
+		for _, b := range fn.Blocks {
+			if !result.In(b).reachable {
+				continue
+			}
+			if ret, ok := b.Instrs[len(b.Instrs)-1].(*ssa.Return); ok {
+				got = append(got, fmt.Sprint(result.Before(ret).value(ret.Results[0])))
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got constant results %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// In g, k is constant throughout the loop.
+	fn, err := buildFunc(src, "g", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := dataflow.Solve(fn, constantPropagation())
+	var phis []string
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if phi, ok := instr.(*ssa.Phi); ok {
+				phis = append(phis, fmt.Sprintf("%s=%v", phi.Comment, result.Out(b).value(phi)))
+			}
+		}
+	}
+	sort.Strings(phis)
+	if got, want := strings.Join(phis, " "), "i=<nil> k=1"; got != want {
+		t.Errorf("g: got φ-node values %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dataflow_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/dataflow"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// A set is a fact consisting of a finite set of elements.
+// Sets are never modified once they have been computed.
+type set[T comparable] map[T]bool
+
+// add returns the union of s and the given elements.
+func (s set[T]) add(elems ...T) set[T] {
+	u := make(set[T], len(s)+len(elems))
+	for x := range s {
+		u[x] = true
+	}
+	for _, x := range elems {
+		u[x] = true
+	}
+	return u
+}
+
+// setLattice is the lattice of finite sets ordered by inclusion.
+type setLattice[T comparable] struct{}
+
+func (setLattice[T]) Bottom() set[T] { return nil }
+
+func (setLattice[T]) Join(x, y set[T]) set[T] {
+	if len(x) == 0 {
+		return y
+	}
+	if len(y) == 0 {
+		return x
+	}
+	u := x
+	for e := range y {
+		if !x[e] {
+			if len(u) == len(x) {
+				u = x.add()
+			}
+			u[e] = true
+		}
+	}
+	return u
+}
+
+func (setLattice[T]) Equal(x, y set[T]) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for e := range x {
+		if !y[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// liveness returns a backward analysis that computes the set of SSA
+// values that are live at each point of a function, that is, whose
+// values may be used later.
+//
+// A φ-node uses each of its operands at the end of the
+// corresponding predecessor block, not at the start of its own
+// block, so the operands are added by the Edge function.
+func liveness() *dataflow.Analysis[set[ssa.Value]] {
+	return &dataflow.Analysis[set[ssa.Value]]{
+		Direction: dataflow.Backward,
+		Lattice:   setLattice[ssa.Value]{},
+		Transfer: func(instr ssa.Instruction, live set[ssa.Value]) set[ssa.Value] {
+			live = live.add()
+			if v, ok := instr.(ssa.Value); ok {
+				delete(live, v)
+			}
+			if _, ok := instr.(*ssa.Phi); !ok {
+				for _, op := range instr.Operands(nil) {
+					if isVar(*op) {
+						live[*op] = true
+					}
+				}
+			}
+			return live
+		},
+		Edge: func(succ, pred *ssa.BasicBlock, live set[ssa.Value]) set[ssa.Value] {
+			for i, p := range succ.Preds {
+				if p != pred {
+					continue
+				}
+				for _, instr := range succ.Instrs {
+					phi, ok := instr.(*ssa.Phi)
+					if !ok {
+						break
+					}
+					if isVar(phi.Edges[i]) {
+						live = live.add(phi.Edges[i])
+					}
+				}
+			}
+			return live
+		},
+	}
+}
+
+// isVar reports whether v is a variable of the function,
+// as opposed to a constant or global.
+func isVar(v ssa.Value) bool {
+	switch v.(type) {
+	case *ssa.Parameter, *ssa.FreeVar:
+		return true
+	case ssa.Instruction:
+		return true
+	}
+	return false
+}
+
+// buildFunc builds the SSA form of the named function in the given
+// source file, which must not import other packages.
+func buildFunc(src, name string, mode ssa.BuilderMode) (*ssa.Function, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	pkg := types.NewPackage("p", "")
+	ssapkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, pkg, []*ast.File{f}, mode)
+	if err != nil {
+		return nil, err
+	}
+	fn := ssapkg.Func(name)
+	if fn == nil {
+		return nil, fmt.Errorf("no function %s", name)
+	}
+	return fn, nil
+}
+
+// This example shows how to compute the set of live SSA values at
+// the start of each block of a function.
+func ExampleSolve() {
+	const src = `package p
+
+func sum(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	return s
+}
+`
+	fn, err := buildFunc(src, "sum", 0)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	result := dataflow.Solve(fn, liveness())
+	for _, b := range fn.Blocks {
+		var names []string
+		for v := range result.In(b) {
+			names = append(names, v.Name())
+		}
+		sort.Strings(names)
+		fmt.Printf("%d %s: live-in [%s]\n", b.Index, b.Comment, strings.Join(names, " "))
+	}
+
+	// Output:
+	// 0 entry: live-in [n]
+	// 1 for.loop: live-in [n]
+	// 2 for.body: live-in [n t0 t1]
+	// 3 for.done: live-in [t0]
+}
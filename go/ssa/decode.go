@@ -0,0 +1,771 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines the loading of packages serialized by
+// Package.Encode. See encode.go for a description of the encoding.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math/big"
+	"strings"
+
+	"github.com/troll-zhao/tools/core/gcimporter"
+	"golang.org/x/tools/go/types/objectpath"
+)
+
+// DecodePackage loads into prog a package serialized by
+// [Package.Encode], and returns it. The package is created and built:
+// its members and the bodies of its functions are those of the
+// encoded package, except that the functions have no syntax and their
+// bodies have no DebugRef instructions.
+//
+// The packages to which the package refers, including its imports,
+// must already have been created in prog, from syntax or by an earlier
+// call to DecodePackage, so a client typically decodes packages in
+// order of their dependencies. The package itself must not have been
+// created.
+//
+// A generic function of a decoded package has no syntax from which to
+// build instances, so only the instances that were encoded with the
+// packages that refer to them have bodies. Any other instance built
+// with [InstantiateGenerics], such as one referred to only by a
+// package created from syntax, has no body, like an instance of a
+// generic function loaded from export data.
+//
+// DecodePackage must not be called concurrently with the creation or
+// building of packages of prog. If it fails after the package was
+// created, prog is left in an inconsistent state.
+func (prog *Program) DecodePackage(data []byte) (_ *Package, err error) {
+	var enc encPackage
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&enc); err != nil {
+		return nil, fmt.Errorf("cannot decode package: %v", err)
+	}
+	if enc.Version != encodingVersion {
+		return nil, fmt.Errorf("cannot decode package %s: encoding version %d, want %d", enc.Path, enc.Version, encodingVersion)
+	}
+	if prog.imported[enc.Path] != nil {
+		return nil, fmt.Errorf("cannot decode package %s: package already exists", enc.Path)
+	}
+
+	// Find the types of the packages of prog and their dependencies.
+	known := map[string]*types.Package{"unsafe": types.Unsafe}
+	var visit func(pkg *types.Package)
+	visit = func(pkg *types.Package) {
+		if known[pkg.Path()] == nil {
+			known[pkg.Path()] = pkg
+			for _, imp := range pkg.Imports() {
+				visit(imp)
+			}
+		}
+	}
+	for pkg := range prog.packages {
+		visit(pkg)
+	}
+
+	getPackages := func(items []gcimporter.GetPackagesItem) error {
+		for i, item := range items {
+			if item.Path == enc.Path {
+				items[i].Pkg = types.NewPackage(item.Path, item.Name)
+			} else if pkg := known[item.Path]; pkg != nil {
+				items[i].Pkg = pkg
+			} else {
+				return fmt.Errorf("package %s was not created", item.Path)
+			}
+		}
+		return nil
+	}
+	tpkg, err := gcimporter.IImportShallow(prog.Fset, getPackages, enc.ExportData, enc.Path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode package %s: %v", enc.Path, err)
+	}
+	known[enc.Path] = tpkg
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(codecError); ok {
+				err = fmt.Errorf("cannot decode package %s: %v", enc.Path, e.err)
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	d := &decoder{
+		prog:    prog,
+		enc:     &enc,
+		b:       new(builder),
+		pkgs:    make([]*types.Package, len(enc.Packages)),
+		types:   make([]types.Type, len(enc.Types)),
+		objects: make([]*types.Func, len(enc.Objects)),
+		funcs:   make([]*Function, len(enc.Funcs)),
+		bodies:  make(map[int]*encBody),
+	}
+	for i, ep := range enc.Packages[1:] {
+		pkg := known[ep.Path]
+		if pkg == nil {
+			codecErrorf("package %s was not created", ep.Path)
+		}
+		d.pkgs[1+i] = pkg
+	}
+	for _, ef := range enc.Files {
+		f := prog.Fset.AddFile(ef.Name, -1, ef.Size)
+		if !f.SetLines(ef.Lines) {
+			codecErrorf("invalid line table for file %s", ef.Name)
+		}
+		d.files = append(d.files, f)
+	}
+
+	p := prog.CreatePackage(tpkg, nil, nil, true)
+	p.syntax = enc.Syntax
+	p.decoded = true
+
+	// The declared init functions are not package-level objects.
+	initPos := make(map[string]token.Pos)
+	for i := range enc.Bodies {
+		body := &enc.Bodies[i]
+		if ef := &enc.Funcs[body.Func]; ef.Kind == funcMember {
+			initPos[ef.Name] = d.pos(body.Pos)
+		}
+	}
+	for i := 1; i <= enc.NumInits; i++ {
+		name := fmt.Sprintf("init#%d", i)
+		obj := types.NewFunc(initPos[name], tpkg, "init", new(types.Signature))
+		fn := createFunction(prog, obj, name, nil, nil, "")
+		fn.Pkg = p
+		p.objects[obj] = fn
+		p.Members[name] = fn
+		p.ninit++
+	}
+
+	// Replace the bodies of the package's functions by those
+	// decoded, and build them.
+	for i := range enc.Bodies {
+		body := &enc.Bodies[i]
+		d.bodies[body.Func] = body
+	}
+	for i := range enc.Bodies {
+		body := &enc.Bodies[i]
+		if kind := enc.Funcs[body.Func].Kind; kind != funcMember && kind != funcMethod {
+			continue // instance
+		}
+		fn := d.function(body.Func)
+		if fn.Pkg != p || fn.build == nil {
+			codecErrorf("function %s is not declared by the package", fn)
+		}
+		d.setBody(fn, body)
+		d.b.enqueue(fn)
+	}
+	d.b.iterate()
+
+	// The package is now built.
+	p.buildOnce.Do(func() {})
+	p.created = nil
+	p.initVersion = nil
+
+	if prog.mode&SanityCheckFunctions != 0 {
+		sanityCheckPackage(p)
+	}
+	return p, nil
+}
+
+type decoder struct {
+	prog    *Program
+	enc     *encPackage
+	b       *builder // builder of the decoded functions and those they create
+	files   []*token.File
+	pkgs    []*types.Package
+	types   []types.Type
+	objects []*types.Func
+	funcs   []*Function
+	bodies  map[int]*encBody // bodies of non-anonymous functions, by reference
+}
+
+// setBody arranges for the body of fn to be decoded from body when fn
+// is built.
+func (d *decoder) setBody(fn *Function, body *encBody) {
+	fn.Synthetic = body.Synthetic
+	fn.build = func(_ *builder, fn *Function) {
+		d.body(fn, body)
+	}
+}
+
+func (d *decoder) pos(pos encPos) token.Pos {
+	if pos.File == 0 {
+		return token.NoPos
+	}
+	return d.files[pos.File-1].Pos(pos.Offset)
+}
+
+func (d *decoder) pkg(i int) *types.Package {
+	return d.pkgs[i]
+}
+
+// ssaPackage returns the created package of the i'th package.
+func (d *decoder) ssaPackage(i int) *Package {
+	p := d.prog.packages[d.pkg(i)]
+	if p == nil {
+		codecErrorf("package %s was not created", d.enc.Packages[i].Path)
+	}
+	return p
+}
+
+// lookup returns the object of the specified name declared at package
+// level in the i'th package, or in the universe.
+func (d *decoder) lookup(i int, name string) types.Object {
+	var obj types.Object
+	if pkg := d.pkg(i); pkg != nil {
+		obj = pkg.Scope().Lookup(name)
+	} else {
+		obj = types.Universe.Lookup(name)
+	}
+	if obj == nil {
+		codecErrorf("no declaration of %s in package %s", name, d.enc.Packages[i].Path)
+	}
+	return obj
+}
+
+func (d *decoder) typ(i int) types.Type {
+	if i == 0 {
+		return nil
+	}
+	if t := d.types[i]; t != nil {
+		return t
+	}
+	et := &d.enc.Types[i]
+	var t types.Type
+	switch et.Kind {
+	case typeBasic:
+		t = types.Typ[et.Basic]
+		if t.(*types.Basic).Name() != et.Name {
+			t = types.Universe.Lookup(et.Name).Type() // byte or rune
+		}
+
+	case typePointer:
+		t = types.NewPointer(d.typ(et.Elem))
+
+	case typeSlice:
+		t = types.NewSlice(d.typ(et.Elem))
+
+	case typeArray:
+		t = types.NewArray(d.typ(et.Elem), et.Len)
+
+	case typeMap:
+		t = types.NewMap(d.typ(et.Key), d.typ(et.Elem))
+
+	case typeChan:
+		t = types.NewChan(et.Dir, d.typ(et.Elem))
+
+	case typeStruct:
+		var fields []*types.Var
+		for _, v := range et.Vars {
+			fields = append(fields, types.NewField(token.NoPos, d.pkg(v.Pkg), v.Name, d.typ(v.Type), v.Embedded))
+		}
+		t = types.NewStruct(fields, et.Tags)
+
+	case typeTuple:
+		t = types.NewTuple(d.vars(et.Vars)...)
+
+	case typeSignature:
+		t = types.NewSignatureType(nil, nil, nil, d.tuple(et.Params), d.tuple(et.Results), et.Variadic)
+
+	case typeInterface:
+		var methods []*types.Func
+		for _, v := range et.Vars {
+			// NewInterfaceType sets the receiver of the
+			// signature, which may be shared, so copy it.
+			sig := d.typ(v.Type).(*types.Signature)
+			sig = types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+			methods = append(methods, types.NewFunc(token.NoPos, d.pkg(v.Pkg), v.Name, sig))
+		}
+		var embeddeds []types.Type
+		for _, e := range et.Types {
+			embeddeds = append(embeddeds, d.typ(e))
+		}
+		t = types.NewInterfaceType(methods, embeddeds).Complete()
+
+	case typeUnion:
+		t = types.NewUnion(d.terms(et.Terms))
+
+	case typeNamed:
+		tname, ok := d.lookup(et.Pkg, et.Name).(*types.TypeName)
+		if !ok {
+			codecErrorf("%s is not a type", et.Name)
+		}
+		t = tname.Type()
+		if len(et.Types) > 0 {
+			inst, err := types.Instantiate(d.prog.ctxt, t, d.typeList(et.Types), false)
+			if err != nil {
+				codecErrorf("%v", err)
+			}
+			t = inst
+		}
+
+	case typeLocal:
+		obj := types.NewTypeName(d.pos(et.Pos), d.pkg(et.Pkg), et.Name, nil)
+		named := types.NewNamed(obj, nil, nil)
+		d.types[i] = named // before recursion
+		named.SetUnderlying(d.typ(et.Elem))
+		return named
+
+	case typeAlias:
+		tname, ok := d.lookup(et.Pkg, et.Name).(*types.TypeName)
+		if !ok {
+			codecErrorf("%s is not a type", et.Name)
+		}
+		t = tname.Type()
+
+	case typeParam:
+		obj, err := objectpath.Object(d.pkg(et.Pkg), objectpath.Path(et.Path))
+		if err != nil {
+			codecErrorf("type parameter: %v", err)
+		}
+		t = obj.Type()
+
+	case typeOpaque:
+		switch et.Name {
+		case "iter":
+			t = tRangeIter
+		case "deferStack":
+			t = tDeferStack
+		default:
+			codecErrorf("unexpected opaque type %s", et.Name)
+		}
+
+	default:
+		codecErrorf("invalid type kind %d", et.Kind)
+	}
+	d.types[i] = t
+	return t
+}
+
+func (d *decoder) typeList(list []int) []types.Type {
+	var ts []types.Type
+	for _, i := range list {
+		ts = append(ts, d.typ(i))
+	}
+	return ts
+}
+
+func (d *decoder) tuple(i int) *types.Tuple {
+	t, _ := d.typ(i).(*types.Tuple)
+	return t
+}
+
+func (d *decoder) vars(list []encVar) []*types.Var {
+	var vars []*types.Var
+	for _, v := range list {
+		vars = append(vars, types.NewParam(token.NoPos, d.pkg(v.Pkg), v.Name, d.typ(v.Type)))
+	}
+	return vars
+}
+
+func (d *decoder) terms(list []encTerm) []*types.Term {
+	var terms []*types.Term
+	for _, term := range list {
+		terms = append(terms, types.NewTerm(term.Tilde, d.typ(term.Type)))
+	}
+	return terms
+}
+
+func (d *decoder) object(i int) *types.Func {
+	if obj := d.objects[i]; obj != nil {
+		return obj
+	}
+	eo := &d.enc.Objects[i]
+	var obj types.Object
+	switch {
+	case eo.Origin > 0:
+		orig := d.object(eo.Origin - 1)
+		obj = d.prog.canon.instantiateMethod(orig, d.typeList(eo.TypeArgs), d.prog.ctxt)
+	case eo.Path != "":
+		var err error
+		obj, err = objectpath.Object(d.pkg(eo.Pkg), objectpath.Path(eo.Path))
+		if err != nil {
+			codecErrorf("%v", err)
+		}
+	default:
+		obj, _, _ = types.LookupFieldOrMethod(d.typ(eo.Recv), true, d.pkg(eo.Pkg), eo.Name)
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		codecErrorf("method %s not found", eo.Name)
+	}
+	d.objects[i] = fn
+	return fn
+}
+
+// function returns the function denoted by the i'th reference,
+// creating it if necessary.
+func (d *decoder) function(i int) *Function {
+	if fn := d.funcs[i]; fn != nil {
+		return fn
+	}
+	ef := &d.enc.Funcs[i]
+	var fn *Function
+	switch ef.Kind {
+	case funcMember:
+		fn, _ = d.ssaPackage(ef.Pkg).Members[ef.Name].(*Function)
+		if fn == nil {
+			codecErrorf("no function %s in package %s", ef.Name, d.enc.Packages[ef.Pkg].Path)
+		}
+
+	case funcMethod:
+		fn = d.prog.objectMethod(d.object(ef.Object), d.b)
+
+	case funcAnon:
+		parent := d.function(ef.Parent)
+		if ef.Index >= len(parent.AnonFuncs) {
+			codecErrorf("%s has no anonymous function #%d", parent, ef.Index+1)
+		}
+		fn = parent.AnonFuncs[ef.Index]
+
+	case funcInstance:
+		origin := d.function(ef.Parent)
+		if origin.generic == nil {
+			codecErrorf("%s is not generic", origin)
+		}
+		n := len(d.b.fns)
+		fn = origin.instance(d.typeList(ef.TypeArgs), d.b)
+		// A new instance of an origin without syntax would
+		// have no body; use the encoded one.
+		if len(d.b.fns) > n && d.b.fns[n] == fn && origin.syntax == nil && strings.HasPrefix(fn.Synthetic, "instance of ") {
+			if body := d.bodies[i]; body != nil {
+				d.setBody(fn, body)
+			} else {
+				fn.build = (*builder).buildParamsOnly // it had no body when encoded
+			}
+		}
+
+	case funcWrapper:
+		obj := d.object(ef.Object)
+		sel := d.prog.MethodSets.MethodSet(d.typ(ef.Recv)).Lookup(obj.Pkg(), obj.Name())
+		if sel == nil {
+			codecErrorf("no method %s in method set of %s", obj.Name(), d.typ(ef.Recv))
+		}
+		fn = d.prog.MethodValue(sel)
+		if fn == nil {
+			codecErrorf("no method value for %s", sel)
+		}
+
+	case funcThunk:
+		fn = createThunk(d.prog, &selection{
+			kind:     types.MethodExpr,
+			recv:     d.typ(ef.Recv),
+			typ:      d.typ(ef.Type),
+			obj:      d.object(ef.Object),
+			index:    ef.Path,
+			indirect: ef.Indirect,
+		})
+		d.b.enqueue(fn)
+
+	case funcBound:
+		fn = createBound(d.prog, d.object(ef.Object))
+		d.b.enqueue(fn)
+
+	default:
+		codecErrorf("invalid function kind %d", ef.Kind)
+	}
+	d.funcs[i] = fn
+	return fn
+}
+
+func (d *decoder) constant(i int) *Const {
+	ec := &d.enc.Consts[i]
+	var val constant.Value
+	if ec.Value != nil {
+		val = decodeValue(ec.Value)
+	}
+	return NewConst(val, d.typ(ec.Type))
+}
+
+func decodeValue(ev *encValue) constant.Value {
+	switch ev.Kind {
+	case constant.Bool:
+		return constant.MakeBool(ev.Bool)
+	case constant.String:
+		return constant.MakeString(ev.Text)
+	case constant.Int:
+		x, ok := new(big.Int).SetString(ev.Text, 10)
+		if !ok {
+			codecErrorf("invalid integer constant %q", ev.Text)
+		}
+		return constant.Make(x)
+	case constant.Float:
+		if ev.Float != nil {
+			x := new(big.Float)
+			if err := x.GobDecode(ev.Float); err != nil {
+				codecErrorf("invalid float constant: %v", err)
+			}
+			return constant.Make(x)
+		}
+		x, ok := new(big.Rat).SetString(ev.Text)
+		if !ok {
+			codecErrorf("invalid float constant %q", ev.Text)
+		}
+		return constant.Make(x)
+	case constant.Complex:
+		re := decodeValue(ev.Real)
+		im := constant.MakeImag(decodeValue(ev.Imag))
+		return constant.BinaryOp(re, token.ADD, im)
+	}
+	return constant.MakeUnknown()
+}
+
+func (d *decoder) global(i int) *Global {
+	eg := &d.enc.Globals[i]
+	g, _ := d.ssaPackage(eg.Pkg).Members[eg.Name].(*Global)
+	if g == nil {
+		codecErrorf("no global %s in package %s", eg.Name, d.enc.Packages[eg.Pkg].Path)
+	}
+	return g
+}
+
+func (d *decoder) builtin(i int) *Builtin {
+	eb := &d.enc.Builtins[i]
+	if eb.Name == vDeferStack.name {
+		return vDeferStack
+	}
+	return &Builtin{name: eb.Name, sig: d.typ(eb.Type).(*types.Signature)}
+}
+
+// body decodes the body of fn, and those of its anonymous functions.
+func (d *decoder) body(fn *Function, body *encBody) {
+	for _, ea := range body.AnonFuncs {
+		anon := &Function{
+			name:       ea.Name,
+			Signature:  d.typ(ea.Signature).(*types.Signature),
+			pos:        d.pos(ea.Pos),
+			Synthetic:  ea.Synthetic,
+			parent:     fn,
+			anonIdx:    int32(len(fn.AnonFuncs)),
+			Pkg:        fn.Pkg,
+			Prog:       fn.Prog,
+			typeparams: fn.typeparams, // share the parent's type parameters.
+			typeargs:   fn.typeargs,   // share the parent's type arguments.
+		}
+		fn.AnonFuncs = append(fn.AnonFuncs, anon)
+	}
+
+	// Parameters are the variables of the signature, if they match.
+	var sigVars []*types.Var
+	if recv := fn.Signature.Recv(); recv != nil {
+		sigVars = append(sigVars, recv)
+	}
+	for i := 0; i < fn.Signature.Params().Len(); i++ {
+		sigVars = append(sigVars, fn.Signature.Params().At(i))
+	}
+	for i, ep := range body.Params {
+		var obj *types.Var
+		if len(sigVars) == len(body.Params) {
+			obj = sigVars[i]
+		} else {
+			obj = types.NewParam(token.NoPos, nil, ep.Name, d.typ(ep.Type))
+		}
+		fn.Params = append(fn.Params, &Parameter{name: ep.Name, object: obj, typ: d.typ(ep.Type), parent: fn})
+	}
+	for _, efv := range body.FreeVars {
+		fn.FreeVars = append(fn.FreeVars, &FreeVar{name: efv.Name, typ: d.typ(efv.Type), pos: d.pos(efv.Pos), parent: fn})
+	}
+
+	// Create the blocks and instructions, then set the operands,
+	// which may refer to later instructions.
+	var instrs []Instruction
+	for _, eblock := range body.Blocks {
+		b := &BasicBlock{Index: len(fn.Blocks), Comment: eblock.Comment, parent: fn}
+		b.Succs = b.succs2[:0]
+		fn.Blocks = append(fn.Blocks, b)
+	}
+	for i, eblock := range body.Blocks {
+		b := fn.Blocks[i]
+		for _, pred := range eblock.Preds {
+			b.Preds = append(b.Preds, fn.Blocks[pred])
+		}
+		for _, succ := range eblock.Succs {
+			b.Succs = append(b.Succs, fn.Blocks[succ])
+		}
+		for j := range eblock.Instrs {
+			instr := d.newInstr(&eblock.Instrs[j])
+			instr.setBlock(b)
+			b.Instrs = append(b.Instrs, instr)
+			instrs = append(instrs, instr)
+		}
+	}
+	operand := func(eo encOperand) Value {
+		switch eo.Kind {
+		case 0:
+			return nil
+		case valueInstr:
+			if v, ok := instrs[eo.Index].(Value); ok {
+				return v
+			}
+		case valueParam:
+			return fn.Params[eo.Index]
+		case valueFreeVar:
+			return fn.FreeVars[eo.Index]
+		case valueConst:
+			return d.constant(eo.Index)
+		case valueGlobal:
+			return d.global(eo.Index)
+		case valueFunc:
+			return d.function(eo.Index)
+		case valueBuiltin:
+			return d.builtin(eo.Index)
+		}
+		codecErrorf("%s: invalid operand", fn)
+		return nil
+	}
+	var rands []*Value
+	k := 0
+	for _, eblock := range body.Blocks {
+		for j := range eblock.Instrs {
+			ei := &eblock.Instrs[j]
+			instr := instrs[k]
+			k++
+			rands = instr.Operands(rands[:0])
+			if len(rands) != len(ei.Rands) {
+				codecErrorf("%s: %T has %d operands, want %d", fn, instr, len(ei.Rands), len(rands))
+			}
+			for r, rand := range rands {
+				*rand = operand(ei.Rands[r])
+			}
+			if mi, ok := instr.(*MakeInterface); ok {
+				// Record the runtime types, as does emitConv.
+				if t := mi.X.Type(); fn.typeparams.Len() == 0 || !fn.Prog.isParameterized(t) {
+					addMakeInterfaceType(fn.Prog, t)
+				}
+			}
+		}
+	}
+	for _, l := range body.Locals {
+		fn.Locals = append(fn.Locals, instrs[l].(*Alloc))
+	}
+	if body.Recover > 0 {
+		fn.Recover = fn.Blocks[body.Recover-1]
+	}
+
+	for i, anon := range fn.AnonFuncs {
+		d.body(anon, &body.AnonFuncs[i])
+	}
+
+	buildReferrers(fn)
+	if len(fn.Blocks) > 0 {
+		buildDomTree(fn)
+	}
+	numberRegisters(fn)
+}
+
+// newInstr returns a new instruction encoded by ei, without its
+// operands.
+func (d *decoder) newInstr(ei *encInstr) Instruction {
+	call := func(c *CallCommon, pos token.Pos) {
+		c.Args = make([]Value, ei.N)
+		c.pos = pos
+		if ei.Method > 0 {
+			c.Method = d.object(ei.Method - 1)
+		}
+	}
+	pos := d.pos(ei.Pos)
+	var instr Instruction
+	switch ei.Op {
+	case opAlloc:
+		instr = &Alloc{Comment: ei.Comment, Heap: ei.Flag}
+	case opPhi:
+		instr = &Phi{Comment: ei.Comment, Edges: make([]Value, ei.N)}
+	case opCall:
+		v := &Call{}
+		call(&v.Call, pos)
+		instr = v
+	case opBinOp:
+		instr = &BinOp{Op: ei.Token}
+	case opUnOp:
+		instr = &UnOp{Op: ei.Token, CommaOk: ei.Flag}
+	case opChangeType:
+		instr = &ChangeType{}
+	case opConvert:
+		instr = &Convert{}
+	case opMultiConvert:
+		instr = &MultiConvert{from: d.terms(ei.From), to: d.terms(ei.To)}
+	case opChangeInterface:
+		instr = &ChangeInterface{}
+	case opSliceToArrayPointer:
+		instr = &SliceToArrayPointer{}
+	case opMakeInterface:
+		instr = &MakeInterface{}
+	case opMakeClosure:
+		instr = &MakeClosure{Bindings: make([]Value, ei.N)}
+	case opMakeMap:
+		instr = &MakeMap{}
+	case opMakeChan:
+		instr = &MakeChan{}
+	case opMakeSlice:
+		instr = &MakeSlice{}
+	case opSlice:
+		instr = &Slice{}
+	case opFieldAddr:
+		instr = &FieldAddr{Field: ei.Index}
+	case opField:
+		instr = &Field{Field: ei.Index}
+	case opIndexAddr:
+		instr = &IndexAddr{}
+	case opIndex:
+		instr = &Index{}
+	case opLookup:
+		instr = &Lookup{CommaOk: ei.Flag}
+	case opSelect:
+		v := &Select{Blocking: ei.Flag}
+		for _, st := range ei.States {
+			v.States = append(v.States, &SelectState{Dir: st.Dir, Pos: d.pos(st.Pos)})
+		}
+		instr = v
+	case opRange:
+		instr = &Range{}
+	case opNext:
+		instr = &Next{IsString: ei.Flag}
+	case opTypeAssert:
+		instr = &TypeAssert{AssertedType: d.typ(ei.Asserted), CommaOk: ei.Flag}
+	case opExtract:
+		instr = &Extract{Index: ei.Index}
+	case opJump:
+		instr = &Jump{}
+	case opIf:
+		instr = &If{}
+	case opReturn:
+		instr = &Return{Results: make([]Value, ei.N), pos: pos}
+	case opRunDefers:
+		instr = &RunDefers{}
+	case opPanic:
+		instr = &Panic{pos: pos}
+	case opGo:
+		v := &Go{pos: pos}
+		call(&v.Call, d.pos(ei.CallPos))
+		instr = v
+	case opDefer:
+		v := &Defer{pos: pos}
+		call(&v.Call, d.pos(ei.CallPos))
+		instr = v
+	case opSend:
+		instr = &Send{pos: pos}
+	case opStore:
+		instr = &Store{pos: pos}
+	case opMapUpdate:
+		instr = &MapUpdate{pos: pos}
+	default:
+		codecErrorf("invalid instruction kind %d", ei.Op)
+	}
+	if r, ok := instr.(interface {
+		setType(types.Type)
+		setPos(token.Pos)
+	}); ok {
+		r.setType(d.typ(ei.Type))
+		r.setPos(pos)
+	}
+	return instr
+}
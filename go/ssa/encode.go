@@ -0,0 +1,975 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines the serialization of built packages, which lets a
+// client save the SSA form of a package, for example in a cache keyed
+// by a digest of its source and dependencies, and later load it into
+// another Program without type-checking or building it again.
+// See decode.go for the inverse operation.
+//
+// A package is encoded as a gob-encoded encPackage. The types of its
+// package-level declarations are carried by the shallow export data
+// of the package, which refers to other packages by object path. The
+// bodies of its functions are encoded instruction by instruction, in
+// terms of tables of the types, functions, constants, globals and
+// other entities to which they refer. Named types, methods and type
+// parameters are referred to symbolically, by their package and name
+// or object path; other types are recorded by their structure.
+//
+// Functions are referred to symbolically too: package-level functions
+// and methods by their package and name or object; anonymous functions
+// by their enclosing function and index; instances of generic
+// functions by their origin and type arguments; and synthetic
+// wrappers by the method selection they implement. The decoder finds
+// or creates each one in the decoding Program. The bodies of wrappers
+// are built there again, but those of the instances referred to by
+// the package are encoded with it, since their origin in the decoding
+// Program may not have syntax from which to build them.
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/troll-zhao/tools/core/aliases"
+	"github.com/troll-zhao/tools/core/gcimporter"
+	"golang.org/x/tools/go/types/objectpath"
+)
+
+// encodingVersion is the version of the encoding of packages.
+// Increase it with each change to the encoding.
+const encodingVersion = 1
+
+// Encode returns a serialization of the SSA form of package p, which
+// [Program.DecodePackage] loads into another Program. The package must
+// have been built, by [Package.Build] or [Program.Build], or created
+// without syntax.
+//
+// The encoding includes the bodies of the package's functions and
+// methods, including the package initializer, and of the instances of
+// generic functions to which they refer. It does not include DebugRef
+// instructions, nor the syntax of the functions.
+//
+// The encoding is specific to the version of this package that
+// produced it. A client that saves it in the file system should
+// include a digest of the executable in the key, as well as the
+// digests of the package and of its dependencies.
+//
+// Encode must not be called concurrently with the building of
+// functions to which the package refers.
+func (p *Package) Encode() (data []byte, err error) {
+	if p.info != nil {
+		return nil, fmt.Errorf("cannot encode package %s: it is not built", p.Pkg.Path())
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(codecError); ok {
+				err = fmt.Errorf("cannot encode package %s: %v", p.Pkg.Path(), e.err)
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	exportData, err := gcimporter.IExportShallowAll(p.Prog.Fset, p.Pkg, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode package %s: %v", p.Pkg.Path(), err)
+	}
+
+	e := &encoder{
+		prog:     p.Prog,
+		pkgs:     make(map[*types.Package]int),
+		files:    make(map[*token.File]int),
+		types:    make(map[types.Type]int),
+		objects:  make(map[*types.Func]int),
+		funcs:    make(map[*Function]int),
+		consts:   make(map[*Const]int),
+		globals:  make(map[*Global]int),
+		builtins: make(map[encBuiltin]int),
+		out: &encPackage{
+			Version:    encodingVersion,
+			Path:       p.Pkg.Path(),
+			Name:       p.Pkg.Name(),
+			Syntax:     p.syntax,
+			ExportData: exportData,
+			NumInits:   int(p.ninit),
+			Packages:   []encPkg{{}},  // the nil package
+			Types:      []encType{{}}, // the nil type
+		},
+	}
+
+	// Encode the package's functions and methods, in a
+	// deterministic order.
+	seen := make(map[*Function]bool)
+	var fns []*Function
+	addFunc := func(fn *Function) {
+		if !seen[fn] {
+			seen[fn] = true
+			fns = append(fns, fn)
+		}
+	}
+	for _, mem := range p.Members {
+		if fn, ok := mem.(*Function); ok {
+			addFunc(fn)
+		}
+	}
+	for _, mem := range p.objects {
+		if fn, ok := mem.(*Function); ok && fn.Signature.Recv() != nil {
+			addFunc(fn)
+		}
+	}
+	sort.Slice(fns, func(i, j int) bool { return fns[i].String() < fns[j].String() })
+	for _, fn := range fns {
+		e.body(fn)
+	}
+
+	// Encode the instances to which they refer, transitively.
+	for len(e.queue) > 0 {
+		fn := e.queue[0]
+		e.queue = e.queue[1:]
+		e.body(fn)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(e.out); err != nil {
+		return nil, fmt.Errorf("cannot encode package %s: %v", p.Pkg.Path(), err)
+	}
+	return buf.Bytes(), nil
+}
+
+// -- encoding format --
+
+// An encPackage is the encoding of a Package.
+//
+// References to the Packages and Types tables are indices, the zero
+// index denoting nil; references to other tables are plain indices.
+type encPackage struct {
+	Version    int
+	Path, Name string
+	Syntax     bool   // package was loaded from syntax
+	ExportData []byte // shallow export data of all declarations
+	NumInits   int    // number of declared init functions
+	Files      []encFile
+	Packages   []encPkg
+	Types      []encType
+	Objects    []encObject
+	Funcs      []encFunc
+	Consts     []encConst
+	Globals    []encGlobal
+	Builtins   []encBuiltin
+	Bodies     []encBody // of package-level functions, methods and instances
+}
+
+type encFile struct {
+	Name  string
+	Size  int
+	Lines []int
+}
+
+// An encPos is a position in a file of the Files table.
+// The zero value denotes token.NoPos.
+type encPos struct {
+	File   int // index+1
+	Offset int
+}
+
+type encPkg struct {
+	Path, Name string
+}
+
+// Kinds of encType.
+const (
+	_ = iota
+	typeBasic
+	typePointer
+	typeSlice
+	typeArray
+	typeMap
+	typeChan
+	typeStruct
+	typeTuple
+	typeSignature
+	typeInterface
+	typeUnion
+	typeNamed  // package-level named type, or an instance of one
+	typeLocal  // named type declared within a function
+	typeAlias  // package-level alias
+	typeParam  // type parameter
+	typeOpaque // one of the builder's opaque types
+)
+
+// An encType is the encoding of a type.
+type encType struct {
+	Kind     int
+	Basic    types.BasicKind
+	Name     string
+	Pkg      int
+	Path     string        // object path of a type parameter
+	Pos      encPos        // of a local named type
+	Elem     int           // element type, or underlying type of a local named type
+	Key      int           // map key type
+	Len      int64         // array length
+	Dir      types.ChanDir // channel direction
+	Vars     []encVar      // struct fields, tuple elements, or interface methods
+	Tags     []string      // struct tags
+	Params   int           // signature parameters
+	Results  int           // signature results
+	Variadic bool
+	Types    []int     // type arguments, or embedded interface types
+	Terms    []encTerm // union terms
+}
+
+type encVar struct {
+	Name     string
+	Pkg      int
+	Type     int
+	Embedded bool
+}
+
+type encTerm struct {
+	Tilde bool
+	Type  int
+}
+
+// An encObject is the encoding of a method, which is either the
+// instance of a generic method, an object identified by its object
+// path, or a method found by looking up its name in a receiver type.
+type encObject struct {
+	Origin   int // index+1 of the generic method, if an instance
+	TypeArgs []int
+	Pkg      int
+	Path     string
+	Recv     int
+	Name     string
+}
+
+// Kinds of encFunc.
+const (
+	_            = iota
+	funcMember   // package-level function
+	funcMethod   // declared method
+	funcAnon     // anonymous function
+	funcInstance // instance of a generic function
+	funcWrapper  // method wrapper
+	funcThunk    // method expression thunk
+	funcBound    // bound method closure
+)
+
+// An encFunc is the encoding of a reference to a function.
+type encFunc struct {
+	Kind     int
+	Pkg      int    // funcMember
+	Name     string // funcMember
+	Object   int    // funcMethod, funcWrapper, funcThunk, funcBound
+	Parent   int    // enclosing function (funcAnon) or origin (funcInstance)
+	Index    int    // index among the anonymous functions of Parent
+	TypeArgs []int  // funcInstance
+	Recv     int    // receiver type of the selection (funcWrapper, funcThunk)
+	Type     int    // type of the selection (funcThunk)
+	Path     []int  // index of the selection (funcThunk)
+	Indirect bool   // selection requires indirection (funcThunk)
+}
+
+type encConst struct {
+	Type  int
+	Value *encValue // nil for the zero value
+}
+
+// An encValue is the encoding of a constant.Value.
+type encValue struct {
+	Kind       constant.Kind
+	Bool       bool
+	Text       string    // string; decimal integer; or rational float
+	Float      []byte    // gob encoding of a big.Float
+	Real, Imag *encValue // complex
+}
+
+type encGlobal struct {
+	Pkg  int
+	Name string
+}
+
+type encBuiltin struct {
+	Name string
+	Type int
+}
+
+// An encBody is the encoding of the body of a function, and those of
+// its anonymous functions.
+type encBody struct {
+	Func      int    // reference to the function, unless anonymous
+	Name      string // name of an anonymous function
+	Signature int    // type of an anonymous function
+	Pos       encPos
+	Synthetic string
+	Params    []encVar
+	FreeVars  []encFreeVar
+	Locals    []int // instruction indices of Allocs
+	Blocks    []encBlock
+	Recover   int // index+1 of the recover block
+	AnonFuncs []encBody
+}
+
+type encFreeVar struct {
+	Name string
+	Type int
+	Pos  encPos
+}
+
+type encBlock struct {
+	Comment      string
+	Preds, Succs []int
+	Instrs       []encInstr
+}
+
+// Kinds of encInstr.
+const (
+	_ = iota
+	opAlloc
+	opPhi
+	opCall
+	opBinOp
+	opUnOp
+	opChangeType
+	opConvert
+	opMultiConvert
+	opChangeInterface
+	opSliceToArrayPointer
+	opMakeInterface
+	opMakeClosure
+	opMakeMap
+	opMakeChan
+	opMakeSlice
+	opSlice
+	opFieldAddr
+	opField
+	opIndexAddr
+	opIndex
+	opLookup
+	opSelect
+	opRange
+	opNext
+	opTypeAssert
+	opExtract
+	opJump
+	opIf
+	opReturn
+	opRunDefers
+	opPanic
+	opGo
+	opDefer
+	opSend
+	opStore
+	opMapUpdate
+)
+
+// An encInstr is the encoding of an instruction.
+type encInstr struct {
+	Op       int
+	Type     int // type of the value, if any
+	Pos      encPos
+	CallPos  encPos      // Go, Defer: position of the call
+	Token    token.Token // BinOp, UnOp
+	Flag     bool        // Alloc.Heap, Lookup.CommaOk, Next.IsString, Select.Blocking, TypeAssert.CommaOk, UnOp.CommaOk
+	Index    int         // Extract.Index, Field.Field, FieldAddr.Field
+	N        int         // number of call arguments, Phi edges, closure bindings, results or select states
+	Comment  string
+	Method   int // index+1 of the method of an invoke-mode call
+	Asserted int // TypeAssert.AssertedType
+	From, To []encTerm
+	States   []encSelectState
+	Rands    []encOperand // in the order of Operands
+}
+
+type encSelectState struct {
+	Dir types.ChanDir
+	Pos encPos
+}
+
+// Kinds of encOperand.
+const (
+	_ = iota
+	valueInstr
+	valueParam
+	valueFreeVar
+	valueConst
+	valueGlobal
+	valueFunc
+	valueBuiltin
+)
+
+// An encOperand is the encoding of an operand of an instruction: an
+// index in the table denoted by its kind. The zero value denotes nil.
+type encOperand struct {
+	Kind  int
+	Index int
+}
+
+// A codecError is a panic value that reports an error in encoding or
+// decoding a package.
+type codecError struct{ err error }
+
+func codecErrorf(format string, args ...interface{}) {
+	panic(codecError{fmt.Errorf(format, args...)})
+}
+
+// -- encoder --
+
+type encoder struct {
+	prog  *Program
+	out   *encPackage
+	queue []*Function // instances whose bodies remain to be encoded
+
+	objpath  objectpath.Encoder
+	pkgs     map[*types.Package]int
+	files    map[*token.File]int
+	types    map[types.Type]int
+	objects  map[*types.Func]int
+	funcs    map[*Function]int
+	consts   map[*Const]int
+	globals  map[*Global]int
+	builtins map[encBuiltin]int
+}
+
+func (e *encoder) pkg(pkg *types.Package) int {
+	if pkg == nil {
+		return 0
+	}
+	i, ok := e.pkgs[pkg]
+	if !ok {
+		i = len(e.out.Packages)
+		e.out.Packages = append(e.out.Packages, encPkg{Path: pkg.Path(), Name: pkg.Name()})
+		e.pkgs[pkg] = i
+	}
+	return i
+}
+
+func (e *encoder) pos(pos token.Pos) encPos {
+	if !pos.IsValid() {
+		return encPos{}
+	}
+	f := e.prog.Fset.File(pos)
+	if f == nil {
+		return encPos{}
+	}
+	i, ok := e.files[f]
+	if !ok {
+		i = len(e.out.Files)
+		e.out.Files = append(e.out.Files, encFile{Name: f.Name(), Size: f.Size(), Lines: f.Lines()})
+		e.files[f] = i
+	}
+	return encPos{File: i + 1, Offset: f.Offset(pos)}
+}
+
+// isPackageLevel reports whether obj is declared at package level, or
+// in the universe.
+func isPackageLevel(obj types.Object) bool {
+	if obj.Pkg() == nil {
+		return obj.Parent() == types.Universe
+	}
+	return obj.Parent() == obj.Pkg().Scope()
+}
+
+func (e *encoder) typ(t types.Type) int {
+	if t == nil {
+		return 0
+	}
+	if alias, ok := t.(*types.Alias); ok && (!isPackageLevel(alias.Obj()) || aliases.TypeArgs(alias).Len() > 0) {
+		return e.typ(types.Unalias(t)) // local alias, or instance of a generic one
+	}
+	if i, ok := e.types[t]; ok {
+		return i
+	}
+	i := len(e.out.Types)
+	e.out.Types = append(e.out.Types, encType{})
+	e.types[t] = i // before recursion, for local named types
+
+	var et encType
+	switch t := t.(type) {
+	case *types.Basic:
+		et = encType{Kind: typeBasic, Basic: t.Kind(), Name: t.Name()}
+
+	case *types.Pointer:
+		if t == tDeferStack {
+			et = encType{Kind: typeOpaque, Name: "deferStack"}
+		} else {
+			et = encType{Kind: typePointer, Elem: e.typ(t.Elem())}
+		}
+
+	case *types.Slice:
+		et = encType{Kind: typeSlice, Elem: e.typ(t.Elem())}
+
+	case *types.Array:
+		et = encType{Kind: typeArray, Elem: e.typ(t.Elem()), Len: t.Len()}
+
+	case *types.Map:
+		et = encType{Kind: typeMap, Key: e.typ(t.Key()), Elem: e.typ(t.Elem())}
+
+	case *types.Chan:
+		et = encType{Kind: typeChan, Dir: t.Dir(), Elem: e.typ(t.Elem())}
+
+	case *types.Struct:
+		et = encType{Kind: typeStruct}
+		for i := 0; i < t.NumFields(); i++ {
+			f := t.Field(i)
+			et.Vars = append(et.Vars, encVar{Name: f.Name(), Pkg: e.pkg(f.Pkg()), Type: e.typ(f.Type()), Embedded: f.Embedded()})
+			et.Tags = append(et.Tags, t.Tag(i))
+		}
+
+	case *types.Tuple:
+		et = encType{Kind: typeTuple, Vars: e.vars(t)}
+
+	case *types.Signature:
+		if t.TypeParams().Len() > 0 || t.RecvTypeParams().Len() > 0 {
+			codecErrorf("generic signature %s", t)
+		}
+		// The receiver, if any, is not encoded.
+		et = encType{Kind: typeSignature, Params: e.typ(t.Params()), Results: e.typ(t.Results()), Variadic: t.Variadic()}
+
+	case *types.Interface:
+		et = encType{Kind: typeInterface}
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			m := t.ExplicitMethod(i)
+			et.Vars = append(et.Vars, encVar{Name: m.Name(), Pkg: e.pkg(m.Pkg()), Type: e.typ(m.Type())})
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			et.Types = append(et.Types, e.typ(t.EmbeddedType(i)))
+		}
+
+	case *types.Union:
+		et = encType{Kind: typeUnion, Terms: e.terms(unionTerms(t))}
+
+	case *types.Named:
+		obj := t.Obj()
+		if isPackageLevel(obj) {
+			et = encType{Kind: typeNamed, Pkg: e.pkg(obj.Pkg()), Name: obj.Name(), Types: e.typeList(typeArgs(t))}
+		} else {
+			if t.TypeParams().Len() > 0 || t.TypeArgs().Len() > 0 {
+				codecErrorf("local generic type %s", t)
+			}
+			et = encType{Kind: typeLocal, Pkg: e.pkg(obj.Pkg()), Name: obj.Name(), Pos: e.pos(obj.Pos()), Elem: e.typ(t.Underlying())}
+		}
+
+	case *types.Alias:
+		obj := t.Obj()
+		et = encType{Kind: typeAlias, Pkg: e.pkg(obj.Pkg()), Name: obj.Name()}
+
+	case *types.TypeParam:
+		obj := t.Obj()
+		path, err := e.objpath.For(obj)
+		if err != nil {
+			codecErrorf("type parameter %s: %v", t, err)
+		}
+		et = encType{Kind: typeParam, Pkg: e.pkg(obj.Pkg()), Path: string(path)}
+
+	case *opaqueType:
+		if t != tRangeIter {
+			codecErrorf("unexpected opaque type %s", t)
+		}
+		et = encType{Kind: typeOpaque, Name: t.name}
+
+	default:
+		codecErrorf("unexpected type %T", t)
+	}
+	e.out.Types[i] = et
+	return i
+}
+
+func (e *encoder) typeList(ts []types.Type) []int {
+	var list []int
+	for _, t := range ts {
+		list = append(list, e.typ(t))
+	}
+	return list
+}
+
+func (e *encoder) vars(t *types.Tuple) []encVar {
+	var vars []encVar
+	for i := 0; i < t.Len(); i++ {
+		v := t.At(i)
+		vars = append(vars, encVar{Name: v.Name(), Pkg: e.pkg(v.Pkg()), Type: e.typ(v.Type())})
+	}
+	return vars
+}
+
+func (e *encoder) terms(terms []*types.Term) []encTerm {
+	var list []encTerm
+	for _, term := range terms {
+		list = append(list, encTerm{Tilde: term.Tilde(), Type: e.typ(term.Type())})
+	}
+	return list
+}
+
+// typeArgs returns the type arguments of t.
+func typeArgs(t *types.Named) []types.Type {
+	var targs []types.Type
+	for i := 0; i < t.TypeArgs().Len(); i++ {
+		targs = append(targs, t.TypeArgs().At(i))
+	}
+	return targs
+}
+
+// unionTerms returns the terms of u.
+func unionTerms(u *types.Union) []*types.Term {
+	terms := make([]*types.Term, u.Len())
+	for i := range terms {
+		terms[i] = u.Term(i)
+	}
+	return terms
+}
+
+// object returns the index of the encoding of method obj.
+func (e *encoder) object(obj *types.Func) int {
+	if i, ok := e.objects[obj]; ok {
+		return i
+	}
+	recv := obj.Type().(*types.Signature).Recv()
+	if recv == nil {
+		codecErrorf("%s is not a method", obj)
+	}
+	var eo encObject
+	if orig := obj.Origin(); orig != obj {
+		eo = encObject{Origin: 1 + e.object(orig), TypeArgs: e.typeList(receiverTypeArgs(obj))}
+	} else if path, err := e.objpath.For(obj); err == nil {
+		eo = encObject{Pkg: e.pkg(obj.Pkg()), Path: string(path)}
+	} else {
+		// A method of a local type, or of an interface
+		// unreachable from the package-level declarations.
+		eo = encObject{Pkg: e.pkg(obj.Pkg()), Recv: e.typ(recv.Type()), Name: obj.Name()}
+	}
+	i := len(e.out.Objects)
+	e.out.Objects = append(e.out.Objects, eo)
+	e.objects[obj] = i
+	return i
+}
+
+// function returns the index of the encoding of a reference to fn.
+func (e *encoder) function(fn *Function) int {
+	if i, ok := e.funcs[fn]; ok {
+		return i
+	}
+	var ef encFunc
+	switch {
+	case fn.parent != nil:
+		ef = encFunc{Kind: funcAnon, Parent: e.function(fn.parent), Index: int(fn.anonIdx)}
+
+	case fn.topLevelOrigin != nil:
+		ef = encFunc{Kind: funcInstance, Parent: e.function(fn.topLevelOrigin), TypeArgs: e.typeList(fn.typeargs)}
+		if fn.Blocks != nil && strings.HasPrefix(fn.Synthetic, "instance of ") {
+			e.queue = append(e.queue, fn)
+		}
+
+	case fn.method != nil:
+		sel := fn.method
+		ef = encFunc{Kind: funcWrapper, Recv: e.typ(sel.recv), Object: e.object(sel.obj.(*types.Func))}
+		if sel.kind == types.MethodExpr {
+			ef.Kind = funcThunk
+			ef.Type = e.typ(sel.typ)
+			ef.Path = sel.index
+			ef.Indirect = sel.indirect
+		}
+
+	case fn.object != nil && fn.Signature.Recv() == nil && fn.object.Type().(*types.Signature).Recv() != nil:
+		ef = encFunc{Kind: funcBound, Object: e.object(fn.object)}
+
+	case fn.Pkg != nil && fn.Pkg.Members[fn.name] == fn:
+		ef = encFunc{Kind: funcMember, Pkg: e.pkg(fn.Pkg.Pkg), Name: fn.name}
+
+	case fn.object != nil && fn.Signature.Recv() != nil:
+		ef = encFunc{Kind: funcMethod, Object: e.object(fn.object)}
+
+	default:
+		codecErrorf("reference to function %s", fn)
+	}
+	i := len(e.out.Funcs)
+	e.out.Funcs = append(e.out.Funcs, ef)
+	e.funcs[fn] = i
+	return i
+}
+
+func (e *encoder) constant(c *Const) int {
+	if i, ok := e.consts[c]; ok {
+		return i
+	}
+	ec := encConst{Type: e.typ(c.typ)}
+	if c.Value != nil {
+		ec.Value = encodeValue(c.Value)
+	}
+	i := len(e.out.Consts)
+	e.out.Consts = append(e.out.Consts, ec)
+	e.consts[c] = i
+	return i
+}
+
+func encodeValue(v constant.Value) *encValue {
+	ev := &encValue{Kind: v.Kind()}
+	switch v.Kind() {
+	case constant.Bool:
+		ev.Bool = constant.BoolVal(v)
+	case constant.String:
+		ev.Text = constant.StringVal(v)
+	case constant.Int:
+		ev.Text = v.ExactString()
+	case constant.Float:
+		switch x := constant.Val(v).(type) {
+		case *big.Rat:
+			ev.Text = x.RatString()
+		case *big.Float:
+			data, err := x.GobEncode()
+			if err != nil {
+				codecErrorf("constant %s: %v", v, err)
+			}
+			ev.Float = data
+		}
+	case constant.Complex:
+		ev.Real = encodeValue(constant.Real(v))
+		ev.Imag = encodeValue(constant.Imag(v))
+	}
+	return ev
+}
+
+func (e *encoder) global(g *Global) int {
+	if i, ok := e.globals[g]; ok {
+		return i
+	}
+	if g.Pkg == nil || g.Pkg.Members[g.name] != g {
+		codecErrorf("reference to global %s", g)
+	}
+	i := len(e.out.Globals)
+	e.out.Globals = append(e.out.Globals, encGlobal{Pkg: e.pkg(g.Pkg.Pkg), Name: g.name})
+	e.globals[g] = i
+	return i
+}
+
+func (e *encoder) builtin(b *Builtin) int {
+	eb := encBuiltin{Name: b.name, Type: e.typ(b.sig)}
+	i, ok := e.builtins[eb]
+	if !ok {
+		i = len(e.out.Builtins)
+		e.out.Builtins = append(e.out.Builtins, eb)
+		e.builtins[eb] = i
+	}
+	return i
+}
+
+// body appends to the Bodies table the encoding of the body of
+// function fn, which is not anonymous.
+func (e *encoder) body(fn *Function) {
+	ref := e.function(fn)
+	eb := e.encodeBody(fn)
+	eb.Func = ref
+	e.out.Bodies = append(e.out.Bodies, eb)
+}
+
+func (e *encoder) encodeBody(fn *Function) encBody {
+	eb := encBody{Pos: e.pos(fn.pos), Synthetic: fn.Synthetic}
+	for _, p := range fn.Params {
+		eb.Params = append(eb.Params, encVar{Name: p.name, Type: e.typ(p.typ)})
+	}
+	for _, fv := range fn.FreeVars {
+		eb.FreeVars = append(eb.FreeVars, encFreeVar{Name: fv.name, Type: e.typ(fv.typ), Pos: e.pos(fv.pos)})
+	}
+
+	// Number the instructions, omitting DebugRefs.
+	index := make(map[Instruction]int)
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			if _, ok := instr.(*DebugRef); !ok {
+				index[instr] = len(index)
+			}
+		}
+	}
+	operand := func(v Value) encOperand {
+		switch v := v.(type) {
+		case nil:
+			return encOperand{}
+		case *Parameter:
+			for i, p := range fn.Params {
+				if p == v {
+					return encOperand{Kind: valueParam, Index: i}
+				}
+			}
+		case *FreeVar:
+			for i, fv := range fn.FreeVars {
+				if fv == v {
+					return encOperand{Kind: valueFreeVar, Index: i}
+				}
+			}
+		case *Const:
+			return encOperand{Kind: valueConst, Index: e.constant(v)}
+		case *Global:
+			return encOperand{Kind: valueGlobal, Index: e.global(v)}
+		case *Function:
+			return encOperand{Kind: valueFunc, Index: e.function(v)}
+		case *Builtin:
+			return encOperand{Kind: valueBuiltin, Index: e.builtin(v)}
+		case Instruction:
+			if i, ok := index[v]; ok {
+				return encOperand{Kind: valueInstr, Index: i}
+			}
+		}
+		codecErrorf("%s: operand %s is not local", fn, v.Name())
+		panic("unreachable")
+	}
+
+	for _, b := range fn.Blocks {
+		eblock := encBlock{Comment: b.Comment}
+		for _, pred := range b.Preds {
+			eblock.Preds = append(eblock.Preds, pred.Index)
+		}
+		for _, succ := range b.Succs {
+			eblock.Succs = append(eblock.Succs, succ.Index)
+		}
+		for _, instr := range b.Instrs {
+			if _, ok := instr.(*DebugRef); !ok {
+				eblock.Instrs = append(eblock.Instrs, e.instr(instr, operand))
+			}
+		}
+		eb.Blocks = append(eb.Blocks, eblock)
+	}
+	for _, l := range fn.Locals {
+		eb.Locals = append(eb.Locals, index[l])
+	}
+	if fn.Recover != nil {
+		eb.Recover = fn.Recover.Index + 1
+	}
+
+	for _, anon := range fn.AnonFuncs {
+		ea := e.encodeBody(anon)
+		ea.Name = anon.name
+		ea.Signature = e.typ(anon.Signature)
+		eb.AnonFuncs = append(eb.AnonFuncs, ea)
+	}
+	return eb
+}
+
+func (e *encoder) instr(instr Instruction, operand func(Value) encOperand) encInstr {
+	var ei encInstr
+	call := func(c *CallCommon) {
+		ei.N = len(c.Args)
+		if c.Method != nil {
+			ei.Method = 1 + e.object(c.Method)
+		}
+	}
+	switch instr := instr.(type) {
+	case *Alloc:
+		ei.Op = opAlloc
+		ei.Comment = instr.Comment
+		ei.Flag = instr.Heap
+	case *Phi:
+		ei.Op = opPhi
+		ei.Comment = instr.Comment
+		ei.N = len(instr.Edges)
+	case *Call:
+		ei.Op = opCall
+		call(&instr.Call)
+	case *BinOp:
+		ei.Op = opBinOp
+		ei.Token = instr.Op
+	case *UnOp:
+		ei.Op = opUnOp
+		ei.Token = instr.Op
+		ei.Flag = instr.CommaOk
+	case *ChangeType:
+		ei.Op = opChangeType
+	case *Convert:
+		ei.Op = opConvert
+	case *MultiConvert:
+		ei.Op = opMultiConvert
+		ei.From = e.terms(instr.from)
+		ei.To = e.terms(instr.to)
+	case *ChangeInterface:
+		ei.Op = opChangeInterface
+	case *SliceToArrayPointer:
+		ei.Op = opSliceToArrayPointer
+	case *MakeInterface:
+		ei.Op = opMakeInterface
+	case *MakeClosure:
+		ei.Op = opMakeClosure
+		ei.N = len(instr.Bindings)
+	case *MakeMap:
+		ei.Op = opMakeMap
+	case *MakeChan:
+		ei.Op = opMakeChan
+	case *MakeSlice:
+		ei.Op = opMakeSlice
+	case *Slice:
+		ei.Op = opSlice
+	case *FieldAddr:
+		ei.Op = opFieldAddr
+		ei.Index = instr.Field
+	case *Field:
+		ei.Op = opField
+		ei.Index = instr.Field
+	case *IndexAddr:
+		ei.Op = opIndexAddr
+	case *Index:
+		ei.Op = opIndex
+	case *Lookup:
+		ei.Op = opLookup
+		ei.Flag = instr.CommaOk
+	case *Select:
+		ei.Op = opSelect
+		ei.Flag = instr.Blocking
+		ei.N = len(instr.States)
+		for _, st := range instr.States {
+			ei.States = append(ei.States, encSelectState{Dir: st.Dir, Pos: e.pos(st.Pos)})
+		}
+	case *Range:
+		ei.Op = opRange
+	case *Next:
+		ei.Op = opNext
+		ei.Flag = instr.IsString
+	case *TypeAssert:
+		ei.Op = opTypeAssert
+		ei.Asserted = e.typ(instr.AssertedType)
+		ei.Flag = instr.CommaOk
+	case *Extract:
+		ei.Op = opExtract
+		ei.Index = instr.Index
+	case *Jump:
+		ei.Op = opJump
+	case *If:
+		ei.Op = opIf
+	case *Return:
+		ei.Op = opReturn
+		ei.N = len(instr.Results)
+	case *RunDefers:
+		ei.Op = opRunDefers
+	case *Panic:
+		ei.Op = opPanic
+	case *Go:
+		ei.Op = opGo
+		ei.CallPos = e.pos(instr.Call.pos)
+		call(&instr.Call)
+	case *Defer:
+		ei.Op = opDefer
+		ei.CallPos = e.pos(instr.Call.pos)
+		call(&instr.Call)
+	case *Send:
+		ei.Op = opSend
+	case *Store:
+		ei.Op = opStore
+	case *MapUpdate:
+		ei.Op = opMapUpdate
+	default:
+		codecErrorf("unexpected instruction %T", instr)
+	}
+	if v, ok := instr.(Value); ok {
+		ei.Type = e.typ(v.Type())
+	}
+	ei.Pos = e.pos(instr.Pos())
+	for _, rand := range instr.Operands(nil) {
+		ei.Rands = append(ei.Rands, operand(*rand))
+	}
+	return ei
+}
@@ -0,0 +1,439 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// mapImporter imports packages from a map of already type-checked packages.
+type mapImporter map[string]*types.Package
+
+func (m mapImporter) Import(path string) (*types.Package, error) {
+	if pkg, ok := m[path]; ok {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("no package %q", path)
+}
+
+// TestEncodeDecode checks that packages survive a round trip through
+// Package.Encode and Program.DecodePackage: every function of the
+// decoded program must print exactly as it did in the original one.
+func TestEncodeDecode(t *testing.T) {
+	srcs := []struct{ path, src string }{
+		{"example.com/a", `package a
+
+type Number interface{ ~int | ~float64 }
+
+func Sum[T Number](xs ...T) (s T) {
+	for _, x := range xs {
+		s += x
+	}
+	return
+}
+
+type List[T any] struct {
+	head *node[T]
+}
+
+type node[T any] struct {
+	val  T
+	next *node[T]
+}
+
+func (l *List[T]) Push(v T) { l.head = &node[T]{v, l.head} }
+
+func (l *List[T]) All() func(yield func(T) bool) {
+	return func(yield func(T) bool) {
+		for n := l.head; n != nil; n = n.next {
+			if !yield(n.val) {
+				return
+			}
+		}
+	}
+}
+
+type Shape interface {
+	Area() float64
+}
+
+type Rect struct{ W, H float64 }
+
+func (r Rect) Area() float64 { return r.W * r.H }
+
+type counter int
+
+func (c *counter) inc() { *c++ }
+
+var (
+	total counter
+	names = map[string]int{"x": 1, "y": 2}
+)
+
+const Big = 1 << 70 >> 68
+
+func init() { total.inc() }
+`},
+		{"example.com/b", `package b
+
+import "example.com/a"
+
+type Square struct {
+	a.Rect
+	label string
+}
+
+func Areas(shapes ...a.Shape) float64 {
+	var fs []float64
+	for _, s := range shapes {
+		fs = append(fs, s.Area())
+	}
+	return a.Sum(fs...)
+}
+
+func Collect() (xs []int) {
+	var l a.List[int]
+	for i := range 3 {
+		l.Push(i)
+	}
+	for x := range l.All() {
+		if x == 1 {
+			continue
+		}
+		xs = append(xs, x)
+	}
+	return
+}
+
+func Safe(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = r.(error)
+		}
+	}()
+	f()
+	return nil
+}
+
+func Pump(in <-chan int, out chan<- string, done chan struct{}) int {
+	n := 0
+	for {
+		select {
+		case x, ok := <-in:
+			if !ok {
+				return n
+			}
+			n += x
+		case out <- "tick":
+		case <-done:
+			return -1
+		}
+	}
+}
+
+func Bound(s Square) func() float64 { return s.Area }
+
+func Thunk() func(a.Rect) float64 { return a.Rect.Area }
+
+var Complex = 1.5 + 2i
+
+func Kinds(x interface{}) string {
+	switch x := x.(type) {
+	case int:
+		return "int"
+	case [2]string:
+		return x[0]
+	case *Square:
+		return x.label
+	}
+	return ""
+}
+`},
+	}
+
+	mode := ssa.SanityCheckFunctions | ssa.InstantiateGenerics
+	fset := token.NewFileSet()
+	imp := make(mapImporter)
+	prog := ssa.NewProgram(fset, mode)
+	var pkgs []*ssa.Package
+	for _, s := range srcs {
+		f, err := parser.ParseFile(fset, s.path+"/x.go", s.src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := []*ast.File{f}
+		info := &types.Info{
+			Types:        make(map[ast.Expr]types.TypeAndValue),
+			Defs:         make(map[*ast.Ident]types.Object),
+			Uses:         make(map[*ast.Ident]types.Object),
+			Implicits:    make(map[ast.Node]types.Object),
+			Instances:    make(map[*ast.Ident]types.Instance),
+			Scopes:       make(map[ast.Node]*types.Scope),
+			Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+			FileVersions: make(map[*ast.File]string),
+		}
+		conf := types.Config{Importer: imp}
+		tpkg, err := conf.Check(s.path, fset, files, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imp[s.path] = tpkg
+		pkgs = append(pkgs, prog.CreatePackage(tpkg, files, info, false))
+	}
+	prog.Build()
+
+	// Encode each package, checking that the encoding is deterministic.
+	var datas [][]byte
+	for _, pkg := range pkgs {
+		data, err := pkg.Encode()
+		if err != nil {
+			t.Fatalf("encoding %s: %v", pkg.Pkg.Path(), err)
+		}
+		again, err := pkg.Encode()
+		if err != nil {
+			t.Fatalf("encoding %s again: %v", pkg.Pkg.Path(), err)
+		}
+		if !bytes.Equal(data, again) {
+			t.Errorf("encoding %s is not deterministic", pkg.Pkg.Path())
+		}
+		datas = append(datas, data)
+	}
+
+	// Decode them, in dependency order, into a fresh program.
+	prog2 := ssa.NewProgram(token.NewFileSet(), mode)
+	for i, data := range datas {
+		if _, err := prog2.DecodePackage(data); err != nil {
+			t.Fatalf("decoding %s: %v", pkgs[i].Pkg.Path(), err)
+		}
+	}
+	if _, err := prog2.DecodePackage(datas[0]); err == nil {
+		t.Errorf("decoding %s twice succeeded", pkgs[0].Pkg.Path())
+	}
+
+	want := dumpFunctions(prog)
+	got := dumpFunctions(prog2)
+	for name, w := range want {
+		if g, ok := got[name]; !ok {
+			t.Errorf("decoded program lacks function %s", name)
+		} else if g != w {
+			t.Errorf("function %s differs after decoding:\n--- want ---\n%s--- got ---\n%s", name, w, g)
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("decoded program has unexpected function %s", name)
+		}
+	}
+}
+
+// TestDecodeMixed checks that packages created from syntax may refer
+// to decoded packages, and that both may import packages of the
+// standard library, and that building an instance of a decoded generic
+// function that was not encoded fails loudly rather than producing an
+// empty body.
+func TestDecodeMixed(t *testing.T) {
+	const (
+		srcA = `package a
+
+import "strings"
+
+func Upper(s string) string { return strings.ToUpper(s) }
+
+func Sum[T ~int | ~float64](xs ...T) (s T) {
+	for _, x := range xs {
+		s += x
+	}
+	return
+}
+
+func Ints() int { return Sum(1, 2) }
+`
+		srcC = `package c
+
+import (
+	"strings"
+
+	"example.com/a"
+)
+
+func F() string { return a.Upper(strings.TrimSpace(" x ")) }
+
+func G() int { return a.Sum(3, 4) }
+`
+		srcD = `package d
+
+import "example.com/a"
+
+func H() float64 { return a.Sum(1.5, 2) }
+`
+	)
+
+	mode := ssa.SanityCheckFunctions | ssa.InstantiateGenerics
+	std, err := importer.Default().Import("strings")
+	if err != nil {
+		t.Skipf("cannot import strings: %v", err)
+	}
+
+	// create type-checks and creates a package from source in prog,
+	// importing the packages already created in prog.
+	create := func(prog *ssa.Program, path, src string) *ssa.Package {
+		f, err := parser.ParseFile(prog.Fset, path+"/x.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		imp := make(mapImporter)
+		for _, pkg := range prog.AllPackages() {
+			imp[pkg.Pkg.Path()] = pkg.Pkg
+		}
+		files := []*ast.File{f}
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Instances:  make(map[*ast.Ident]types.Instance),
+			Scopes:     make(map[ast.Node]*types.Scope),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		}
+		conf := types.Config{Importer: imp}
+		tpkg, err := conf.Check(path, prog.Fset, files, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return prog.CreatePackage(tpkg, files, info, false)
+	}
+	// createStd creates strings and its dependencies in prog, without syntax.
+	createStd := func(prog *ssa.Program) {
+		var visit func(pkg *types.Package)
+		visit = func(pkg *types.Package) {
+			if prog.Package(pkg) == nil {
+				prog.CreatePackage(pkg, nil, nil, true)
+				for _, imp := range pkg.Imports() {
+					visit(imp)
+				}
+			}
+		}
+		visit(std)
+	}
+
+	// Encode a, which imports strings.
+	prog := ssa.NewProgram(token.NewFileSet(), mode)
+	createStd(prog)
+	a := create(prog, "example.com/a", srcA)
+	a.Build()
+	data, err := a.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode it into a fresh program, and create c from source.
+	prog2 := ssa.NewProgram(token.NewFileSet(), mode)
+	createStd(prog2)
+	a2, err := prog2.DecodePackage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := create(prog2, "example.com/c", srcC)
+	c.Build()
+
+	// c calls the decoded a.Upper, which calls strings.ToUpper,
+	// and the instance a.Sum[int], which was encoded with a.
+	var buf bytes.Buffer
+	ssa.WriteFunction(&buf, a2.Func("Upper"))
+	if want := "strings.ToUpper"; !strings.Contains(buf.String(), want) {
+		t.Errorf("decoded a.Upper does not call %s:\n%s", want, buf.String())
+	}
+	callees := make(map[string]*ssa.Function)
+	for _, name := range []string{"F", "G"} {
+		for _, b := range c.Func(name).Blocks {
+			for _, instr := range b.Instrs {
+				if call, ok := instr.(*ssa.Call); ok {
+					if callee := call.Call.StaticCallee(); callee != nil {
+						callees[callee.String()] = callee
+					}
+				}
+			}
+		}
+	}
+	for _, name := range []string{"example.com/a.Upper", "example.com/a.Sum[int]"} {
+		if fn := callees[name]; fn == nil {
+			t.Errorf("c does not call %s", name)
+		} else if len(fn.Blocks) == 0 {
+			t.Errorf("%s has no body", fn)
+		}
+	}
+
+	// d requires the instance a.Sum[float64], which was not encoded,
+	// and whose origin has no syntax, so it is built without a body.
+	d := create(prog2, "example.com/d", srcD)
+	d.Build()
+	var sum *ssa.Function
+	for _, b := range d.Func("H").Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(*ssa.Call); ok {
+				if callee := call.Call.StaticCallee(); callee != nil && callee.String() == "example.com/a.Sum[float64]" {
+					sum = callee
+				}
+			}
+		}
+	}
+	if sum == nil {
+		t.Error("d does not call example.com/a.Sum[float64]")
+	} else if len(sum.Blocks) != 0 {
+		t.Errorf("%s has a body, but its origin has no syntax", sum)
+	}
+}
+
+// dumpFunctions returns the printed form of each function of the
+// program's packages, and of each function they refer to, keyed by
+// name.
+func dumpFunctions(prog *ssa.Program) map[string]string {
+	seen := make(map[*ssa.Function]bool)
+	var fns []*ssa.Function
+	var visit func(fn *ssa.Function)
+	visit = func(fn *ssa.Function) {
+		if seen[fn] {
+			return
+		}
+		seen[fn] = true
+		fns = append(fns, fn)
+		for _, anon := range fn.AnonFuncs {
+			visit(anon)
+		}
+		var rands []*ssa.Value
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				for _, rand := range instr.Operands(rands[:0]) {
+					if g, ok := (*rand).(*ssa.Function); ok {
+						visit(g)
+					}
+				}
+			}
+		}
+	}
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg != nil && strings.HasPrefix(fn.Pkg.Pkg.Path(), "example.com/") {
+			visit(fn)
+		}
+	}
+	dump := make(map[string]string)
+	for _, fn := range fns {
+		var buf bytes.Buffer
+		ssa.WriteFunction(&buf, fn)
+		dump[fn.String()] = buf.String()
+	}
+	return dump
+}
@@ -382,6 +382,10 @@ func (f *Function) finishBody() {
 		lift(f)
 	}
 
+	if f.Prog.mode&PruneDeadBranches != 0 {
+		sccp(f)
+	}
+
 	// clear remaining builder state
 	f.results = nil    // (used by lifting)
 	f.deferstack = nil // (used by lifting)
@@ -85,6 +85,8 @@ func createInstance(fn *Function, targs []types.Type) *Function {
 			subst = makeSubster(prog.ctxt, obj, fn.typeparams, targs, false)
 			build = (*builder).buildFromSyntax
 		} else {
+			// No syntax, as for a function loaded from export data
+			// or decoded by DecodePackage: the instance has no body.
 			build = (*builder).buildParamsOnly
 		}
 	} else {
@@ -0,0 +1,263 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// This file defines the recording of execution counts, and their
+// presentation as a coverage profile.
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// A Coverage records the number of times the interpreter executed
+// each basic block and called each function (see [Config.Coverage]).
+// The zero value is ready to use.
+type Coverage struct {
+	mu     sync.Mutex
+	blocks map[*ssa.Function][]int64 // execution counts, indexed by block; updated atomically
+	calls  map[*ssa.Function]int
+}
+
+// enter records a call to fn, and returns the counters of its blocks,
+// which the caller increments atomically as it executes them.
+func (c *Coverage) enter(fn *ssa.Function) []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.blocks == nil {
+		c.blocks = make(map[*ssa.Function][]int64)
+		c.calls = make(map[*ssa.Function]int)
+	}
+	counts, ok := c.blocks[fn]
+	if !ok {
+		counts = make([]int64, len(fn.Blocks))
+		c.blocks[fn] = counts
+	}
+	c.calls[fn]++
+	return counts
+}
+
+// count returns the number of times the block of instr was executed.
+// The caller must hold c.mu.
+func (c *Coverage) count(instr ssa.Instruction) int {
+	b := instr.Block()
+	if counts := c.blocks[b.Parent()]; counts != nil {
+		return int(atomic.LoadInt64(&counts[b.Index]))
+	}
+	return 0
+}
+
+// Count returns the number of times instr was executed, which is
+// the number of times execution entered its basic block. (An
+// instruction that follows one that panicked is counted too.)
+func (c *Coverage) Count(instr ssa.Instruction) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count(instr)
+}
+
+// Calls returns the number of times fn was called.
+func (c *Coverage) Calls(fn *ssa.Function) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[fn]
+}
+
+// WriteProfile writes to w a statement coverage profile of the
+// functions declared in the specified packages, in the "count" format
+// of "go test -coverprofile", which may be parsed by
+// [golang.org/x/tools/cover.ParseProfiles] and displayed by "go tool
+// cover". File names are those recorded in the program's FileSet.
+//
+// The count of a statement is the greatest count of the instructions
+// of a function attributed to it by their positions, summed over the
+// instances of a generic function. The range of a statement excludes
+// those of the statements of the function literals within it, so that
+// no two statements overlap. Statements without instructions that have
+// a position, such as some assignments, are not reported.
+// Building the program in [ssa.GlobalDebug] mode, which adds a
+// DebugRef instruction for each reference to a variable, lets more
+// statements be reported.
+func (c *Coverage) WriteProfile(w io.Writer, pkgs ...*ssa.Package) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "mode: count")
+	if len(pkgs) == 0 {
+		return out.Flush()
+	}
+	prog := pkgs[0].Prog
+	isTarget := make(map[*ssa.Package]bool)
+	for _, pkg := range pkgs {
+		isTarget[pkg] = true
+	}
+
+	// Find the statements of each declared function, and
+	// attribute to them the instructions of all functions derived
+	// from it (its anonymous functions and instances).
+	type stmt struct {
+		start, end token.Pos
+		count      int
+		covered    bool // statement has an instruction with a position
+	}
+	stmts := make(map[*ssa.Function][]*stmt) // indexed by declared function
+	var decls []*ssa.Function
+	for fn := range ssautil.AllFunctions(prog) {
+		decl := declaredFunc(fn)
+		if decl == nil || !isTarget[decl.Pkg] {
+			continue
+		}
+		list, ok := stmts[decl]
+		if !ok {
+			for _, rng := range statements(decl.Syntax().(*ast.FuncDecl).Body) {
+				list = append(list, &stmt{start: rng[0], end: rng[1]})
+			}
+			stmts[decl] = list
+			decls = append(decls, decl)
+		}
+
+		counts := make(map[*stmt]int) // greatest count of fn's instructions in each statement
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				pos := instr.Pos()
+				if _, ok := instr.(*ssa.Phi); ok || !pos.IsValid() {
+					continue // φ-nodes merge values; they are not statements
+				}
+				// Find the innermost statement containing pos,
+				// which is the last one to start before it.
+				var inner *stmt
+				for _, s := range list {
+					if s.start <= pos && pos < s.end {
+						inner = s
+					}
+				}
+				if inner != nil {
+					inner.covered = true
+					if n := c.count(instr); n > counts[inner] {
+						counts[inner] = n
+					}
+				}
+			}
+		}
+		for s, n := range counts {
+			s.count += n
+		}
+	}
+
+	// Print the statements in order. The statements of the function
+	// literals within a statement are removed from its range, so that
+	// no two blocks of the profile overlap.
+	type block struct {
+		start, end token.Pos
+		count      int
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Pos() < decls[j].Pos() })
+	for _, decl := range decls {
+		list := stmts[decl]
+		var blocks []block
+		for k, s := range list {
+			if !s.covered {
+				continue
+			}
+			start := s.start
+			for _, inner := range list[k+1:] { // (ordered by start)
+				if inner.start >= s.end {
+					break
+				}
+				if inner.start < start {
+					continue // within a statement already removed
+				}
+				if start < inner.start {
+					blocks = append(blocks, block{start, inner.start, s.count})
+				}
+				start = inner.end
+			}
+			if start < s.end {
+				blocks = append(blocks, block{start, s.end, s.count})
+			}
+		}
+		sort.Slice(blocks, func(i, j int) bool { return blocks[i].start < blocks[j].start })
+		for _, b := range blocks {
+			start := prog.Fset.Position(b.start)
+			end := prog.Fset.Position(b.end)
+			fmt.Fprintf(out, "%s:%d.%d,%d.%d 1 %d\n",
+				start.Filename, start.Line, start.Column, end.Line, end.Column, b.count)
+		}
+	}
+	return out.Flush()
+}
+
+// declaredFunc returns the function declared by a FuncDecl from which
+// fn was derived, as an anonymous function or an instance, or nil if
+// there is none, as for wrappers and package initializers, or if the
+// declaration has no body, as for functions implemented in assembly.
+func declaredFunc(fn *ssa.Function) *ssa.Function {
+	for fn.Parent() != nil {
+		fn = fn.Parent()
+	}
+	if orig := fn.Origin(); orig != nil {
+		fn = orig
+	}
+	if decl, ok := fn.Syntax().(*ast.FuncDecl); !ok || decl.Body == nil || fn.Pkg == nil {
+		return nil
+	}
+	return fn
+}
+
+// statements returns the ranges of the statements of a function body,
+// including those of function literals, in order of their start. The
+// range of a compound statement such as an if or switch statement is
+// that of its header, up to the opening brace of its body, and that of
+// a case clause extends to its colon, including any statements within
+// the header, such as its init statement. Ranges are nested only within
+// statements that contain function literals.
+func statements(body *ast.BlockStmt) [][2]token.Pos {
+	var ranges [][2]token.Pos
+	header := make(map[ast.Stmt]bool) // statements within a header
+	add := func(start, end token.Pos, stmts ...ast.Stmt) {
+		ranges = append(ranges, [2]token.Pos{start, end})
+		for _, stmt := range stmts {
+			header[stmt] = true
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt, *ast.LabeledStmt:
+			// Only the statements within are reported.
+		case *ast.IfStmt:
+			add(n.Pos(), n.Body.Lbrace, n.Init)
+		case *ast.ForStmt:
+			add(n.Pos(), n.Body.Lbrace, n.Init, n.Post)
+		case *ast.RangeStmt:
+			add(n.Pos(), n.Body.Lbrace)
+		case *ast.SwitchStmt:
+			add(n.Pos(), n.Body.Lbrace, n.Init)
+		case *ast.TypeSwitchStmt:
+			add(n.Pos(), n.Body.Lbrace, n.Init, n.Assign)
+		case *ast.SelectStmt:
+			add(n.Pos(), n.Body.Lbrace)
+		case *ast.CaseClause:
+			add(n.Pos(), n.Colon+1)
+		case *ast.CommClause:
+			add(n.Pos(), n.Colon+1, n.Comm)
+		case ast.Stmt:
+			if !header[n] {
+				add(n.Pos(), n.End())
+			}
+		}
+		return true
+	})
+	return ranges
+}
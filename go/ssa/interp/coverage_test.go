@@ -0,0 +1,107 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"runtime"
+	"testing"
+
+	"golang.org/x/tools/cover"
+	"golang.org/x/tools/go/ssa/interp"
+)
+
+func TestCoverage(t *testing.T) {
+	const src = `
+package main
+
+func f(x int) int {
+	if x > 5 {
+		return 1
+	}
+	return 0
+}
+
+func main() {
+	n := 0
+	for i := 0; i < 10; i++ {
+		n += f(i)
+	}
+	if n != 4 {
+		panic(n)
+	}
+}
+
+func g(n int) int {
+	return call(func() int {
+		return n
+	}) + 1
+}
+
+func call(f func() int) int { return f() }
+
+func init() { g(2) }
+
+func ext() // declared without a body
+`
+	mainPkg := buildMain(t, makeGoroot(t), src)
+	var cov interp.Coverage
+	sizes := types.SizesFor("gc", runtime.GOARCH)
+	if exitCode := interp.InterpretWithConfig(mainPkg, 0, sizes, "main", nil, &interp.Config{Coverage: &cov}); exitCode != 0 {
+		t.Fatalf("exit code %d", exitCode)
+	}
+
+	if got := cov.Calls(mainPkg.Func("f")); got != 10 {
+		t.Errorf("Calls(f) = %d, want 10", got)
+	}
+
+	var buf bytes.Buffer
+	if err := cov.WriteProfile(&buf, mainPkg); err != nil {
+		t.Fatal(err)
+	}
+	profile := buf.String()
+	profiles, err := cover.ParseProfilesFromReader(&buf)
+	if err != nil {
+		t.Fatalf("invalid profile: %v\n%s", err, profile)
+	}
+	if len(profiles) != 1 || profiles[0].FileName != "main.go" || profiles[0].Mode != "count" {
+		t.Fatalf("got %d profiles, want one of main.go in count mode:\n%s", len(profiles), profile)
+	}
+	got := make(map[string]int)
+	for _, b := range profiles[0].Blocks {
+		got[fmt.Sprintf("%d.%d,%d.%d", b.StartLine, b.StartCol, b.EndLine, b.EndCol)] = b.Count
+	}
+	for block, want := range map[string]int{
+		"5.2,5.11":   10, // if x > 5
+		"6.3,6.11":   4,  // return 1
+		"8.2,8.10":   6,  // return 0
+		"13.2,13.26": 11, // for i := 0; i < 10; i++
+		"14.3,14.12": 10, // n += f(i)
+		"16.2,16.12": 1,  // if n != 4
+		"17.3,17.11": 0,  // panic(n)
+		"22.2,23.3":  1,  // return call(func() int {
+		"23.3,23.11": 1,  // return n
+		"23.11,24.8": 1,  // }) + 1
+	} {
+		if count, ok := got[block]; !ok {
+			t.Errorf("profile has no block %s:\n%s", block, profile)
+		} else if count != want {
+			t.Errorf("block %s has count %d, want %d", block, count, want)
+		}
+	}
+
+	// "go tool cover" requires that blocks not overlap.
+	blocks := profiles[0].Blocks
+	for i := 1; i < len(blocks); i++ {
+		prev, b := blocks[i-1], blocks[i]
+		if b.StartLine < prev.EndLine || b.StartLine == prev.EndLine && b.StartCol < prev.EndCol {
+			t.Errorf("block %d.%d,%d.%d overlaps block %d.%d,%d.%d",
+				b.StartLine, b.StartCol, b.EndLine, b.EndCol,
+				prev.StartLine, prev.StartCol, prev.EndLine, prev.EndCol)
+		}
+	}
+}
@@ -290,6 +290,10 @@ func ext۰runtime۰GC(fr *frame, args []value) value {
 }
 
 func ext۰runtime۰Gosched(fr *frame, args []value) value {
+	if fr.i.sched != nil {
+		fr.i.sched.yield()
+		return nil
+	}
 	runtime.Gosched()
 	return nil
 }
@@ -299,6 +303,10 @@ func ext۰runtime۰NumCPU(fr *frame, args []value) value {
 }
 
 func ext۰time۰Sleep(fr *frame, args []value) value {
+	if fr.i.sched != nil {
+		fr.i.sched.yield() // don't sleep; only the interleaving matters
+		return nil
+	}
 	time.Sleep(time.Duration(args[0].(int64)))
 	return nil
 }
@@ -26,7 +26,8 @@
 // * "sync/atomic" operations are not atomic due to the "boxed" value
 // representation: it is not possible to read, modify and write an
 // interface value atomically. As a consequence, Mutexes are currently
-// broken.
+// broken, except under the deterministic scheduler (see Config),
+// which runs one goroutine at a time.
 //
 // * recover is only partially implemented.  Also, the interpreter
 // makes no attempt to distinguish target panics from interpreter
@@ -88,6 +89,8 @@ type interpreter struct {
 	runtimeErrorString types.Type             // the runtime.errorString type
 	sizes              types.Sizes            // the effective type-sizing function
 	goroutines         int32                  // atomically updated
+	sched              *scheduler             // the deterministic scheduler, if enabled
+	cov                *Coverage              // execution counts, if enabled
 }
 
 type deferred struct {
@@ -108,6 +111,7 @@ type frame struct {
 	result           value
 	panicking        bool
 	panic            interface{}
+	counts           []int64 // execution counts of blocks, if coverage is enabled
 }
 
 func (fr *frame) get(key ssa.Value) value {
@@ -192,6 +196,14 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		// no-op
 
 	case *ssa.UnOp:
+		if instr.Op == token.ARROW && fr.i.sched != nil {
+			v, ok := fr.i.sched.recv(fr, instr.Pos(), fr.get(instr.X))
+			if instr.CommaOk {
+				v = tuple{v, ok}
+			}
+			fr.env[instr] = v
+			break
+		}
 		fr.env[instr] = unop(instr, fr.get(instr.X))
 
 	case *ssa.BinOp:
@@ -244,6 +256,10 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		panic(targetPanic{fr.get(instr.X)})
 
 	case *ssa.Send:
+		if fr.i.sched != nil {
+			fr.i.sched.send(fr, instr.Pos(), fr.get(instr.Chan), fr.get(instr.X))
+			break
+		}
 		fr.get(instr.Chan).(chan value) <- fr.get(instr.X)
 
 	case *ssa.Store:
@@ -276,6 +292,10 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 
 	case *ssa.Go:
 		fn, args := prepareCall(fr, &instr.Call)
+		if fr.i.sched != nil {
+			fr.i.sched.spawn(instr.Pos(), fn, args)
+			break
+		}
 		atomic.AddInt32(&fr.i.goroutines, 1)
 		go func() {
 			call(fr.i, nil, instr.Pos(), fn, args)
@@ -283,6 +303,11 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		}()
 
 	case *ssa.MakeChan:
+		if fr.i.sched != nil {
+			elem := instr.Type().Underlying().(*types.Chan).Elem()
+			fr.env[instr] = newChannel(elem, int(asInt64(fr.get(instr.Size))))
+			break
+		}
 		fr.env[instr] = make(chan value, asInt64(fr.get(instr.Size)))
 
 	case *ssa.Alloc:
@@ -387,6 +412,10 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 		}
 
 	case *ssa.Select:
+		if fr.i.sched != nil {
+			fr.env[instr] = selectScheduled(fr, instr)
+			break
+		}
 		var cases []reflect.SelectCase
 		if !instr.Blocking {
 			cases = append(cases, reflect.SelectCase{
@@ -440,6 +469,38 @@ func visitInstr(fr *frame, instr ssa.Instruction) continuation {
 	return kNext
 }
 
+// selectScheduled implements a Select instruction
+// under the deterministic scheduler.
+func selectScheduled(fr *frame, instr *ssa.Select) value {
+	var cases []selectCase
+	for _, state := range instr.States {
+		c := selectCase{ch: asChannel(fr.get(state.Chan))}
+		if state.Dir != types.RecvOnly {
+			c.send = true
+			c.val = fr.get(state.Send)
+		}
+		cases = append(cases, c)
+	}
+	status := "select"
+	if len(cases) == 0 {
+		status = "select (no cases)"
+	}
+	chosen, recv, recvOk := fr.i.sched.doSelect(fr, instr.Pos(), cases, instr.Blocking, status)
+	r := tuple{chosen, recvOk}
+	for i, st := range instr.States {
+		if st.Dir == types.RecvOnly {
+			var v value
+			if i == chosen && recvOk {
+				v = recv
+			} else {
+				v = zero(st.Chan.Type().Underlying().(*types.Chan).Elem())
+			}
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
 // prepareCall determines the function value and argument values for a
 // function call in a Call, Go or Defer instruction, performing
 // interface method lookup if needed.
@@ -507,6 +568,12 @@ func callSSA(i *interpreter, caller *frame, callpos token.Pos, fn *ssa.Function,
 		}
 		defer fmt.Fprintf(os.Stderr, "Leaving %s%s.\n", fn, suffix)
 	}
+	if i.sched != nil && fn.Pkg != nil {
+		switch fn.Pkg.Pkg.Path() {
+		case "sync", "sync/atomic":
+			i.sched.yield() // scheduling point
+		}
+	}
 	fr := &frame{
 		i:      i,
 		caller: caller, // for panic/recover
@@ -530,6 +597,9 @@ func callSSA(i *interpreter, caller *frame, callpos token.Pos, fn *ssa.Function,
 		panic("interp requires ssa.BuilderMode to include InstantiateGenerics to execute generics")
 	}
 
+	if i.cov != nil {
+		fr.counts = i.cov.enter(fn)
+	}
 	fr.env = make(map[ssa.Value]value)
 	fr.block = fn.Blocks[0]
 	fr.locals = make([]value, len(fn.Locals))
@@ -573,6 +643,9 @@ func runFrame(fr *frame) {
 		if fr.block == nil {
 			return // normal return
 		}
+		if fr.i.sched != nil && fr.i.sched.stopped {
+			return // the program has terminated; see scheduler.stop
+		}
 		if fr.i.mode&DisableRecover != 0 {
 			return // let interpreter crash
 		}
@@ -589,6 +662,9 @@ func runFrame(fr *frame) {
 		if fr.i.mode&EnableTracing != 0 {
 			fmt.Fprintf(os.Stderr, ".%s:\n", fr.block)
 		}
+		if fr.counts != nil {
+			atomic.AddInt64(&fr.counts[fr.block.Index], 1)
+		}
 	block:
 		for _, instr := range fr.block.Instrs {
 			if fr.i.mode&EnableTracing != 0 {
@@ -598,6 +674,9 @@ func runFrame(fr *frame) {
 					fmt.Fprintln(os.Stderr, "\t", instr)
 				}
 			}
+			if fr.i.sched != nil && fr.i.sched.trace != nil {
+				fr.i.sched.traceInstr(fr, instr)
+			}
 			switch visitInstr(fr, instr) {
 			case kReturn:
 				return
@@ -619,6 +698,9 @@ func doRecover(caller *frame) value {
 	if caller.i.mode&DisableRecover == 0 &&
 		caller != nil && !caller.panicking &&
 		caller.caller != nil && caller.caller.panicking {
+		if _, ok := caller.caller.panic.(abortPanic); ok {
+			return iface{} // the program is terminating
+		}
 		caller.caller.panicking = false
 		p := caller.caller.panic
 		caller.caller.panic = nil
@@ -654,6 +736,47 @@ func doRecover(caller *frame) value {
 // Type parameterized functions must have been built with
 // InstantiateGenerics in the ssa.BuilderMode to be interpreted.
 func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename string, args []string) (exitCode int) {
+	return interpret(mainpkg, mode, sizes, filename, args, nil)
+}
+
+// A Config specifies optional features of the interpreter.
+type Config struct {
+	// Scheduler, if non-nil, causes the goroutines of the program
+	// to be run by a deterministic cooperative scheduler.
+	//
+	// Only one goroutine runs at a time, and goroutines switch only
+	// at channel operations, select statements, calls to functions of
+	// packages sync and sync/atomic, and calls to runtime.Gosched and
+	// time.Sleep, which do not sleep. The choice of the next goroutine
+	// at each of these points, and of the case of a select statement
+	// among several ready ones, is pseudo-random, so each execution is
+	// entirely determined by Scheduler.Seed, and different seeds
+	// explore different interleavings. (Map iteration order remains
+	// random.) Since no other goroutine can intervene between two
+	// scheduling points, sync/atomic operations are atomic and Mutexes
+	// work.
+	//
+	// If all goroutines are blocked, the program terminates with exit
+	// code 2 and a report of the blocked goroutines. An unrecovered
+	// panic in any goroutine terminates the program with exit code 2.
+	//
+	// If Scheduler.Trace is non-nil, each instruction executed by the
+	// program, and each scheduling event, is written to it, labeled by
+	// the goroutine, such as "[g2]".
+	Scheduler *SchedulerConfig
+
+	// Coverage, if non-nil, records the number of executions of
+	// each instruction and of calls to each function.
+	Coverage *Coverage
+}
+
+// InterpretWithConfig is like [Interpret], but it enables the
+// optional features specified by config.
+func InterpretWithConfig(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename string, args []string, config *Config) (exitCode int) {
+	return interpret(mainpkg, mode, sizes, filename, args, config)
+}
+
+func interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename string, args []string, config *Config) (exitCode int) {
 	i := &interpreter{
 		prog:       mainpkg.Prog,
 		globals:    make(map[*ssa.Global]*value),
@@ -666,6 +789,12 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 		panic("ssa.Program doesn't include runtime package")
 	}
 	i.runtimeErrorString = runtimePkg.Type("errorString").Object().Type()
+	if config != nil {
+		if config.Scheduler != nil {
+			i.sched = newScheduler(i, config.Scheduler)
+		}
+		i.cov = config.Coverage
+	}
 
 	initReflect(i)
 
@@ -685,16 +814,26 @@ func Interpret(mainpkg *ssa.Package, mode Mode, sizes types.Sizes, filename stri
 		}
 	}
 
+	if i.sched != nil {
+		defer i.sched.stop()
+	}
+
 	// Top-level error handler.
 	exitCode = 2
 	defer func() {
 		if exitCode != 2 || i.mode&DisableRecover != 0 {
 			return
 		}
-		switch p := recover().(type) {
+		p := recover()
+		if a, ok := p.(abortPanic); ok {
+			p = a.v // terminated by another goroutine or by deadlock
+		}
+		switch p := p.(type) {
 		case exitPanic:
 			exitCode = int(p)
 			return
+		case deadlockPanic:
+			fmt.Fprint(os.Stderr, string(p))
 		case targetPanic:
 			fmt.Fprintln(os.Stderr, "panic:", toString(p.v))
 		case runtime.Error:
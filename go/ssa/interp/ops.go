@@ -1000,6 +1000,10 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 		return copy(args[0].([]value), src.([]value))
 
 	case "close": // close(chan T)
+		if ch, ok := args[0].(*channel); ok {
+			caller.i.sched.close(ch)
+			return nil
+		}
 		close(args[0].(chan value))
 		return nil
 
@@ -1045,6 +1049,8 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 			return x.len()
 		case chan value:
 			return len(x)
+		case *channel:
+			return len(x.buf)
 		default:
 			panic(fmt.Sprintf("len: illegal operand: %T", x))
 		}
@@ -1059,6 +1065,8 @@ func callBuiltin(caller *frame, callpos token.Pos, fn *ssa.Builtin, args []value
 			return cap(x)
 		case chan value:
 			return cap(x)
+		case *channel:
+			return x.cap
 		default:
 			panic(fmt.Sprintf("cap: illegal operand: %T", x))
 		}
@@ -283,6 +283,8 @@ func ext۰reflect۰Value۰Len(fr *frame, args []value) value {
 		return len(v)
 	case chan value:
 		return cap(v)
+	case *channel:
+		return v.cap
 	case []value:
 		return len(v)
 	case *hashmap:
@@ -355,6 +357,8 @@ func ext۰reflect۰Value۰Pointer(fr *frame, args []value) value {
 		return uintptr(unsafe.Pointer(v))
 	case chan value:
 		return reflect.ValueOf(v).Pointer()
+	case *channel:
+		return uintptr(unsafe.Pointer(v))
 	case []value:
 		return reflect.ValueOf(v).Pointer()
 	case *hashmap:
@@ -465,6 +469,8 @@ func ext۰reflect۰Value۰IsNil(fr *frame, args []value) value {
 		return x == nil
 	case chan value:
 		return x == nil
+	case *channel:
+		return false
 	case map[value]value:
 		return x == nil
 	case *hashmap:
@@ -0,0 +1,462 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp
+
+// This file defines the deterministic scheduler.
+//
+// Each goroutine of the target program is still executed by a
+// goroutine of the interpreter, but only one of them, the current
+// goroutine, runs at a time; the others wait on their wake channel.
+// The current goroutine relinquishes control only at a scheduling
+// point: a channel operation (send, receive, close, or select), a call
+// to a function of package sync or sync/atomic, or a call to
+// runtime.Gosched or time.Sleep. At each scheduling point the next
+// goroutine is chosen pseudo-randomly among the runnable ones, as is
+// the case of a select statement when several are ready, so an
+// execution is entirely determined by the seed.
+//
+// When the program terminates, the wake channels of the remaining
+// goroutines are closed, and their interpreter goroutines exit without
+// running the deferred calls of the target program.
+//
+// Channels are represented by *channel, not chan value, so that no
+// channel operation blocks the interpreter goroutine. A nil channel
+// is still represented by chan value(nil), the zero value.
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"math/rand"
+	"runtime"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// A SchedulerConfig configures the deterministic scheduler
+// (see [Config.Scheduler]).
+type SchedulerConfig struct {
+	Seed  int64     // seed of the pseudo-random scheduling decisions
+	Trace io.Writer // if non-nil, receives a trace of each instruction and scheduling event
+}
+
+// If the program must terminate because of an event in a goroutine
+// other than the main one, such as an unrecovered panic, the main
+// goroutine panics with this type, which cannot be recovered.
+type abortPanic struct {
+	v interface{}
+}
+
+// If all goroutines are blocked, the program aborts with this type,
+// whose value is a report of the blocked goroutines.
+type deadlockPanic string
+
+type scheduler struct {
+	i       *interpreter
+	rand    *rand.Rand
+	trace   io.Writer
+	all     []*goroutine // live goroutines, in order of creation
+	main    *goroutine
+	current *goroutine  // the running goroutine
+	nextID  int         // id of the next goroutine
+	abort   interface{} // if non-nil, the value that terminates the program
+	stopped bool        // the program has terminated; see stop
+}
+
+type goroutine struct {
+	id   int
+	wake chan struct{} // receives a value when the goroutine is to run; closed by stop
+
+	// If the goroutine is blocked, status describes the operation,
+	// such as "chan receive", and pos and frame its location.
+	status string
+	pos    token.Pos
+	frame  *frame
+}
+
+func newScheduler(i *interpreter, config *SchedulerConfig) *scheduler {
+	s := &scheduler{
+		i:     i,
+		rand:  rand.New(rand.NewSource(config.Seed)),
+		trace: config.Trace,
+	}
+	s.main = s.newGoroutine()
+	s.current = s.main
+	return s
+}
+
+func (s *scheduler) newGoroutine() *goroutine {
+	s.nextID++
+	g := &goroutine{id: s.nextID, wake: make(chan struct{}, 1)}
+	s.all = append(s.all, g)
+	return g
+}
+
+func (s *scheduler) tracef(format string, args ...interface{}) {
+	if s.trace != nil {
+		fmt.Fprintf(s.trace, "[g%d] %s\n", s.current.id, fmt.Sprintf(format, args...))
+	}
+}
+
+// traceInstr writes a trace of the execution of instr in frame fr.
+func (s *scheduler) traceInstr(fr *frame, instr ssa.Instruction) {
+	if v, ok := instr.(ssa.Value); ok {
+		s.tracef("%s: %s = %s", fr.fn, v.Name(), instr)
+	} else {
+		s.tracef("%s: %s", fr.fn, instr)
+	}
+}
+
+// spawn creates a goroutine that calls fn with arguments args.
+// The new goroutine is runnable, but the current one keeps running.
+func (s *scheduler) spawn(pos token.Pos, fn value, args []value) {
+	g := s.newGoroutine()
+	s.tracef("go %s (g%d)", funcName(fn), g.id)
+	go func() {
+		ok := false
+		defer func() {
+			if ok || s.stopped {
+				return
+			}
+			p := recover()
+			if p == nil {
+				s.exit() // runtime.Goexit
+				return
+			}
+			if s.i.mode&DisableRecover != 0 {
+				panic(p) // let interpreter crash
+			}
+			s.terminate(p)
+		}()
+		s.wait(g)
+		s.tracef("start")
+		call(s.i, nil, pos, fn, args)
+		ok = true
+		s.exit()
+	}()
+}
+
+// funcName returns the name of the function value fn.
+func funcName(fn value) string {
+	switch fn := fn.(type) {
+	case *ssa.Function:
+		return fn.String()
+	case *closure:
+		return fn.Fn.String()
+	case *ssa.Builtin:
+		return fn.Name()
+	}
+	return toString(fn)
+}
+
+// exit removes the current goroutine, which has returned from its
+// function, and resumes another one.
+func (s *scheduler) exit() {
+	g := s.current
+	for k, x := range s.all {
+		if x == g {
+			s.all = append(s.all[:k], s.all[k+1:]...)
+			break
+		}
+	}
+	s.tracef("exit")
+	next := s.choose()
+	if next == nil {
+		s.deadlock()
+		return
+	}
+	s.current = next
+	next.wake <- struct{}{}
+}
+
+// yield is a scheduling point of the current goroutine, which is
+// runnable. It may resume another goroutine, in which case yield
+// returns when the current goroutine is next chosen.
+func (s *scheduler) yield() {
+	if s.abort != nil {
+		// The program is terminating, but the main goroutine
+		// is running deferred functions.
+		panic(abortPanic{s.abort})
+	}
+	s.reschedule()
+}
+
+// reschedule resumes a runnable goroutine chosen at random, which may
+// be the current one, and returns when the current goroutine is next
+// chosen.
+func (s *scheduler) reschedule() {
+	g := s.current
+	next := s.choose()
+	if next == nil {
+		s.deadlock()
+		s.wait(g) // the program has terminated, so wait never returns
+	}
+	if next == g {
+		return
+	}
+	s.current = next
+	next.wake <- struct{}{}
+	s.wait(g)
+	if s.abort != nil {
+		panic(abortPanic{s.abort}) // (only the main goroutine is woken)
+	}
+	s.tracef("resume")
+}
+
+// wait blocks until goroutine g is chosen to run. If instead the
+// program terminates, the calling interpreter goroutine exits.
+func (s *scheduler) wait(g *goroutine) {
+	<-g.wake
+	if s.stopped {
+		runtime.Goexit()
+	}
+}
+
+// stop releases the interpreter goroutines of the goroutines that
+// remain blocked or runnable when the program terminates, which must
+// be called by the main goroutine.
+func (s *scheduler) stop() {
+	s.stopped = true
+	for _, g := range s.all {
+		if g != s.main {
+			close(g.wake)
+		}
+	}
+}
+
+// choose returns a runnable goroutine chosen at random,
+// or nil if all goroutines are blocked.
+func (s *scheduler) choose() *goroutine {
+	var runnable []*goroutine
+	for _, g := range s.all {
+		if g.status == "" {
+			runnable = append(runnable, g)
+		}
+	}
+	if len(runnable) == 0 {
+		return nil
+	}
+	return runnable[s.rand.Intn(len(runnable))]
+}
+
+// terminate terminates the program by causing the main goroutine to
+// panic with p. It returns only if the current goroutine is not the
+// main one, in which case the current goroutine must not run again.
+func (s *scheduler) terminate(p interface{}) {
+	s.abort = p
+	if s.current == s.main {
+		panic(abortPanic{p})
+	}
+	s.current = s.main
+	s.main.wake <- struct{}{}
+}
+
+// deadlock terminates the program, all of whose goroutines are blocked,
+// with a report of their state.
+func (s *scheduler) deadlock() {
+	var buf strings.Builder
+	buf.WriteString("fatal error: all goroutines are asleep - deadlock!\n")
+	for _, g := range s.all {
+		fmt.Fprintf(&buf, "\ngoroutine %d [%s]:\n", g.id, g.status)
+		for fr := g.frame; fr != nil; fr = fr.caller {
+			if fr == g.frame {
+				fmt.Fprintf(&buf, "\t%s%s\n", fr.fn, loc(s.i.prog.Fset, g.pos))
+			} else {
+				fmt.Fprintf(&buf, "\t%s\n", fr.fn)
+			}
+		}
+	}
+	s.tracef("deadlock")
+	s.terminate(deadlockPanic(buf.String()))
+}
+
+// A runtimeError is a run-time panic raised by the scheduler, such as
+// a send on a closed channel. Like the errors of the Go runtime, with
+// which the interpreter reports the same panics when the scheduler is
+// disabled, it implements runtime.Error.
+type runtimeError string
+
+func (e runtimeError) Error() string { return string(e) }
+func (e runtimeError) RuntimeError() {}
+
+// -- channels --
+
+// A channel is a channel of the target program.
+type channel struct {
+	elem   types.Type // element type
+	buf    []value
+	cap    int
+	closed bool
+	recvq  []*sudog // blocked receivers
+	sendq  []*sudog // blocked senders
+}
+
+func newChannel(elem types.Type, size int) *channel {
+	return &channel{elem: elem, cap: size}
+}
+
+// A selectCase is a case of a select operation, or a send or receive.
+type selectCase struct {
+	ch   *channel // nil for a nil channel
+	send bool     // send (or receive)
+	val  value    // value to send
+}
+
+// A selection is the state of a blocked select operation.
+type selection struct {
+	g      *goroutine
+	cases  []selectCase
+	done   bool  // a case has been chosen
+	chosen int   // index of chosen case
+	recv   value // value received, if chosen case is a receive
+	recvOk bool  // whether a value was received
+	closed bool  // the chosen case is a send on a closed channel
+}
+
+// A sudog records a blocked select operation in the queue of the
+// channel of one of its cases.
+type sudog struct {
+	sel   *selection
+	index int // index of case
+}
+
+// asChannel returns the channel represented by x, or nil for a nil
+// channel.
+func asChannel(x value) *channel {
+	ch, _ := x.(*channel)
+	return ch
+}
+
+// dequeue removes and returns the first sudog of q whose select
+// operation is still blocked, or nil if there is none.
+func dequeue(q *[]*sudog) *sudog {
+	for len(*q) > 0 {
+		sg := (*q)[0]
+		*q = (*q)[1:]
+		if !sg.sel.done {
+			return sg
+		}
+	}
+	return nil
+}
+
+// complete unblocks the select operation of sg by choosing its case.
+func complete(sg *sudog, recv value, recvOk bool) {
+	sel := sg.sel
+	sel.done = true
+	sel.chosen = sg.index
+	sel.recv = recv
+	sel.recvOk = recvOk
+	sel.g.status = ""
+}
+
+// send implements a send operation of value x on channel ch.
+func (s *scheduler) send(fr *frame, pos token.Pos, ch value, x value) {
+	status := "chan send"
+	if asChannel(ch) == nil {
+		status += " (nil chan)"
+	}
+	s.doSelect(fr, pos, []selectCase{{ch: asChannel(ch), send: true, val: x}}, true, status)
+}
+
+// recv implements a receive operation on channel ch.
+func (s *scheduler) recv(fr *frame, pos token.Pos, ch value) (value, bool) {
+	status := "chan receive"
+	if asChannel(ch) == nil {
+		status += " (nil chan)"
+	}
+	_, v, ok := s.doSelect(fr, pos, []selectCase{{ch: asChannel(ch)}}, true, status)
+	return v, ok
+}
+
+// close implements the close operation on channel ch.
+func (s *scheduler) close(ch *channel) {
+	s.yield()
+	if ch.closed {
+		panic(runtimeError("close of closed channel"))
+	}
+	ch.closed = true
+	for sg := dequeue(&ch.recvq); sg != nil; sg = dequeue(&ch.recvq) {
+		complete(sg, zero(ch.elem), false)
+	}
+	for sg := dequeue(&ch.sendq); sg != nil; sg = dequeue(&ch.sendq) {
+		complete(sg, nil, false)
+		sg.sel.closed = true
+	}
+}
+
+// doSelect implements a select operation, which blocks if no case is
+// ready, unless blocking is false, in which case it returns -1.
+// status describes the operation in the report of a deadlock.
+func (s *scheduler) doSelect(fr *frame, pos token.Pos, cases []selectCase, blocking bool, status string) (chosen int, recv value, recvOk bool) {
+	s.yield()
+
+	// Poll the cases in random order.
+	for _, i := range s.rand.Perm(len(cases)) {
+		c := cases[i]
+		ch := c.ch
+		if ch == nil {
+			continue // never ready
+		}
+		if c.send {
+			if ch.closed {
+				panic(runtimeError("send on closed channel"))
+			}
+			if sg := dequeue(&ch.recvq); sg != nil {
+				complete(sg, c.val, true)
+				return i, nil, false
+			}
+			if len(ch.buf) < ch.cap {
+				ch.buf = append(ch.buf, c.val)
+				return i, nil, false
+			}
+		} else {
+			if len(ch.buf) > 0 {
+				v := ch.buf[0]
+				ch.buf = ch.buf[1:]
+				if sg := dequeue(&ch.sendq); sg != nil {
+					ch.buf = append(ch.buf, sg.sel.cases[sg.index].val)
+					complete(sg, nil, false)
+				}
+				return i, v, true
+			}
+			if sg := dequeue(&ch.sendq); sg != nil {
+				v := sg.sel.cases[sg.index].val
+				complete(sg, nil, false)
+				return i, v, true
+			}
+			if ch.closed {
+				return i, zero(ch.elem), false
+			}
+		}
+	}
+	if !blocking {
+		return -1, nil, false
+	}
+
+	// Block until another goroutine chooses a case.
+	g := s.current
+	sel := &selection{g: g, cases: cases}
+	for i, c := range cases {
+		if c.ch != nil {
+			sg := &sudog{sel, i}
+			if c.send {
+				c.ch.sendq = append(c.ch.sendq, sg)
+			} else {
+				c.ch.recvq = append(c.ch.recvq, sg)
+			}
+		}
+	}
+	g.status, g.pos, g.frame = status, pos, fr
+	s.tracef("block: %s%s", status, loc(s.i.prog.Fset, pos))
+	s.reschedule()
+	g.pos, g.frame = token.NoPos, nil
+	if sel.closed {
+		panic(runtimeError("send on closed channel"))
+	}
+	return sel.chosen, sel.recv, sel.recvOk
+}
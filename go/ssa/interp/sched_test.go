@@ -0,0 +1,315 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package interp_test
+
+import (
+	"bytes"
+	"go/build"
+	"go/types"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/interp"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// buildMain builds the main package whose source is src,
+// using the fake standard library of goroot.
+func buildMain(t *testing.T, goroot, src string) *ssa.Package {
+	ctx := build.Default // copy
+	ctx.GOROOT = goroot
+	ctx.GOOS = runtime.GOOS
+	ctx.GOARCH = runtime.GOARCH
+
+	conf := loader.Config{Build: &ctx}
+	f, err := conf.ParseFile("main.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CreateFromFiles("main", f)
+	conf.Import("runtime")
+	iprog, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prog := ssautil.CreateProgram(iprog, ssa.InstantiateGenerics|ssa.SanityCheckFunctions)
+	prog.Build()
+	return prog.Package(iprog.Created[0].Pkg)
+}
+
+// runScheduled interprets mainPkg under the deterministic scheduler,
+// and returns its exit code, its output, and its standard error.
+func runScheduled(t *testing.T, mainPkg *ssa.Package, config *interp.SchedulerConfig) (int, string, string) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		stderr <- string(data)
+	}()
+	saved := os.Stderr
+	os.Stderr = w
+	interp.CapturedOutput = new(bytes.Buffer)
+	defer func() {
+		interp.CapturedOutput = nil
+	}()
+
+	sizes := types.SizesFor("gc", runtime.GOARCH)
+	exitCode := interp.InterpretWithConfig(mainPkg, 0, sizes, "main", nil, &interp.Config{Scheduler: config})
+
+	os.Stderr = saved
+	w.Close()
+	return exitCode, interp.CapturedOutput.String(), <-stderr
+}
+
+const interleave = `
+package main
+
+import "runtime"
+
+func main() {
+	done := make(chan bool)
+	for i := 0; i < 3; i++ {
+		go func() {
+			for j := 0; j < 3; j++ {
+				print(i)
+				runtime.Gosched()
+			}
+			done <- true
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+}
+`
+
+// TestSchedulerSeed checks that an execution is determined by the seed,
+// and that different seeds produce different interleavings.
+func TestSchedulerSeed(t *testing.T) {
+	mainPkg := buildMain(t, makeGoroot(t), interleave)
+	outputs := make(map[string]bool)
+	for seed := int64(0); seed < 10; seed++ {
+		var first string
+		for run := 0; run < 3; run++ {
+			exitCode, output, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{Seed: seed})
+			if exitCode != 0 {
+				t.Fatalf("seed %d: exit code %d\n%s", seed, exitCode, stderr)
+			}
+			if run == 0 {
+				first = output
+			} else if output != first {
+				t.Errorf("seed %d: output %q differs from that of first run, %q", seed, output, first)
+			}
+		}
+		outputs[first] = true
+	}
+	if len(outputs) < 2 {
+		t.Errorf("10 seeds produced only %d interleavings", len(outputs))
+	}
+}
+
+// TestSchedulerMutex checks that a Mutex excludes other goroutines.
+func TestSchedulerMutex(t *testing.T) {
+	const src = `
+package main
+
+import (
+	"runtime"
+	"sync"
+)
+
+func main() {
+	var mu sync.Mutex
+	n := 0
+	done := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func() {
+			mu.Lock()
+			x := n
+			runtime.Gosched()
+			n = x + 1
+			mu.Unlock()
+			done <- true
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	if n != 10 {
+		panic(n)
+	}
+}
+`
+	mainPkg := buildMain(t, makeGoroot(t), src)
+	for seed := int64(0); seed < 5; seed++ {
+		if exitCode, _, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{Seed: seed}); exitCode != 0 {
+			t.Errorf("seed %d: exit code %d\n%s", seed, exitCode, stderr)
+		}
+	}
+}
+
+func TestSchedulerDeadlock(t *testing.T) {
+	const src = `
+package main
+
+func main() {
+	a, b := make(chan int), make(chan int)
+	go func() {
+		select {
+		case <-a:
+		case b <- 1:
+		}
+		a <- 1
+	}()
+	<-b
+	<-b
+}
+`
+	mainPkg := buildMain(t, makeGoroot(t), src)
+	exitCode, _, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{})
+	if exitCode != 2 {
+		t.Errorf("exit code %d, want 2", exitCode)
+	}
+	for _, want := range []string{
+		"fatal error: all goroutines are asleep - deadlock!",
+		"goroutine 1 [chan receive]:\n\tmain.main at main.go:14:2",
+		"goroutine 2 [chan send]:\n\tmain.main$1 at main.go:11:5",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("deadlock report does not contain %q:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestSchedulerPanic(t *testing.T) {
+	const src = `
+package main
+
+func main() {
+	defer func() {
+		recover()
+	}()
+	go func() {
+		panic("oops")
+	}()
+	select {}
+}
+`
+	mainPkg := buildMain(t, makeGoroot(t), src)
+	exitCode, _, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{})
+	if exitCode != 2 {
+		t.Errorf("exit code %d, want 2", exitCode)
+	}
+	if want := "panic: (string, oops)"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr does not contain %q:\n%s", want, stderr)
+	}
+}
+
+// TestSchedulerRuntimeError checks that the panics raised by channel
+// operations are runtime errors, as they are without the scheduler.
+func TestSchedulerRuntimeError(t *testing.T) {
+	const src = `
+package main
+
+import "runtime"
+
+func check(want string, f func()) {
+	defer func() {
+		err, ok := recover().(runtime.Error)
+		if !ok || err.Error() != "runtime error: "+want {
+			panic(err)
+		}
+	}()
+	f()
+}
+
+func main() {
+	check("close of closed channel", func() {
+		ch := make(chan int)
+		close(ch)
+		close(ch)
+	})
+	check("send on closed channel", func() {
+		ch := make(chan int, 1)
+		close(ch)
+		ch <- 1
+	})
+}
+`
+	mainPkg := buildMain(t, makeGoroot(t), src)
+	if exitCode, _, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{}); exitCode != 0 {
+		t.Errorf("exit code %d\n%s", exitCode, stderr)
+	}
+}
+
+// TestSchedulerStop checks that the interpreter goroutines of target
+// goroutines that are blocked when the program terminates exit.
+func TestSchedulerStop(t *testing.T) {
+	const src = `
+package main
+
+func main() {
+	ch := make(chan int)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer println("deferred call run after termination")
+			<-ch
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		go func() {}() // never started
+	}
+	ch <- 1
+}
+`
+	mainPkg := buildMain(t, makeGoroot(t), src)
+	before := runtime.NumGoroutine()
+	for _, seed := range []int64{0, 1} {
+		exitCode, output, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{Seed: seed})
+		if exitCode != 0 {
+			t.Fatalf("exit code %d\n%s", exitCode, stderr)
+		}
+		if strings.Contains(output, "deferred") {
+			t.Errorf("deferred calls of blocked goroutines ran after termination:\n%s", output)
+		}
+	}
+	var after int
+	for try := 0; try < 100; try++ {
+		if after = runtime.NumGoroutine(); after <= before {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("%d goroutines before, %d after", before, after)
+}
+
+func TestSchedulerTrace(t *testing.T) {
+	mainPkg := buildMain(t, makeGoroot(t), interleave)
+	var trace bytes.Buffer
+	if exitCode, _, stderr := runScheduled(t, mainPkg, &interp.SchedulerConfig{Trace: &trace}); exitCode != 0 {
+		t.Fatalf("exit code %d\n%s", exitCode, stderr)
+	}
+	for _, want := range []string{
+		`\[g1\] go main\.main\$1 \(g2\)`,
+		`\[g2\] start`,
+		`\[g2\] main\.main\$1: t\d+ = print\(t\d+\)`,
+		`\[g1\] block: chan receive at main\.go:18:3`,
+		`\[g4\] exit`,
+	} {
+		if !regexp.MustCompile(want).MatchString(trace.String()) {
+			t.Errorf("trace does not match %q:\n%s", want, trace.String())
+		}
+	}
+}
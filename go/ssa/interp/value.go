@@ -15,6 +15,7 @@ package interp
 // - map[value]value --- maps for which  usesBuiltinMap(keyType)
 //   *hashmap        --- maps for which !usesBuiltinMap(keyType)
 // - chan value
+// - *channel --- non-nil channels under the deterministic scheduler.
 // - []value --- slices
 // - iface --- interfaces.
 // - structure --- structs.  Fields are ordered and accessed by numeric indices.
@@ -238,7 +239,11 @@ func equals(t types.Type, x, y value) bool {
 	case *value:
 		return x == y.(*value)
 	case chan value:
-		return x == y.(chan value)
+		y, ok := y.(chan value) // (y may be a *channel)
+		return ok && x == y
+	case *channel:
+		y, ok := y.(*channel)
+		return ok && x == y
 	case structure:
 		return x.eq(t, y)
 	case array:
@@ -299,6 +304,8 @@ func hash(t types.Type, x value) int {
 		return int(uintptr(unsafe.Pointer(x)))
 	case chan value:
 		return int(uintptr(reflect.ValueOf(x).Pointer()))
+	case *channel:
+		return int(uintptr(unsafe.Pointer(x)))
 	case structure:
 		return x.hash(t)
 	case array:
@@ -398,6 +405,9 @@ func writeValue(buf *bytes.Buffer, v value) {
 	case chan value:
 		fmt.Fprintf(buf, "%v", v) // (an address)
 
+	case *channel:
+		fmt.Fprintf(buf, "%p", v)
+
 	case *value:
 		if v == nil {
 			buf.WriteString("<nil>")
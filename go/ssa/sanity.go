@@ -521,6 +521,8 @@ func (s *sanity) checkFunction(fn *Function) bool {
 			// ok (we always have the syntax set for instantiation)
 		} else if _, rng := fn.syntax.(*ast.RangeStmt); rng && fn.Synthetic == "range-over-func yield" {
 			// ok (range-func-yields are both synthetic and keep syntax)
+		} else if fn.Pkg != nil && fn.Pkg.decoded {
+			// ok (decoded functions have no syntax)
 		} else {
 			s.errorf("got fromSource=%t, hasSyntax=%t; want same values", src, syn)
 		}
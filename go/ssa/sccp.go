@@ -0,0 +1,463 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines the optional sparse conditional constant
+// propagation pass, enabled by the PruneDeadBranches builder mode.
+//
+// The algorithm is that of Wegman and Zadeck, 1991. Constant
+// propagation with conditional branches.
+// https://dl.acm.org/doi/10.1145/103135.103136
+//
+// It computes, optimistically, which blocks of a function are
+// executable and which registers hold constant values, by
+// propagating facts along both the control-flow graph and the
+// def/use graph. Then it replaces each If instruction whose condition
+// is constant by a Jump, deletes the blocks that cannot be executed,
+// and removes the corresponding φ-node edges.
+//
+// Only integer, boolean, and string values are folded: floating-point
+// arithmetic is performed exactly by go/constant, which may differ
+// from the rounded result computed at run time.
+
+import (
+	"go/constant"
+	"go/token"
+	"go/types"
+	"os"
+)
+
+// If true, print the functions changed by sccp.
+const debugSCCP = false
+
+// A lattice value describes what is known about a register.
+// The zero value is "undefined" (no definition yet executed);
+// a non-nil c is a constant; and overdefined means the value may
+// vary at run time.
+type latticeValue struct {
+	c           constant.Value
+	overdefined bool
+}
+
+var overdefined = latticeValue{overdefined: true}
+
+func (x latticeValue) equal(y latticeValue) bool {
+	if x.overdefined || y.overdefined || x.c == nil || y.c == nil {
+		return x.overdefined == y.overdefined && (x.c == nil) == (y.c == nil)
+	}
+	return x.c.Kind() == y.c.Kind() && constant.Compare(x.c, token.EQL, y.c)
+}
+
+// meet returns the greatest lower bound of x and y.
+func (x latticeValue) meet(y latticeValue) latticeValue {
+	switch {
+	case x.overdefined || y.c == nil && !y.overdefined:
+		return x
+	case y.overdefined || x.c == nil:
+		return y
+	case x.equal(y):
+		return x
+	}
+	return overdefined
+}
+
+// sccpState holds the state of sparse conditional constant
+// propagation over a single function.
+type sccpState struct {
+	fn         *Function
+	values     map[Value]latticeValue
+	executable []bool   // executable blocks, by Index
+	edges      [][]bool // executable edges, by successor Index and predecessor index
+	blockWork  []*BasicBlock
+	instrWork  []Instruction
+}
+
+// sccp performs sparse conditional constant propagation on fn and
+// removes the blocks and edges that cannot be executed.
+//
+// Preconditions:
+// - fn has no dead blocks (blockopt has run).
+// - Def/use info (Operands and Referrers) is up-to-date.
+//
+// Postcondition: if sccp changed fn, it rebuilt the dominator tree.
+func sccp(fn *Function) {
+	if len(fn.Blocks) == 0 {
+		return
+	}
+	s := &sccpState{
+		fn:         fn,
+		values:     make(map[Value]latticeValue),
+		executable: make([]bool, len(fn.Blocks)),
+		edges:      make([][]bool, len(fn.Blocks)),
+	}
+	for _, b := range fn.Blocks {
+		s.edges[b.Index] = make([]bool, len(b.Preds))
+	}
+
+	s.markExecutable(fn.Blocks[0])
+	if fn.Recover != nil {
+		s.markExecutable(fn.Recover)
+	}
+	for len(s.blockWork) > 0 || len(s.instrWork) > 0 {
+		for len(s.blockWork) > 0 {
+			b := s.blockWork[len(s.blockWork)-1]
+			s.blockWork = s.blockWork[:len(s.blockWork)-1]
+			for _, instr := range b.Instrs {
+				s.visit(instr)
+			}
+		}
+		for len(s.instrWork) > 0 {
+			instr := s.instrWork[len(s.instrWork)-1]
+			s.instrWork = s.instrWork[:len(s.instrWork)-1]
+			if s.executable[instr.Block().Index] {
+				s.visit(instr)
+			}
+		}
+	}
+
+	if s.prune() {
+		if debugSCCP {
+			fn.WriteTo(os.Stderr)
+		}
+		buildDomTree(fn)
+	}
+}
+
+// markExecutable marks block b executable, if it is not already.
+func (s *sccpState) markExecutable(b *BasicBlock) {
+	if !s.executable[b.Index] {
+		s.executable[b.Index] = true
+		s.blockWork = append(s.blockWork, b)
+	}
+}
+
+// markEdge marks the edge from block b to its successor b.Succs[i]
+// executable, and revisits the φ-nodes of the successor.
+func (s *sccpState) markEdge(b *BasicBlock, i int) {
+	succ := b.Succs[i]
+	changed := false
+	for j, pred := range succ.Preds {
+		if pred == b && !s.edges[succ.Index][j] {
+			s.edges[succ.Index][j] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	if s.executable[succ.Index] {
+		for _, phi := range succ.phis() {
+			s.visit(phi)
+		}
+	} else {
+		s.markExecutable(succ)
+	}
+}
+
+// value returns the lattice value of v.
+func (s *sccpState) value(v Value) latticeValue {
+	switch c := v.(type) {
+	case *Const:
+		if c.Value != nil && foldable(c.Type()) {
+			return latticeValue{c: c.Value}
+		}
+		return overdefined
+	case Instruction:
+		return s.values[v]
+	}
+	return overdefined // parameters, free variables, globals, functions
+}
+
+// setValue records that v has lattice value x, and if this is new
+// information, schedules the referrers of v to be revisited.
+func (s *sccpState) setValue(v Value, x latticeValue) {
+	if old := s.values[v]; !old.equal(x) {
+		s.values[v] = x
+		if refs := v.Referrers(); refs != nil {
+			s.instrWork = append(s.instrWork, *refs...)
+		}
+	}
+}
+
+// visit evaluates instruction instr, which is in an executable block.
+func (s *sccpState) visit(instr Instruction) {
+	switch instr := instr.(type) {
+	case *Phi:
+		var x latticeValue
+		for i, edge := range instr.Edges {
+			if s.edges[instr.Block().Index][i] {
+				x = x.meet(s.value(edge))
+			}
+		}
+		s.setValue(instr, x)
+
+	case *BinOp:
+		x, y := s.value(instr.X), s.value(instr.Y)
+		switch {
+		case x.overdefined || y.overdefined || !foldable(instr.Type()):
+			s.setValue(instr, overdefined)
+		case x.c != nil && y.c != nil:
+			s.setValue(instr, foldBinOp(instr.Op, x.c, y.c, instr.Type()))
+		}
+
+	case *UnOp:
+		x := s.value(instr.X)
+		switch {
+		case x.overdefined || !foldable(instr.Type()) || instr.Op == token.MUL || instr.Op == token.ARROW:
+			s.setValue(instr, overdefined)
+		case x.c != nil:
+			s.setValue(instr, foldUnOp(instr.Op, x.c, instr.Type()))
+		}
+
+	case *If:
+		cond := s.value(instr.Cond)
+		switch {
+		case cond.overdefined:
+			s.markEdge(instr.Block(), 0)
+			s.markEdge(instr.Block(), 1)
+		case cond.c != nil && constant.BoolVal(cond.c):
+			s.markEdge(instr.Block(), 0)
+		case cond.c != nil:
+			s.markEdge(instr.Block(), 1)
+		}
+
+	case *Jump:
+		s.markEdge(instr.Block(), 0)
+
+	case Value:
+		s.setValue(instr, overdefined)
+	}
+}
+
+// prune replaces each If instruction with a constant condition by a
+// Jump, and deletes the blocks that are not executable.
+// It reports whether it changed the function.
+func (s *sccpState) prune() bool {
+	fn := s.fn
+
+	// Check that every edge that was not found to be executable
+	// is either from a dead block or a constant If. (This is
+	// always true of valid SSA, but it is cheap to make sure.)
+	changed := false
+	for _, b := range fn.Blocks {
+		if !s.executable[b.Index] {
+			changed = true
+			continue
+		}
+		for j, pred := range b.Preds {
+			if !s.edges[b.Index][j] {
+				if !s.executable[pred.Index] {
+					continue
+				}
+				ifInstr, ok := pred.Instrs[len(pred.Instrs)-1].(*If)
+				if !ok || s.value(ifInstr.Cond).c == nil {
+					return false // can't happen
+				}
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return false
+	}
+
+	// Fold constant conditions.
+	for _, b := range fn.Blocks {
+		if !s.executable[b.Index] {
+			continue
+		}
+		ifInstr, ok := b.Instrs[len(b.Instrs)-1].(*If)
+		if !ok {
+			continue
+		}
+		cond := s.value(ifInstr.Cond)
+		if cond.c == nil {
+			continue
+		}
+		taken, dead := b.Succs[0], b.Succs[1]
+		if !constant.BoolVal(cond.c) {
+			taken, dead = dead, taken
+		}
+		if refs := ifInstr.Cond.Referrers(); refs != nil {
+			*refs = removeInstr(*refs, ifInstr)
+		}
+		jump := &Jump{}
+		jump.setBlock(b)
+		b.Instrs[len(b.Instrs)-1] = jump
+		b.Succs = append(b.succs2[:0], taken)
+		if taken != dead {
+			s.removePred(dead, b)
+		}
+	}
+
+	// Delete dead blocks, and their edges to live ones.
+	var rands []*Value
+	for i, b := range fn.Blocks {
+		if s.executable[b.Index] {
+			continue
+		}
+		for _, succ := range b.Succs {
+			if s.executable[succ.Index] {
+				s.removePred(succ, b)
+			}
+		}
+		for _, instr := range b.Instrs {
+			rands = instr.Operands(rands[:0])
+			for _, rand := range rands {
+				if *rand != nil {
+					if refs := (*rand).Referrers(); refs != nil {
+						*refs = removeInstr(*refs, instr)
+					}
+				}
+			}
+			if alloc, ok := instr.(*Alloc); ok && !alloc.Heap {
+				for j, l := range fn.Locals {
+					if l == alloc {
+						fn.Locals = append(fn.Locals[:j], fn.Locals[j+1:]...)
+						break
+					}
+				}
+			}
+		}
+		fn.Blocks[i] = nil
+	}
+	fn.removeNilBlocks()
+	return true
+}
+
+// removePred removes the edge from block pred to block b, updating
+// the φ-nodes of b and the referrers of their discarded operands.
+func (s *sccpState) removePred(b, pred *BasicBlock) {
+	i := b.predIndex(pred)
+	for _, instr := range b.phis() {
+		phi := instr.(*Phi)
+		if v := phi.Edges[i]; v != nil {
+			if refs := v.Referrers(); refs != nil {
+				*refs = removeInstr(*refs, phi)
+			}
+		}
+	}
+	b.removePred(pred)
+
+	// Restore referrers for operands still used by other edges.
+	for _, instr := range b.phis() {
+		phi := instr.(*Phi)
+		for _, v := range phi.Edges {
+			if refs := v.Referrers(); refs != nil && !containsInstr(*refs, phi) {
+				*refs = append(*refs, phi)
+			}
+		}
+	}
+}
+
+func containsInstr(refs []Instruction, instr Instruction) bool {
+	for _, ref := range refs {
+		if ref == instr {
+			return true
+		}
+	}
+	return false
+}
+
+// foldable reports whether sccp computes constant values of type t.
+func foldable(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsInteger|types.IsBoolean|types.IsString) != 0
+}
+
+// foldBinOp returns the lattice value of x op y, for constants x
+// and y, with the result of type t.
+func foldBinOp(op token.Token, x, y constant.Value, t types.Type) latticeValue {
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		if x.Kind() != y.Kind() {
+			return overdefined
+		}
+		return latticeValue{c: constant.MakeBool(constant.Compare(x, op, y))}
+
+	case token.SHL, token.SHR:
+		n, ok := constant.Uint64Val(y)
+		if !ok || y.Kind() != constant.Int || x.Kind() != constant.Int || n >= 64 {
+			return overdefined
+		}
+		return checkIntRange(constant.Shift(x, op, uint(n)), t)
+
+	case token.QUO, token.REM:
+		if y.Kind() != constant.Int || constant.Sign(y) == 0 {
+			return overdefined // division by zero panics
+		}
+		if op == token.QUO {
+			op = token.QUO_ASSIGN // integer division
+		}
+
+	case token.ADD, token.SUB, token.MUL, token.AND, token.OR, token.XOR, token.AND_NOT:
+		// ok
+
+	default:
+		return overdefined
+	}
+	if x.Kind() != y.Kind() {
+		return overdefined
+	}
+	return checkIntRange(constant.BinaryOp(x, op, y), t)
+}
+
+// foldUnOp returns the lattice value of op x, for constant x, with
+// the result of type t.
+func foldUnOp(op token.Token, x constant.Value, t types.Type) latticeValue {
+	switch op {
+	case token.NOT:
+		if x.Kind() == constant.Bool {
+			return latticeValue{c: constant.UnaryOp(op, x, 0)}
+		}
+	case token.SUB:
+		if x.Kind() == constant.Int {
+			return checkIntRange(constant.UnaryOp(op, x, 0), t)
+		}
+	case token.XOR:
+		// The complement of an unsigned value depends on its size.
+		if basic, ok := t.Underlying().(*types.Basic); ok && x.Kind() == constant.Int && basic.Info()&types.IsUnsigned == 0 {
+			return checkIntRange(constant.UnaryOp(op, x, 0), t)
+		}
+	}
+	return overdefined
+}
+
+// checkIntRange returns the constant c if it is representable by
+// values of type t, and overdefined if the operation that computed
+// it may have overflowed. Non-integer values are returned unchanged.
+//
+// The size of int, uint, and uintptr depends on the target
+// platform, so we assume conservatively that they have 32 bits.
+func checkIntRange(c constant.Value, t types.Type) latticeValue {
+	basic := t.Underlying().(*types.Basic)
+	if basic.Info()&types.IsInteger == 0 {
+		return latticeValue{c: c}
+	}
+	if c.Kind() != constant.Int {
+		return overdefined
+	}
+	var bits uint
+	switch basic.Kind() {
+	case types.Int8, types.Uint8:
+		bits = 8
+	case types.Int16, types.Uint16:
+		bits = 16
+	case types.Int32, types.Uint32, types.Int, types.Uint, types.Uintptr:
+		bits = 32
+	default:
+		bits = 64
+	}
+	if basic.Info()&types.IsUnsigned != 0 {
+		if u, exact := constant.Uint64Val(c); exact && (bits == 64 || u < 1<<bits) {
+			return latticeValue{c: c}
+		}
+	} else {
+		if i, exact := constant.Int64Val(c); exact && (bits == 64 || -1<<(bits-1) <= i && i < 1<<(bits-1)) {
+			return latticeValue{c: c}
+		}
+	}
+	return overdefined
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa_test
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestPruneDeadBranches(t *testing.T) {
+	const input = `package p
+
+const debug = false
+
+func dead()
+
+func constCond(x int) int {
+	if debug {
+		dead()
+	}
+	return x
+}
+
+func derivedCond(x int) int {
+	d := 2
+	if d*3 > 5 {
+		x++
+	} else {
+		dead()
+		x--
+	}
+	return x
+}
+
+func constPhi(x int) int {
+	k := 1
+	for i := 0; i < x; i++ {
+		k = k * 1
+	}
+	if k != 1 {
+		dead()
+	}
+	return k
+}
+
+func overflow() int8 {
+	x := int8(100)
+	if x+x > 0 { // overflows at run time
+		return 1
+	}
+	return 2
+}
+
+func varCond(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 2
+}
+
+func shortCircuit(x int) bool {
+	return debug && x > 0 || x < 0
+}
+`
+	pkg, _ := buildPackage(t, input, ssa.PruneDeadBranches|ssa.SanityCheckFunctions)
+	for _, test := range []struct {
+		fn      string
+		ifs     int  // number of If instructions after pruning
+		hasDead bool // contains a call to dead
+	}{
+		{"constCond", 0, false},
+		{"derivedCond", 0, false},
+		{"constPhi", 1, false}, // the loop condition remains
+		{"overflow", 1, false},
+		{"varCond", 1, false},
+		{"shortCircuit", 0, false},
+	} {
+		fn := pkg.Func(test.fn)
+		var ifs int
+		var hasDead bool
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch instr := instr.(type) {
+				case *ssa.If:
+					ifs++
+				case *ssa.Call:
+					if callee := instr.Call.StaticCallee(); callee != nil && callee.Name() == "dead" {
+						hasDead = true
+					}
+				}
+			}
+		}
+		if ifs != test.ifs || hasDead != test.hasDead {
+			var buf bytes.Buffer
+			ssa.WriteFunction(&buf, fn)
+			t.Errorf("%s: got %d Ifs and dead=%t, want %d and %t:\n%s",
+				test.fn, ifs, hasDead, test.ifs, test.hasDead, buf.String())
+		}
+	}
+}
+
+func TestBuilderModeString(t *testing.T) {
+	for _, s := range []string{"", "C", "DPFCNLIGB"} {
+		var m ssa.BuilderMode
+		if err := m.Set(s); err != nil {
+			t.Fatal(err)
+		}
+		var m2 ssa.BuilderMode
+		if err := m2.Set(m.String()); err != nil {
+			t.Fatal(err)
+		}
+		if m != m2 {
+			t.Errorf("Set(%q).String() = %q does not round-trip", s, m.String())
+		}
+	}
+}
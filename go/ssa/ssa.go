@@ -63,6 +63,7 @@ type Package struct {
 	init    *Function               // Func("init"); the package's init function
 	debug   bool                    // include full debug info in this package
 	syntax  bool                    // package was loaded from syntax
+	decoded bool                    // package was loaded by Program.DecodePackage
 
 	// The following fields are set transiently, then cleared
 	// after building.
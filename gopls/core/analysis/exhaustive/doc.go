@@ -0,0 +1,50 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package exhaustive defines an analyzer that reports switch
+// statements with missing cases.
+//
+// # Analyzer exhaustive
+//
+// exhaustive: report switch statements with missing cases
+//
+// The exhaustive analyzer reports a switch statement that has no
+// default case and does not cover every possible value of its
+// operand. It applies to two kinds of switch:
+//
+//   - a switch over a value of a named integer or string type whose
+//     package declares constants of that type (an "enum"), which must
+//     have a case for each accessible constant, or for another
+//     constant of the same value;
+//   - a type switch over a value of a named interface type with an
+//     unexported method (a "sealed" interface, which only types of
+//     its own package may implement), which must have a case for each
+//     accessible concrete type T or *T of that package that implements
+//     the interface, or for an interface that the type implements.
+//
+// By default, only switches over types whose declaration is marked
+// with an //exhaustive directive are checked:
+//
+//	//exhaustive
+//	type Suit int8
+//
+//	const (
+//		Spades Suit = iota
+//		Hearts
+//		Diamonds
+//		Clubs
+//	)
+//
+//	func f(s Suit) {
+//		switch s { // missing cases in switch of type Suit: Clubs, Diamonds
+//		case Spades, Hearts:
+//		}
+//	}
+//
+// The directive applies to uses of the type in any package. If the
+// -all flag is set, all switches over enum and sealed interface types
+// are checked, whether or not the type is marked.
+//
+// In gopls, the "Add cases" code action fills in the missing cases.
+package exhaustive
@@ -0,0 +1,213 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exhaustive
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"github.com/troll-zhao/tools/core/typesinternal"
+	"github.com/troll-zhao/tools/gopls/core/analysis/fillswitch"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:      "exhaustive",
+	Doc:       analysisinternal.MustExtractDoc(doc, "exhaustive"),
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{(*exhaustiveFact)(nil)},
+	URL:       "https://pkg.go.dev/golang.org/x/tools/gopls/internal/analysis/exhaustive",
+}
+
+var all bool // -all flag
+
+func init() {
+	Analyzer.Flags.BoolVar(&all, "all", false, "check switches over all enum and sealed interface types, not just those marked //exhaustive")
+}
+
+// An exhaustiveFact marks a type declared with an //exhaustive directive.
+type exhaustiveFact struct{}
+
+func (*exhaustiveFact) AFact()         {}
+func (*exhaustiveFact) String() string { return "exhaustive" }
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// Mark the types of this package declared with the directive.
+	inspect.Preorder([]ast.Node{(*ast.GenDecl)(nil)}, func(n ast.Node) {
+		decl := n.(*ast.GenDecl)
+		if decl.Tok != token.TYPE {
+			return
+		}
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.TypeSpec)
+			doc := spec.Doc
+			if doc == nil && len(decl.Specs) == 1 {
+				doc = decl.Doc
+			}
+			if hasDirective(doc) {
+				if obj, ok := pass.TypesInfo.Defs[spec.Name].(*types.TypeName); ok {
+					pass.ExportObjectFact(obj, new(exhaustiveFact))
+				}
+			}
+		}
+	})
+
+	nodeFilter := []ast.Node{
+		(*ast.SwitchStmt)(nil),
+		(*ast.TypeSwitchStmt)(nil),
+	}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		var (
+			named   *types.Named
+			missing []string
+		)
+		switch n := n.(type) {
+		case *ast.SwitchStmt:
+			if n.Tag == nil || hasDefaultCase(n.Body) {
+				return
+			}
+			var consts []*types.Const
+			named, consts = fillswitch.MissingConsts(n, pass.Pkg, pass.TypesInfo)
+			if named == nil || !isEnum(named) || !checked(pass, named) {
+				return
+			}
+			missing = missingConsts(pass.TypesInfo, n.Body, consts)
+
+		case *ast.TypeSwitchStmt:
+			if hasDefaultCase(n.Body) {
+				return
+			}
+			var concrete []types.Type
+			named, concrete = fillswitch.MissingTypes(n, pass.Pkg, pass.TypesInfo)
+			if named == nil || !isSealed(named) || !checked(pass, named) {
+				return
+			}
+			missing = missingTypes(pass.Pkg, pass.TypesInfo, n.Body, concrete)
+		}
+		if len(missing) > 0 {
+			pass.Report(analysis.Diagnostic{
+				Pos: n.Pos(),
+				End: n.Pos() + token.Pos(len("switch")),
+				Message: fmt.Sprintf("missing cases in switch of type %s: %s",
+					types.TypeString(named, typesinternal.NameRelativeTo(pass.Pkg)),
+					strings.Join(missing, ", ")),
+			})
+		}
+	})
+	return nil, nil
+}
+
+// checked reports whether switches over the named type are checked.
+func checked(pass *analysis.Pass, named *types.Named) bool {
+	return all || pass.ImportObjectFact(named.Obj(), new(exhaustiveFact))
+}
+
+// missingConsts returns the names of the constants that are missing
+// from the switch body, ignoring those whose value is covered by
+// another case or by an earlier missing constant.
+func missingConsts(info *types.Info, body *ast.BlockStmt, consts []*types.Const) []string {
+	var covered []constant.Value
+	for _, clause := range body.List {
+		for _, e := range clause.(*ast.CaseClause).List {
+			if tv := info.Types[e]; tv.Value != nil {
+				covered = append(covered, tv.Value)
+			}
+		}
+	}
+	var missing []string
+outer:
+	for _, c := range consts {
+		for _, v := range covered {
+			if constant.Compare(v, token.EQL, c.Val()) {
+				continue outer
+			}
+		}
+		covered = append(covered, c.Val())
+		missing = append(missing, c.Name())
+	}
+	return missing
+}
+
+// missingTypes returns the names of the types that are missing
+// from the switch body, ignoring those that implement an interface
+// type named by a case.
+func missingTypes(pkg *types.Package, info *types.Info, body *ast.BlockStmt, ts []types.Type) []string {
+	var ifaces []types.Type
+	for _, clause := range body.List {
+		for _, e := range clause.(*ast.CaseClause).List {
+			if tv := info.Types[e]; tv.IsType() && types.IsInterface(tv.Type) {
+				ifaces = append(ifaces, tv.Type)
+			}
+		}
+	}
+	var missing []string
+outer:
+	for _, t := range ts {
+		for _, iface := range ifaces {
+			if types.AssignableTo(t, iface) {
+				continue outer
+			}
+		}
+		missing = append(missing, types.TypeString(t, typesinternal.NameRelativeTo(pkg)))
+	}
+	return missing
+}
+
+// isEnum reports whether named has an underlying integer or string type.
+func isEnum(named *types.Named) bool {
+	basic, ok := named.Underlying().(*types.Basic)
+	return ok && basic.Info()&(types.IsInteger|types.IsString) != 0
+}
+
+// isSealed reports whether named is an interface type with an
+// unexported method.
+func isSealed(named *types.Named) bool {
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		return false
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		if !iface.Method(i).Exported() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirective reports whether the comment group contains an
+// //exhaustive directive.
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc != nil {
+		for _, comment := range doc.List {
+			if strings.TrimRight(comment.Text, " \t") == "//exhaustive" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasDefaultCase(body *ast.BlockStmt) bool {
+	for _, clause := range body.List {
+		if clause.(*ast.CaseClause).List == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exhaustive_test
+
+import (
+	"testing"
+
+	"github.com/troll-zhao/tools/gopls/core/analysis/exhaustive"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, exhaustive.Analyzer, "a")
+}
+
+func TestAll(t *testing.T) {
+	testdata := analysistest.TestData()
+	exhaustive.Analyzer.Flags.Set("all", "true")
+	defer exhaustive.Analyzer.Flags.Set("all", "false")
+	analysistest.Run(t, testdata, exhaustive.Analyzer, "b")
+}
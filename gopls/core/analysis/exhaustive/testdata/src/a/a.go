@@ -0,0 +1,101 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package a
+
+import "b"
+
+//exhaustive
+type Suit int8 // want Suit:"exhaustive"
+
+const (
+	Spades Suit = iota
+	Hearts
+	Diamonds
+	Clubs
+	Trumps = Clubs // an alias of Clubs
+)
+
+func suits(s Suit) {
+	switch s { // want `missing cases in switch of type Suit: Clubs, Diamonds$`
+	case Spades, Hearts:
+	}
+
+	switch s { // want `missing cases in switch of type Suit: Diamonds$`
+	case Spades, Hearts, Trumps:
+	}
+
+	switch s {
+	case Spades, Hearts, Diamonds, Clubs:
+	}
+
+	switch s {
+	case Spades:
+	default:
+	}
+}
+
+// Unmarked types are not checked.
+type Color string
+
+const (
+	Red   Color = "red"
+	Green Color = "green"
+)
+
+func colors(c Color) {
+	switch c {
+	case Red:
+	}
+}
+
+// Shape is a sealed interface.
+//
+//exhaustive
+type Shape interface { // want Shape:"exhaustive"
+	area() float64
+}
+
+type Circle struct{}
+
+func (Circle) area() float64 { return 0 }
+
+type Square struct{}
+
+func (*Square) area() float64 { return 0 }
+
+type Polygon interface {
+	Shape
+	sides() int
+}
+
+type Triangle struct{}
+
+func (Triangle) area() float64 { return 0 }
+func (Triangle) sides() int    { return 3 }
+
+func shapes(s Shape) {
+	switch s.(type) { // want `missing cases in switch of type Shape: \*Square, Triangle$`
+	case Circle:
+	}
+
+	switch s := s.(type) { // want `missing cases in switch of type Shape: \*Square$`
+	case Circle, Polygon:
+		_ = s
+	}
+
+	switch s.(type) {
+	case Circle, *Square, Triangle:
+	}
+}
+
+func imported(l b.Level, k b.Kind) {
+	switch l { // want `missing cases in switch of type b.Level: High$`
+	case b.Low:
+	}
+
+	switch k { // ok: Kind is not marked
+	case b.A:
+	}
+}
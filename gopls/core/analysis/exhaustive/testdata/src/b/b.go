@@ -0,0 +1,48 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package b
+
+//exhaustive
+type Level int // want Level:"exhaustive"
+
+const (
+	Low Level = iota
+	High
+)
+
+type Kind uint
+
+const (
+	A Kind = iota
+	B
+	c
+)
+
+func kinds(k Kind) {
+	switch k { // want `missing cases in switch of type Kind: B, c$`
+	case A:
+	}
+}
+
+type Float float64
+
+const Pi Float = 3.14
+
+func floats(f Float) {
+	switch f { // ok: not an integer or string type
+	}
+}
+
+// Exported is not sealed.
+type Exported interface{ M() }
+
+type T struct{}
+
+func (T) M() {}
+
+func exported(e Exported) {
+	switch e.(type) { // ok: not sealed
+	}
+}
@@ -55,16 +55,68 @@ func suggestedFixTypeSwitch(stmt *ast.TypeSwitchStmt, pkg *types.Package, info *
 		return nil
 	}
 
+	namedType, missing := MissingTypes(stmt, pkg, info)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, t := range missing {
+		if buf.Len() > 0 {
+			buf.WriteString("\t")
+		}
+
+		buf.WriteString("case ")
+		named, ok := t.(*types.Named)
+		if !ok {
+			buf.WriteByte('*')
+			named = t.(*types.Pointer).Elem().(*types.Named)
+		}
+
+		if p := named.Obj().Pkg(); p != pkg {
+			// TODO: use the correct package name when the import is renamed
+			buf.WriteString(p.Name())
+			buf.WriteByte('.')
+		}
+		buf.WriteString(named.Obj().Name())
+		buf.WriteString(":\n")
+	}
+
+	switch assign := stmt.Assign.(type) {
+	case *ast.AssignStmt:
+		addDefaultCase(&buf, namedType, assign.Lhs[0])
+	case *ast.ExprStmt:
+		if assert, ok := assign.X.(*ast.TypeAssertExpr); ok {
+			addDefaultCase(&buf, namedType, assert.X)
+		}
+	}
+
+	return &analysis.SuggestedFix{
+		Message: "Add cases for " + types.TypeString(namedType, typesinternal.NameRelativeTo(pkg)),
+		TextEdits: []analysis.TextEdit{{
+			Pos:     stmt.End() - token.Pos(len("}")),
+			End:     stmt.End() - token.Pos(len("}")),
+			NewText: buf.Bytes(),
+		}},
+	}
+}
+
+// MissingTypes returns the named type of the operand of a type
+// switch, and the accessible package-level concrete types T, or
+// pointers *T, declared in the package of that type, that implement
+// it but have no case in the switch. It returns nil, nil if the
+// operand does not have a named type.
+func MissingTypes(stmt *ast.TypeSwitchStmt, pkg *types.Package, info *types.Info) (*types.Named, []types.Type) {
 	namedType := namedTypeFromTypeSwitch(stmt, info)
 	if namedType == nil {
-		return nil
+		return nil, nil
 	}
 
 	existingCases := caseTypes(stmt.Body, info)
 	// Gather accessible package-level concrete types
 	// that implement the switch interface type.
 	scope := namedType.Obj().Pkg().Scope()
-	var buf bytes.Buffer
+	var missing []types.Type
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
 		if tname, ok := obj.(*types.TypeName); !ok || tname.IsAlias() {
@@ -88,43 +140,45 @@ func suggestedFixTypeSwitch(stmt *ast.TypeSwitchStmt, pkg *types.Package, info *
 			key.ptr = true
 		}
 
-		if key.named != nil {
-			if existingCases[key] {
-				continue
-			}
-
-			if buf.Len() > 0 {
-				buf.WriteString("\t")
-			}
-
-			buf.WriteString("case ")
+		if key.named != nil && !existingCases[key] {
 			if key.ptr {
-				buf.WriteByte('*')
-			}
-
-			if p := key.named.Obj().Pkg(); p != pkg {
-				// TODO: use the correct package name when the import is renamed
-				buf.WriteString(p.Name())
-				buf.WriteByte('.')
+				missing = append(missing, types.NewPointer(key.named))
+			} else {
+				missing = append(missing, key.named)
 			}
-			buf.WriteString(key.named.Obj().Name())
-			buf.WriteString(":\n")
 		}
 	}
 
-	if buf.Len() == 0 {
+	return namedType, missing
+}
+
+func suggestedFixSwitch(stmt *ast.SwitchStmt, pkg *types.Package, info *types.Info) *analysis.SuggestedFix {
+	if hasDefaultCase(stmt.Body) {
+		return nil
+	}
+
+	namedType, missing := MissingConsts(stmt, pkg, info)
+	if len(missing) == 0 {
 		return nil
 	}
 
-	switch assign := stmt.Assign.(type) {
-	case *ast.AssignStmt:
-		addDefaultCase(&buf, namedType, assign.Lhs[0])
-	case *ast.ExprStmt:
-		if assert, ok := assign.X.(*ast.TypeAssertExpr); ok {
-			addDefaultCase(&buf, namedType, assert.X)
+	var buf bytes.Buffer
+	for _, c := range missing {
+		if buf.Len() > 0 {
+			buf.WriteString("\t")
 		}
+
+		buf.WriteString("case ")
+		if c.Pkg() != pkg {
+			buf.WriteString(c.Pkg().Name())
+			buf.WriteByte('.')
+		}
+		buf.WriteString(c.Name())
+		buf.WriteString(":\n")
 	}
 
+	addDefaultCase(&buf, namedType, stmt.Tag)
+
 	return &analysis.SuggestedFix{
 		Message: "Add cases for " + types.TypeString(namedType, typesinternal.NameRelativeTo(pkg)),
 		TextEdits: []analysis.TextEdit{{
@@ -135,55 +189,31 @@ func suggestedFixTypeSwitch(stmt *ast.TypeSwitchStmt, pkg *types.Package, info *
 	}
 }
 
-func suggestedFixSwitch(stmt *ast.SwitchStmt, pkg *types.Package, info *types.Info) *analysis.SuggestedFix {
-	if hasDefaultCase(stmt.Body) {
-		return nil
-	}
-
+// MissingConsts returns the named type of the tag of a switch
+// statement, and the accessible package-level constants of that type,
+// declared in its package, that are not named by a case of the
+// switch. It returns nil, nil if the tag does not have a named type.
+func MissingConsts(stmt *ast.SwitchStmt, pkg *types.Package, info *types.Info) (*types.Named, []*types.Const) {
 	namedType, ok := info.TypeOf(stmt.Tag).(*types.Named)
 	if !ok {
-		return nil
+		return nil, nil
 	}
 
 	existingCases := caseConsts(stmt.Body, info)
 	// Gather accessible named constants of the same type as the switch value.
 	scope := namedType.Obj().Pkg().Scope()
-	var buf bytes.Buffer
+	var missing []*types.Const
 	for _, name := range scope.Names() {
 		obj := scope.Lookup(name)
 		if c, ok := obj.(*types.Const); ok &&
 			(obj.Pkg() == pkg || obj.Exported()) && // accessible
 			types.Identical(obj.Type(), namedType.Obj().Type()) &&
 			!existingCases[c] {
-
-			if buf.Len() > 0 {
-				buf.WriteString("\t")
-			}
-
-			buf.WriteString("case ")
-			if c.Pkg() != pkg {
-				buf.WriteString(c.Pkg().Name())
-				buf.WriteByte('.')
-			}
-			buf.WriteString(c.Name())
-			buf.WriteString(":\n")
+			missing = append(missing, c)
 		}
 	}
 
-	if buf.Len() == 0 {
-		return nil
-	}
-
-	addDefaultCase(&buf, namedType, stmt.Tag)
-
-	return &analysis.SuggestedFix{
-		Message: "Add cases for " + types.TypeString(namedType, typesinternal.NameRelativeTo(pkg)),
-		TextEdits: []analysis.TextEdit{{
-			Pos:     stmt.End() - token.Pos(len("}")),
-			End:     stmt.End() - token.Pos(len("}")),
-			NewText: buf.Bytes(),
-		}},
-	}
+	return namedType, missing
 }
 
 func addDefaultCase(buf *bytes.Buffer, named *types.Named, expr ast.Expr) {
@@ -0,0 +1,38 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package modernize provides the modernize analyzer.
+//
+// # Analyzer modernize
+//
+// modernize: simplify code by using modern constructs
+//
+// This analyzer reports opportunities for simplifying and clarifying
+// existing code by using more modern features of Go, such as:
+//
+//   - replacing interface{} by the 'any' type added in go1.18;
+//   - replacing an if/else conditional assignment by a call to the
+//     built-in min or max functions added in go1.21;
+//   - replacing a loop that searches a slice for an element by a call
+//     to slices.Contains or slices.Index, added in go1.21;
+//   - replacing a loop that copies one map into another by a call to
+//     maps.Copy, added in go1.21;
+//   - replacing sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+//     by a call to slices.Sort(s) or slices.SortFunc, added in go1.21;
+//   - replacing a 3-clause loop for i := 0; i < n; i++ {} by
+//     for i := range n {}, added in go1.22.
+//
+// Each suggestion is offered only in files whose effective Go version
+// (from the go directive of the module's go.mod file, or from a
+// "//go:build go1.N" constraint in the file) is at least the version
+// that introduced the feature. Files whose version is unknown, and
+// generated files, are not analyzed.
+//
+// Each suggestion comes with a fix that makes the change. A rewrite
+// is suggested only when it preserves the behavior of the program: for
+// example, min and max are not suggested for floating-point operands,
+// whose comparison with NaN differs, and range-over-int is suggested
+// only when the loop body does not modify the loop variable or the
+// limit.
+package modernize
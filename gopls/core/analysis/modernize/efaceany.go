@@ -0,0 +1,29 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"go/ast"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// efaceany offers a fix to replace interface{} by any (go1.18).
+func efaceany(pass *analysis.Pass, file *ast.File, iface *ast.InterfaceType) {
+	if len(iface.Methods.List) > 0 || hasComments(file, iface.Pos(), iface.End()) {
+		return
+	}
+	if !isUniverse(pass.TypesInfo, file, iface.Pos(), "any") {
+		return // 'any' is shadowed
+	}
+	report(pass, iface.Pos(), iface.End(), "efaceany",
+		"interface{} can be replaced by any",
+		"Replace interface{} by any",
+		[]analysis.TextEdit{{
+			Pos:     iface.Pos(),
+			End:     iface.End(),
+			NewText: []byte("any"),
+		}})
+}
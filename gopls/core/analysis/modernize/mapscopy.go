@@ -0,0 +1,66 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// mapscopy offers a fix to replace a loop that copies the entries
+// of one map into another by a call to maps.Copy (go1.21):
+//
+//	for k, v := range src { dst[k] = v }	=>	maps.Copy(dst, src)
+func mapscopy(pass *analysis.Pass, file *ast.File, rng *ast.RangeStmt) {
+	info := pass.TypesInfo
+
+	if rng.Tok != token.DEFINE || len(rng.Body.List) != 1 || hasComments(file, rng.Pos(), rng.End()) {
+		return
+	}
+	key, value := defined(info, rng.Key), defined(info, rng.Value)
+	if key == nil || value == nil {
+		return
+	}
+	assign, ok := rng.Body.List[0].(*ast.AssignStmt)
+	if !ok || assign.Tok != token.ASSIGN || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return
+	}
+	index, ok := assign.Lhs[0].(*ast.IndexExpr)
+	if !ok || !isVar(info, index.Index, key) || !isVar(info, assign.Rhs[0], value) {
+		return
+	}
+	dst := index.X
+	if !isPure(dst) || uses(info, dst, key) || uses(info, dst, value) {
+		return
+	}
+
+	// maps.Copy requires that both maps have identical key and
+	// element types, whereas the assignment permits conversions.
+	srcMap, ok := underlying(info, rng.X).(*types.Map)
+	if !ok {
+		return
+	}
+	dstMap, ok := underlying(info, dst).(*types.Map)
+	if !ok || !types.Identical(srcMap.Key(), dstMap.Key()) || !types.Identical(srcMap.Elem(), dstMap.Elem()) {
+		return
+	}
+
+	name, edits := addImport(info, file, rng.Pos(), "maps")
+	report(pass, rng.Pos(), rng.End(), "mapscopy",
+		"loop can be simplified using maps.Copy",
+		"Replace loop by maps.Copy",
+		append(edits, analysis.TextEdit{
+			Pos: rng.Pos(),
+			End: rng.End(),
+			NewText: fmt.Appendf(nil, "%s.Copy(%s, %s)",
+				name,
+				formatNode(pass.Fset, dst),
+				formatNode(pass.Fset, rng.X)),
+		}))
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// minmax offers a fix to replace an if/else conditional assignment
+// by a call to the built-in min or max function (go1.21):
+//
+//	if a < b { x = a } else { x = b }	=>	x = min(a, b)
+//
+// The statement must be an element of a statement list,
+// not the else branch of another if statement.
+func minmax(pass *analysis.Pass, file *ast.File, ifStmt *ast.IfStmt) {
+	if ifStmt.Init != nil || hasComments(file, ifStmt.Pos(), ifStmt.End()) {
+		return
+	}
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok {
+		return
+	}
+	elseBlock, ok := ifStmt.Else.(*ast.BlockStmt)
+	if !ok {
+		return
+	}
+	tassign := singleAssign(ifStmt.Body)
+	fassign := singleAssign(elseBlock)
+	if tassign == nil || fassign == nil || !equalSyntax(pass.Fset, tassign.Lhs[0], fassign.Lhs[0]) {
+		return
+	}
+
+	// Both operands are evaluated twice by the original
+	// statement, but only once by the call.
+	x, y := cond.X, cond.Y
+	if !isPure(x) || !isPure(y) {
+		return
+	}
+
+	// NaN compares false with all values, so the if/else
+	// statement and min/max disagree on floating-point operands.
+	for _, operand := range []ast.Expr{x, y} {
+		tv, ok := pass.TypesInfo.Types[operand]
+		if !ok {
+			return
+		}
+		basic, ok := tv.Type.Underlying().(*types.Basic)
+		if !ok || basic.Info()&(types.IsInteger|types.IsString) == 0 {
+			return
+		}
+	}
+
+	// The then branch assigns one operand, the else branch the other.
+	var thenX bool
+	switch {
+	case equalSyntax(pass.Fset, tassign.Rhs[0], x) && equalSyntax(pass.Fset, fassign.Rhs[0], y):
+		thenX = true
+	case equalSyntax(pass.Fset, tassign.Rhs[0], y) && equalSyntax(pass.Fset, fassign.Rhs[0], x):
+		thenX = false
+	default:
+		return
+	}
+
+	var fn string
+	switch cond.Op {
+	case token.LSS, token.LEQ:
+		fn = choose(thenX, "min", "max")
+	case token.GTR, token.GEQ:
+		fn = choose(thenX, "max", "min")
+	default:
+		return
+	}
+	if !isUniverse(pass.TypesInfo, file, ifStmt.Pos(), fn) {
+		return // min or max is shadowed
+	}
+
+	report(pass, ifStmt.Pos(), ifStmt.End(), "minmax",
+		fmt.Sprintf("if/else statement can be modernized using %s", fn),
+		fmt.Sprintf("Replace if/else with %s", fn),
+		[]analysis.TextEdit{{
+			Pos: ifStmt.Pos(),
+			End: ifStmt.End(),
+			NewText: fmt.Appendf(nil, "%s = %s(%s, %s)",
+				formatNode(pass.Fset, tassign.Lhs[0]),
+				fn,
+				formatNode(pass.Fset, x),
+				formatNode(pass.Fset, y)),
+		}})
+}
+
+// singleAssign returns the sole statement of the block if it is a
+// simple assignment x = y, or nil otherwise.
+func singleAssign(block *ast.BlockStmt) *ast.AssignStmt {
+	if len(block.List) == 1 {
+		if assign, ok := block.List[0].(*ast.AssignStmt); ok &&
+			assign.Tok == token.ASSIGN &&
+			len(assign.Lhs) == 1 &&
+			len(assign.Rhs) == 1 {
+			return assign
+		}
+	}
+	return nil
+}
+
+func choose(cond bool, x, y string) string {
+	if cond {
+		return x
+	}
+	return y
+}
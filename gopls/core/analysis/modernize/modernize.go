@@ -0,0 +1,208 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"bytes"
+	_ "embed"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"github.com/troll-zhao/tools/core/versions"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "modernize",
+	Doc:      analysisinternal.MustExtractDoc(doc, "modernize"),
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+	URL:      "https://pkg.go.dev/golang.org/x/tools/gopls/internal/analysis/modernize",
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.File)(nil),
+		(*ast.BlockStmt)(nil),
+		(*ast.CaseClause)(nil),
+		(*ast.CommClause)(nil),
+		(*ast.CallExpr)(nil),
+		(*ast.ForStmt)(nil),
+		(*ast.InterfaceType)(nil),
+		(*ast.RangeStmt)(nil),
+	}
+	var (
+		file    *ast.File
+		version string // effective Go version of file; "" => don't analyze
+	)
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		if _, ok := n.(*ast.File); !ok && version == "" {
+			return
+		}
+		switch n := n.(type) {
+		case *ast.File:
+			file = n
+			version = versions.FileVersion(pass.TypesInfo, n)
+			if version == versions.Future || ast.IsGenerated(n) {
+				version = ""
+			}
+
+		case *ast.InterfaceType:
+			if versions.AtLeast(version, versions.Go1_18) {
+				efaceany(pass, file, n)
+			}
+
+		case *ast.BlockStmt:
+			stmts(pass, file, version, n.List)
+		case *ast.CaseClause:
+			stmts(pass, file, version, n.Body)
+		case *ast.CommClause:
+			stmts(pass, file, version, n.Body)
+
+		case *ast.RangeStmt:
+			if versions.AtLeast(version, versions.Go1_21) {
+				mapscopy(pass, file, n)
+			}
+
+		case *ast.CallExpr:
+			if versions.AtLeast(version, versions.Go1_21) {
+				sortslice(pass, file, n)
+			}
+
+		case *ast.ForStmt:
+			if versions.AtLeast(version, versions.Go1_22) {
+				rangeint(pass, file, n)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// stmts applies the modernizers that operate on statement lists.
+func stmts(pass *analysis.Pass, file *ast.File, version string, list []ast.Stmt) {
+	if !versions.AtLeast(version, versions.Go1_21) {
+		return
+	}
+	for i, stmt := range list {
+		switch stmt := stmt.(type) {
+		case *ast.IfStmt:
+			minmax(pass, file, stmt)
+		case *ast.RangeStmt:
+			if i+1 < len(list) {
+				if ret, ok := list[i+1].(*ast.ReturnStmt); ok {
+					slicescontains(pass, file, stmt, ret)
+				}
+			}
+		}
+	}
+}
+
+// report reports a diagnostic of the specified category
+// spanning [pos, end), with a single fix comprising edits.
+func report(pass *analysis.Pass, pos, end token.Pos, category, message, fix string, edits []analysis.TextEdit) {
+	pass.Report(analysis.Diagnostic{
+		Pos:      pos,
+		End:      end,
+		Category: category,
+		Message:  message,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   fix,
+			TextEdits: edits,
+		}},
+	})
+}
+
+// formatNode returns the source form of n.
+func formatNode(fset *token.FileSet, n ast.Node) string {
+	var buf bytes.Buffer
+	format.Node(&buf, fset, n) // ignore errors
+	return buf.String()
+}
+
+// equalSyntax reports whether x and y have the same source form.
+func equalSyntax(fset *token.FileSet, x, y ast.Node) bool {
+	return formatNode(fset, x) == formatNode(fset, y)
+}
+
+// isPure reports whether e is an expression free of side effects
+// whose repeated evaluation always yields the same value in the
+// absence of intervening assignments: an identifier, a literal, or a
+// field selection from such an expression.
+func isPure(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	case *ast.ParenExpr:
+		return isPure(e.X)
+	case *ast.SelectorExpr:
+		return isPure(e.X)
+	}
+	return false
+}
+
+// uses reports whether n contains a reference to obj.
+func uses(info *types.Info, n ast.Node, obj types.Object) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && info.Uses[id] == obj {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// hasComments reports whether the file has a comment within
+// [pos, end). Such comments would be lost by replacing that range.
+func hasComments(file *ast.File, pos, end token.Pos) bool {
+	for _, cg := range file.Comments {
+		if pos <= cg.Pos() && cg.End() <= end {
+			return true
+		}
+	}
+	return false
+}
+
+// isUniverse reports whether name refers to the universal
+// (predeclared) object of that name at pos in file.
+func isUniverse(info *types.Info, file *ast.File, pos token.Pos, name string) bool {
+	scope := info.Scopes[file].Innermost(pos)
+	if scope == nil {
+		return false
+	}
+	_, obj := scope.LookupParent(name, pos)
+	return obj != nil && obj == types.Universe.Lookup(name)
+}
+
+// isConstant reports whether e is a constant expression with
+// the specified value (as formatted by constant.Value.String).
+func isConstant(info *types.Info, e ast.Expr, value string) bool {
+	tv, ok := info.Types[e]
+	return ok && tv.Value != nil && tv.Value.String() == value
+}
+
+// underlying returns the underlying type of expression e, or nil.
+func underlying(info *types.Info, e ast.Expr) types.Type {
+	if tv, ok := info.Types[e]; ok {
+		return tv.Type.Underlying()
+	}
+	return nil
+}
+
+// addImport returns the name by which the standard package of the
+// specified path may be referred to at pos in file, along with
+// edits to add an import of it, if needed.
+func addImport(info *types.Info, file *ast.File, pos token.Pos, path string) (string, []analysis.TextEdit) {
+	return analysisinternal.AddImport(info, file, pos, path, path)
+}
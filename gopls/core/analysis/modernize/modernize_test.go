@@ -0,0 +1,16 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize_test
+
+import (
+	"testing"
+
+	"github.com/troll-zhao/tools/gopls/core/analysis/modernize"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func Test(t *testing.T) {
+	analysistest.RunWithEachSuggestedFix(t, analysistest.TestData(), modernize.Analyzer, "example.com/a", "example.com/old")
+}
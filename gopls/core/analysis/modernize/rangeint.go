@@ -0,0 +1,200 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// rangeint offers a fix to replace a 3-clause for loop over the
+// integers [0, n) by a range over an integer (go1.22):
+//
+//	for i := 0; i < n; i++ { ... }	=>	for i := range n { ... }
+//
+// The range expression is evaluated once, whereas the loop condition
+// is evaluated on each iteration, so the limit n must be a constant,
+// or a local variable (or len of one) that the loop body does not
+// assign, and that no function literal captures and whose address is
+// not taken; and the body must not assign the loop variable.
+func rangeint(pass *analysis.Pass, file *ast.File, loop *ast.ForStmt) {
+	info := pass.TypesInfo
+
+	// i := 0
+	init, ok := loop.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+		return
+	}
+	index := defined(info, init.Lhs[0])
+	if index == nil || !isConstant(info, init.Rhs[0], "0") {
+		return
+	}
+
+	// i < limit
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.LSS || !isVar(info, cond.X, index) {
+		return
+	}
+	limit := cond.Y
+
+	// i++
+	post, ok := loop.Post.(*ast.IncDecStmt)
+	if !ok || post.Tok != token.INC || !isVar(info, post.X, index) {
+		return
+	}
+
+	if hasComments(file, loop.Init.Pos(), loop.Post.End()) {
+		return
+	}
+	if basic, ok := underlying(info, limit).(*types.Basic); !ok || basic.Info()&types.IsInteger == 0 {
+		return // e.g. untyped float constant
+	}
+	if !invariant(info, file, limit, loop.Body) || assigns(info, loop.Body, index) {
+		return
+	}
+
+	// The range variable has the type of the limit, or int if the
+	// limit is an untyped constant, so it must match the index type.
+	// (The type that go/types records for an untyped constant limit
+	// is that of the index, to which it is converted.) A constant
+	// limit is converted to the type of a non-int index, using the
+	// type of the conversion that initializes the index:
+	//
+	//	for i := uint8(0); i < 10; i++	=>	for i := range uint8(10)
+	rng := formatNode(pass.Fset, limit)
+	if uses(info, loop.Body, index) {
+		if info.Types[limit].Value != nil {
+			if !types.Identical(index.Type(), types.Typ[types.Int]) && !isTypedConst(info, limit, index.Type()) {
+				conv, ok := init.Rhs[0].(*ast.CallExpr)
+				if !ok || len(conv.Args) != 1 || !info.Types[conv.Fun].IsType() {
+					return // e.g. i := zero, where zero is a typed constant
+				}
+				rng = fmt.Sprintf("%s(%s)", formatNode(pass.Fset, conv.Fun), rng)
+			}
+		} else if !types.Identical(index.Type(), info.TypeOf(limit)) {
+			return
+		}
+		rng = init.Lhs[0].(*ast.Ident).Name + " := range " + rng
+	} else {
+		rng = "range " + rng
+	}
+
+	report(pass, loop.Pos(), loop.Body.Lbrace, "rangeint",
+		"for loop can be modernized using range over int",
+		fmt.Sprintf("Replace for loop with range %s", formatNode(pass.Fset, limit)),
+		[]analysis.TextEdit{{
+			Pos:     loop.Init.Pos(),
+			End:     loop.Post.End(),
+			NewText: []byte(rng),
+		}})
+}
+
+// isTypedConst reports whether e is a reference to a constant
+// declared with type t.
+func isTypedConst(info *types.Info, e ast.Expr, t types.Type) bool {
+	id, ok := ast.Unparen(e).(*ast.Ident)
+	if !ok {
+		return false
+	}
+	c, ok := info.Uses[id].(*types.Const)
+	return ok && types.Identical(c.Type(), t)
+}
+
+// invariant reports whether the loop limit e has the same value
+// on every iteration of the loop whose body is specified.
+func invariant(info *types.Info, file *ast.File, e ast.Expr, body *ast.BlockStmt) bool {
+	if tv, ok := info.Types[e]; ok && tv.Value != nil {
+		return true // constant
+	}
+	// len(x)
+	if call, ok := e.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		if id, ok := call.Fun.(*ast.Ident); ok {
+			if _, ok := info.Uses[id].(*types.Builtin); ok && id.Name == "len" {
+				e = call.Args[0]
+			}
+		}
+	}
+	// A local variable not assigned by the loop body, nor by a
+	// call. (Package-level variables may be assigned by any call.)
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	v, ok := info.Uses[id].(*types.Var)
+	if !ok || v.Pkg() == nil || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+		return false
+	}
+	return !assigns(info, body, v) && !escapes(info, file, v)
+}
+
+// escapes reports whether the local variable v may be assigned by a
+// call: whether a function literal captures it, or its address is
+// taken, anywhere in its scope.
+func escapes(info *types.Info, file *ast.File, v *types.Var) bool {
+	scope := v.Parent()
+	found := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if found || n == nil || n.End() <= scope.Pos() || scope.End() <= n.Pos() {
+			return false // outside the scope of v
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// (A function literal that declares v does not capture it.)
+			if !(n.Pos() <= v.Pos() && v.Pos() < n.End()) && uses(info, n.Body, v) {
+				found = true
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND && isVar(info, n.X, v) {
+				found = true
+			}
+		case *ast.SelectorExpr:
+			// v.M, where M has a pointer receiver, takes the address of v.
+			if sel, ok := info.Selections[n]; ok && sel.Kind() == types.MethodVal && isVar(info, n.X, v) {
+				recv := sel.Obj().Type().(*types.Signature).Recv().Type()
+				if isPointer(recv) && !isPointer(v.Type()) {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isPointer reports whether t is a pointer type.
+func isPointer(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Pointer)
+	return ok
+}
+
+// assigns reports whether n may assign v, either directly or by
+// taking its address.
+func assigns(info *types.Info, n ast.Node, v *types.Var) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if isVar(info, lhs, v) {
+					found = true
+				}
+			}
+		case *ast.IncDecStmt:
+			if isVar(info, n.X, v) {
+				found = true
+			}
+		case *ast.UnaryExpr:
+			if n.Op == token.AND && isVar(info, n.X, v) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// slicescontains offers a fix to replace a loop that searches a
+// slice, followed by a return statement, by a call to
+// slices.Contains or slices.Index (go1.21):
+//
+//	for _, elem := range s {
+//		if elem == needle {
+//			return true
+//		}
+//	}
+//	return false
+//
+// =>
+//
+//	return slices.Contains(s, needle)
+//
+// and similarly for a loop that returns the index i of the element,
+// or -1, which becomes slices.Index.
+func slicescontains(pass *analysis.Pass, file *ast.File, rng *ast.RangeStmt, ret *ast.ReturnStmt) {
+	info := pass.TypesInfo
+
+	if rng.Tok != token.DEFINE || rng.Value == nil || len(rng.Body.List) != 1 || len(ret.Results) != 1 {
+		return
+	}
+	if hasComments(file, rng.Pos(), ret.End()) {
+		return
+	}
+	slice, ok := underlying(info, rng.X).(*types.Slice)
+	if !ok {
+		return
+	}
+	elem := defined(info, rng.Value)
+	if elem == nil {
+		return
+	}
+	key := defined(info, rng.Key) // nil if absent or blank
+
+	// if elem == needle { return result }
+	ifStmt, ok := rng.Body.List[0].(*ast.IfStmt)
+	if !ok || ifStmt.Init != nil || ifStmt.Else != nil || len(ifStmt.Body.List) != 1 {
+		return
+	}
+	cond, ok := ifStmt.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.EQL {
+		return
+	}
+	var needle ast.Expr
+	switch {
+	case isVar(info, cond.X, elem):
+		needle = cond.Y
+	case isVar(info, cond.Y, elem):
+		needle = cond.X
+	default:
+		return
+	}
+	// The needle is evaluated once by the call, but on each
+	// iteration by the loop, so it must be pure and loop-invariant.
+	if !isPure(needle) || uses(info, needle, elem) || key != nil && uses(info, needle, key) {
+		return
+	}
+	// slices.Contains infers the element type from the slice, so
+	// the needle must have that type, or be an untyped constant.
+	if t := info.TypeOf(needle); !types.Identical(t, slice.Elem()) &&
+		!(isUntyped(t) && types.AssignableTo(t, slice.Elem())) {
+		return
+	}
+	inner, ok := ifStmt.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(inner.Results) != 1 {
+		return
+	}
+
+	var fn string
+	switch {
+	case key == nil && isConstant(info, inner.Results[0], "true") && isConstant(info, ret.Results[0], "false"):
+		fn = "Contains"
+	case key != nil && isVar(info, inner.Results[0], key) && isConstant(info, ret.Results[0], "-1"):
+		fn = "Index"
+	default:
+		return
+	}
+
+	name, edits := addImport(info, file, rng.Pos(), "slices")
+	report(pass, rng.Pos(), ret.End(), "slicescontains",
+		fmt.Sprintf("loop can be simplified using slices.%s", fn),
+		fmt.Sprintf("Replace loop by slices.%s", fn),
+		append(edits, analysis.TextEdit{
+			Pos: rng.Pos(),
+			End: ret.End(),
+			NewText: fmt.Appendf(nil, "return %s.%s(%s, %s)",
+				name,
+				fn,
+				formatNode(pass.Fset, rng.X),
+				formatNode(pass.Fset, needle)),
+		}))
+}
+
+// isUntyped reports whether t is the type of an untyped constant or nil.
+func isUntyped(t types.Type) bool {
+	basic, ok := t.(*types.Basic)
+	return ok && basic.Info()&types.IsUntyped != 0
+}
+
+// defined returns the variable defined by the identifier e,
+// or nil if e is not a non-blank identifier.
+func defined(info *types.Info, e ast.Expr) *types.Var {
+	if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+		v, _ := info.Defs[id].(*types.Var)
+		return v
+	}
+	return nil
+}
+
+// isVar reports whether e is a reference to v.
+func isVar(info *types.Info, e ast.Expr, v *types.Var) bool {
+	id, ok := e.(*ast.Ident)
+	return ok && info.Uses[id] == v
+}
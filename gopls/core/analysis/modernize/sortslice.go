@@ -0,0 +1,189 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modernize
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"golang.org/x/tools/go/analysis"
+)
+
+// sortslice offers a fix to replace a call to sort.Slice by a call to
+// slices.Sort or slices.SortFunc (go1.21):
+//
+//	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+//	=> slices.Sort(s)
+//
+//	sort.Slice(s, func(i, j int) bool { return s[i].f < s[j].f })
+//	=> slices.SortFunc(s, func(a, b T) int { return cmp.Compare(a.f, b.f) })
+//
+// The less function must compare the same pure function of s[i] and
+// s[j] and refer to i and j only as indices of s. If the call is the
+// only use of package sort in the file, the import is deleted.
+func sortslice(pass *analysis.Pass, file *ast.File, call *ast.CallExpr) {
+	info := pass.TypesInfo
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || len(call.Args) != 2 {
+		return
+	}
+	fn, ok := info.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "sort" || fn.Name() != "Slice" {
+		return
+	}
+	s := call.Args[0]
+	slice, ok := underlying(info, s).(*types.Slice)
+	if !ok || !isPure(s) {
+		return
+	}
+
+	// func(i, j int) bool { return x < y }
+	lit, ok := call.Args[1].(*ast.FuncLit)
+	if !ok || len(lit.Body.List) != 1 || hasComments(file, lit.Pos(), lit.End()) {
+		return
+	}
+	var params []*types.Var
+	for _, field := range lit.Type.Params.List {
+		for _, name := range field.Names {
+			params = append(params, defined(info, name))
+		}
+	}
+	if len(params) != 2 || params[0] == nil || params[1] == nil {
+		return
+	}
+	i, j := params[0], params[1]
+	ret, ok := lit.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return
+	}
+	less, ok := ret.Results[0].(*ast.BinaryExpr)
+	if !ok || less.Op != token.LSS && less.Op != token.GTR {
+		return
+	}
+
+	// Replace s[i] in the left operand and s[j] in the right
+	// by placeholders, and check that the results are equal.
+	const a, b = "a", "b"
+	if mentions(less, a, b) {
+		return // placeholder names would be captured
+	}
+	xa, ok := substIndex(pass.Fset, info, less.X, s, i, a)
+	if !ok {
+		return
+	}
+	yb, ok := substIndex(pass.Fset, info, less.Y, s, j, b)
+	if !ok {
+		return
+	}
+	if xb, _ := substIndex(pass.Fset, info, less.X, s, i, b); xb != yb {
+		return
+	}
+
+	slicesName, edits := addImport(info, file, call.Pos(), "slices")
+	var fnName, newText string
+	if less.Op == token.LSS && xa == a {
+		// s[i] < s[j]
+		fnName = "Sort"
+		newText = fmt.Sprintf("%s.Sort(%s)", slicesName, formatNode(pass.Fset, s))
+	} else {
+		elem, ok := typeString(info, file, pass.Pkg, slice.Elem())
+		if !ok {
+			return
+		}
+		if less.Op == token.GTR {
+			xa, yb = yb, xa // descending
+		}
+		cmpName, cmpEdits := addImport(info, file, call.Pos(), "cmp")
+		edits = append(edits, cmpEdits...)
+		fnName = "SortFunc"
+		newText = fmt.Sprintf("%s.SortFunc(%s, func(%s, %s %s) int { return %s.Compare(%s, %s) })",
+			slicesName, formatNode(pass.Fset, s), a, b, elem, cmpName, xa, yb)
+	}
+	edits = append(edits, analysis.TextEdit{
+		Pos:     call.Pos(),
+		End:     call.End(),
+		NewText: []byte(newText),
+	})
+	edits = append(edits, analysisinternal.DeleteUnusedImport(info, file, sel)...)
+
+	report(pass, call.Pos(), call.End(), "sortslice",
+		fmt.Sprintf("sort.Slice can be modernized using slices.%s", fnName),
+		fmt.Sprintf("Replace sort.Slice by slices.%s", fnName),
+		edits)
+}
+
+// substIndex returns the source form of e in which each occurrence
+// of s[i] is replaced by the specified name. It reports false if e
+// is impure, or refers to i other than as an index of s.
+func substIndex(fset *token.FileSet, info *types.Info, e ast.Expr, s ast.Expr, i *types.Var, name string) (string, bool) {
+	sText := formatNode(fset, s)
+	var subst func(e ast.Expr) (string, bool)
+	subst = func(e ast.Expr) (string, bool) {
+		switch e := e.(type) {
+		case *ast.IndexExpr:
+			if isVar(info, e.Index, i) && formatNode(fset, e.X) == sText {
+				return name, true
+			}
+		case *ast.ParenExpr:
+			x, ok := subst(e.X)
+			return "(" + x + ")", ok
+		case *ast.SelectorExpr:
+			x, ok := subst(e.X)
+			return x + "." + e.Sel.Name, ok
+		}
+		if !isPure(e) || uses(info, e, i) {
+			return "", false
+		}
+		return formatNode(fset, e), true
+	}
+	return subst(e)
+}
+
+// mentions reports whether n contains an identifier with one of the
+// specified names.
+func mentions(n ast.Node, names ...string) bool {
+	found := false
+	ast.Inspect(n, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			for _, name := range names {
+				if id.Name == name {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// typeString returns the source form of t as it may be written in
+// file, which must already import each package that t mentions.
+func typeString(info *types.Info, file *ast.File, pkg *types.Package, t types.Type) (string, bool) {
+	ok := true
+	qual := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		for _, spec := range file.Imports {
+			var obj types.Object
+			if spec.Name != nil {
+				obj = info.Defs[spec.Name]
+			} else {
+				obj = info.Implicits[spec]
+			}
+			if pkgname, is := obj.(*types.PkgName); is && pkgname.Imported() == p {
+				return pkgname.Name()
+			}
+		}
+		ok = false
+		return p.Name()
+	}
+	s := types.TypeString(t, qual)
+	return s, ok
+}
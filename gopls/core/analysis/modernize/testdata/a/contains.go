@@ -0,0 +1,29 @@
+package a
+
+func _(s []string, x string) bool {
+	for _, e := range s { if e == x { return true } }; return false // want "loop can be simplified using slices.Contains"
+}
+
+func _(s []string, x string) bool {
+	for _, e := range s { if e == x { return false } }; return true // nope: inverted
+}
+
+func _(s []string, m map[string]bool) bool {
+	for _, e := range s { if m[e] { return true } }; return false // nope: not a comparison
+}
+
+func _(s []int) bool {
+	for _, e := range s { if e == f() { return true } }; return false // nope: impure needle
+}
+
+func _(s []any, x any) bool {
+	for _, e := range s { if e == x { return true } }; return false // want "loop can be simplified using slices.Contains"
+}
+
+func _(s []any, x int) bool {
+	for _, e := range s { if e == x { return true } }; return false // nope: needle type is not the element type
+}
+
+func _(s []*T) bool {
+	for _, e := range s { if e == nil { return true } }; return false // want "loop can be simplified using slices.Contains"
+}
@@ -0,0 +1,20 @@
+package a
+
+var _ interface{} // want "interface{} can be replaced by any"
+
+func _(x interface{}) { // want "interface{} can be replaced by any"
+	_ = x
+}
+
+func _() interface{} { // want "interface{} can be replaced by any"
+	return nil
+}
+
+type _ interface { // nope: not empty
+	M()
+}
+
+func _() {
+	type any int
+	var _ interface{} // nope: any is shadowed
+}
@@ -0,0 +1,12 @@
+//go:build go1.21
+
+package a
+
+func _(n int) {
+	for i := 0; i < n; i++ { // nope: range-over-int requires go1.22
+		println(i)
+	}
+	var x int
+	if n < 10 { x = n } else { x = 10 } // want "if/else statement can be modernized using min"
+	_ = x
+}
@@ -0,0 +1,15 @@
+package a
+
+type T struct{ f int }
+
+func _(s []T, x T) int {
+	for i, e := range s { if x == e { return i } }; return -1 // want "loop can be simplified using slices.Index"
+}
+
+func _(s []T, x T) int {
+	for i, e := range s { if x == e { return i + 1 } }; return -1 // nope: not the index
+}
+
+func _(s []T) int {
+	for i, e := range s { if e == s[i] { return i } }; return -1 // nope: needle depends on the loop
+}
@@ -0,0 +1,16 @@
+package a
+
+type M map[string]int
+
+func _(dst, src map[string]int) {
+	for k, v := range src { dst[k] = v } // want "loop can be simplified using maps.Copy"
+}
+
+func _(dst map[string]int, src M) {
+	for k, v := range src { dst[k] = v + 1 } // nope: not a copy
+	for k, v := range src { dst[k+"x"] = v } // nope: not a copy
+}
+
+func _(dst map[string]any, src map[string]int) {
+	for k, v := range src { dst[k] = v } // nope: element types differ
+}
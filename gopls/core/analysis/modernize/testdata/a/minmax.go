@@ -0,0 +1,39 @@
+package a
+
+func _(a, b int) {
+	var x int
+	if a < b { x = a } else { x = b } // want "if/else statement can be modernized using min"
+	if a <= b { x = b } else { x = a } // want "if/else statement can be modernized using max"
+	if a > b { x = a } else { x = b } // want "if/else statement can be modernized using max"
+	if a > 10 { x = 10 } else { x = a } // want "if/else statement can be modernized using min"
+	_ = x
+}
+
+func _(a, b int, s string) {
+	var x int
+	if a < b { x = b } else { x = b } // nope: same operand
+	if a < b { x = a } else if a > 0 { x = b } // nope: else if
+	if a < b { x = a } else { x = b; s = "" } // nope: not a single assignment
+	if a < f() { x = a } else { x = f() } // nope: impure
+	if a < b {
+		x = a // a comment that would be lost
+	} else {
+		x = b
+	}
+	_, _ = x, s
+}
+
+func _(a, b float64) {
+	var x float64
+	if a < b { x = a } else { x = b } // nope: NaN
+	_ = x
+}
+
+func _(a, b string) {
+	min := 0
+	var x string
+	if a < b { x = a } else { x = b } // nope: min is shadowed
+	_, _ = x, min
+}
+
+func f() int
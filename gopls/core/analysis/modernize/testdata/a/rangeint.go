@@ -0,0 +1,91 @@
+package a
+
+func _(s []int, n int) {
+	for i := 0; i < 10; i++ { // want "for loop can be modernized using range over int"
+		println(i)
+	}
+	for i := 0; i < len(s); i++ { // want "for loop can be modernized using range over int"
+		println(s[i])
+	}
+	for i := 0; i < n; i++ { // want "for loop can be modernized using range over int"
+		println()
+	}
+	for i := 0; i < n; i++ { // nope: limit is assigned
+		n--
+	}
+	for i := 0; i < n; i++ { // nope: index is assigned
+		i++
+	}
+	for i := 0; i < len(s); i++ { // nope: limit is assigned
+		s = append(s, i)
+	}
+	for i := 1; i < n; i++ { // nope: not from zero
+	}
+	for i := 0; i <= n; i++ { // nope: not <
+	}
+	for i := 0; i < g; i++ { // nope: package-level limit
+	}
+}
+
+func _(n int) {
+	dec := func() { n-- }
+	for i := 0; i < n; i++ { // nope: limit is captured by a function literal
+		dec()
+	}
+}
+
+func _(n int) {
+	p := &n
+	for i := 0; i < n; i++ { // nope: address of limit is taken
+		*p--
+	}
+}
+
+type counter int
+
+func (c *counter) dec() { *c-- }
+
+func _(n counter) {
+	for i := counter(0); i < n; i++ { // nope: address of limit is taken by method call
+		n.dec()
+	}
+}
+
+func _(n int) {
+	f := func() {
+		m := n
+		for i := 0; i < m; i++ { // want "for loop can be modernized using range over int"
+			println(i)
+		}
+	}
+	f()
+}
+
+var g int
+
+type ID int
+
+const maxID ID = 3
+
+func _(n uint8, m ID) {
+	for i := uint8(0); i < 10; i++ { // want "for loop can be modernized using range over int"
+		println(i)
+	}
+	for i := ID(0); i < maxID; i++ { // want "for loop can be modernized using range over int"
+		println(i)
+	}
+	for i := uint8(0); i < n; i++ { // want "for loop can be modernized using range over int"
+		println(i)
+	}
+	for i := ID(0); i < m; i++ { // want "for loop can be modernized using range over int"
+		println(i)
+	}
+	for i := zero; i < 10; i++ { // nope: no type for the limit
+		println(i)
+	}
+	for i := uint8(0); i < 10; i++ { // want "for loop can be modernized using range over int"
+		println() // index unused
+	}
+}
+
+const zero ID = 0
@@ -0,0 +1,7 @@
+package a
+
+import "sort"
+
+func _(s []int) {
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] }) // want "sort.Slice can be modernized using slices.Sort"
+}
@@ -0,0 +1,15 @@
+package a
+
+import (
+	"sort"
+	"strings"
+)
+
+type Person struct{ Name string }
+
+func _(s []Person) {
+	sort.Slice(s, func(i, j int) bool { return s[i].Name > s[j].Name }) // want "sort.Slice can be modernized using slices.SortFunc"
+	sort.Slice(s, func(i, j int) bool { return s[i].Name < s[i].Name }) // nope: not s[j]
+	sort.Slice(s, func(i, j int) bool { return i < j }) // nope: not an element
+	sort.Slice(s, func(i, j int) bool { return strings.ToLower(s[i].Name) < strings.ToLower(s[j].Name) }) // nope: impure
+}
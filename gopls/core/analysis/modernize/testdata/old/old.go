@@ -0,0 +1,10 @@
+package old
+
+import "sort"
+
+func _(s []int, a, b int, m map[int]int) (x interface{}) { // want "interface{} can be replaced by any"
+	if a < b { x = a } else { x = b } // nope: min requires go1.21
+	for k, v := range m { m[k] = v } // nope: maps.Copy requires go1.21
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] }) // nope: slices.Sort requires go1.21
+	return
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package yield defines an Analyzer that checks that iterator
+// functions follow the iteration protocol.
+//
+// # Analyzer yield
+//
+// yield: check that iterators obey the iteration protocol
+//
+// An iterator function, such as an iter.Seq or iter.Seq2, calls its
+// yield parameter once for each element of the sequence, and must stop
+// as soon as yield returns false, which indicates that the loop body
+// of the range statement has exited by a break, return, goto, or panic.
+// This analyzer reports violations of that protocol, which cause a
+// run-time panic.
+//
+// It reports a call to yield whose result may be false when yield is
+// called again, for example because the result is ignored:
+//
+//	func All(s []int) iter.Seq[int] {
+//		return func(yield func(int) bool) {
+//			for _, x := range s {
+//				yield(x) // error: result is ignored
+//			}
+//		}
+//	}
+//
+// or because the loop continues after yield returns false.
+//
+// It reports yield functions that are retained after the iterator
+// returns, because they are stored in a variable or data structure
+// that outlives the iterator, or sent on a channel: the loop body may
+// not be executed after the range statement completes. It also reports
+// yield functions used by a new goroutine, which would run the loop
+// body on a different goroutine from the range statement, even if the
+// iterator waits for it.
+//
+// It also reports calls to yield from deferred functions, which run
+// after iteration may have stopped, and deferred calls to recover in
+// an iterator that do not panic again: a panic in the loop body
+// propagates through the call to yield, and the iterator must not
+// recover from it.
+package yield
@@ -0,0 +1,210 @@
+package a
+
+import (
+	"iter"
+	"sync"
+)
+
+func ignored(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, x := range s {
+			yield(x) // want `result of yield is ignored, so yield may be called again \(line 11\) after returning false`
+		}
+	}
+}
+
+func twice(yield func(int) bool) {
+	yield(1) // want `result of yield is ignored, so yield may be called again \(line 18\) after returning false`
+	yield(2)
+}
+
+func continues(s []int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for i, x := range s {
+			if !yield(i, x) { // want `yield may be called again \(line 28\) after returning false`
+				break
+			}
+		}
+		yield(-1, -1)
+	}
+}
+
+func savedResult(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, x := range s {
+			ok := yield(x) // want `yield may be called again \(line 35\) after returning false`
+			if !ok {
+				println("stopped")
+			}
+		}
+	}
+}
+
+// Correct iterators.
+
+func All(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, x := range s {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
+
+func Pairs(yield func(int, int) bool) {
+	_ = yield(1, 1) && yield(2, 2) && yield(3, 3)
+}
+
+func Last(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		if len(s) > 0 {
+			yield(s[len(s)-1])
+		}
+	}
+}
+
+type Tree struct {
+	Left, Right *Tree
+	Value       int
+}
+
+func (t *Tree) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		t.walk(yield)
+	}
+}
+
+func (t *Tree) walk(yield func(int) bool) bool {
+	return t == nil || t.Left.walk(yield) && yield(t.Value) && t.Right.walk(yield)
+}
+
+func Closure(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		each := func(x int) bool {
+			return yield(x)
+		}
+		for _, x := range s {
+			if !each(x) {
+				return
+			}
+		}
+	}
+}
+
+// Retaining yield.
+
+var saved func(int) bool
+
+func retained(yield func(int) bool) {
+	saved = yield // want `yield is retained after the iterator returns`
+}
+
+func goroutine(ch chan int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() { // want `yield is used by a new goroutine, so the loop body runs on a different goroutine from the range statement`
+			defer wg.Done()
+			for x := range ch {
+				if !yield(x) {
+					return
+				}
+			}
+		}()
+		wg.Wait()
+	}
+}
+
+type walker struct {
+	yield func(int) bool
+}
+
+func (w *walker) walk(xs []int) bool {
+	for _, x := range xs {
+		if !w.yield(x) {
+			return false
+		}
+	}
+	return true
+}
+
+func localStruct(xs []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		w := walker{yield: yield}
+		w.walk(xs)
+	}
+}
+
+func localArray(x int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		fs := [1]func(int) bool{yield}
+		fs[0](x)
+	}
+}
+
+func localSlice(x int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		fs := []func(int) bool{yield}
+		for _, f := range fs {
+			if !f(x) {
+				return
+			}
+		}
+	}
+}
+
+func escapingSlice(x int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		fs := []func(int) bool{yield} // want `yield is retained after the iterator returns`
+		keep(fs)
+	}
+}
+
+var kept []func(int) bool
+
+func keep(fs []func(int) bool) { kept = fs }
+
+func sent(ch chan func(int) bool) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		ch <- yield // want `yield is retained after the iterator returns`
+	}
+}
+
+// Defer and recover.
+
+func deferred(yield func(int) bool) {
+	defer yield(0) // want `yield is called by a deferred call, which runs after iteration may have stopped`
+	if !yield(1) {
+		return
+	}
+}
+
+func recovers(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer func() {
+			recover() // want `iterator recovers from a panic in the loop body and does not panic again`
+		}()
+		for _, x := range s {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
+
+func repanics(s []int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				println("cleanup")
+				panic(r)
+			}
+		}()
+		for _, x := range s {
+			if !yield(x) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,426 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package yield
+
+import (
+	_ "embed"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/troll-zhao/tools/core/analysisinternal"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/buildssa"
+	"golang.org/x/tools/go/ssa"
+)
+
+//go:embed doc.go
+var doc string
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "yield",
+	Doc:      analysisinternal.MustExtractDoc(doc, "yield"),
+	Requires: []*analysis.Analyzer{buildssa.Analyzer},
+	Run:      run,
+	URL:      "https://pkg.go.dev/golang.org/x/tools/gopls/internal/analysis/yield",
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ssainput := pass.ResultOf[buildssa.Analyzer].(*buildssa.SSA)
+	for _, fn := range ssainput.SrcFuncs {
+		if yield := yieldParam(fn); yield != nil {
+			c := &checker{
+				pass:  pass,
+				calls: make(map[*ssa.Function][]*ssa.Call),
+			}
+			c.visit(yield)
+			c.checkCalls()
+			c.checkRecover(fn)
+		}
+	}
+	return nil, nil
+}
+
+// yieldParam returns the yield parameter of fn if fn is an iterator
+// function, of the form func(yield func(...) bool), or nil otherwise.
+func yieldParam(fn *ssa.Function) *ssa.Parameter {
+	sig := fn.Signature
+	if sig.Recv() != nil || sig.Params().Len() != 1 || sig.Results().Len() != 0 || len(fn.Params) != 1 {
+		return nil
+	}
+	ysig, ok := sig.Params().At(0).Type().Underlying().(*types.Signature)
+	if !ok || ysig.Results().Len() != 1 {
+		return nil
+	}
+	if basic, ok := ysig.Results().At(0).Type().Underlying().(*types.Basic); !ok || basic.Kind() != types.Bool {
+		return nil
+	}
+	return fn.Params[0]
+}
+
+type checker struct {
+	pass  *analysis.Pass
+	calls map[*ssa.Function][]*ssa.Call // calls to yield, by enclosing function
+}
+
+// visit checks the uses of yield, a value holding the yield function
+// of an iterator: the parameter itself, or a free variable of a
+// function literal within the iterator.
+func (c *checker) visit(yield ssa.Value) {
+	for _, ref := range *yield.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Call:
+			if ref.Call.Value == yield {
+				c.calls[ref.Parent()] = append(c.calls[ref.Parent()], ref)
+			}
+
+		case *ssa.Defer:
+			if ref.Call.Value == yield {
+				c.pass.ReportRangef(c.callRange(ref), "yield is called by a deferred call, which runs after iteration may have stopped")
+			}
+
+		case *ssa.Go:
+			c.pass.ReportRangef(c.callRange(ref), "yield is used by a new goroutine, so the loop body runs on a different goroutine from the range statement")
+
+		case *ssa.MakeClosure:
+			fn := ref.Fn.(*ssa.Function)
+			if !c.checkClosure(ref) {
+				continue // already reported
+			}
+			for i, b := range ref.Bindings {
+				if b == yield {
+					c.visit(fn.FreeVars[i])
+				}
+			}
+
+		case *ssa.Store:
+			if ref.Val == yield {
+				if retains(ref.Addr) {
+					c.retained(ref.Pos())
+				} else {
+					c.visitAddr(ref.Addr)
+				}
+			}
+
+		case *ssa.Send:
+			if ref.X == yield {
+				c.retained(ref.Pos())
+			}
+
+		case *ssa.MapUpdate:
+			if ref.Value == yield || ref.Key == yield {
+				c.retained(ref.Pos())
+			}
+
+		case *ssa.Return:
+			c.retained(ref.Pos())
+		}
+	}
+}
+
+// visitAddr checks the uses of addr, the address of a local variable
+// holding the yield function, such as a parameter captured by a
+// function literal.
+func (c *checker) visitAddr(addr ssa.Value) {
+	for _, ref := range *addr.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.UnOp:
+			if ref.Op == token.MUL {
+				c.visit(ref)
+			}
+		case *ssa.MakeClosure:
+			fn := ref.Fn.(*ssa.Function)
+			if !c.checkClosure(ref) {
+				continue // already reported
+			}
+			for i, b := range ref.Bindings {
+				if b == addr {
+					c.visitAddr(fn.FreeVars[i])
+				}
+			}
+		}
+	}
+}
+
+// retains reports whether a store to addr may retain a value
+// beyond the return of the function containing the store: that is,
+// unless addr is a local variable, or a field or element of one whose
+// address does not escape.
+func retains(addr ssa.Value) bool {
+	if _, ok := addr.(*ssa.Alloc); ok {
+		return false
+	}
+	root := addr
+	for {
+		switch x := root.(type) {
+		case *ssa.FieldAddr:
+			root = x.X
+			continue
+		case *ssa.IndexAddr:
+			root = x.X
+			continue
+		case *ssa.Slice:
+			root = x.X
+			continue
+		}
+		break
+	}
+	alloc, ok := root.(*ssa.Alloc)
+	return !ok || alloc.Heap && escapes(alloc, make(map[ssa.Value]bool))
+}
+
+// escapes reports whether v, the address of memory or a slice of it,
+// may be used other than to access its elements, including within
+// the functions to which it is passed: for example, stored in memory,
+// returned, or passed to a dynamic call.
+func escapes(v ssa.Value, seen map[ssa.Value]bool) bool {
+	if seen[v] {
+		return false
+	}
+	seen[v] = true
+	for _, ref := range *v.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.FieldAddr, *ssa.IndexAddr, *ssa.Slice:
+			if escapes(ref.(ssa.Value), seen) {
+				return true
+			}
+		case *ssa.Store:
+			if ref.Val == v {
+				return true
+			}
+		case *ssa.UnOp:
+			if ref.Op != token.MUL {
+				return true
+			}
+		case *ssa.Call:
+			if escapesCall(v, ref.Common(), seen) {
+				return true
+			}
+		case *ssa.DebugRef:
+			// ignore
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// escapesCall reports whether v escapes when passed to the call
+// described by common: the call must be to len or cap, or to a function
+// within whose body the corresponding parameter does not escape.
+func escapesCall(v ssa.Value, common *ssa.CallCommon, seen map[ssa.Value]bool) bool {
+	if b, ok := common.Value.(*ssa.Builtin); ok {
+		return b.Name() != "len" && b.Name() != "cap"
+	}
+	callee := common.StaticCallee()
+	if callee == nil || callee.Blocks == nil || len(callee.Params) != len(common.Args) {
+		return true
+	}
+	for i, arg := range common.Args {
+		if arg == v && escapes(callee.Params[i], seen) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) retained(pos token.Pos) {
+	if pos.IsValid() {
+		c.pass.Reportf(pos, "yield is retained after the iterator returns")
+	}
+}
+
+// checkClosure checks the uses of a function literal that refers to
+// yield, and reports whether it is used in a way that is consistent
+// with the iteration protocol.
+func (c *checker) checkClosure(mc *ssa.MakeClosure) bool {
+	for _, ref := range *mc.Referrers() {
+		switch ref := ref.(type) {
+		case *ssa.Go:
+			if ref.Call.Value == mc {
+				c.pass.ReportRangef(c.callRange(ref), "yield is used by a new goroutine, so the loop body runs on a different goroutine from the range statement")
+				return false
+			}
+		case *ssa.Defer:
+			if ref.Call.Value == mc {
+				c.pass.ReportRangef(c.callRange(ref), "yield is used by a deferred function, which runs after iteration may have stopped")
+				return false
+			}
+		case *ssa.Store:
+			if ref.Val == mc && retains(ref.Addr) {
+				c.retained(ref.Pos())
+				return false
+			}
+		case *ssa.Send, *ssa.MapUpdate, *ssa.Return:
+			c.retained(ref.Pos())
+			return false
+		}
+	}
+	return true
+}
+
+// checkCalls reports calls to yield that may be followed by another
+// call to yield even though the first returned false.
+func (c *checker) checkCalls() {
+	for _, calls := range c.calls {
+		for _, call := range calls {
+			again := c.callAfterFalse(call, calls)
+			if again == nil {
+				continue
+			}
+			line := c.pass.Fset.Position(again.Pos()).Line
+			if len(*call.Referrers()) == 0 {
+				c.pass.ReportRangef(c.callRange(call), "result of yield is ignored, so yield may be called again (line %d) after returning false", line)
+			} else {
+				c.pass.ReportRangef(c.callRange(call), "yield may be called again (line %d) after returning false", line)
+			}
+		}
+	}
+}
+
+// callAfterFalse returns a call among calls that may be executed
+// after call returns false, or nil if there is none.
+func (c *checker) callAfterFalse(call *ssa.Call, calls []*ssa.Call) *ssa.Call {
+	isCall := func(instr ssa.Instruction) bool {
+		for _, x := range calls {
+			if instr == x {
+				return true
+			}
+		}
+		return false
+	}
+	seen := make(map[*ssa.BasicBlock]bool)
+	var search func(b *ssa.BasicBlock, instrs []ssa.Instruction) *ssa.Call
+	search = func(b *ssa.BasicBlock, instrs []ssa.Instruction) *ssa.Call {
+		for _, instr := range instrs {
+			if isCall(instr) {
+				return instr.(*ssa.Call)
+			}
+		}
+		succs := b.Succs
+		if cond, ok := b.Instrs[len(b.Instrs)-1].(*ssa.If); ok {
+			// Follow only the branch taken when call returns false.
+			if v, ok := valueIfFalse(cond.Cond, call); ok {
+				if v {
+					succs = succs[:1]
+				} else {
+					succs = succs[1:]
+				}
+			}
+		}
+		for _, succ := range succs {
+			if !seen[succ] {
+				seen[succ] = true
+				if again := search(succ, succ.Instrs); again != nil {
+					return again
+				}
+			}
+		}
+		return nil
+	}
+	b := call.Block()
+	for i, instr := range b.Instrs {
+		if instr == call {
+			return search(b, b.Instrs[i+1:])
+		}
+	}
+	return nil
+}
+
+// valueIfFalse returns the value of the boolean cond, if it is
+// determined by the knowledge that the result of call is false.
+func valueIfFalse(cond ssa.Value, call *ssa.Call) (value, ok bool) {
+	switch cond := cond.(type) {
+	case *ssa.Call:
+		if cond == call {
+			return false, true
+		}
+	case *ssa.UnOp:
+		if cond.Op == token.NOT {
+			v, ok := valueIfFalse(cond.X, call)
+			return !v, ok
+		}
+	}
+	return false, false
+}
+
+// checkRecover reports deferred calls to recover in iterator fn
+// that do not panic again.
+func (c *checker) checkRecover(fn *ssa.Function) {
+	if len(c.calls) == 0 {
+		return
+	}
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			d, ok := instr.(*ssa.Defer)
+			if !ok {
+				continue
+			}
+			var deferred *ssa.Function
+			switch v := d.Call.Value.(type) {
+			case *ssa.Function:
+				deferred = v
+			case *ssa.MakeClosure:
+				deferred = v.Fn.(*ssa.Function)
+			}
+			if deferred == nil || deferred.Blocks == nil {
+				continue
+			}
+			var recover *ssa.Call
+			repanics := false
+			for _, b := range deferred.Blocks {
+				for _, instr := range b.Instrs {
+					switch instr := instr.(type) {
+					case *ssa.Call:
+						if builtin, ok := instr.Call.Value.(*ssa.Builtin); ok && builtin.Name() == "recover" {
+							recover = instr
+						}
+					case *ssa.Panic:
+						repanics = true
+					}
+				}
+			}
+			if recover != nil && !repanics {
+				c.pass.ReportRangef(c.callRange(recover), "iterator recovers from a panic in the loop body and does not panic again")
+			}
+		}
+	}
+}
+
+// callRange returns the syntax of the call expression of a call
+// instruction, or the go or defer statement containing it.
+func (c *checker) callRange(instr ssa.CallInstruction) analysis.Range {
+	var found ast.Node
+	if syntax := instr.Parent().Syntax(); syntax != nil {
+		pos := instr.Pos()
+		ast.Inspect(syntax, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if n.Lparen == pos {
+					found = n
+				}
+			case *ast.GoStmt:
+				if n.Go == pos {
+					found = n
+				}
+			case *ast.DeferStmt:
+				if n.Defer == pos {
+					found = n
+				}
+			}
+			return found == nil
+		})
+	}
+	if found == nil {
+		return posRange(instr.Pos())
+	}
+	return found
+}
+
+// posRange is an analysis.Range of a single position.
+type posRange token.Pos
+
+func (p posRange) Pos() token.Pos { return token.Pos(p) }
+func (p posRange) End() token.Pos { return token.Pos(p) }
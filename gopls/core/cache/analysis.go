@@ -26,12 +26,15 @@ import (
 	"runtime/debug"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/troll-zhao/tools/core/analysisconfig"
 	"github.com/troll-zhao/tools/core/analysisinternal"
+	"github.com/troll-zhao/tools/core/analysisprofile"
 	"github.com/troll-zhao/tools/core/event"
 	"github.com/troll-zhao/tools/core/facts"
 	"github.com/troll-zhao/tools/core/gcimporter"
@@ -190,12 +193,24 @@ func (s *Snapshot) Analyze(ctx context.Context, pkgs map[PackageID]*metadata.Pac
 	ctx, done := event.Start(ctx, "snapshot.Analyze", label.Package.Of(tagStr))
 	defer done()
 
+	// Read the shared analyzer configuration file, if any.
+	// A malformed file is logged but does not prevent analysis.
+	config, err := s.analysisConfig(ctx)
+	if err != nil {
+		event.Error(ctx, "reading analyzer configuration", err)
+	}
+
 	// Filter and sort enabled root analyzers.
 	// A disabled analyzer may still be run if required by another.
+	// The "analyses" setting takes precedence over the configuration file.
 	toSrc := make(map[*analysis.Analyzer]*settings.Analyzer)
 	var enabledAnalyzers []*analysis.Analyzer // enabled subset + transitive requirements
 	for _, a := range analyzers {
-		if enabled, ok := s.Options().Analyses[a.Analyzer().Name]; enabled || !ok && a.EnabledByDefault() {
+		enabled, ok := s.Options().Analyses[a.Analyzer().Name]
+		if !ok {
+			enabled = config.Enabled(a.Analyzer().Name, a.EnabledByDefault())
+		}
+		if enabled {
 			toSrc[a.Analyzer()] = a
 			enabledAnalyzers = append(enabledAnalyzers, a.Analyzer())
 		}
@@ -479,13 +494,108 @@ func (s *Snapshot) Analyze(ctx context.Context, pkgs map[PackageID]*metadata.Pac
 				continue // action failed
 			}
 			for _, gobDiag := range summary.Diagnostics {
-				results = append(results, toSourceDiagnostic(srcAnalyzer, &gobDiag))
+				if config.Excluded(gobDiag.Location.URI.Path(), a.Name) {
+					continue
+				}
+				diag := toSourceDiagnostic(srcAnalyzer, &gobDiag)
+				if sev, ok := configSeverities[config.Severity(a.Name)]; ok {
+					diag.Severity = sev
+				}
+				results = append(results, diag)
 			}
 		}
 	}
 	return results, nil
 }
 
+// configSeverities maps each severity of an analyzer configuration
+// file to the corresponding LSP diagnostic severity.
+var configSeverities = map[string]protocol.DiagnosticSeverity{
+	"error":   protocol.SeverityError,
+	"warning": protocol.SeverityWarning,
+	"info":    protocol.SeverityInformation,
+	"hint":    protocol.SeverityHint,
+}
+
+// analysisConfigURI returns the URI of the analyzer configuration
+// file ([analysisconfig.FileName]) in the root directory of the view.
+func (s *Snapshot) analysisConfigURI() protocol.DocumentURI {
+	return protocol.URIFromPath(filepath.Join(s.view.root.Path(), analysisconfig.FileName))
+}
+
+// analysisConfig returns the analyzer configuration file of the
+// view, or nil if there is none.
+//
+// The configuration applies the enabled set, severities, and
+// excludes of the file, but not its analyzer flags: analyzers are
+// shared by all sessions, so their flags cannot vary by view.
+// AnalysisConfigDiagnostics reports the flags that are ignored.
+func (s *Snapshot) analysisConfig(ctx context.Context) (*analysisconfig.Config, error) {
+	uri := s.analysisConfigURI()
+	fh, err := s.ReadFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	content, err := fh.Content()
+	if err != nil {
+		return nil, nil // no configuration file
+	}
+	return analysisconfig.Parse(uri.Path(), content)
+}
+
+// AnalysisConfigDiagnostics returns diagnostics for the analyzer
+// configuration file of the view: an error if the file is malformed,
+// and a warning for each analyzer whose flags it sets, since gopls
+// ignores them.
+func (s *Snapshot) AnalysisConfigDiagnostics(ctx context.Context) (map[protocol.DocumentURI][]*Diagnostic, error) {
+	uri := s.analysisConfigURI()
+	fh, err := s.ReadFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	content, err := fh.Content()
+	if err != nil {
+		return nil, nil // no configuration file
+	}
+
+	var diags []*Diagnostic
+	cfg, err := analysisconfig.Parse(uri.Path(), content)
+	if err != nil {
+		diags = append(diags, &Diagnostic{
+			URI:      uri,
+			Severity: protocol.SeverityError,
+			Source:   AnalysisConfigError,
+			Message:  err.Error(),
+		})
+	} else {
+		mapper := protocol.NewMapper(uri, content)
+		for _, name := range moremaps.KeySlice(cfg.Analyzers) {
+			if len(cfg.Analyzers[name].Flags) == 0 {
+				continue
+			}
+			// Report the "flags" key of the analyzer's entry, if we can find it.
+			var rng protocol.Range
+			if i := bytes.Index(content, []byte(strconv.Quote(name))); i >= 0 {
+				if j := bytes.Index(content[i:], []byte(`"flags"`)); j >= 0 {
+					start := i + j
+					rng, _ = mapper.OffsetRange(start, start+len(`"flags"`))
+				}
+			}
+			diags = append(diags, &Diagnostic{
+				URI:      uri,
+				Range:    rng,
+				Severity: protocol.SeverityWarning,
+				Source:   AnalysisConfigError,
+				Message:  fmt.Sprintf("flags of analyzer %s are ignored: gopls does not support analyzer flags", name),
+			})
+		}
+	}
+	if len(diags) == 0 {
+		return nil, nil
+	}
+	return map[protocol.DocumentURI][]*Diagnostic{uri: diags}, nil
+}
+
 func (an *analysisNode) decrefPreds() {
 	if an.unfinishedPreds.Add(-1) == 0 {
 		an.summary.Actions = nil
@@ -1393,6 +1503,7 @@ func (act *action) exec(ctx context.Context) (any, *actionSummary, error) {
 	// Now run the (pkg, analyzer) action.
 	var diagnostics []gobDiagnostic
 
+	nfacts := 0 // number of facts exported by this pass
 	pass := &analysis.Pass{
 		Analyzer:     analyzer,
 		Fset:         pkg.fset,
@@ -1415,12 +1526,18 @@ func (act *action) exec(ctx context.Context) (any, *actionSummary, error) {
 			}
 			diagnostics = append(diagnostics, diagnostic)
 		},
-		ImportObjectFact:  factset.ImportObjectFact,
-		ExportObjectFact:  factset.ExportObjectFact,
+		ImportObjectFact: factset.ImportObjectFact,
+		ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+			nfacts++
+			factset.ExportObjectFact(obj, fact)
+		},
 		ImportPackageFact: factset.ImportPackageFact,
-		ExportPackageFact: factset.ExportPackageFact,
-		AllObjectFacts:    func() []analysis.ObjectFact { return factset.AllObjectFacts(factFilter) },
-		AllPackageFacts:   func() []analysis.PackageFact { return factset.AllPackageFacts(factFilter) },
+		ExportPackageFact: func(fact analysis.Fact) {
+			nfacts++
+			factset.ExportPackageFact(fact)
+		},
+		AllObjectFacts:  func() []analysis.ObjectFact { return factset.AllObjectFacts(factFilter) },
+		AllPackageFacts: func() []analysis.PackageFact { return factset.AllPackageFacts(factFilter) },
 	}
 
 	pass.ReadFile = func(filename string) ([]byte, error) {
@@ -1449,8 +1566,8 @@ func (act *action) exec(ctx context.Context) (any, *actionSummary, error) {
 	// Recover from panics (only) within the analyzer logic.
 	// (Use an anonymous function to limit the recover scope.)
 	var result interface{}
+	start := analysisprofile.Begin()
 	func() {
-		start := time.Now()
 		defer func() {
 			if r := recover(); r != nil {
 				// An Analyzer panicked, likely due to a bug.
@@ -1473,16 +1590,13 @@ func (act *action) exec(ctx context.Context) (any, *actionSummary, error) {
 					err = fmt.Errorf("analysis %s for package %s panicked: %v", analyzer.Name, pass.Pkg.Path(), r)
 				}
 			}
-
-			// Accumulate running time for each checker.
-			analyzerRunTimesMu.Lock()
-			analyzerRunTimes[analyzer] += time.Since(start)
-			analyzerRunTimesMu.Unlock()
 		}()
 
 		result, err = pass.Analyzer.Run(pass)
 	}()
+	span := analysisprofile.End(start)
 	if err != nil {
+		analyzerProfile.Add(analyzer.Name, span, nfacts, 0, len(diagnostics))
 		return nil, nil, err
 	}
 
@@ -1501,7 +1615,13 @@ func (act *action) exec(ctx context.Context) (any, *actionSummary, error) {
 		panic(fmt.Sprintf("%v: Pass.ExportPackageFact(%T) called after Run", act, fact))
 	}
 
-	factsdata := factset.Encode()
+	// Accumulate statistics for each analyzer, including the
+	// size of the facts it exported about this package. (The
+	// encoded fact set also contains facts inherited from
+	// dependencies, which are not this pass's cost.)
+	factsdata, factsSize := factset.EncodeSize()
+	analyzerProfile.Add(analyzer.Name, span, nfacts, factsSize, len(diagnostics))
+
 	return result, &actionSummary{
 		Diagnostics: diagnostics,
 		Facts:       factsdata,
@@ -1509,31 +1629,14 @@ func (act *action) exec(ctx context.Context) (any, *actionSummary, error) {
 	}, nil
 }
 
-var (
-	analyzerRunTimesMu sync.Mutex
-	analyzerRunTimes   = make(map[*analysis.Analyzer]time.Duration)
-)
-
-type LabelDuration struct {
-	Label    string
-	Duration time.Duration
-}
-
-// AnalyzerRunTimes returns the accumulated time spent in each Analyzer's
-// Run function since process start, in descending order.
-func AnalyzerRunTimes() []LabelDuration {
-	analyzerRunTimesMu.Lock()
-	defer analyzerRunTimesMu.Unlock()
+// analyzerProfile accumulates statistics about each Analyzer's Run
+// function since process start. As analyzers run in parallel, only
+// its wall-clock times are accurate.
+var analyzerProfile analysisprofile.Profile
 
-	slice := make([]LabelDuration, 0, len(analyzerRunTimes))
-	for a, t := range analyzerRunTimes {
-		slice = append(slice, LabelDuration{Label: a.Name, Duration: t})
-	}
-	sort.Slice(slice, func(i, j int) bool {
-		return slice[i].Duration > slice[j].Duration
-	})
-	return slice
-}
+// AnalyzerProfile returns the accumulated statistics for each Analyzer
+// since process start.
+func AnalyzerProfile() *analysisprofile.Profile { return &analyzerProfile }
 
 // requiredAnalyzers returns the transitive closure of required analyzers in preorder.
 func requiredAnalyzers(analyzers []*analysis.Analyzer) []*analysis.Analyzer {
@@ -109,6 +109,7 @@ const (
 	Govulncheck              DiagnosticSource = "govulncheck"
 	TemplateError            DiagnosticSource = "template"
 	WorkFileError            DiagnosticSource = "go.work file"
+	AnalysisConfigError      DiagnosticSource = "analyzer configuration"
 	ConsistencyInfo          DiagnosticSource = "consistency"
 )
 
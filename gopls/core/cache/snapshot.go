@@ -25,6 +25,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/troll-zhao/tools/core/analysisconfig"
 	"github.com/troll-zhao/tools/core/event"
 	"github.com/troll-zhao/tools/core/event/label"
 	"github.com/troll-zhao/tools/core/gocommand"
@@ -844,6 +845,12 @@ func (s *Snapshot) fileWatchingGlobPatterns() map[protocol.RelativePattern]unit
 		patterns[workPattern] = unit{}
 	}
 
+	// Watch the analyzer configuration file.
+	patterns[protocol.RelativePattern{
+		BaseURI: s.view.root,
+		Pattern: analysisconfig.FileName,
+	}] = unit{}
+
 	extensions := "go,mod,sum,work"
 	for _, ext := range s.Options().TemplateExtensions {
 		extensions += "," + ext
@@ -1719,6 +1726,12 @@ func (s *Snapshot) clone(ctx, bgCtx context.Context, changed StateChange, done f
 		}
 	}
 
+	// A change to the analyzer configuration file affects the
+	// analysis diagnostics of every package.
+	if _, ok := changedFiles[s.analysisConfigURI()]; ok {
+		needsDiagnosis = true
+	}
+
 	// The snapshot should be initialized if either s was uninitialized, or we've
 	// detected a change that triggers reinitialization.
 	if reinit {
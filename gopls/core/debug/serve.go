@@ -24,6 +24,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/troll-zhao/tools/core/analysisprofile"
 	"github.com/troll-zhao/tools/core/event"
 	"github.com/troll-zhao/tools/core/event/core"
 	"github.com/troll-zhao/tools/core/event/export"
@@ -109,7 +110,7 @@ func (st *State) Analysis() (_ analysisTmpl) { return }
 
 type analysisTmpl struct{}
 
-func (analysisTmpl) AnalyzerRunTimes() []cache.LabelDuration { return cache.AnalyzerRunTimes() }
+func (analysisTmpl) AnalyzerStats() []analysisprofile.Stats { return cache.AnalyzerProfile().Stats() }
 
 // Sessions returns the set of Session objects currently being served.
 func (st *State) Sessions() []*cache.Session {
@@ -456,6 +457,12 @@ func (i *Instance) Serve(ctx context.Context, addr string) (string, error) {
 			mux.HandleFunc("/trace/", render(TraceTmpl, i.traces.getData))
 		}
 		mux.HandleFunc("/analysis/", render(AnalysisTmpl, i.getAnalysis))
+		mux.HandleFunc("/analysis/profile.json", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := cache.AnalyzerProfile().WriteJSON(w); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		})
 		mux.HandleFunc("/cache/", render(CacheTmpl, i.getCache))
 		mux.HandleFunc("/session/", render(SessionTmpl, i.getSession))
 		mux.HandleFunc("/client/", render(ClientTmpl, i.getClient))
@@ -783,8 +790,13 @@ Errors: <b>{{$stats.Errs}}</b><br>
 var AnalysisTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
 {{define "title"}}Analysis{{end}}
 {{define "body"}}
-<h2>Analyzer.Run times</h2>
-<ul>{{range .AnalyzerRunTimes}}<li>{{.Duration}} {{.Label}}</li>{{end}}</ul>
+<h2>Analyzer.Run statistics</h2>
+<p>As analyzers run in parallel, CPU and allocation figures are approximate.
+Also available as <a href="/analysis/profile.json">JSON</a>.</p>
+<table>
+<tr><th>Analyzer</th><th>Packages</th><th>Wall</th><th>CPU</th><th>Allocs</th><th>Alloc bytes</th><th>Facts</th><th>Fact bytes</th><th>Diagnostics</th></tr>
+{{range .AnalyzerStats}}<tr><td>{{.Analyzer}}</td><td>{{.Packages}}</td><td>{{.Wall}}</td><td>{{.CPU}}</td><td>{{.Allocs}}</td><td>{{.AllocBytes}}</td><td>{{.Facts}}</td><td>{{.FactBytes}}</td><td>{{.Diagnostics}}</td></tr>
+{{end}}</table>
 {{end}}
 `))
 
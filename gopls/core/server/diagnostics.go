@@ -359,6 +359,13 @@ func (s *server) diagnose(ctx context.Context, snapshot *cache.Snapshot) (diagMa
 	}
 	store("diagnosing go.mod file", modReports, modErr)
 
+	// Diagnose analyzer configuration file.
+	configReports, configErr := snapshot.AnalysisConfigDiagnostics(ctx)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	store("diagnosing analyzer configuration file", configReports, configErr)
+
 	// Diagnose go.mod upgrades.
 	upgradeReports, upgradeErr := mod.UpgradeDiagnostics(ctx, snapshot)
 	if ctx.Err() != nil {
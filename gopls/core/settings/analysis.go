@@ -7,8 +7,10 @@ package settings
 import (
 	"github.com/troll-zhao/tools/gopls/core/analysis/deprecated"
 	"github.com/troll-zhao/tools/gopls/core/analysis/embeddirective"
+	"github.com/troll-zhao/tools/gopls/core/analysis/exhaustive"
 	"github.com/troll-zhao/tools/gopls/core/analysis/fillreturns"
 	"github.com/troll-zhao/tools/gopls/core/analysis/infertypeargs"
+	"github.com/troll-zhao/tools/gopls/core/analysis/modernize"
 	"github.com/troll-zhao/tools/gopls/core/analysis/nonewvars"
 	"github.com/troll-zhao/tools/gopls/core/analysis/norangeoverfunc"
 	"github.com/troll-zhao/tools/gopls/core/analysis/noresultvalues"
@@ -19,6 +21,7 @@ import (
 	"github.com/troll-zhao/tools/gopls/core/analysis/unusedparams"
 	"github.com/troll-zhao/tools/gopls/core/analysis/unusedvariable"
 	"github.com/troll-zhao/tools/gopls/core/analysis/useany"
+	"github.com/troll-zhao/tools/gopls/core/analysis/yield"
 	"github.com/troll-zhao/tools/gopls/core/protocol"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/appends"
@@ -178,12 +181,14 @@ func init() {
 		{analyzer: atomicalign.Analyzer, enabled: true},
 		{analyzer: deepequalerrors.Analyzer, enabled: true},
 		{analyzer: nilness.Analyzer, enabled: true}, // uses go/ssa
+		{analyzer: yield.Analyzer, enabled: true},   // uses go/ssa
 		{analyzer: sortslice.Analyzer, enabled: true},
 		{analyzer: embeddirective.Analyzer, enabled: true},
 
 		// disabled due to high false positives
-		{analyzer: shadow.Analyzer, enabled: false}, // very noisy
-		{analyzer: useany.Analyzer, enabled: false}, // never a bug
+		{analyzer: shadow.Analyzer, enabled: false},     // very noisy
+		{analyzer: useany.Analyzer, enabled: false},     // never a bug
+		{analyzer: exhaustive.Analyzer, enabled: false}, // opt-in; see also fillswitch
 		// fieldalignment is not even off-by-default; see #67762.
 
 		// "simplifiers": analyzers that offer mere style fixes
@@ -193,6 +198,7 @@ func init() {
 		{analyzer: simplifyslice.Analyzer, enabled: true, actionKinds: []protocol.CodeActionKind{protocol.SourceFixAll, protocol.QuickFix}},
 		// other simplifiers:
 		{analyzer: infertypeargs.Analyzer, enabled: true, severity: protocol.SeverityHint},
+		{analyzer: modernize.Analyzer, enabled: true, severity: protocol.SeverityHint},
 		{analyzer: unusedparams.Analyzer, enabled: true},
 		{analyzer: unusedwrite.Analyzer, enabled: true}, // uses go/ssa
 
@@ -7,6 +7,7 @@ package misc
 import (
 	"testing"
 
+	"github.com/troll-zhao/tools/gopls/core/protocol"
 	. "github.com/troll-zhao/tools/gopls/core/test/integration"
 
 	"github.com/troll-zhao/tools/core/testenv"
@@ -253,3 +254,90 @@ func TestDeprecatedSettings(t *testing.T) {
 		)
 	})
 }
+
+// Test that the shared analyzer configuration file in the view root
+// controls the enabled set, severities, and excluded files.
+func TestAnalyzerConfigFile(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- goanalysis.json --
+{
+	"analyzers": {
+		"nilness": {"severity": "error"},
+		"shadow": {"enabled": true}
+	},
+	"exclude": [{"path": "gen/...", "analyzers": ["nilness"]}]
+}
+-- a/a.go --
+package a
+
+func _(err error) {
+	var x *int
+	y := *x
+	_ = y
+	if true {
+		err := err
+		_ = err
+	}
+}
+-- gen/b.go --
+package gen
+
+func _() {
+	var x *int
+	y := *x
+	_ = y
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		env.OpenFile("gen/b.go")
+		env.AfterChange(
+			Diagnostics(
+				env.AtRegexp("a/a.go", `\*x`),
+				WithSeverityTags("nilness", protocol.SeverityError, nil),
+			),
+			Diagnostics(
+				ForFile("a/a.go"),
+				WithMessage("shadows declaration"),
+			),
+			NoDiagnostics(ForFile("gen/b.go")),
+		)
+
+		// The "analyses" setting takes precedence over the file.
+		cfg := env.Editor.Config()
+		cfg.Settings = map[string]any{
+			"analyses": map[string]any{"shadow": false},
+		}
+		env.ChangeConfiguration(cfg)
+		env.AfterChange(
+			NoDiagnostics(ForFile("a/a.go"), WithMessage("shadows declaration")),
+		)
+
+		// Edits to the file take effect without a restart,
+		// and analyzer flags, which gopls ignores, are reported.
+		env.WriteWorkspaceFile("goanalysis.json", `{
+	"analyzers": {
+		"nilness": {"severity": "warning"},
+		"printf": {"flags": {"funcs": "Logf"}}
+	}
+}`)
+		env.AfterChange(
+			Diagnostics(
+				env.AtRegexp("a/a.go", `\*x`),
+				WithSeverityTags("nilness", protocol.SeverityWarning, nil),
+			),
+			Diagnostics(
+				ForFile("gen/b.go"),
+				WithMessage("nil dereference"),
+			),
+			Diagnostics(
+				env.AtRegexp("goanalysis.json", `"flags"`),
+				WithMessage("flags of analyzer printf are ignored"),
+			),
+		)
+	})
+}